@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"log/slog"
+	"net"
+	"time"
+)
+
+// Field names of the Lagoon schema conventions. Dotted names are expanded
+// into objects by Elasticsearch, so fields sharing a prefix end up together
+// without the duplicate keys separate slog groups of the same name produce.
+const (
+	FieldUserID         = "user.id"
+	FieldHTTPMethod     = "http.method"
+	FieldHTTPPath       = "http.path"
+	FieldHTTPRoute      = "http.route"
+	FieldHTTPStatusCode = "http.status_code"
+	FieldHTTPBytes      = "http.response_bytes"
+	FieldRemoteAddr     = "http.remote_addr"
+	FieldRequestID      = "request_id"
+	FieldDurationMS     = "duration_ms"
+	FieldDurationBucket = "duration_bucket"
+	FieldError          = "error"
+	FieldTraceID        = "trace_id"
+	FieldSpanID         = "span_id"
+	FieldPanic          = "panic"
+	FieldStack          = "stack"
+	FieldVerbosity      = "v"
+)
+
+// User identifies the user an event concerns
+func User(id string) slog.Attr {
+	return slog.String(FieldUserID, id)
+}
+
+// HTTPMethod is the method of an HTTP request
+func HTTPMethod(method string) slog.Attr {
+	return slog.String(FieldHTTPMethod, method)
+}
+
+// HTTPPath is the path of an HTTP request
+func HTTPPath(path string) slog.Attr {
+	return slog.String(FieldHTTPPath, path)
+}
+
+// HTTPRoute is the route pattern that matched an HTTP request, e.g.
+// "/users/{id}", which unlike the path has few distinct values
+func HTTPRoute(pattern string) slog.Attr {
+	return slog.String(FieldHTTPRoute, pattern)
+}
+
+// HTTPStatus is the status code of an HTTP response
+func HTTPStatus(code int) slog.Attr {
+	return slog.Int(FieldHTTPStatusCode, code)
+}
+
+// HTTPBytes is the size of the body of an HTTP response
+func HTTPBytes(n int64) slog.Attr {
+	return slog.Int64(FieldHTTPBytes, n)
+}
+
+// RemoteAddr is the address of the client of an HTTP request, without its
+// port
+func RemoteAddr(addr string) slog.Attr {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return slog.String(FieldRemoteAddr, addr)
+}
+
+// RequestID correlates the events of a single request
+func RequestID(id string) slog.Attr {
+	return slog.String(FieldRequestID, id)
+}
+
+// DurationMS is an elapsed time in whole milliseconds, which unlike
+// slog.Duration's nanoseconds reads naturally in Kibana
+func DurationMS(d time.Duration) slog.Attr {
+	return slog.Int64(FieldDurationMS, d.Milliseconds())
+}
+
+// Err is the message of err, or an empty attribute slog omits when err is nil
+func Err(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+	return slog.String(FieldError, err.Error())
+}
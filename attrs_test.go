@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestAttrHelpers(t *testing.T) {
+	tests := []struct {
+		name     string
+		attr     slog.Attr
+		key      string
+		expected any
+	}{
+		{"User", User("42"), "user.id", "42"},
+		{"HTTPMethod", HTTPMethod("GET"), "http.method", "GET"},
+		{"HTTPPath", HTTPPath("/users/42"), "http.path", "/users/42"},
+		{"HTTPRoute", HTTPRoute("/users/{id}"), "http.route", "/users/{id}"},
+		{"HTTPStatus", HTTPStatus(404), "http.status_code", int64(404)},
+		{"RequestID", RequestID("abc"), "request_id", "abc"},
+		{"DurationMS", DurationMS(1500 * time.Millisecond), "duration_ms", int64(1500)},
+		{"Err", Err(errors.New("boom")), "error", "boom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.attr.Key != tt.key {
+				t.Errorf("%s() key = %q, want %q", tt.name, tt.attr.Key, tt.key)
+			}
+			if got := tt.attr.Value.Any(); got != tt.expected {
+				t.Errorf("%s() value = %v (%T), want %v (%T)", tt.name, got, got, tt.expected, tt.expected)
+			}
+		})
+	}
+}
+
+func TestErr_Nil(t *testing.T) {
+	if attr := Err(nil); !attr.Equal(slog.Attr{}) {
+		t.Errorf("Err(nil) = %v, want an empty attribute", attr)
+	}
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"runtime"
+	"time"
+)
+
+const (
+	// tuneWindow is how long an adaptive batcher observes before retuning
+	tuneWindow = time.Second
+	// busyCPU is the share of the available CPU above which batches are
+	// made as large as the latency target allows, to save writes
+	busyCPU = 0.5
+)
+
+// batchTuner adapts the size and interval of a batchWriter to the observed
+// throughput and CPU usage, so records are written within the target
+// latency. It is guarded by the mutex of the batchWriter.
+type batchTuner struct {
+	target  time.Duration
+	maxSize int
+	cpu     func() (time.Duration, bool)
+	now     func() time.Time
+
+	// the observations of the current window
+	start        time.Time
+	cpuStart     time.Duration
+	records      int
+	writes       int
+	longestWrite time.Duration
+}
+
+// newBatchTuner returns a tuner keeping the latency under target with
+// batches of up to maxSize records
+func newBatchTuner(target time.Duration, maxSize int) *batchTuner {
+	t := &batchTuner{target: target, maxSize: maxSize, cpu: processCPUTime, now: time.Now}
+	t.reset()
+	return t
+}
+
+// reset starts a new window
+func (t *batchTuner) reset() {
+	t.start = t.now()
+	t.cpuStart, _ = t.cpu()
+	t.records, t.writes, t.longestWrite = 0, 0, 0
+}
+
+// flushed observes a batch of records whose write started at started
+func (t *batchTuner) flushed(records int, started time.Time) {
+	t.records += records
+	t.writes++
+	t.longestWrite = max(t.longestWrite, t.now().Sub(started))
+}
+
+// tune returns the batch size and interval for the next window once the
+// current one is over, false before. A record waits for the interval at
+// most and is then written, so the interval is what remains of the target
+// after the longest write. While the CPU is idle, half of that is used,
+// trading more writes for lower latency. The size holds the records
+// expected during an interval.
+func (t *batchTuner) tune() (int, time.Duration, bool) {
+	elapsed := t.now().Sub(t.start)
+	if elapsed < tuneWindow || t.writes == 0 {
+		return 0, 0, false
+	}
+	defer t.reset()
+
+	interval := max(t.target-t.longestWrite, t.target/10)
+	if busy, ok := t.busy(elapsed); !ok || busy < busyCPU {
+		interval /= 2
+	}
+	interval = max(interval, time.Millisecond)
+
+	rate := float64(t.records) / elapsed.Seconds()
+	size := min(max(int(rate*interval.Seconds()), 1), t.maxSize)
+
+	trace("Tuned batching", "batch_size", size, "batch_interval", interval, "records_per_second", int(rate), "longest_write", t.longestWrite)
+	return size, interval, true
+}
+
+// busy returns the share of the available CPU the process used during the
+// window, false when it is unknown
+func (t *batchTuner) busy(elapsed time.Duration) (float64, bool) {
+	used, ok := t.cpu()
+	if !ok {
+		return 0, false
+	}
+	return (used - t.cpuStart).Seconds() / (elapsed.Seconds() * float64(runtime.GOMAXPROCS(0))), true
+}
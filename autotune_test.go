@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// fakeTuner returns a tuner on a fake clock, whose CPU usage over a window
+// is busy of the available CPU
+func fakeTuner(target time.Duration, maxSize int, busy float64) (*batchTuner, *time.Time) {
+	now := time.Unix(0, 0)
+	t := &batchTuner{target: target, maxSize: maxSize, now: func() time.Time { return now }}
+	t.cpu = func() (time.Duration, bool) {
+		elapsed := now.Sub(time.Unix(0, 0))
+		return time.Duration(float64(elapsed) * busy * float64(runtime.GOMAXPROCS(0))), true
+	}
+	t.reset()
+	return t, &now
+}
+
+func TestBatchTuner(t *testing.T) {
+	tests := []struct {
+		name     string
+		busy     float64
+		write    time.Duration
+		records  int // per window
+		size     int
+		interval time.Duration
+	}{
+		{"busy CPU uses the whole target", 0.9, 10 * time.Millisecond, 1000, 90, 90 * time.Millisecond},
+		{"idle CPU halves the latency", 0.1, 10 * time.Millisecond, 1000, 45, 45 * time.Millisecond},
+		{"slow writes leave a tenth of the target", 0.9, 200 * time.Millisecond, 1000, 10, 10 * time.Millisecond},
+		{"low throughput writes every record", 0.9, 0, 5, 1, 100 * time.Millisecond},
+		{"high throughput is capped", 0.9, 0, 100000, 500, 100 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tuner, now := fakeTuner(100*time.Millisecond, 500, tt.busy)
+
+			started := *now
+			*now = now.Add(tt.write)
+			tuner.flushed(tt.records, started)
+			if _, _, ok := tuner.tune(); ok {
+				t.Fatal("tune() retuned before the window was over")
+			}
+
+			*now = time.Unix(0, 0).Add(tuneWindow)
+			size, interval, ok := tuner.tune()
+			if !ok {
+				t.Fatal("tune() did not retune after the window")
+			}
+			if size != tt.size || interval != tt.interval {
+				t.Errorf("tune() = %d records every %v, want %d every %v", size, interval, tt.size, tt.interval)
+			}
+			if tuner.writes != 0 {
+				t.Error("tune() did not start a new window")
+			}
+		})
+	}
+}
+
+func TestBatchWriter_Tuned(t *testing.T) {
+	w := &recordingConn{}
+	b := newBatchWriter(w, ProtocolTCP, 100, time.Hour)
+	tuner, now := fakeTuner(100*time.Millisecond, 100, 0.9)
+	b.tuner = tuner
+
+	// a window of 20 records per second
+	for i := 0; i < 20; i++ {
+		b.Write([]byte("record\n"))
+	}
+	*now = now.Add(tuneWindow)
+	b.Close()
+
+	if b.size != 2 || b.interval != 100*time.Millisecond {
+		t.Errorf("retuned to %d records every %v, want 2 every 100ms", b.size, b.interval)
+	}
+}
@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// udpBatchBytes is the largest batch sent in one UDP datagram, the
+	// payload fitting an Ethernet MTU of 1500 bytes after the IPv4 and UDP
+	// headers, so batches are never fragmented
+	udpBatchBytes = 1472
+	// streamBatchBytes caps a batch written to a TCP connection
+	streamBatchBytes = 64 << 10
+)
+
+// batchWriter coalesces newline-delimited records into batches written to w
+// in one call, once a batch holds size records, would outgrow maxBytes or
+// has waited for interval. A record larger than maxBytes is written alone.
+// With a tuner, size and interval adapt to the observed load.
+type batchWriter struct {
+	w        io.Writer
+	size     int
+	maxBytes int
+	interval time.Duration
+	tuner    *batchTuner
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	records int
+	timer   *time.Timer
+	closed  bool
+}
+
+// newBatchWriter returns a batchWriter writing to w with the limits of the
+// protocol
+func newBatchWriter(w io.Writer, protocol string, size int, interval time.Duration) *batchWriter {
+	maxBytes := udpBatchBytes
+	switch protocol {
+	case ProtocolTCP, ProtocolForward:
+		maxBytes = streamBatchBytes
+	case ProtocolHTTP, ProtocolOTLP:
+		maxBytes = httpBatchBytes
+	default:
+		if t, ok := registeredTransport(protocol); ok {
+			maxBytes = cmp.Or(t.BatchBytes, httpBatchBytes)
+		}
+	}
+	return &batchWriter{w: w, size: size, maxBytes: maxBytes, interval: interval}
+}
+
+func (b *batchWriter) Write(p []byte) (int, error) {
+	return b.writeContext(context.Background(), p)
+}
+
+// writeContext adds p to the batch, writing the batch with ctx when it is
+// complete. An error reports a failed batch, which may hold earlier records.
+func (b *batchWriter) writeContext(ctx context.Context, p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return writeContext(ctx, b.w, p)
+	}
+
+	if b.records > 0 && b.buf.Len()+len(p) > b.maxBytes {
+		if err := b.flush(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	b.buf.Write(p)
+	b.records++
+	if b.records >= b.size || b.buf.Len() >= b.maxBytes {
+		if err := b.flush(ctx); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.interval, b.flushTimer)
+	}
+	return len(p), nil
+}
+
+// flush writes the pending batch, the caller holds mu
+func (b *batchWriter) flush(ctx context.Context) error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if b.records == 0 {
+		return nil
+	}
+
+	var started time.Time
+	if b.tuner != nil {
+		started = b.tuner.now()
+	}
+	fctx, flushing := startPhase(ctx, phaseFlush, "")
+	_, err := writeContext(fctx, b.w, b.buf.Bytes())
+	flushing.end()
+	if b.tuner != nil {
+		b.tuner.flushed(b.records, started)
+		if size, interval, ok := b.tuner.tune(); ok {
+			b.size, b.interval = size, interval
+		}
+	}
+	b.buf.Reset()
+	b.records = 0
+	return err
+}
+
+// flushTimer writes a batch that waited for the interval
+func (b *batchWriter) flushTimer() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_ = b.flush(context.Background())
+}
+
+// Close writes the pending batch and closes w, later records are written
+// to w unbatched
+func (b *batchWriter) Close() error {
+	b.mu.Lock()
+	err := b.flush(context.Background())
+	b.closed = true
+	b.mu.Unlock()
+
+	if closer, ok := b.w.(io.Closer); ok {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// abort makes a delivery pool behind the batcher give up on its records
+func (b *batchWriter) abort() {
+	if a, ok := b.w.(interface{ abort() }); ok {
+		a.abort()
+	}
+}
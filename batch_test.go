@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+func TestBatchWriter_Size(t *testing.T) {
+	conn := &recordingConn{}
+	b := newBatchWriter(conn, ProtocolTCP, 3, time.Hour)
+
+	for i := 0; i < 4; i++ {
+		fmt.Fprintf(b, "{\"n\":%d}\n", i)
+	}
+
+	if got := conn.delivered(); len(got) != 1 || got[0] != "{\"n\":0}\n{\"n\":1}\n{\"n\":2}\n" {
+		t.Errorf("delivered %q, want the first three records in one write", got)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() returned unexpected error: %v", err)
+	}
+	if got := conn.delivered(); len(got) != 2 || got[1] != "{\"n\":3}\n" {
+		t.Errorf("delivered %q, want Close to flush the last record", got)
+	}
+	if !conn.closed {
+		t.Error("Close() should close the underlying writer")
+	}
+}
+
+func TestBatchWriter_UDPFitsDatagram(t *testing.T) {
+	conn := &recordingConn{}
+	b := newBatchWriter(conn, ProtocolUDP, 100, time.Hour)
+
+	record := strings.Repeat("x", 599) + "\n"
+	for i := 0; i < 3; i++ {
+		_, _ = b.Write([]byte(record))
+	}
+	// larger than a datagram on its own
+	_, _ = b.Write([]byte(strings.Repeat("y", udpBatchBytes+1)))
+	_ = b.Close()
+
+	got := conn.delivered()
+	if len(got) != 3 {
+		t.Fatalf("delivered %d writes, want 3", len(got))
+	}
+	if len(got[0]) != 2*len(record) || len(got[1]) != len(record) {
+		t.Errorf("batch sizes = %d, %d, want two records then one", len(got[0]), len(got[1]))
+	}
+	if len(got[2]) != udpBatchBytes+1 {
+		t.Errorf("oversized record written as %d bytes, want it alone", len(got[2]))
+	}
+}
+
+func TestBatchWriter_Interval(t *testing.T) {
+	conn := &recordingConn{}
+	b := newBatchWriter(conn, ProtocolTCP, 100, 20*time.Millisecond)
+	defer b.Close()
+
+	_, _ = b.Write([]byte("{}\n"))
+	_, _ = b.Write([]byte("{}\n"))
+
+	deadline := time.Now().Add(time.Second)
+	for len(conn.delivered()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := conn.delivered(); len(got) != 1 || got[0] != "{}\n{}\n" {
+		t.Errorf("delivered %q, want both records flushed by the timer", got)
+	}
+}
+
+func TestInitialize_Batching(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	receiver, err := loggertest.Listen(loggertest.UDP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer receiver.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "batch-type"
+	cfg.LogHost = receiver.Host()
+	cfg.LogPort = receiver.Port()
+	cfg.BatchSize = 10
+	cfg.BatchInterval = 20 * time.Millisecond
+
+	handler, err := NewHandler(cfg)
+	if err != nil {
+		t.Fatalf("NewHandler() returned unexpected error: %v", err)
+	}
+	logger := slog.New(handler)
+	for i := 0; i < 25; i++ {
+		logger.Info("batched", "seq", i)
+	}
+
+	if !receiver.Wait(25, time.Second) {
+		t.Fatalf("received %d records, want 25", receiver.Count())
+	}
+	for i, event := range receiver.Events() {
+		if event["seq"] != float64(i) {
+			t.Errorf("event %d = %v, want seq %d", i, event, i)
+		}
+	}
+}
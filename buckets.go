@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultDurationBuckets are the upper bounds of the duration_bucket values of
+// access records
+var defaultDurationBuckets = []time.Duration{100 * time.Millisecond, 500 * time.Millisecond, time.Second}
+
+// WithDurationBuckets replaces the upper bounds of the duration_bucket field
+// of access records, by default 100ms, 500ms and 1s. Without bounds the field
+// is left out.
+func WithDurationBuckets(bounds ...time.Duration) MiddlewareOption {
+	bounds = slices.Clone(bounds)
+	slices.Sort(bounds)
+	return func(m *middleware) { m.buckets = bounds }
+}
+
+// DurationBucket names the range among bounds, sorted ascending, that d falls
+// in, e.g. "<100ms", "100-500ms" or ">1s", so Kibana terms aggregations work
+// without range queries
+func DurationBucket(d time.Duration, bounds []time.Duration) slog.Attr {
+	return slog.String(FieldDurationBucket, durationBucket(d, bounds))
+}
+
+func durationBucket(d time.Duration, bounds []time.Duration) string {
+	if len(bounds) == 0 {
+		return ""
+	}
+
+	// a bound belongs to the bucket it starts
+	i, found := slices.BinarySearch(bounds, d)
+	if found {
+		i++
+	}
+
+	switch {
+	case i == 0:
+		return "<" + bounds[0].String()
+	case i == len(bounds):
+		return ">" + bounds[len(bounds)-1].String()
+	}
+
+	lower, upper := bounds[i-1].String(), bounds[i].String()
+	// write "100-500ms" rather than "100ms-500ms" when the units match
+	if unit := durationUnit(lower); unit == durationUnit(upper) {
+		lower = strings.TrimSuffix(lower, unit)
+	}
+	return lower + "-" + upper
+}
+
+// durationUnit returns the unit suffix of a formatted duration, such as "ms"
+// of "500ms", or the whole compound form "1m30s" does not have a single unit
+func durationUnit(formatted string) string {
+	unit := strings.TrimLeft(formatted, "0123456789.")
+	if strings.ContainsAny(unit, "0123456789") {
+		return ""
+	}
+	return unit
+}
@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDurationBucket(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		bounds   []time.Duration
+		expected string
+	}{
+		{50 * time.Millisecond, defaultDurationBuckets, "<100ms"},
+		{100 * time.Millisecond, defaultDurationBuckets, "100-500ms"},
+		{499 * time.Millisecond, defaultDurationBuckets, "100-500ms"},
+		{750 * time.Millisecond, defaultDurationBuckets, "500ms-1s"},
+		{time.Second, defaultDurationBuckets, ">1s"},
+		{time.Minute, []time.Duration{time.Second, 5 * time.Second}, ">5s"},
+		{90 * time.Second, []time.Duration{time.Minute, 2*time.Minute + 30*time.Second}, "1m0s-2m30s"},
+		{time.Second, nil, ""},
+	}
+
+	for _, tt := range tests {
+		attr := DurationBucket(tt.d, tt.bounds)
+		if attr.Key != FieldDurationBucket {
+			t.Errorf("DurationBucket() key = %q, want %q", attr.Key, FieldDurationBucket)
+		}
+		if got := attr.Value.String(); got != tt.expected {
+			t.Errorf("DurationBucket(%v, %v) = %q, want %q", tt.d, tt.bounds, got, tt.expected)
+		}
+	}
+}
+
+func TestHTTPMiddleware_DurationBuckets(t *testing.T) {
+	record := serve(t, statusHandler(http.StatusOK), "/")
+	if record[FieldDurationBucket] != "<100ms" {
+		t.Errorf("%s = %v, want <100ms", FieldDurationBucket, record[FieldDurationBucket])
+	}
+
+	record = serve(t, statusHandler(http.StatusOK), "/", WithDurationBuckets(time.Nanosecond))
+	if record[FieldDurationBucket] != ">1ns" {
+		t.Errorf("%s = %v, want >1ns", FieldDurationBucket, record[FieldDurationBucket])
+	}
+
+	record = serve(t, statusHandler(http.StatusOK), "/", WithDurationBuckets())
+	if _, ok := record[FieldDurationBucket]; ok {
+		t.Errorf("%s should be left out without bounds", FieldDurationBucket)
+	}
+}
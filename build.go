@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Build-time defaults, set by platform base images with
+//
+//	go build -ldflags "-X github.com/salsadigitalauorg/go-lagoon-log-forwarder.buildLogHost=logs.cluster.local"
+//
+// so services log to the cluster's endpoint without code changes. Values set
+// by the application always win.
+var (
+	// buildLogHost is the LogHost of NewConfig
+	buildLogHost string
+	// buildLogPort is the LogPort of NewConfig, 5140 when empty
+	buildLogPort string
+	// buildLogTypePrefix is prepended to every LogType that does not already
+	// start with it
+	buildLogTypePrefix string
+)
+
+// defaultLogPort returns the LogPort of NewConfig. An invalid build-time port
+// is a broken build, which panics rather than logging to the wrong endpoint.
+func defaultLogPort() int {
+	if len(buildLogPort) == 0 {
+		return 5140
+	}
+
+	port, err := strconv.Atoi(buildLogPort)
+	if err != nil || port < 1 || port > 65535 {
+		panic(fmt.Sprintf("logger: invalid build-time log port %q", buildLogPort))
+	}
+	return port
+}
+
+// prefixLogType applies the build-time type prefix to logType
+func prefixLogType(logType string) string {
+	if len(logType) == 0 || strings.HasPrefix(logType, buildLogTypePrefix) {
+		return logType
+	}
+	return buildLogTypePrefix + logType
+}
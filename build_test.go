@@ -0,0 +1,71 @@
+package logger
+
+import "testing"
+
+// setBuildDefaults overrides the build-time defaults for the duration of t
+func setBuildDefaults(t *testing.T, host, port, typePrefix string) {
+	t.Helper()
+	originalHost, originalPort, originalPrefix := buildLogHost, buildLogPort, buildLogTypePrefix
+	t.Cleanup(func() {
+		buildLogHost, buildLogPort, buildLogTypePrefix = originalHost, originalPort, originalPrefix
+	})
+	buildLogHost, buildLogPort, buildLogTypePrefix = host, port, typePrefix
+}
+
+func TestNewConfig_BuildDefaults(t *testing.T) {
+	setBuildDefaults(t, "logs.cluster.local", "5170", "")
+
+	cfg := NewConfig()
+	if cfg.LogHost != "logs.cluster.local" {
+		t.Errorf("NewConfig().LogHost = %q, want %q", cfg.LogHost, "logs.cluster.local")
+	}
+	if cfg.LogPort != 5170 {
+		t.Errorf("NewConfig().LogPort = %d, want %d", cfg.LogPort, 5170)
+	}
+}
+
+func TestNewConfig_InvalidBuildPort(t *testing.T) {
+	for _, port := range []string{"udp", "0", "70000"} {
+		t.Run(port, func(t *testing.T) {
+			setBuildDefaults(t, "", port, "")
+
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewConfig() with build-time port %q did not panic", port)
+				}
+			}()
+			NewConfig()
+		})
+	}
+}
+
+func TestConfig_BuildTypePrefix(t *testing.T) {
+	preserveConfig(t)
+	setBuildDefaults(t, "", "", "au2-")
+
+	tests := []struct {
+		logType string
+		want    string
+	}{
+		{"shop-main", "au2-shop-main"},
+		{"au2-shop-main", "au2-shop-main"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.logType, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.LogType = tt.logType
+			if err := config(cfg); err != nil {
+				t.Fatalf("config() returned unexpected error: %v", err)
+			}
+			if logType != tt.want {
+				t.Errorf("logType = %q, want %q", logType, tt.want)
+			}
+		})
+	}
+
+	cfg := NewConfig()
+	if err := config(cfg); err == nil {
+		t.Error("config() expected error for empty LogType despite the prefix")
+	}
+}
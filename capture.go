@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// stderrFile is where the diagnostics are written, the stderr of the process
+// before CaptureStderr redirected it, so they never loop through the capture
+var stderrFile atomic.Pointer[os.File]
+
+// diagnosticsOutput writes to stderrFile
+type diagnosticsOutput struct{}
+
+func (diagnosticsOutput) Write(p []byte) (int, error) {
+	if f := stderrFile.Load(); f != nil {
+		return f.Write(p)
+	}
+	return os.Stderr.Write(p)
+}
+
+// streamKey names the stream a captured record was read from
+const streamKey = "stream"
+
+// captureStderr redirects the stderr of the process, including writes of the
+// runtime and of other libraries to file descriptor 2, into records handled
+// by h until ctx is done. Crash reports of the runtime, which end the process
+// before they can be forwarded, are also written to the original stderr.
+func captureStderr(ctx context.Context, h slog.Handler) {
+	r, original, restore, err := redirectStderr()
+	if err != nil {
+		diag().Warn("Failed to capture stderr", "error", err)
+		return
+	}
+	stderrFile.Store(original)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		readStderr(r, h)
+	}()
+
+	<-ctx.Done()
+	if err := restore(); err != nil {
+		diag().Warn("Failed to restore stderr", "error", err)
+	}
+	// restore closed the write end, the records left are read to the end
+	<-done
+	_ = r.Close()
+	stderrFile.Store(nil)
+	_ = original.Close()
+}
+
+// readStderr logs the lines read from r to h until it is closed. A write of
+// several lines whose following lines are indented, such as a stack trace,
+// is logged as one record.
+func readStderr(r io.Reader, h slog.Handler) {
+	buf := make([]byte, 64*1024)
+	var partial []byte
+	for {
+		n, err := r.Read(buf)
+		data := append(partial, buf[:n]...)
+		end := bytes.LastIndexByte(data, '\n') + 1
+		if err != nil {
+			end = len(data)
+		}
+		for _, text := range stderrRecords(string(data[:end])) {
+			logStderr(h, text)
+		}
+		partial = append([]byte(nil), data[end:]...)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// stderrRecords splits the lines of text into records, appending indented
+// and blank lines to the record before them. A goroutine trace, such as
+// that of debug.PrintStack, runs to the end of text: its function lines
+// aren't indented.
+func stderrRecords(text string) []string {
+	var records []string
+	var current []string
+	trace := false
+	for line := range strings.Lines(text) {
+		line = strings.TrimRight(line, "\r\n")
+		trace = trace || strings.HasPrefix(line, "goroutine ")
+		continued := len(current) > 0 && (trace || len(line) == 0 || line[0] == ' ' || line[0] == '\t')
+		if !continued && len(current) > 0 {
+			records = append(records, strings.TrimRight(strings.Join(current, "\n"), "\n"))
+			current = nil
+		}
+		if len(line) > 0 || len(current) > 0 {
+			current = append(current, line)
+		}
+	}
+	if len(current) > 0 {
+		records = append(records, strings.TrimRight(strings.Join(current, "\n"), "\n"))
+	}
+	return records
+}
+
+// logStderr logs a record read from stderr, at LevelCritical when it
+// reports a panic and slog.LevelWarn otherwise
+func logStderr(h slog.Handler, text string) {
+	level := slog.LevelWarn
+	if strings.HasPrefix(text, "panic: ") || strings.HasPrefix(text, "fatal error: ") {
+		level = LevelCritical
+	}
+	ctx := context.Background()
+	if !h.Enabled(ctx, level) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), level, text, 0)
+	r.AddAttrs(slog.String(streamKey, "stderr"))
+	_ = h.Handle(ctx, r)
+}
@@ -0,0 +1,41 @@
+//go:build linux
+
+package logger
+
+import (
+	"os"
+	"runtime/debug"
+	"syscall"
+)
+
+// redirectStderr points file descriptor 2 at a pipe, returning its read end,
+// the original stderr and a function pointing it back and closing the write
+// end
+func redirectStderr() (*os.File, *os.File, func() error, error) {
+	fd, err := syscall.Dup(2)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	original := os.NewFile(uintptr(fd), "/dev/stderr")
+	r, w, err := os.Pipe()
+	if err != nil {
+		_ = original.Close()
+		return nil, nil, nil, err
+	}
+	if err := syscall.Dup3(int(w.Fd()), 2, 0); err != nil {
+		_ = original.Close()
+		_ = r.Close()
+		_ = w.Close()
+		return nil, nil, nil, err
+	}
+	// the runtime exits right after a crash report, before it is read
+	_ = debug.SetCrashOutput(original, debug.CrashOptions{})
+
+	restore := func() error {
+		_ = debug.SetCrashOutput(nil, debug.CrashOptions{})
+		err := syscall.Dup3(int(original.Fd()), 2, 0)
+		_ = w.Close()
+		return err
+	}
+	return r, original, restore, nil
+}
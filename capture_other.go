@@ -0,0 +1,13 @@
+//go:build !linux
+
+package logger
+
+import (
+	"errors"
+	"os"
+)
+
+// redirectStderr is only supported on Linux, where Lagoon runs
+func redirectStderr() (*os.File, *os.File, func() error, error) {
+	return nil, nil, nil, errors.New("capturing stderr is only supported on linux")
+}
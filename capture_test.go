@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestStderrRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"lines", "first\nsecond\n", []string{"first", "second"}},
+		{"indented continuation", "error: bad config\n  at line 3\n\nnext\n", []string{"error: bad config\n  at line 3", "next"}},
+		{"trace", "goroutine 1 [running]:\nmain.main()\n\t/app/main.go:12 +0x1d\n", []string{"goroutine 1 [running]:\nmain.main()\n\t/app/main.go:12 +0x1d"}},
+		{"panic", "panic: boom\n\ngoroutine 7 [running]:\nmain.work()\n", []string{"panic: boom\n\ngoroutine 7 [running]:\nmain.work()"}},
+		{"leading blank lines", "\n\nwarning\n", []string{"warning"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stderrRecords(tt.text); fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("stderrRecords(%q) = %q, want %q", tt.text, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadStderr(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: replaceAttr})
+
+	readStderr(strings.NewReader("deprecated flag\npanic: boom\n\ngoroutine 1 [running]:\nmain.main()\nno newline"), h)
+
+	out := buf.String()
+	for _, want := range []string{
+		`"level":"WARN","message":"deprecated flag","stream":"stderr"`,
+		`"level":"CRITICAL","message":"panic: boom\n\ngoroutine 1 [running]:\nmain.main()"`,
+		`"message":"no newline"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output = %s, want %s", out, want)
+		}
+	}
+}
+
+func TestCaptureStderr(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("stderr is only captured on linux")
+	}
+	var captured capturedDiagnostics
+	h := slog.NewJSONHandler(&captured, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		captureStderr(ctx, h)
+		close(done)
+	}()
+	// wait for the redirection before writing
+	for stderrFile.Load() == nil {
+		runtime.Gosched()
+	}
+	fmt.Fprintln(os.Stderr, "written by a library")
+	cancel()
+	<-done
+
+	if !strings.Contains(captured.String(), `"msg":"written by a library","stream":"stderr"`) {
+		t.Errorf("captured = %s, want the write to stderr", captured.String())
+	}
+	if stderrFile.Load() != nil {
+		t.Error("the diagnostics still write to the captured stderr")
+	}
+}
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	// changelogMessage is the message of the event listing the settings
+	// changed at runtime
+	changelogMessage = "Configuration changed"
+	// changesKey lists the changes, e.g. "level: info→debug"
+	changesKey = "changes"
+	// changeSourceKey names what changed the configuration, e.g. "remote"
+	changeSourceKey = "config_source"
+)
+
+// change describes a setting changed from one value to another, empty when
+// the value is the same
+func change(name, from, to string) string {
+	if from == to {
+		return ""
+	}
+	return name + ": " + from + "→" + to
+}
+
+// levelName returns the lower case name of the level configured as name
+func levelName(name string) string {
+	// levels are validated before they are applied
+	level, _ := ParseLevel(name)
+	return strings.ToLower(levelString(level))
+}
+
+// logChanges writes an event listing the changes to h, skipping empty ones.
+// The event bypasses the level of h, so configuration drift stays auditable
+// from the logs even when the change raised the level.
+func logChanges(h slog.Handler, source string, changes ...string) {
+	var changed []string
+	for _, c := range changes {
+		if len(c) > 0 {
+			changed = append(changed, c)
+		}
+	}
+	if h == nil || len(changed) == 0 {
+		return
+	}
+
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, changelogMessage, 0)
+	r.AddAttrs(slog.Any(changesKey, changed), slog.String(changeSourceKey, source))
+	if err := h.Handle(context.Background(), r); err != nil {
+		diag().Warn("Failed to log configuration changes", "changes", changed, "error", err)
+	}
+}
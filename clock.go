@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock schedules the retries of the forwarder: reconnecting to an
+// unreachable endpoint, redelivering failed records, retrying HTTP requests
+// and probing the primary endpoint after a failover. Applications can set a
+// manual clock such as loggertest.ManualClock in their tests, to step through
+// failure handling without waiting for real backoff delays.
+type Clock interface {
+	Now() time.Time
+	// After sends the time on the channel once d has passed
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock of the time package
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// clockHolder keeps a Clock in an atomic.Value, which needs a concrete type
+type clockHolder struct {
+	Clock
+}
+
+// retryClock holds the Clock of the applied config, read by background
+// retries while a config may be applied
+var retryClock atomic.Value
+
+// configuredClock returns the Clock of the applied config, nil when it is
+// the system clock
+func configuredClock() Clock {
+	c, _ := retryClock.Load().(clockHolder)
+	return c.Clock
+}
+
+// schedulerClock returns the Clock retries are scheduled with
+func schedulerClock() Clock {
+	if c := configuredClock(); c != nil {
+		return c
+	}
+	return systemClock{}
+}
@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Keys of the records of a wrapped command
+const (
+	commandKey  = "command"
+	exitCodeKey = "exit_code"
+)
+
+// Cmd is an exec.Cmd whose output is logged, see WrapCmd
+type Cmd struct {
+	*exec.Cmd
+
+	attrs          []slog.Attr
+	stdout, stderr io.WriteCloser
+	start          time.Time
+}
+
+// WrapCmd logs the output of cmd with the default logger: every line of
+// stdout at slog.LevelInfo and of stderr at slog.LevelWarn, with stream and
+// command attributes and attrs. Once the command exits, Run and Wait log its
+// exit code and duration, at slog.LevelError when it failed. Cron jobs and
+// tasks shelling out so keep the output in the pipeline:
+//
+//	cmd := logger.WrapCmd(exec.Command("drush", "cron"), slog.String("job", "cron"))
+//	err := cmd.Run()
+//
+// Stdout and Stderr of cmd must not be set, so Output and CombinedOutput
+// can't be used.
+func WrapCmd(cmd *exec.Cmd, attrs ...slog.Attr) *Cmd {
+	attrs = slices.Concat([]slog.Attr{slog.String(commandKey, filepath.Base(cmd.Path))}, attrs)
+	c := &Cmd{
+		Cmd:    cmd,
+		attrs:  attrs,
+		stdout: Writer(slog.LevelInfo, slices.Concat(attrs, []slog.Attr{slog.String(streamKey, "stdout")})...).(io.WriteCloser),
+		stderr: Writer(slog.LevelWarn, slices.Concat(attrs, []slog.Attr{slog.String(streamKey, "stderr")})...).(io.WriteCloser),
+	}
+	cmd.Stdout, cmd.Stderr = c.stdout, c.stderr
+	return c
+}
+
+// Run starts the command and waits for it, like exec.Cmd.Run
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Start starts the command, like exec.Cmd.Start
+func (c *Cmd) Start() error {
+	c.start = time.Now()
+	return c.Cmd.Start()
+}
+
+// Wait waits for the command to exit, logs the last lines of its output
+// and its exit, like exec.Cmd.Wait
+func (c *Cmd) Wait() error {
+	err := c.Cmd.Wait()
+	_ = c.stdout.Close()
+	_ = c.stderr.Close()
+
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelError
+	}
+	ctx, h := context.Background(), slog.Default().Handler()
+	if h.Enabled(ctx, level) {
+		// the exit of the process has no source in the application
+		r := slog.NewRecord(time.Now(), level, "Command exited", 0)
+		r.AddAttrs(c.attrs...)
+		r.AddAttrs(slog.Int(exitCodeKey, c.ProcessState.ExitCode()), DurationMS(time.Since(c.start)))
+		if err != nil {
+			r.AddAttrs(Err(err))
+		}
+		_ = h.Handle(ctx, r)
+	}
+	return err
+}
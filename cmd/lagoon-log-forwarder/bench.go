@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/bits"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+)
+
+// benchResult summarizes a load generation run
+type benchResult struct {
+	elapsed time.Duration
+	target  int
+	sent    int
+	dropped int
+	// latency is how long the handler took to encode and write each event,
+	// which for UDP ends once the datagram is handed to the kernel
+	latency latencyHistogram
+}
+
+// bench generates synthetic events at a fixed rate against the configured
+// endpoint and reports achieved throughput, drops and handler latency
+func bench(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cf := addConfigFlags(fs)
+	rate := fs.Int("rate", 1000, "events per second to generate")
+	duration := fs.Duration("duration", 10*time.Second, "how long to generate events for")
+	size := fs.Int("size", 128, "approximate message size in bytes")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: lagoon-log-forwarder bench [flags]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *rate <= 0 || *duration <= 0 {
+		fmt.Fprintln(stderr, "error: --rate and --duration must be positive")
+		return 2
+	}
+
+	cfg, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	cfg.AddSource = false
+
+	conn, err := logger.Dial(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: connect to %s:%d: %v\n", cfg.LogHost, cfg.LogPort, err)
+		return 1
+	}
+	defer conn.Close()
+
+	w := &countingWriter{w: conn}
+	handler, err := logger.NewWriterHandler(cfg, w)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	result := generate(ctx, handler, w, *rate, strings.Repeat("x", *size))
+	result.report(stdout)
+
+	return 0
+}
+
+// generate emits events at rate until ctx is done
+func generate(ctx context.Context, handler slog.Handler, w *countingWriter, rate int, payload string) benchResult {
+	start := time.Now()
+	result := benchResult{}
+
+	for ctx.Err() == nil {
+		elapsed := time.Since(start)
+		due := int(elapsed.Seconds() * float64(rate))
+
+		for result.sent+result.dropped < due && ctx.Err() == nil {
+			record := slog.NewRecord(time.Now(), slog.LevelInfo, "bench event", 0)
+			record.AddAttrs(slog.Int("seq", result.sent+result.dropped), slog.String("payload", payload))
+
+			before := w.failures
+			began := time.Now()
+			_ = handler.Handle(ctx, record)
+			result.latency.record(time.Since(began))
+
+			if w.failures > before {
+				result.dropped++
+			} else {
+				result.sent++
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	result.elapsed = time.Since(start)
+	result.target = int(result.elapsed.Seconds() * float64(rate))
+	return result
+}
+
+func (r benchResult) report(w io.Writer) {
+	seconds := r.elapsed.Seconds()
+	fmt.Fprintf(w, "duration:   %s\n", r.elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "target:     %d events (%.0f/s)\n", r.target, float64(r.target)/seconds)
+	fmt.Fprintf(w, "sent:       %d events (%.0f/s)\n", r.sent, float64(r.sent)/seconds)
+	fmt.Fprintf(w, "dropped:    %d events\n", r.dropped)
+	fmt.Fprintf(w, "behind:     %d events\n", max(r.target-r.sent-r.dropped, 0))
+
+	if r.latency.count == 0 {
+		return
+	}
+	fmt.Fprintf(w, "handler latency: p50=%s p95=%s p99=%s max=%s\n",
+		r.latency.percentile(0.50), r.latency.percentile(0.95), r.latency.percentile(0.99), r.latency.max)
+}
+
+// latencySubBuckets is the number of buckets each power of two is split into,
+// bounding the error of a percentile to 1/latencySubBuckets
+const latencySubBuckets = 8
+
+// latencyHistogram counts durations in log-linear buckets, so a long run
+// takes constant memory
+type latencyHistogram struct {
+	counts [64 * latencySubBuckets]int
+	count  int
+	max    time.Duration
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	h.counts[latencyBucket(max(d, 0))]++
+	h.count++
+	h.max = max(h.max, d)
+}
+
+// percentile returns the upper bound of the bucket holding the p-th duration
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	rank := int(p * float64(h.count-1))
+	seen := 0
+	for i, n := range h.counts {
+		if seen += n; seen > rank {
+			return min(latencyBound(i), h.max)
+		}
+	}
+	return h.max
+}
+
+// latencyBucket returns the bucket of d: durations below 2*latencySubBuckets
+// nanoseconds have their own, larger ones share one with those of the same
+// power of two and leading bits
+func latencyBucket(d time.Duration) int {
+	if d < 2*latencySubBuckets {
+		return int(d)
+	}
+	shift := bits.Len64(uint64(d)) - bits.Len64(latencySubBuckets)
+	return shift*latencySubBuckets + int(d>>shift)
+}
+
+// latencyBound returns the largest duration of bucket i
+func latencyBound(i int) time.Duration {
+	if i < 2*latencySubBuckets {
+		return time.Duration(i)
+	}
+	shift := i/latencySubBuckets - 1
+	return time.Duration(i%latencySubBuckets+latencySubBuckets+1)<<shift - 1
+}
+
+// countingWriter counts failed writes to the endpoint
+type countingWriter struct {
+	w        io.Writer
+	failures int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	if err != nil {
+		c.failures++
+	}
+	return n, err
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBench(t *testing.T) {
+	_, port := listenUDP(t)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"bench", "--type=bench", "--host=127.0.0.1", "--port=" + port, "--rate=500", "--duration=200ms", "--size=16"}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("bench exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+
+	for _, want := range []string{"duration:", "sent:", "dropped:", "latency:"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("bench report missing %q in %q", want, stdout.String())
+		}
+	}
+}
+
+func TestBench_InvalidFlags(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"bench", "--type=bench", "--rate=0"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("bench with zero rate exit code = %d, want 2", code)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("unreachable") }
+
+func TestCountingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &countingWriter{w: &buf}
+	if _, err := w.Write([]byte("ok")); err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+	w.w = failingWriter{}
+	if _, err := w.Write([]byte("fail")); err == nil {
+		t.Error("Write() should return the underlying error")
+	}
+	if w.failures != 1 {
+		t.Errorf("countingWriter.failures = %d, want 1", w.failures)
+	}
+}
+
+func TestBenchResultReport(t *testing.T) {
+	r := benchResult{
+		elapsed: time.Second,
+		target:  10,
+		sent:    8,
+		dropped: 1,
+	}
+	for _, d := range []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond} {
+		r.latency.record(d)
+	}
+
+	var buf bytes.Buffer
+	r.report(&buf)
+
+	for _, want := range []string{"sent:       8 events", "dropped:    1 events", "behind:     1 events", "handler latency:", "max=3ms"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report() missing %q in %q", want, buf.String())
+		}
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	for d := time.Duration(0); d < time.Hour; d = d*9/8 + 1 {
+		i := latencyBucket(d)
+		if bound := latencyBound(i); d > bound || (i > 0 && d <= latencyBound(i-1)) {
+			t.Fatalf("latencyBucket(%v) = %d, whose bounds don't hold it", d, i)
+		}
+	}
+
+	var h latencyHistogram
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Microsecond)
+	}
+	for _, tt := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0.50, 500 * time.Microsecond},
+		{0.99, 990 * time.Microsecond},
+	} {
+		// a bucket is at most an eighth of its durations wide
+		if got := h.percentile(tt.p); got < tt.want || got > tt.want*9/8 {
+			t.Errorf("percentile(%v) = %v, want %v within 12.5%%", tt.p, got, tt.want)
+		}
+	}
+	if h.percentile(1) != time.Millisecond || h.max != time.Millisecond {
+		t.Errorf("percentile(1) = %v, max = %v, want 1ms", h.percentile(1), h.max)
+	}
+}
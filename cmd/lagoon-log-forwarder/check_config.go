@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+)
+
+// checkConfig validates a config file with the LOGGER_* environment applied,
+// optionally probes the configured endpoint and prints the effective config
+// as JSON on stdout
+func checkConfig(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("check-config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	online := fs.Bool("online", false, "resolve and dial the configured endpoint")
+	timeout := fs.Duration("timeout", 5*time.Second, "deadline for online probes")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: lagoon-log-forwarder check-config [--online] [--timeout=5s] <file>")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := logger.LoadConfigFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	// the effective config is the one the other commands run with
+	if cfg, err = cfg.FromEnv(); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(stderr, "error: invalid config: %v\n", err)
+		return 1
+	}
+
+	if len(cfg.LogHost) == 0 {
+		fmt.Fprintln(stderr, "warning: logHost is not supplied and will default to localhost")
+	}
+
+	if *online {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+
+		if err := probe(ctx, cfg, stderr); err != nil {
+			fmt.Fprintf(stderr, "error: endpoint probe failed: %v\n", err)
+			return 1
+		}
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(cfg); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// probe resolves the configured host and dials the endpoint, completing the
+// TLS handshake when TLS is configured, and reports each step on w. Only
+// stream protocols and sockets prove the endpoint is listening.
+func probe(ctx context.Context, cfg logger.Config, w io.Writer) error {
+	if cfg.Protocol == logger.ProtocolUnix {
+		// the forwarder writes datagrams to the socket at the path
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "unixgram", cfg.LogHost)
+		if err != nil {
+			return fmt.Errorf("dial unixgram %s: %w", cfg.LogHost, err)
+		}
+		fmt.Fprintf(w, "dialed unixgram %s\n", cfg.LogHost)
+		return conn.Close()
+	}
+
+	host := cfg.LogHost
+	if len(host) == 0 {
+		host = "localhost"
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	fmt.Fprintf(w, "resolved %s: %v\n", host, addrs)
+
+	network := cfg.Protocol
+	switch network {
+	case "":
+		network = logger.ProtocolUDP
+	case logger.ProtocolHTTP, logger.ProtocolForward, logger.ProtocolOTLP:
+		// all run over a TCP connection
+		network = logger.ProtocolTCP
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(cfg.LogPort))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return fmt.Errorf("dial %s %s: %w", network, address, err)
+	}
+	if network == logger.ProtocolUDP {
+		// a UDP dial sends nothing, so it succeeds with nobody listening
+		fmt.Fprintf(w, "dialed %s %s, reachability not verified: UDP has no handshake\n", network, address)
+		return conn.Close()
+	}
+	fmt.Fprintf(w, "dialed %s %s\n", network, address)
+
+	if cfg.TLS == nil {
+		return conn.Close()
+	}
+	config, err := cfg.TLS.ClientConfig(host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	client := tls.Client(conn, config)
+	if err := client.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("tls handshake with %s: %w", address, err)
+	}
+	fmt.Fprintf(w, "completed tls handshake with %s\n", address)
+	return client.Close()
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestCheckConfig_Valid(t *testing.T) {
+	t.Setenv("TEST_LOG_TYPE", "env-type")
+	t.Setenv("LOGGER_PORT", "5141")
+	path := writeConfig(t, `{"logType": "${TEST_LOG_TYPE}", "logHost": "logstash.example.com"}`)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"check-config", path}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("check-config exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+
+	var cfg logger.Config
+	if err := json.Unmarshal(stdout.Bytes(), &cfg); err != nil {
+		t.Fatalf("check-config output is not a valid config: %v", err)
+	}
+	if cfg.LogType != "env-type" {
+		t.Errorf("effective LogType = %q, want %q", cfg.LogType, "env-type")
+	}
+	if cfg.LogPort != 5141 {
+		t.Errorf("effective LogPort = %d, want LOGGER_PORT 5141", cfg.LogPort)
+	}
+	if cfg.LogHost != "logstash.example.com" {
+		t.Errorf("effective LogHost = %q, want the file's", cfg.LogHost)
+	}
+}
+
+func TestCheckConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		contains string
+	}{
+		{"missing type", `{"logHost": "logstash.example.com"}`, "logType is required"},
+		{"unknown field", `{"logType": "x", "bogus": true}`, "unknown field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"check-config", writeConfig(t, tt.content)}, nil, &stdout, &stderr)
+			if code != 1 {
+				t.Errorf("check-config exit code = %d, want 1", code)
+			}
+			if !strings.Contains(stderr.String(), tt.contains) {
+				t.Errorf("check-config stderr = %q, want it to contain %q", stderr.String(), tt.contains)
+			}
+		})
+	}
+}
+
+func TestCheckConfig_EmptyHostWarning(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"check-config", writeConfig(t, `{"logType": "x"}`)}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("check-config exit code = %d, want 0", code)
+	}
+	if !strings.Contains(stderr.String(), "warning: logHost is not supplied") {
+		t.Errorf("check-config should warn about empty logHost, got %q", stderr.String())
+	}
+}
+
+func TestCheckConfig_Online(t *testing.T) {
+	path := writeConfig(t, `{"logType": "x", "logHost": "127.0.0.1", "logPort": 5140}`)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"check-config", "--online", path}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("check-config --online exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "dialed udp 127.0.0.1:5140, reachability not verified") {
+		t.Errorf("check-config --online should report the dial as unverified, got %q", stderr.String())
+	}
+}
+
+func TestCheckConfig_OnlineTCP(t *testing.T) {
+	receiver, err := loggertest.Listen(loggertest.TCP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer receiver.Close()
+
+	var stdout, stderr bytes.Buffer
+	path := writeConfig(t, fmt.Sprintf(`{"logType": "x", "protocol": "tcp", "logHost": %q, "logPort": %d}`, receiver.Host(), receiver.Port()))
+	code := run([]string{"check-config", "--online", path}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("check-config --online exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "dialed tcp "+receiver.Addr()) {
+		t.Errorf("check-config --online should report the tcp dial, got %q", stderr.String())
+	}
+}
+
+func TestCheckConfig_OnlineUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram() returned unexpected error: %v", err)
+	}
+	defer listener.Close()
+
+	var stdout, stderr bytes.Buffer
+	config := writeConfig(t, fmt.Sprintf(`{"logType": "x", "protocol": "unix", "logHost": %q}`, path))
+	if code := run([]string{"check-config", "--online", config}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("check-config --online exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "dialed unixgram "+path) {
+		t.Errorf("check-config --online should report the socket dial, got %q", stderr.String())
+	}
+
+	listener.Close()
+	stderr.Reset()
+	if code := run([]string{"check-config", "--online", config}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("check-config --online exit code = %d without a socket, want 1 (stderr: %s)", code, stderr.String())
+	}
+}
+
+func TestCheckConfig_OnlineTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(ca, cert, 0o600); err != nil {
+		t.Fatalf("failed to write CA: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	config := writeConfig(t, fmt.Sprintf(`{"logType": "x", "protocol": "tcp", "logHost": %q, "logPort": %s, "tls": {"caFile": %q}}`, host, port, ca))
+	if code := run([]string{"check-config", "--online", config}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("check-config --online exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "completed tls handshake with "+server.Listener.Addr().String()) {
+		t.Errorf("check-config --online should report the tls handshake, got %q", stderr.String())
+	}
+
+	// a plain TCP endpoint accepts the connection but not the handshake
+	receiver, err := loggertest.Listen(loggertest.TCP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer receiver.Close()
+	stderr.Reset()
+	config = writeConfig(t, fmt.Sprintf(`{"logType": "x", "protocol": "tcp", "logHost": %q, "logPort": %d, "tls": {"caFile": %q}}`, receiver.Host(), receiver.Port(), ca))
+	if code := run([]string{"check-config", "--online", "--timeout=1s", config}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("check-config --online exit code = %d against a plain endpoint, want 1 (stderr: %s)", code, stderr.String())
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"no args", nil, 2},
+		{"unknown command", []string{"bogus"}, 2},
+		{"help", []string{"help"}, 0},
+		{"check-config without file", []string{"check-config"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, nil, &stdout, &stderr); code != tt.code {
+				t.Errorf("run(%v) exit code = %d, want %d", tt.args, code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/tailer"
+)
+
+// containerLog is the container a file under the container log directory
+// belongs to
+type containerLog struct {
+	pod         string
+	namespace   string
+	container   string
+	containerID string
+}
+
+// parseContainerLogName splits the kubelet symlink name
+// <pod>_<namespace>_<container>-<container id>.log into its parts
+func parseContainerLogName(path string) (*containerLog, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), ".log")
+	parts := strings.SplitN(name, "_", 3)
+	if len(parts) != 3 {
+		return nil, false
+	}
+
+	dash := strings.LastIndex(parts[2], "-")
+	if dash <= 0 {
+		return nil, false
+	}
+
+	return &containerLog{
+		pod:         parts[0],
+		namespace:   parts[1],
+		container:   parts[2][:dash],
+		containerID: parts[2][dash+1:],
+	}, true
+}
+
+// agent ships the records a tailer reads from the container log files in
+// the Lagoon format, one handler per namespace. It is the handler of the
+// tailer, which follows the files across rotation and parses the lines of
+// the container runtime.
+type agent struct {
+	cfg     logger.Config
+	out     io.Writer
+	stderr  io.Writer
+	kubelet *kubeletClient
+	refresh time.Duration
+	// scopes are the WithAttrs and WithGroup calls the agent was derived
+	// with, applied to the handler of every namespace
+	scopes []func(slog.Handler) slog.Handler
+
+	handlers map[string]slog.Handler
+	pods     map[string]podMeta
+	podsAt   time.Time
+
+	// shipping failures are warned about once per warnInterval, with the
+	// number of lines that failed since the last warning
+	warnInterval time.Duration
+	warnedAt     time.Time
+	failed       int
+}
+
+func newAgent(cfg logger.Config, out, stderr io.Writer) *agent {
+	return &agent{
+		cfg:      cfg,
+		out:      out,
+		stderr:   stderr,
+		refresh:  30 * time.Second,
+		handlers: map[string]slog.Handler{},
+
+		warnInterval: time.Minute,
+	}
+}
+
+// follow returns the tailer of the container log files of dir shipping to a
+func (a *agent) follow(dir string, cfg tailer.Config) (*tailer.Tailer, error) {
+	cfg.Paths = []string{filepath.Join(dir, "*.log")}
+	return tailer.New(cfg, a)
+}
+
+// Enabled reports true, the level is checked by the handler of the namespace
+func (a *agent) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle ships a record of a container log file with the metadata of its
+// container. Shipping failures are reported on stderr so the other files are
+// still followed, and the lines of files not named by the kubelet are
+// dropped.
+func (a *agent) Handle(ctx context.Context, r slog.Record) error {
+	var path string
+	record := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == tailer.FileKey {
+			path = attr.Value.String()
+		} else {
+			record.AddAttrs(attr)
+		}
+		return true
+	})
+	file, ok := parseContainerLogName(path)
+	if !ok {
+		return nil
+	}
+
+	a.refreshPods(ctx)
+	if err := a.ship(ctx, file, record); err != nil {
+		a.warn(path, err)
+	}
+	return nil
+}
+
+// warn reports that a line of path failed to ship, unless a warning was
+// written within the interval, so an unreachable endpoint doesn't flood
+// stderr with one warning per line
+func (a *agent) warn(path string, err error) {
+	a.failed++
+	now := time.Now()
+	if !a.warnedAt.IsZero() && now.Sub(a.warnedAt) < a.warnInterval {
+		return
+	}
+	fmt.Fprintf(a.stderr, "warning: ship %s: %v (%d lines failed since the last warning)\n", path, err, a.failed)
+	a.warnedAt, a.failed = now, 0
+}
+
+// WithAttrs returns an agent adding attrs to the records of every namespace
+func (a *agent) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return a.scoped(func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs) })
+}
+
+// WithGroup returns an agent nesting the attrs of the records of every
+// namespace under name
+func (a *agent) WithGroup(name string) slog.Handler {
+	return a.scoped(func(h slog.Handler) slog.Handler { return h.WithGroup(name) })
+}
+
+// scoped returns a copy of a applying scope after its own scopes
+func (a *agent) scoped(scope func(slog.Handler) slog.Handler) *agent {
+	c := newAgent(a.cfg, a.out, a.stderr)
+	c.kubelet, c.refresh, c.warnInterval = a.kubelet, a.refresh, a.warnInterval
+	c.scopes = append(a.scopes[:len(a.scopes):len(a.scopes)], scope)
+	return c
+}
+
+// ship forwards a record of file with the metadata of its container
+func (a *agent) ship(ctx context.Context, file *containerLog, record slog.Record) error {
+	handler, err := a.handler(file.namespace)
+	if err != nil {
+		return err
+	}
+	if !handler.Enabled(ctx, record.Level) {
+		return nil
+	}
+
+	attrs := []any{
+		slog.String("namespace", file.namespace),
+		slog.String("pod", file.pod),
+		slog.String("container", file.container),
+		slog.String("container_id", file.containerID),
+	}
+	if meta, ok := a.pods[file.namespace+"/"+file.pod]; ok {
+		attrs = append(attrs, slog.String("pod_uid", meta.UID), slog.String("node", meta.NodeName))
+		if len(meta.Labels) > 0 {
+			labels := make([]any, 0, len(meta.Labels))
+			for key, value := range meta.Labels {
+				labels = append(labels, slog.String(key, value))
+			}
+			attrs = append(attrs, slog.Group("labels", labels...))
+		}
+	}
+
+	record.AddAttrs(slog.Group("kubernetes", attrs...))
+
+	return handler.Handle(ctx, record)
+}
+
+// handler returns the Lagoon handler for a namespace, whose type defaults to
+// the namespace name unless one was configured
+func (a *agent) handler(namespace string) (slog.Handler, error) {
+	if handler, ok := a.handlers[namespace]; ok {
+		return handler, nil
+	}
+
+	cfg := a.cfg
+	if len(cfg.LogType) == 0 {
+		cfg.LogType = namespace
+	}
+	handler, err := logger.NewWriterHandler(cfg, a.out)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range a.scopes {
+		handler = scope(handler)
+	}
+	a.handlers[namespace] = handler
+
+	return handler, nil
+}
+
+func (a *agent) refreshPods(ctx context.Context) {
+	if a.kubelet == nil || time.Since(a.podsAt) < a.refresh {
+		return
+	}
+
+	pods, err := a.kubelet.pods(ctx)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "warning: kubelet metadata unavailable: %v\n", err)
+		return
+	}
+	a.pods = pods
+	a.podsAt = time.Now()
+}
+
+// daemonset follows the node's container logs and forwards them
+func daemonset(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("daemonset", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cf := addConfigFlags(fs)
+	dir := fs.String("log-dir", "/var/log/containers", "directory of container log files")
+	interval := fs.Duration("poll", time.Second, "how often to scan for new log lines")
+	fromStart := fs.Bool("from-beginning", false, "ship files present at startup from their beginning")
+	format := fs.String("format", tailer.FormatContainer, "format of the log files (container, cri, docker or plain)")
+	kubeletURL := fs.String("kubelet-url", defaultKubeletURL(), "kubelet API used for pod metadata (empty disables enrichment)")
+	tokenFile := fs.String("token-file", filepath.Join(serviceAccountDir, "token"), "service account token for the kubelet API")
+	caFile := fs.String("kubelet-ca", filepath.Join(serviceAccountDir, "ca.crt"), "CA bundle for the kubelet serving certificate")
+	insecure := fs.Bool("kubelet-insecure", false, "skip kubelet serving certificate verification")
+	refresh := fs.Duration("metadata-refresh", 30*time.Second, "how often to refresh pod metadata")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: lagoon-log-forwarder daemonset [flags]")
+		fmt.Fprintln(stderr, "Follows container log files on the node and forwards them in the Lagoon format.")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format != tailer.FormatPlain {
+		if _, err := tailer.NewContainerParser(*format); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+	}
+
+	cfg, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	// container output has no meaningful caller location
+	cfg.AddSource = false
+
+	// the endpoint is dialled again when it restarts, the node's logs are
+	// followed meanwhile
+	conn, err := dialRedialer(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: connect to %s:%d: %v\n", cfg.LogHost, cfg.LogPort, err)
+		return 1
+	}
+	defer conn.Close()
+
+	a := newAgent(cfg, conn, stderr)
+	a.refresh = *refresh
+	if len(*kubeletURL) > 0 {
+		if a.kubelet, err = newKubeletClient(*kubeletURL, *tokenFile, *caFile, *insecure); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+
+	t, err := a.follow(*dir, tailer.Config{Format: *format, PollInterval: *interval, FromStart: *fromStart})
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := t.Run(ctx); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// defaultKubeletURL points at the node's kubelet when NODE_IP is injected via
+// the downward API
+func defaultKubeletURL() string {
+	if ip := os.Getenv("NODE_IP"); len(ip) > 0 {
+		return "https://" + ip + ":10250"
+	}
+	return ""
+}
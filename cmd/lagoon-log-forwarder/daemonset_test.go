@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/tailer"
+)
+
+func TestParseContainerLogName(t *testing.T) {
+	tests := []struct {
+		path      string
+		ok        bool
+		pod       string
+		namespace string
+		container string
+		id        string
+	}{
+		{"/var/log/containers/nginx-7d9c_drupal-main_nginx-abc123.log", true, "nginx-7d9c", "drupal-main", "nginx", "abc123"},
+		{"/var/log/containers/cli-0_project-dev_php-fpm-def.log", true, "cli-0", "project-dev", "php-fpm", "def"},
+		{"/var/log/containers/not-a-container.log", false, "", "", "", ""},
+		{"/var/log/containers/pod_ns_nocontainerid.log", false, "", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			file, ok := parseContainerLogName(tt.path)
+			if ok != tt.ok {
+				t.Fatalf("parseContainerLogName() ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			got := []string{file.pod, file.namespace, file.container, file.containerID}
+			want := []string{tt.pod, tt.namespace, tt.container, tt.id}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Errorf("parseContainerLogName() = %v, want %v", got, want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func decodeEvents(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if len(line) == 0 {
+			continue
+		}
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("event is not JSON: %v: %q", err, line)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// followDir returns the tailer of the container log files of dir shipping to
+// a
+func followDir(t *testing.T, a *agent, dir string, fromStart bool) *tailer.Tailer {
+	t.Helper()
+	tl, err := a.follow(dir, tailer.Config{Format: tailer.FormatContainer, FromStart: fromStart})
+	if err != nil {
+		t.Fatalf("follow() returned unexpected error: %v", err)
+	}
+	return tl
+}
+
+func poll(t *testing.T, tl *tailer.Tailer) {
+	t.Helper()
+	if err := tl.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll() returned unexpected error: %v", err)
+	}
+}
+
+func TestAgentPoll(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "web-1_old-ns_nginx-111.log")
+	appendFile(t, existing, "history\n")
+
+	kubelet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pods" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"items": [{"metadata": {"name": "cli-0", "namespace": "project-dev", "uid": "uid-1", "labels": {"app": "cli"}}, "spec": {"nodeName": "node-a"}}]}`))
+	}))
+	defer kubelet.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+
+	var out, stderr bytes.Buffer
+	cfg := logger.NewConfig()
+	cfg.AddSource = false
+	a := newAgent(cfg, &out, &stderr)
+	client, err := newKubeletClient(kubelet.URL, tokenFile, "", false)
+	if err != nil {
+		t.Fatalf("newKubeletClient() returned unexpected error: %v", err)
+	}
+	a.kubelet = client
+	tl := followDir(t, a, dir, false)
+
+	poll(t, tl)
+	if out.Len() != 0 {
+		t.Errorf("existing content should be skipped at startup, got %q", out.String())
+	}
+
+	fresh := filepath.Join(dir, "cli-0_project-dev_php-222.log")
+	appendFile(t, fresh, "first line\nsecond ")
+	appendFile(t, existing, "new line\n")
+	poll(t, tl)
+	appendFile(t, fresh, "half\n")
+	poll(t, tl)
+
+	events := decodeEvents(t, out.Bytes())
+	if len(events) != 3 {
+		t.Fatalf("Poll() shipped %d events, want 3: %q", len(events), out.String())
+	}
+
+	messages := map[string]map[string]any{}
+	for _, event := range events {
+		messages[event["message"].(string)] = event
+	}
+	for _, want := range []string{"first line", "new line", "second half"} {
+		if _, ok := messages[want]; !ok {
+			t.Errorf("missing event %q in %v", want, messages)
+		}
+	}
+
+	event := messages["first line"]
+	if event["type"] != "project-dev" {
+		t.Errorf("event type = %v, want namespace project-dev", event["type"])
+	}
+	kube := event["kubernetes"].(map[string]any)
+	if kube["pod"] != "cli-0" || kube["container"] != "php" || kube["node"] != "node-a" || kube["pod_uid"] != "uid-1" {
+		t.Errorf("kubernetes metadata = %v", kube)
+	}
+	if labels, _ := kube["labels"].(map[string]any); labels["app"] != "cli" {
+		t.Errorf("kubernetes labels = %v", kube["labels"])
+	}
+	if messages["new line"]["type"] != "old-ns" {
+		t.Errorf("event type = %v, want old-ns", messages["new line"]["type"])
+	}
+}
+
+// brokenWriter fails every write, like a lost endpoint
+type brokenWriter struct{}
+
+func (brokenWriter) Write([]byte) (int, error) { return 0, errors.New("endpoint went away") }
+
+func TestAgent_WarnsOncePerInterval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "web-1_shop_nginx-111.log")
+	appendFile(t, path, strings.Repeat("2026-10-15T09:30:00Z stdout F line\n", 5))
+
+	var stderr bytes.Buffer
+	a := newAgent(logger.NewConfig(), brokenWriter{}, &stderr)
+	tl := followDir(t, a, dir, true)
+	poll(t, tl)
+	if got := strings.Count(stderr.String(), "warning: ship"); got != 1 {
+		t.Fatalf("warned %d times for 5 failed lines, want once: %q", got, stderr.String())
+	}
+
+	// once the interval has passed the lines failed meanwhile are reported
+	a.warnedAt = a.warnedAt.Add(-a.warnInterval)
+	appendFile(t, path, "2026-10-15T09:30:01Z stdout F line\n")
+	poll(t, tl)
+	if got := strings.Count(stderr.String(), "warning: ship"); got != 2 {
+		t.Fatalf("warned %d times after the interval, want twice: %q", got, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "(5 lines failed since the last warning)") {
+		t.Errorf("warning = %q, want the lines failed since the first warning counted", stderr.String())
+	}
+}
+
+func TestAgentPoll_Truncation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "web-1_ns_nginx-111.log")
+
+	var out, stderr bytes.Buffer
+	tl := followDir(t, newAgent(logger.NewConfig(), &out, &stderr), dir, true)
+
+	appendFile(t, path, "one\ntwo\n")
+	poll(t, tl)
+	if err := os.WriteFile(path, []byte("three\n"), 0o600); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	poll(t, tl)
+
+	events := decodeEvents(t, out.Bytes())
+	if len(events) != 3 || events[2]["message"] != "three" {
+		t.Errorf("Poll() after truncation shipped %v", events)
+	}
+}
+
+func TestAgentPoll_Rotation(t *testing.T) {
+	// the kubelet links the files of the runtime, which rotates them under
+	// the pod directory
+	dir, pods := t.TempDir(), t.TempDir()
+	target := filepath.Join(pods, "0.log")
+	appendFile(t, target, "2026-10-15T09:30:00Z stdout F before rotation\n")
+	if err := os.Symlink(target, filepath.Join(dir, "web-1_ns_nginx-111.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, stderr bytes.Buffer
+	tl := followDir(t, newAgent(logger.NewConfig(), &out, &stderr), dir, true)
+	poll(t, tl)
+
+	appendFile(t, target, "2026-10-15T09:30:01Z stdout F written last\n")
+	if err := os.Rename(target, target+".20261015-093001"); err != nil {
+		t.Fatal(err)
+	}
+	// shorter than the rotated file
+	appendFile(t, target, "2026-10-15T09:30:02Z stdout F new\n")
+	poll(t, tl)
+
+	var got []string
+	for _, event := range decodeEvents(t, out.Bytes()) {
+		got = append(got, event["message"].(string))
+		if _, ok := event[tailer.FileKey]; ok {
+			t.Errorf("event = %v, want no %s", event, tailer.FileKey)
+		}
+	}
+	if want := []string{"before rotation", "written last", "new"}; !slices.Equal(got, want) {
+		t.Errorf("Poll() across rotation shipped %q, want %q", got, want)
+	}
+}
+
+func TestAgentPoll_ContainerFormats(t *testing.T) {
+	dir := t.TempDir()
+	cri := filepath.Join(dir, "web-1_shop_php-111.log")
+	docker := filepath.Join(dir, "web-2_shop_php-222.log")
+
+	var out, stderr bytes.Buffer
+	tl := followDir(t, newAgent(logger.NewConfig(), &out, &stderr), dir, true)
+
+	appendFile(t, cri, "2026-10-15T09:30:00Z stdout P cart \n2026-10-15T09:30:00Z stdout F saved\n")
+	appendFile(t, cri, `2026-10-15T09:30:01Z stderr F {"message": "payment failed", "level": "error", "order": "1001"}`+"\n")
+	appendFile(t, docker, `{"log":"partial ","stream":"stderr","time":"2026-10-15T09:30:02Z"}`+"\n")
+	poll(t, tl)
+	// the docker container is removed before finishing its line
+	if err := os.Remove(docker); err != nil {
+		t.Fatal(err)
+	}
+	poll(t, tl)
+
+	events := decodeEvents(t, out.Bytes())
+	if len(events) != 3 {
+		t.Fatalf("Poll() shipped %d events, want 3: %q", len(events), out.String())
+	}
+	expected := []map[string]any{
+		{"message": "cart saved", "level": "INFO", "stream": "stdout", "@timestamp": "2026-10-15T09:30:00Z"},
+		{"message": "payment failed", "level": "ERROR", "stream": "stderr", "order": "1001"},
+		{"message": "partial ", "stream": "stderr"},
+	}
+	for i, fields := range expected {
+		for key, want := range fields {
+			if events[i][key] != want {
+				t.Errorf("event %d [%q] = %v, want %v", i, key, events[i][key], want)
+			}
+		}
+		if kube, _ := events[i]["kubernetes"].(map[string]any); kube["namespace"] != "shop" {
+			t.Errorf("event %d kubernetes = %v, want the container metadata", i, events[i]["kubernetes"])
+		}
+	}
+}
+
+func TestDaemonset_UnknownFormat(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"daemonset", "--format=xml"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("daemonset exit code = %d, want 2 (stderr: %s)", code, stderr.String())
+	}
+}
+
+func TestDaemonset_LeaderElect(t *testing.T) {
+	// the pod of every node reads the logs of its node, none is exclusive
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"daemonset", "--leader-elect"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("daemonset --leader-elect exit code = %d, want 2 (stderr: %s)", code, stderr.String())
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+)
+
+// diffConfig prints the changes between two config files, one per line, and
+// fails when one of them can't be applied to a running logger, so a rollout
+// can require a restart for it
+func diffConfig(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff-config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "print the changes as a JSON array")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: lagoon-log-forwarder diff-config [--json] <old> <new>")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	var configs [2]logger.Config
+	for i, path := range fs.Args() {
+		cfg, err := logger.LoadConfigFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(stderr, "error: invalid config %s: %v\n", path, err)
+			return 1
+		}
+		configs[i] = cfg
+	}
+
+	changes := logger.CompareConfigs(configs[0], configs[1])
+	if *asJSON {
+		if changes == nil {
+			changes = []logger.Change{}
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(changes); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+
+	unsafe := 0
+	for _, c := range changes {
+		if c.Unsafe {
+			unsafe++
+		}
+		if *asJSON {
+			continue
+		}
+		if c.Unsafe {
+			fmt.Fprintf(stdout, "%s (unsafe: %s)\n", c, c.Reason)
+		} else {
+			fmt.Fprintln(stdout, c)
+		}
+	}
+
+	if unsafe > 0 {
+		fmt.Fprintf(stderr, "%d unsafe changes, restart the logger to apply them\n", unsafe)
+		return 1
+	}
+	return 0
+}
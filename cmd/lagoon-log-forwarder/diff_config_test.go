@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+)
+
+// writeConfigs writes the config files old and new, returning their paths
+func writeConfigs(t *testing.T, old, new string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "old.json"), filepath.Join(dir, "new.json")}
+	for i, content := range []string{old, new} {
+		if err := os.WriteFile(paths[i], []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+	}
+	return paths[0], paths[1]
+}
+
+func TestDiffConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		new      string
+		code     int
+		expected string
+	}{
+		{"unchanged", `{"logType": "app", "level": "info"}`, 0, ""},
+		{"safe", `{"logType": "app", "level": "debug"}`, 0, "level: info→debug\n"},
+		{"unsafe", `{"logType": "app", "level": "info", "logPort": 5141}`, 1, "logPort: 5140→5141 (unsafe: only applies after Shutdown)\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, new := writeConfigs(t, `{"logType": "app", "level": "info"}`, tt.new)
+
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"diff-config", old, new}, nil, &stdout, &stderr)
+			if code != tt.code {
+				t.Errorf("diff-config exit code = %d, want %d (stderr: %s)", code, tt.code, stderr.String())
+			}
+			if stdout.String() != tt.expected {
+				t.Errorf("diff-config output = %q, want %q", stdout.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiffConfig_JSON(t *testing.T) {
+	old, new := writeConfigs(t, `{"logType": "app"}`, `{"logType": "app", "messageVersion": 3}`)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"diff-config", "--json", old, new}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("diff-config exit code = %d, want 1 for an unsafe change", code)
+	}
+	var changes []logger.Change
+	if err := json.Unmarshal(stdout.Bytes(), &changes); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout.String(), err)
+	}
+	if len(changes) != 1 || changes[0].Field != "messageVersion" || !changes[0].Unsafe {
+		t.Errorf("changes = %+v, want the unsafe messageVersion", changes)
+	}
+	if !strings.Contains(stderr.String(), "1 unsafe changes") {
+		t.Errorf("stderr = %q, want the unsafe changes counted", stderr.String())
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/tailer"
+)
+
+// configFlags holds the flags shared by commands that build a logger.Config.
+// Flags that are set explicitly override the LOGGER_* environment variables,
+// which override values from the config file.
+type configFlags struct {
+	fs       *flag.FlagSet
+	file     string
+	logType  string
+	host     string
+	port     int
+	protocol string
+	channel  string
+	app      string
+}
+
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	defaults := logger.NewConfig()
+	f := &configFlags{fs: fs}
+
+	fs.StringVar(&f.file, "config", "", "path to a JSON config file")
+	fs.StringVar(&f.logType, "type", defaults.LogType, "log type (must match the k8s namespace)")
+	fs.StringVar(&f.host, "host", defaults.LogHost, "log endpoint host")
+	fs.IntVar(&f.port, "port", defaults.LogPort, "log endpoint port")
+	fs.StringVar(&f.protocol, "protocol", defaults.Protocol, "log endpoint protocol (udp, tcp, http, forward or otlp)")
+	fs.StringVar(&f.channel, "channel", defaults.LogChannel, "log channel")
+	fs.StringVar(&f.app, "app", defaults.ApplicationName, "application name")
+
+	return f
+}
+
+// load returns the config file (or defaults) with the environment and
+// explicit flags applied
+func (f *configFlags) load() (logger.Config, error) {
+	cfg := logger.NewConfig()
+
+	var err error
+	if len(f.file) > 0 {
+		if cfg, err = logger.LoadConfigFile(f.file); err != nil {
+			return cfg, err
+		}
+	}
+	if cfg, err = cfg.FromEnv(); err != nil {
+		return cfg, err
+	}
+
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "type":
+			cfg.LogType = f.logType
+		case "host":
+			cfg.LogHost = f.host
+		case "port":
+			cfg.LogPort = f.port
+		case "protocol":
+			cfg.Protocol = f.protocol
+		case "channel":
+			cfg.LogChannel = f.channel
+		case "app":
+			cfg.ApplicationName = f.app
+		}
+	})
+
+	return cfg, nil
+}
+
+// fieldFlags holds the flags of the policy for the fields of input lines
+type fieldFlags struct {
+	unknown string
+	known   string
+}
+
+func addFieldFlags(fs *flag.FlagSet) *fieldFlags {
+	f := &fieldFlags{}
+
+	fs.StringVar(&f.unknown, "unknown-fields", tailer.UnknownPass, "fields of JSON lines missing from the event schema (pass, drop or nest)")
+	fs.StringVar(&f.known, "known-fields", "", "comma separated fields kept besides those of the event schema")
+
+	return f
+}
+
+// validate reports whether the policy of the flags is known
+func (f *fieldFlags) validate() error {
+	return tailer.FieldPolicy{Unknown: f.unknown}.Validate()
+}
+
+// policy returns the field policy of the flags, knowing the fields of the
+// schema of the message version of cfg
+func (f *fieldFlags) policy(cfg logger.Config) (tailer.FieldPolicy, error) {
+	p := tailer.FieldPolicy{Unknown: f.unknown}
+	if p.Unknown == tailer.UnknownPass {
+		return p, nil
+	}
+
+	known, err := logger.SchemaFields(cfg.MessageVersion)
+	if err != nil {
+		return p, err
+	}
+	for _, field := range strings.Split(f.known, ",") {
+		if field = strings.TrimSpace(field); len(field) > 0 {
+			known = append(known, field)
+		}
+	}
+	p.Known = known
+	return p, nil
+}
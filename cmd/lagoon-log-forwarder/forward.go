@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/tailer"
+)
+
+// forward reads lines from stdin and forwards each as an event, so processes
+// that aren't written in Go can log through a pipe
+func forward(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("forward", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cf := addConfigFlags(fs)
+	format := fs.String("format", tailer.FormatJSON, "line format (json or plain)")
+	tee := fs.Bool("tee", false, "copy the input lines to stdout")
+	ff := addFieldFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: lagoon-log-forwarder forward [flags]")
+		fmt.Fprintln(stderr, "Reads lines from stdin until it is closed and forwards each as an event.")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var parse func(line string, now time.Time) slog.Record
+	switch *format {
+	case tailer.FormatJSON:
+		parse = tailer.ParseJSON
+	case tailer.FormatPlain:
+		parse = tailer.ParsePlain
+	default:
+		fmt.Fprintf(stderr, "error: unknown format %q\n", *format)
+		return 2
+	}
+	if err := ff.validate(); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+
+	cfg, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	// piped lines have no meaningful caller location
+	cfg.AddSource = false
+	policy, err := ff.policy(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	conn, err := logger.Dial(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: connect to %s:%d: %v\n", cfg.LogHost, cfg.LogPort, err)
+		return 1
+	}
+	defer conn.Close()
+
+	handler, err := logger.NewWriterHandler(cfg, conn)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	failed := 0
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if *tee {
+			fmt.Fprintln(stdout, scanner.Text())
+		}
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		// a failed event doesn't stop the lines after it, which the
+		// process writing them can't hold back
+		if err := handler.Handle(ctx, policy.Apply(parse(scanner.Text(), time.Now()))); err != nil {
+			if failed == 0 {
+				fmt.Fprintf(stderr, "error: %v\n", err)
+			}
+			failed++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if failed > 0 {
+		fmt.Fprintf(stderr, "%d events failed to send\n", failed)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestForward(t *testing.T) {
+	receiver, port := listenUDP(t)
+	input := `{"message": "cache cleared", "level": "warn", "bin": "render"}` + "\n\nplain output\n"
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"forward", "--tee", "--type=drupal", "--channel=drush", "--host=127.0.0.1", "--port=" + port}
+	if code := run(args, strings.NewReader(input), &stdout, &stderr); code != 0 {
+		t.Fatalf("forward exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+	if stdout.String() != input {
+		t.Errorf("stdout = %q, want the input copied", stdout.String())
+	}
+
+	expected := []map[string]any{
+		{"message": "cache cleared", "level": "WARN", "bin": "render", "type": "drupal", "channel": "drush"},
+		{"message": "plain output", "level": "INFO", "type": "drupal", "channel": "drush"},
+	}
+	buf := make([]byte, 65535)
+	for i, fields := range expected {
+		if err := receiver.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+			t.Fatalf("failed to set read deadline: %v", err)
+		}
+		n, err := receiver.Read(buf)
+		if err != nil {
+			t.Fatalf("event %d not received: %v", i, err)
+		}
+		var event map[string]any
+		if err := json.Unmarshal(buf[:n], &event); err != nil {
+			t.Fatalf("received event is not JSON: %v", err)
+		}
+		for key, want := range fields {
+			if event[key] != want {
+				t.Errorf("event %d [%q] = %v, want %v", i, key, event[key], want)
+			}
+		}
+		if _, ok := event["host"]; !ok {
+			t.Errorf("event %d = %v, want host", i, event)
+		}
+	}
+}
+
+func TestForward_Plain(t *testing.T) {
+	receiver, port := listenUDP(t)
+	line := `{"message": "kept verbatim"}`
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"forward", "--format=plain", "--type=drupal", "--host=127.0.0.1", "--port=" + port}
+	if code := run(args, strings.NewReader(line+"\n"), &stdout, &stderr); code != 0 {
+		t.Fatalf("forward exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+	if stdout.Len() > 0 {
+		t.Errorf("stdout = %q, want nothing without --tee", stdout.String())
+	}
+
+	buf := make([]byte, 65535)
+	if err := receiver.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %v", err)
+	}
+	n, err := receiver.Read(buf)
+	if err != nil {
+		t.Fatalf("no event received: %v", err)
+	}
+	var event map[string]any
+	if err := json.Unmarshal(buf[:n], &event); err != nil {
+		t.Fatalf("received event is not JSON: %v", err)
+	}
+	if event["message"] != line {
+		t.Errorf("message = %v, want the line verbatim", event["message"])
+	}
+}
+
+func TestForward_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args []string
+	}{
+		{"arguments", []string{"forward", "app.log"}},
+		{"unknown format", []string{"forward", "--format=regex", "--host=127.0.0.1"}},
+		{"unknown field policy", []string{"forward", "--unknown-fields=keep", "--host=127.0.0.1"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, strings.NewReader(""), &stdout, &stderr); code != 2 {
+				t.Errorf("forward exit code = %d, want 2 (stderr: %s)", code, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// podMeta is the pod metadata attached to container log events
+type podMeta struct {
+	UID      string
+	NodeName string
+	Labels   map[string]string
+}
+
+// kubeletClient reads pod metadata from the node-local kubelet API
+type kubeletClient struct {
+	url       string
+	tokenFile string
+	client    *http.Client
+}
+
+func newKubeletClient(url, tokenFile, caFile string, insecure bool) (*kubeletClient, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecure, // #nosec G402 -- kubelet serving certs are often self-signed, opt-in only
+	}
+
+	if len(caFile) > 0 && !insecure {
+		pem, err := os.ReadFile(caFile) // #nosec G304 -- path is supplied by the operator
+		if err != nil {
+			return nil, fmt.Errorf("read kubelet CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &kubeletClient{
+		url:       strings.TrimRight(url, "/"),
+		tokenFile: tokenFile,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+// pods returns the metadata of every pod on the node keyed by namespace/name
+func (k *kubeletClient) pods(ctx context.Context) (map[string]podMeta, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url+"/pods", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the token is re-read on every request as projected tokens are rotated
+	if len(k.tokenFile) > 0 {
+		token, err := os.ReadFile(k.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("read service account token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kubelet returned %s", resp.Status)
+	}
+
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name      string            `json:"name"`
+				Namespace string            `json:"namespace"`
+				UID       string            `json:"uid"`
+				Labels    map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Spec struct {
+				NodeName string `json:"nodeName"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode kubelet pods: %w", err)
+	}
+
+	pods := make(map[string]podMeta, len(list.Items))
+	for _, item := range list.Items {
+		pods[item.Metadata.Namespace+"/"+item.Metadata.Name] = podMeta{
+			UID:      item.Metadata.UID,
+			NodeName: item.Spec.NodeName,
+			Labels:   item.Metadata.Labels,
+		}
+	}
+
+	return pods, nil
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// microTime is the Kubernetes MicroTime wire format used by Lease objects
+const microTime = "2006-01-02T15:04:05.000000Z07:00"
+
+var errLostLeadership = errors.New("lost leadership of the lease")
+
+// lease is the subset of a coordination.k8s.io/v1 Lease used for election
+type lease struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace"`
+		ResourceVersion string `json:"resourceVersion,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		HolderIdentity       string `json:"holderIdentity"`
+		LeaseDurationSeconds int    `json:"leaseDurationSeconds"`
+		AcquireTime          string `json:"acquireTime,omitempty"`
+		RenewTime            string `json:"renewTime,omitempty"`
+		LeaseTransitions     int    `json:"leaseTransitions"`
+	} `json:"spec"`
+}
+
+// expired reports whether the current holder failed to renew in time
+func (l *lease) expired(now time.Time) bool {
+	renewed, err := time.Parse(microTime, l.Spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewed.Add(time.Duration(l.Spec.LeaseDurationSeconds) * time.Second))
+}
+
+// leaseElector runs a function only while it holds a Kubernetes Lease, so a
+// single replica consumes an exclusive input
+type leaseElector struct {
+	apiURL    string
+	namespace string
+	name      string
+	identity  string
+	tokenFile string
+	duration  time.Duration
+	// renewDeadline is how long the holder keeps running without renewing,
+	// shorter than duration so it stops before another replica can take over
+	renewDeadline time.Duration
+	retry         time.Duration
+	client        *http.Client
+	now           func() time.Time
+	stderr        io.Writer
+}
+
+// run blocks until the lease is acquired, then calls fn with a context that
+// is cancelled if the lease isn't renewed within the renew deadline. Losing
+// the lease returns errLostLeadership so the process can restart as a
+// candidate.
+func (e *leaseElector) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	// the renew deadline runs from before the lease was written, as does the
+	// lease duration others see
+	var attempt time.Time
+	for {
+		attempt = time.Now()
+		acquired, err := e.tryAcquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			fmt.Fprintf(e.stderr, "warning: leader election: %v\n", err)
+		}
+		if acquired {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.retry):
+		}
+	}
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- fn(leaderCtx) }()
+
+	ticker := time.NewTicker(e.retry)
+	defer ticker.Stop()
+	renewBy := attempt.Add(e.renewDeadline)
+	deadline := time.NewTimer(time.Until(renewBy))
+	defer deadline.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-deadline.C:
+			// the lease may expire before a renewal succeeds
+			cancel()
+			<-done
+			return errLostLeadership
+		case <-ticker.C:
+			// a renewal still in flight at the deadline is abandoned
+			attempt = time.Now()
+			renewCtx, cancelRenew := context.WithDeadline(leaderCtx, renewBy)
+			renewed, err := e.tryAcquire(renewCtx)
+			cancelRenew()
+			if renewed {
+				renewBy = attempt.Add(e.renewDeadline)
+				deadline.Reset(time.Until(renewBy))
+				continue
+			}
+			if err == nil {
+				// another replica took over
+				cancel()
+				<-done
+				return errLostLeadership
+			}
+		}
+	}
+}
+
+// tryAcquire creates, takes over or renews the lease
+func (e *leaseElector) tryAcquire(ctx context.Context) (bool, error) {
+	current, err := e.get(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	now := e.now().UTC().Format(microTime)
+
+	if current == nil {
+		l := &lease{APIVersion: "coordination.k8s.io/v1", Kind: "Lease"}
+		l.Metadata.Name = e.name
+		l.Metadata.Namespace = e.namespace
+		l.Spec.HolderIdentity = e.identity
+		l.Spec.LeaseDurationSeconds = e.durationSeconds()
+		l.Spec.AcquireTime = now
+		l.Spec.RenewTime = now
+		return e.write(ctx, http.MethodPost, e.collectionURL(), l)
+	}
+
+	if current.Spec.HolderIdentity != e.identity {
+		if !current.expired(e.now()) {
+			return false, nil
+		}
+		current.Spec.HolderIdentity = e.identity
+		current.Spec.AcquireTime = now
+		current.Spec.LeaseTransitions++
+	}
+	current.Spec.LeaseDurationSeconds = e.durationSeconds()
+	current.Spec.RenewTime = now
+
+	// the resourceVersion makes the update fail with a conflict if another
+	// replica wrote the lease in the meantime
+	return e.write(ctx, http.MethodPut, e.collectionURL()+"/"+e.name, current)
+}
+
+// durationSeconds is the lease duration in the whole seconds Leases support
+func (e *leaseElector) durationSeconds() int {
+	return max(int(e.duration.Seconds()), 1)
+}
+
+func (e *leaseElector) collectionURL() string {
+	return fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", e.apiURL, e.namespace)
+}
+
+func (e *leaseElector) get(ctx context.Context) (*lease, error) {
+	resp, err := e.do(ctx, http.MethodGet, e.collectionURL()+"/"+e.name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusOK:
+		var l lease
+		if err := json.NewDecoder(resp.Body).Decode(&l); err != nil {
+			return nil, fmt.Errorf("decode lease: %w", err)
+		}
+		return &l, nil
+	default:
+		return nil, fmt.Errorf("get lease: %s", resp.Status)
+	}
+}
+
+func (e *leaseElector) write(ctx context.Context, method, url string, l *lease) (bool, error) {
+	body, err := json.Marshal(l)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := e.do(ctx, method, url, body)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return true, nil
+	case http.StatusConflict:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%s lease: %s", strings.ToLower(method), resp.Status)
+	}
+}
+
+func (e *leaseElector) do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(e.tokenFile) > 0 {
+		token, err := os.ReadFile(e.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("read service account token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	return e.client.Do(req)
+}
+
+// leaderFlags holds the opt-in leader election flags of long-running commands
+type leaderFlags struct {
+	enabled   bool
+	name      string
+	namespace string
+	identity  string
+	duration  time.Duration
+	apiURL    string
+}
+
+func addLeaderFlags(fs *flag.FlagSet) *leaderFlags {
+	f := &leaderFlags{}
+	hostname, _ := os.Hostname()
+
+	fs.BoolVar(&f.enabled, "leader-elect", false, "only consume inputs while holding a Kubernetes lease")
+	fs.StringVar(&f.name, "lease-name", "lagoon-log-forwarder", "name of the election lease")
+	fs.StringVar(&f.namespace, "lease-namespace", serviceAccountNamespace(), "namespace of the election lease")
+	fs.StringVar(&f.identity, "lease-identity", hostname, "identity recorded as the lease holder")
+	fs.DurationVar(&f.duration, "lease-duration", 15*time.Second, "how long a lease is valid without renewal")
+	fs.StringVar(&f.apiURL, "kube-api-url", kubernetesAPIURL(), "Kubernetes API server URL")
+
+	return f
+}
+
+// run calls fn directly, or under a lease when election is enabled. Election
+// problems are reported on stderr while waiting for the lease.
+func (f *leaderFlags) run(ctx context.Context, stderr io.Writer, fn func(ctx context.Context) error) error {
+	if !f.enabled {
+		return fn(ctx)
+	}
+
+	elector, err := f.elector(stderr)
+	if err != nil {
+		return err
+	}
+
+	return elector.run(ctx, fn)
+}
+
+func (f *leaderFlags) elector(stderr io.Writer) (*leaseElector, error) {
+	if len(f.apiURL) == 0 || len(f.namespace) == 0 {
+		return nil, errors.New("leader election requires --kube-api-url and --lease-namespace outside a cluster")
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if pem, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt")); err == nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(pem)
+		tlsConfig.RootCAs = pool
+	}
+
+	tokenFile := filepath.Join(serviceAccountDir, "token")
+	if _, err := os.Stat(tokenFile); err != nil {
+		tokenFile = ""
+	}
+
+	return &leaseElector{
+		apiURL:    strings.TrimRight(f.apiURL, "/"),
+		namespace: f.namespace,
+		name:      f.name,
+		identity:  f.identity,
+		tokenFile: tokenFile,
+		duration:  f.duration,
+		// the defaults of client-go: 10s to renew and retrying every 2s of
+		// a 15s lease
+		renewDeadline: f.duration * 2 / 3,
+		retry:         f.duration * 2 / 15,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+		now:    time.Now,
+		stderr: stderr,
+	}, nil
+}
+
+func serviceAccountNamespace() string {
+	namespace, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(namespace))
+}
+
+func kubernetesAPIURL() string {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if len(host) == 0 || len(port) == 0 {
+		return ""
+	}
+	return "https://" + net.JoinHostPort(host, port)
+}
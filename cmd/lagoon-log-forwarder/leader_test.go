@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeLeaseServer is an in-memory coordination.k8s.io Lease endpoint with
+// optimistic concurrency on resourceVersion
+type fakeLeaseServer struct {
+	mu      sync.Mutex
+	lease   *lease
+	version int
+}
+
+func (s *fakeLeaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !strings.HasPrefix(r.URL.Path, "/apis/coordination.k8s.io/v1/namespaces/lagoon/leases") {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if s.lease == nil {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(s.lease)
+	case http.MethodPost, http.MethodPut:
+		var l lease
+		if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if (r.Method == http.MethodPost && s.lease != nil) ||
+			(r.Method == http.MethodPut && (s.lease == nil || l.Metadata.ResourceVersion != s.lease.Metadata.ResourceVersion)) {
+			http.Error(w, "conflict", http.StatusConflict)
+			return
+		}
+		s.version++
+		l.Metadata.ResourceVersion = strconv.Itoa(s.version)
+		s.lease = &l
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *fakeLeaseServer) holder() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lease == nil {
+		return ""
+	}
+	return s.lease.Spec.HolderIdentity
+}
+
+func newTestElector(url, identity string) *leaseElector {
+	return &leaseElector{
+		apiURL:    url,
+		namespace: "lagoon",
+		name:      "forwarder",
+		identity:  identity,
+		duration:  2 * time.Second,
+		// renewDeadline is longer than the tests take
+		renewDeadline: time.Second,
+		retry:         20 * time.Millisecond,
+		client:        http.DefaultClient,
+		now:           time.Now,
+		stderr:        &bytes.Buffer{},
+	}
+}
+
+func TestLeaseElector_TryAcquire(t *testing.T) {
+	fake := &fakeLeaseServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	a := newTestElector(server.URL, "replica-a")
+	b := newTestElector(server.URL, "replica-b")
+	ctx := context.Background()
+
+	if ok, err := a.tryAcquire(ctx); !ok || err != nil {
+		t.Fatalf("tryAcquire() on missing lease = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := b.tryAcquire(ctx); ok || err != nil {
+		t.Fatalf("tryAcquire() on held lease = %v, %v; want false, nil", ok, err)
+	}
+	if ok, err := a.tryAcquire(ctx); !ok || err != nil {
+		t.Fatalf("tryAcquire() renewal = %v, %v; want true, nil", ok, err)
+	}
+
+	// once the holder stops renewing the lease can be taken over
+	b.now = func() time.Time { return time.Now().Add(time.Minute) }
+	if ok, err := b.tryAcquire(ctx); !ok || err != nil {
+		t.Fatalf("tryAcquire() on expired lease = %v, %v; want true, nil", ok, err)
+	}
+	if fake.holder() != "replica-b" {
+		t.Errorf("lease holder = %q, want replica-b", fake.holder())
+	}
+	if fake.lease.Spec.LeaseTransitions != 1 {
+		t.Errorf("lease transitions = %d, want 1", fake.lease.Spec.LeaseTransitions)
+	}
+}
+
+func TestLeaseElector_Run(t *testing.T) {
+	fake := &fakeLeaseServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	a := newTestElector(server.URL, "replica-a")
+	b := newTestElector(server.URL, "replica-b")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, e := range []*leaseElector{a, b} {
+		wg.Add(1)
+		go func(e *leaseElector) {
+			defer wg.Done()
+			_ = e.run(ctx, func(ctx context.Context) error {
+				started <- e.identity
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+				return nil
+			})
+		}(e)
+	}
+
+	first := <-started
+	select {
+	case second := <-started:
+		t.Fatalf("both %s and %s ran while one lease was held", first, second)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if fake.holder() != first {
+		t.Errorf("lease holder = %q, want %q", fake.holder(), first)
+	}
+
+	close(release)
+	cancel()
+	wg.Wait()
+}
+
+func TestLeaseElector_LostLeadership(t *testing.T) {
+	fake := &fakeLeaseServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	a := newTestElector(server.URL, "replica-a")
+
+	err := a.run(context.Background(), func(ctx context.Context) error {
+		// simulate another replica taking over the lease
+		fake.mu.Lock()
+		fake.lease.Spec.HolderIdentity = "replica-b"
+		fake.lease.Spec.RenewTime = time.Now().Add(time.Hour).UTC().Format(microTime)
+		fake.mu.Unlock()
+
+		<-ctx.Done()
+		return nil
+	})
+	if !errors.Is(err, errLostLeadership) {
+		t.Errorf("run() = %v, want errLostLeadership", err)
+	}
+}
+
+func TestLeaseElector_RenewDeadline(t *testing.T) {
+	fake := &fakeLeaseServer{}
+	var hang atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hang.Load() {
+			// the API server stops answering while the lease is held
+			<-r.Context().Done()
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	a := newTestElector(server.URL, "replica-a")
+	a.renewDeadline = 200 * time.Millisecond
+
+	var stopped time.Duration
+	err := a.run(context.Background(), func(ctx context.Context) error {
+		started := time.Now()
+		hang.Store(true)
+		<-ctx.Done()
+		stopped = time.Since(started)
+		return nil
+	})
+	if !errors.Is(err, errLostLeadership) {
+		t.Errorf("run() = %v, want errLostLeadership", err)
+	}
+	if stopped >= a.duration {
+		t.Errorf("fn stopped after %v, want before the lease of %v expires", stopped, a.duration)
+	}
+}
+
+func TestLeaderFlags_Disabled(t *testing.T) {
+	f := &leaderFlags{}
+	called := false
+	err := f.run(context.Background(), &bytes.Buffer{}, func(context.Context) error {
+		called = true
+		return nil
+	})
+	if err != nil || !called {
+		t.Errorf("run() without election = %v, called %v; want nil, true", err, called)
+	}
+
+	f.enabled = true
+	if err := f.run(context.Background(), &bytes.Buffer{}, func(context.Context) error { return nil }); err == nil {
+		t.Error("run() with election outside a cluster should return error")
+	}
+}
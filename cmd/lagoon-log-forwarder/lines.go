@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/tailer"
+)
+
+// parseLine converts a single input line into a record, see
+// tailer.ParseJSON
+func parseLine(line string, now time.Time) slog.Record {
+	return tailer.ParseJSON(line, now)
+}
+
+// prettyWriter indents each JSON event written to it
+type prettyWriter struct {
+	w io.Writer
+}
+
+func (p prettyWriter) Write(b []byte) (int, error) {
+	var out bytes.Buffer
+	if err := json.Indent(&out, bytes.TrimSpace(b), "", "  "); err != nil {
+		return p.w.Write(b)
+	}
+	out.WriteByte('\n')
+	if _, err := p.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
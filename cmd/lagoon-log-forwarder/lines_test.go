@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func recordAttrs(r slog.Record) map[string]string {
+	attrs := map[string]string{}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.String()
+		return true
+	})
+	return attrs
+}
+
+func TestParseLine(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		line    string
+		message string
+		level   slog.Level
+		time    time.Time
+		attrs   map[string]string
+	}{
+		{
+			name:    "plain text",
+			line:    "something happened\n",
+			message: "something happened",
+			level:   slog.LevelInfo,
+			time:    now,
+			attrs:   map[string]string{},
+		},
+		{
+			name:    "json with message and level",
+			line:    `{"message": "db down", "level": "error", "db": "postgres"}`,
+			message: "db down",
+			level:   slog.LevelError,
+			time:    now,
+			attrs:   map[string]string{"db": "postgres"},
+		},
+		{
+			name:    "json with msg and time",
+			line:    `{"msg": "hi", "time": "2023-01-01T00:00:00Z", "count": 3}`,
+			message: "hi",
+			level:   slog.LevelInfo,
+			time:    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			attrs:   map[string]string{"count": "3"},
+		},
+		{
+			name:    "json with unknown level",
+			line:    `{"message": "x", "level": "notice"}`,
+			message: "x",
+			level:   slog.LevelInfo,
+			time:    now,
+			attrs:   map[string]string{"level_original": "notice"},
+		},
+		{
+			name:    "invalid json",
+			line:    `{"message": `,
+			message: `{"message": `,
+			level:   slog.LevelInfo,
+			time:    now,
+			attrs:   map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := parseLine(tt.line, now)
+			if r.Message != tt.message {
+				t.Errorf("parseLine() message = %q, want %q", r.Message, tt.message)
+			}
+			if r.Level != tt.level {
+				t.Errorf("parseLine() level = %v, want %v", r.Level, tt.level)
+			}
+			if !r.Time.Equal(tt.time) {
+				t.Errorf("parseLine() time = %v, want %v", r.Time, tt.time)
+			}
+			attrs := recordAttrs(r)
+			if len(attrs) != len(tt.attrs) {
+				t.Errorf("parseLine() attrs = %v, want %v", attrs, tt.attrs)
+			}
+			for key, want := range tt.attrs {
+				if attrs[key] != want {
+					t.Errorf("parseLine() attr %q = %q, want %q", key, attrs[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestPrettyWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := prettyWriter{w: &buf}
+
+	n, err := w.Write([]byte(`{"a":1}` + "\n"))
+	if err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+	if n != 8 {
+		t.Errorf("Write() = %d, want 8", n)
+	}
+	if buf.String() != "{\n  \"a\": 1\n}\n" {
+		t.Errorf("Write() output = %q", buf.String())
+	}
+}
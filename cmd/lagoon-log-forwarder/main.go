@@ -0,0 +1,64 @@
+// Command lagoon-log-forwarder provides operational tooling around the
+// go-lagoon-log-forwarder package, such as validating config files.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// command is a single lagoon-log-forwarder subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"check-config", "validate a config file and print the effective config", checkConfig},
+	{"diff-config", "print the changes between two config files", diffConfig},
+	{"test-event", "send a single test event to the configured endpoint", testEvent},
+	{"tap", "print the events that would be sent for input lines", tap},
+	{"bench", "generate synthetic load against the configured endpoint", bench},
+	{"daemonset", "follow and forward the node's container logs", daemonset},
+	{"tail", "follow and forward log files matching glob patterns", tail},
+	{"forward", "forward the lines read from stdin", forward},
+	{"schema", "print the JSON Schema of events or validate events against it", schema},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdin, stdout, stderr)
+		}
+	}
+
+	switch args[0] {
+	case "help", "-h", "--help":
+		usage(stdout)
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: lagoon-log-forwarder <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+}
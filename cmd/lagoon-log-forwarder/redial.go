@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+)
+
+// The delay before a lost endpoint is dialled again doubles from
+// redialBackoff up to redialMaxBackoff
+const (
+	redialBackoff    = time.Second
+	redialMaxBackoff = time.Minute
+)
+
+// errRedialing is the error of a write while the endpoint is unreachable
+var errRedialing = errors.New("log endpoint is unreachable, waiting to redial")
+
+// redialer is the connection of a long running command to the log endpoint.
+// A failed write is retried once over a fresh connection. When that fails
+// too, writes fail without dialling until the backoff has passed, so the
+// command keeps following its input while the endpoint restarts.
+type redialer struct {
+	cfg logger.Config
+	now func() time.Time
+
+	mu       sync.Mutex
+	conn     io.WriteCloser
+	failures int
+	retryAt  time.Time
+}
+
+// dialRedialer connects to the endpoint of cfg, failing when it can't be
+// reached the first time
+func dialRedialer(cfg logger.Config) (*redialer, error) {
+	conn, err := logger.Dial(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &redialer{cfg: cfg, now: time.Now, conn: conn}, nil
+}
+
+func (r *redialer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn != nil {
+		n, err := r.conn.Write(p)
+		if err == nil {
+			return n, nil
+		}
+		_ = r.conn.Close()
+		r.conn = nil
+	} else if r.now().Before(r.retryAt) {
+		return 0, errRedialing
+	}
+
+	conn, err := logger.Dial(r.cfg)
+	if err != nil {
+		r.backoff()
+		return 0, err
+	}
+	n, err := conn.Write(p)
+	if err != nil {
+		_ = conn.Close()
+		r.backoff()
+		return n, err
+	}
+	r.conn, r.failures = conn, 0
+	return n, nil
+}
+
+// backoff delays the next dial after another failed one, the caller holds mu
+func (r *redialer) backoff() {
+	r.failures++
+	r.retryAt = r.now().Add(min(redialBackoff<<min(r.failures-1, 16), redialMaxBackoff))
+}
+
+// Close closes the connection
+func (r *redialer) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+func TestRedialer(t *testing.T) {
+	receiver, err := loggertest.Listen(loggertest.TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Close()
+
+	cfg := logger.NewConfig()
+	cfg.Protocol = logger.ProtocolTCP
+	cfg.LogHost = receiver.Host()
+	cfg.LogPort = receiver.Port()
+	r, err := dialRedialer(cfg)
+	if err != nil {
+		t.Fatalf("dialRedialer() returned unexpected error: %v", err)
+	}
+	defer r.Close()
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	if _, err := r.Write([]byte(`{"message":"before"}` + "\n")); err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+	if !receiver.Wait(1, time.Second) {
+		t.Fatal("the first record was not received")
+	}
+
+	// the endpoint refuses connections until it comes back below
+	receiver.Close()
+	for i := 0; ; i++ {
+		if _, err := r.Write([]byte(`{"message":"while down"}` + "\n")); err != nil {
+			break
+		}
+		if i == 100 {
+			t.Fatal("writes kept succeeding after the endpoint went away")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := r.Write([]byte(`{"message":"backing off"}` + "\n")); !errors.Is(err, errRedialing) {
+		t.Fatalf("Write() during the backoff = %v, want %v", err, errRedialing)
+	}
+
+	restarted, err := loggertest.ListenAddr(loggertest.TCP, receiver.Addr())
+	if err != nil {
+		t.Skipf("%s was taken before the endpoint came back: %v", receiver.Addr(), err)
+	}
+	defer restarted.Close()
+	now = now.Add(redialBackoff)
+	if _, err := r.Write([]byte(`{"message":"after"}` + "\n")); err != nil {
+		t.Fatalf("Write() after the backoff returned unexpected error: %v", err)
+	}
+	if !restarted.Wait(1, time.Second) {
+		t.Fatal("the record was not received once the endpoint came back")
+	}
+	if got := restarted.Events()[0]["message"]; got != "after" {
+		t.Errorf("message = %v, want %q", got, "after")
+	}
+}
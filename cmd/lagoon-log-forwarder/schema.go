@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+)
+
+// schema prints the JSON Schema of a message version, or validates events
+// against the schemas of their versions, e.g. the output of tap in CI
+func schema(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	versions := logger.SchemaVersions()
+	version := fs.Int("version", versions[len(versions)-1], fmt.Sprintf("message version of the schema printed, one of %v", versions))
+	validate := fs.Bool("validate", false, "validate the JSON events of the files (or stdin) instead, one per line")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: lagoon-log-forwarder schema [--version N]")
+		fmt.Fprintln(stderr, "       lagoon-log-forwarder schema --validate [file ...]")
+		fmt.Fprintln(stderr, "Prints the JSON Schema of the events of a message version, or validates events against it.")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if !*validate {
+		document, err := logger.Schema(*version)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+		_, _ = stdout.Write(document)
+		return 0
+	}
+
+	inputs := map[string]io.Reader{"stdin": stdin}
+	names := []string{"stdin"}
+	if fs.NArg() > 0 {
+		inputs, names = map[string]io.Reader{}, fs.Args()
+		for _, name := range names {
+			file, err := os.Open(name) // #nosec G304 -- files are supplied by the operator
+			if err != nil {
+				fmt.Fprintf(stderr, "error: %v\n", err)
+				return 1
+			}
+			defer file.Close()
+			inputs[name] = file
+		}
+	}
+
+	invalid := 0
+	for _, name := range names {
+		scanner := bufio.NewScanner(inputs[name])
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			if err := logger.ValidateEvent(scanner.Bytes()); err != nil {
+				fmt.Fprintf(stdout, "%s:%d: %v\n", name, line, err)
+				invalid++
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+
+	if invalid > 0 {
+		fmt.Fprintf(stderr, "%d invalid events\n", invalid)
+		return 1
+	}
+	return 0
+}
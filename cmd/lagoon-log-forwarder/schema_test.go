@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSchema_Print(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"schema", "--version=1"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("schema exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+	var document map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &document); err != nil {
+		t.Fatalf("schema output is not JSON: %v", err)
+	}
+	if !strings.HasSuffix(document["$id"].(string), "/v1.json") {
+		t.Errorf("$id = %v, want the schema of version 1", document["$id"])
+	}
+
+	if code := run([]string{"schema", "--version=9"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("schema of an unknown version exit code = %d, want 2", code)
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"tap", "--type=drupal", "--format=json"}, strings.NewReader("first\nsecond\n"), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("tap exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+	events := stdout.String()
+
+	stdout.Reset()
+	if code := run([]string{"schema", "--validate"}, strings.NewReader(events), &stdout, &stderr); code != 0 {
+		t.Errorf("schema --validate exit code = %d, want 0 (stdout: %s)", code, stdout.String())
+	}
+
+	stdout.Reset()
+	stdin := strings.NewReader(events + `{"@version":3,"message":"incomplete"}` + "\n")
+	if code := run([]string{"schema", "--validate"}, stdin, &stdout, &stderr); code != 1 {
+		t.Errorf("schema --validate exit code = %d, want 1", code)
+	}
+	if !strings.HasPrefix(stdout.String(), "stdin:3: ") || !strings.Contains(stdout.String(), "missing level") {
+		t.Errorf("schema --validate output = %q, want the violation of line 3", stdout.String())
+	}
+}
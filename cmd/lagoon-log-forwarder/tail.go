@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/tailer"
+)
+
+// tail follows log files matching glob patterns and forwards their lines, as
+// a sidecar of applications that only write files
+func tail(args []string, _ io.Reader, _, stderr io.Writer) int {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cf := addConfigFlags(fs)
+	format := fs.String("format", tailer.FormatPlain, "line format (plain, json, regex, cri, docker or container)")
+	pattern := fs.String("pattern", "", "regular expression of the regex format, with message, level and time named groups")
+	timeLayout := fs.String("time-layout", "", "Go time layout of the time group (default RFC 3339)")
+	interval := fs.Duration("poll", time.Second, "how often to read new lines")
+	fromStart := fs.Bool("from-beginning", false, "forward files present at startup from their beginning")
+	once := fs.Bool("once", false, "forward the lines of the files once and exit")
+	lf := addLeaderFlags(fs)
+	ff := addFieldFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: lagoon-log-forwarder tail [flags] <pattern> ...")
+		fmt.Fprintln(stderr, "Follows the log files matching the glob patterns across rotation and forwards their lines.")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	tcfg := tailer.Config{
+		Paths:        fs.Args(),
+		Format:       *format,
+		Pattern:      *pattern,
+		TimeLayout:   *timeLayout,
+		PollInterval: *interval,
+		FromStart:    *fromStart || *once,
+		Fields:       tailer.FieldPolicy{Unknown: ff.unknown},
+	}
+	if err := tcfg.Validate(); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+
+	cfg, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	// file lines have no meaningful caller location
+	cfg.AddSource = false
+	if tcfg.Fields, err = ff.policy(cfg); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	// lines the endpoint misses while it restarts are reported by the tailer
+	conn, err := dialRedialer(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: connect to %s:%d: %v\n", cfg.LogHost, cfg.LogPort, err)
+		return 1
+	}
+	defer conn.Close()
+
+	handler, err := logger.NewWriterHandler(cfg, conn)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	// the config was validated above
+	t, _ := tailer.New(tcfg, handler)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = lf.run(ctx, stderr, func(ctx context.Context) error {
+		if *once {
+			// the last lines are forwarded even without a newline
+			defer t.Close(context.WithoutCancel(ctx))
+			return t.Poll(ctx)
+		}
+		return t.Run(ctx)
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTail_Once(t *testing.T) {
+	receiver, port := listenUDP(t)
+	dir := t.TempDir()
+	appendFile(t, filepath.Join(dir, "app.log"), `{"message": "cron finished", "level": "warn", "job": "cleanup"}`+"\n")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"tail", "--once", "--format=json", "--type=drupal", "--host=127.0.0.1", "--port=" + port, filepath.Join(dir, "*.log")}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("tail exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+
+	buf := make([]byte, 65535)
+	if err := receiver.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %v", err)
+	}
+	n, err := receiver.Read(buf)
+	if err != nil {
+		t.Fatalf("no event received: %v", err)
+	}
+	var event map[string]any
+	if err := json.Unmarshal(buf[:n], &event); err != nil {
+		t.Fatalf("received event is not JSON: %v", err)
+	}
+	expected := map[string]any{
+		"message": "cron finished",
+		"level":   "WARN",
+		"type":    "drupal",
+		"job":     "cleanup",
+		"file":    filepath.Join(dir, "app.log"),
+	}
+	for key, want := range expected {
+		if event[key] != want {
+			t.Errorf("event[%q] = %v, want %v", key, event[key], want)
+		}
+	}
+}
+
+func TestTail_OnceForwardsLastLine(t *testing.T) {
+	receiver, port := listenUDP(t)
+	dir := t.TempDir()
+	appendFile(t, filepath.Join(dir, "app.log"), "first\nlast without newline")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"tail", "--once", "--type=drupal", "--host=127.0.0.1", "--port=" + port, filepath.Join(dir, "*.log")}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("tail exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+
+	buf := make([]byte, 65535)
+	for _, want := range []string{"first", "last without newline"} {
+		if err := receiver.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+			t.Fatalf("failed to set read deadline: %v", err)
+		}
+		n, err := receiver.Read(buf)
+		if err != nil {
+			t.Fatalf("no event received for %q: %v", want, err)
+		}
+		var event map[string]any
+		if err := json.Unmarshal(buf[:n], &event); err != nil {
+			t.Fatalf("received event is not JSON: %v", err)
+		}
+		if event["message"] != want {
+			t.Errorf("message = %v, want %q", event["message"], want)
+		}
+	}
+}
+
+func TestTail_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args []string
+	}{
+		{"no patterns", []string{"tail"}},
+		{"unknown format", []string{"tail", "--format=xml", "--host=127.0.0.1", filepath.Join(os.TempDir(), "*.log")}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, nil, &stdout, &stderr); code != 2 {
+				t.Errorf("tail exit code = %d, want 2 (stderr: %s)", code, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+)
+
+// tap prints the fully transformed events for each input line without
+// sending anything, so field mappings can be debugged locally
+func tap(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("tap", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cf := addConfigFlags(fs)
+	format := fs.String("format", "pretty", "output format (pretty or json)")
+	ff := addFieldFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: lagoon-log-forwarder tap [flags] [file ...]")
+		fmt.Fprintln(stderr, "Reads lines from the files (or stdin) and prints the events that would be sent.")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var out io.Writer
+	switch *format {
+	case "json":
+		out = stdout
+	case "pretty":
+		out = prettyWriter{w: stdout}
+	default:
+		fmt.Fprintf(stderr, "error: unknown format %q\n", *format)
+		return 2
+	}
+	if err := ff.validate(); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+
+	cfg, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	policy, err := ff.policy(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	handler, err := logger.NewWriterHandler(cfg, out)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	inputs := []io.Reader{stdin}
+	if fs.NArg() > 0 {
+		inputs = inputs[:0]
+		for _, name := range fs.Args() {
+			file, err := os.Open(name) // #nosec G304 -- files are supplied by the operator
+			if err != nil {
+				fmt.Fprintf(stderr, "error: %v\n", err)
+				return 1
+			}
+			defer file.Close()
+			inputs = append(inputs, file)
+		}
+	}
+
+	ctx := context.Background()
+	for _, input := range inputs {
+		scanner := bufio.NewScanner(input)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			if err := handler.Handle(ctx, policy.Apply(parseLine(scanner.Text(), time.Now()))); err != nil {
+				fmt.Fprintf(stderr, "error: %v\n", err)
+				return 1
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+
+	return 0
+}
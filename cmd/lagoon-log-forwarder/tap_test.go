@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTap_JSON(t *testing.T) {
+	stdin := strings.NewReader("plain line\n\n" + `{"message": "json line", "level": "warn", "user": "bob"}` + "\n")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"tap", "--type=drupal", "--format=json"}, stdin, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("tap exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("tap printed %d events, want 2: %q", len(lines), stdout.String())
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil {
+		t.Fatalf("tap output is not JSON: %v", err)
+	}
+	expected := map[string]any{
+		"message": "json line",
+		"level":   "WARN",
+		"type":    "drupal",
+		"user":    "bob",
+	}
+	for key, want := range expected {
+		if event[key] != want {
+			t.Errorf("event[%q] = %v, want %v", key, event[key], want)
+		}
+	}
+	if _, ok := event["source"]; ok {
+		t.Error("tap events should not carry the CLI source location")
+	}
+}
+
+func TestTap_PrettyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.log")
+	if err := os.WriteFile(path, []byte("from file\n"), 0o600); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"tap", "--type=drupal", path}, strings.NewReader("ignored\n"), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("tap exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "\n  \"message\": \"from file\"") {
+		t.Errorf("tap pretty output = %q", stdout.String())
+	}
+	if strings.Contains(stdout.String(), "ignored") {
+		t.Error("tap should read files instead of stdin when given")
+	}
+}
+
+func TestTap_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"unknown format", []string{"tap", "--type=drupal", "--format=xml"}, 2},
+		{"missing type", []string{"tap"}, 1},
+		{"missing file", []string{"tap", "--type=drupal", "/nonexistent/input.log"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, strings.NewReader(""), &stdout, &stderr); code != tt.code {
+				t.Errorf("run(%v) exit code = %d, want %d", tt.args, code, tt.code)
+			}
+		})
+	}
+}
+
+func TestTap_UnknownFields(t *testing.T) {
+	line := `{"message": "order paid", "user": "bob", "order": "1001", "kubernetes": {"pod": "shop-1"}}` + "\n"
+
+	tests := []struct {
+		policy string
+		want   map[string]any
+	}{
+		{"drop", map[string]any{"user": "bob"}},
+		{"nest", map[string]any{"user": "bob", "unknown": map[string]any{"order": "1001", "kubernetes": map[string]any{"pod": "shop-1"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := []string{"tap", "--type=drupal", "--format=json", "--unknown-fields=" + tt.policy, "--known-fields=user"}
+			if code := run(args, strings.NewReader(line), &stdout, &stderr); code != 0 {
+				t.Fatalf("tap exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+			}
+
+			var event map[string]any
+			if err := json.Unmarshal(stdout.Bytes(), &event); err != nil {
+				t.Fatalf("tap output is not JSON: %v", err)
+			}
+			if event["message"] != "order paid" || event["type"] != "drupal" {
+				t.Errorf("event = %v, want the message and the fields of the schema", event)
+			}
+			for _, key := range []string{"user", "order", "kubernetes", "unknown"} {
+				got, _ := json.Marshal(event[key])
+				want, _ := json.Marshal(tt.want[key])
+				if !bytes.Equal(got, want) {
+					t.Errorf("event[%q] = %s, want %s", key, got, want)
+				}
+			}
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"tap", "--unknown-fields=keep"}, strings.NewReader(line), &stdout, &stderr); code != 2 {
+		t.Errorf("tap exit code = %d for an unknown policy, want 2", code)
+	}
+}
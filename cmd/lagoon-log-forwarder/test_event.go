@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+)
+
+// testEvent sends a single Lagoon formatted event to the configured endpoint
+// over its protocol and echoes it on stdout, failing when it can't be sent
+func testEvent(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("test-event", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cf := addConfigFlags(fs)
+	level := fs.String("level", "info", "event level (debug, info, warn, error or critical)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: lagoon-log-forwarder test-event [flags] <message>")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	lvl, err := logger.ParseLevel(*level)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+
+	cfg, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	conn, err := logger.Dial(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: connect to %s:%d: %v\n", cfg.LogHost, cfg.LogPort, err)
+		return 1
+	}
+	defer conn.Close()
+
+	handler, err := logger.NewWriterHandler(cfg, io.MultiWriter(stdout, conn))
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	if !handler.Enabled(ctx, lvl) {
+		fmt.Fprintf(stderr, "error: %s events are below the configured level\n", lvl)
+		return 1
+	}
+	// the handler is called directly, as a logger would drop its error
+	r := slog.NewRecord(time.Now(), lvl, strings.Join(fs.Args(), " "), 0)
+	if err := handler.Handle(ctx, r); err != nil {
+		fmt.Fprintf(stderr, "error: send event: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+// listenUDP starts a local UDP receiver and returns it with its port
+func listenUDP(t *testing.T) (*net.UDPConn, string) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen on udp: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func TestTestEvent(t *testing.T) {
+	receiver, port := listenUDP(t)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"test-event", "--type=drupal", "--level=error", "--host=127.0.0.1", "--port=" + port, "pipeline", "check"}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("test-event exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+
+	buf := make([]byte, 65535)
+	if err := receiver.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %v", err)
+	}
+	n, err := receiver.Read(buf)
+	if err != nil {
+		t.Fatalf("no event received: %v", err)
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal(buf[:n], &event); err != nil {
+		t.Fatalf("received event is not JSON: %v", err)
+	}
+
+	expected := map[string]any{
+		"message": "pipeline check",
+		"level":   "ERROR",
+		"type":    "drupal",
+		"channel": "LagoonLogs",
+	}
+	for key, want := range expected {
+		if event[key] != want {
+			t.Errorf("event[%q] = %v, want %v", key, event[key], want)
+		}
+	}
+
+	if !bytes.Equal(bytes.TrimSpace(stdout.Bytes()), bytes.TrimSpace(buf[:n])) {
+		t.Errorf("stdout should echo the sent event, got %q", stdout.String())
+	}
+}
+
+func TestTestEvent_Critical(t *testing.T) {
+	receiver, port := listenUDP(t)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"test-event", "--type=drupal", "--level=critical", "--host=127.0.0.1", "--port=" + port, "disk full"}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("test-event exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+
+	buf := make([]byte, 65535)
+	if err := receiver.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %v", err)
+	}
+	n, err := receiver.Read(buf)
+	if err != nil {
+		t.Fatalf("no event received: %v", err)
+	}
+	var event map[string]any
+	if err := json.Unmarshal(buf[:n], &event); err != nil {
+		t.Fatalf("received event is not JSON: %v", err)
+	}
+	if event["level"] != "CRITICAL" {
+		t.Errorf("event level = %v, want CRITICAL", event["level"])
+	}
+}
+
+func TestTestEvent_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"no message", []string{"test-event", "--type=drupal"}, 2},
+		{"invalid level", []string{"test-event", "--type=drupal", "--level=loud", "msg"}, 2},
+		{"missing type", []string{"test-event", "--host=127.0.0.1", "msg"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, nil, &stdout, &stderr); code != tt.code {
+				t.Errorf("run(%v) exit code = %d, want %d", tt.args, code, tt.code)
+			}
+		})
+	}
+
+	t.Run("level below the configured level", func(t *testing.T) {
+		t.Setenv("LOGGER_LEVEL", "error")
+		var stdout, stderr bytes.Buffer
+		args := []string{"test-event", "--type=drupal", "--host=127.0.0.1", "--level=info", "msg"}
+		if code := run(args, nil, &stdout, &stderr); code != 1 {
+			t.Errorf("run(%v) exit code = %d, want 1 (stderr: %s)", args, code, stderr.String())
+		}
+	})
+}
+
+func TestTestEvent_HTTP(t *testing.T) {
+	receiver, err := loggertest.Listen(loggertest.HTTP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer receiver.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"test-event", "--type=drupal", "--protocol=http", "--host=" + receiver.Host(), "--port=" + strconv.Itoa(receiver.Port()), "over", "http"}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("test-event exit code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+	if !receiver.Wait(1, 2*time.Second) {
+		t.Fatal("no event received over http")
+	}
+	if got := receiver.Events()[0]["message"]; got != "over http" {
+		t.Errorf("message = %v, want over http", got)
+	}
+
+	// an event the endpoint rejects fails the command
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+	host, port, _ := net.SplitHostPort(rejecting.Listener.Addr().String())
+
+	stderr.Reset()
+	args = []string{"test-event", "--type=drupal", "--protocol=http", "--host=" + host, "--port=" + port, "rejected"}
+	if code := run(args, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("test-event exit code = %d, want 1 when the endpoint rejects the event", code)
+	}
+	if !strings.Contains(stderr.String(), "send event") {
+		t.Errorf("stderr = %q, want the send error", stderr.String())
+	}
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"log/slog"
+	"os/exec"
+	"testing"
+)
+
+func TestWrapCmd(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+
+	var err error
+	events := writerEvents(t, func() {
+		cmd := WrapCmd(exec.Command("sh", "-c", "echo migrated; echo 'table locked' >&2; printf partial; exit 3"), slog.String("job", "migrate"))
+		err = cmd.Run()
+	})
+	if err == nil {
+		t.Fatal("Run() of a failing command returned no error")
+	}
+
+	byMessage := map[string]map[string]any{}
+	for _, event := range events {
+		if event["job"] != "migrate" || event[commandKey] != "sh" {
+			t.Errorf("event = %v, want the command and attributes of WrapCmd", event)
+		}
+		byMessage[event["message"].(string)] = event
+	}
+	for _, tt := range []struct {
+		message, level, stream string
+	}{
+		{"migrated", "INFO", "stdout"},
+		{"table locked", "WARN", "stderr"},
+		{"partial", "INFO", "stdout"},
+	} {
+		event := byMessage[tt.message]
+		if event == nil || event["level"] != tt.level || event[streamKey] != tt.stream {
+			t.Errorf("%q logged as %v, want %s on %s", tt.message, event, tt.level, tt.stream)
+		}
+	}
+
+	exit := byMessage["Command exited"]
+	if exit == nil || exit["level"] != "ERROR" || exit[exitCodeKey] != 3.0 || exit[FieldDurationMS] == nil {
+		t.Errorf("exit logged as %v, want the exit code at ERROR", exit)
+	}
+	if last := events[len(events)-1]; last["message"] != "Command exited" {
+		t.Errorf("last event = %v, want the exit after the output", last)
+	}
+}
+
+func TestWrapCmd_NotFound(t *testing.T) {
+	events := writerEvents(t, func() {
+		if err := WrapCmd(exec.Command("lagoon-no-such-command")).Run(); err == nil {
+			t.Error("Run() of a missing command returned no error")
+		}
+	})
+	if len(events) != 0 {
+		t.Errorf("got %v, want nothing logged for a command that didn't start", events)
+	}
+}
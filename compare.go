@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Change is a setting that differs between two configs
+type Change struct {
+	Field string `json:"field"` // name of the setting in config files, e.g. "messageVersion"
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+	// Unsafe changes can't be applied to a running logger, Reason says why
+	Unsafe bool   `json:"unsafe"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// String describes the change as the changelog does, e.g.
+// "messageVersion: 2→3"
+func (c Change) String() string {
+	return change(c.Field, formatSetting(c.Old), formatSetting(c.New))
+}
+
+const (
+	// reasonSchema is why changing the layout of events live is unsafe
+	reasonSchema = "changes the events consumers receive"
+	// reasonRestart is why changing a setting applied once is unsafe
+	reasonRestart = "only applies after Shutdown"
+)
+
+// unsafeChanges are the settings that can't be changed while the logger
+// runs: those changing the layout of the events, and those applied once by
+// the first Initialize or NewHandler, such as the endpoint and the sinks
+var unsafeChanges = map[string]string{
+	"messageVersion": reasonSchema,
+	"compatMode":     reasonSchema,
+	"sourceFormat":   reasonSchema,
+
+	"logHost":              reasonRestart,
+	"logPort":              reasonRestart,
+	"fallbackHosts":        reasonRestart,
+	"failbackInterval":     reasonRestart,
+	"stdoutLevel":          reasonRestart,
+	"forwardLevel":         reasonRestart,
+	"stdoutFormat":         reasonRestart,
+	"protocol":             reasonRestart,
+	"writeTimeout":         reasonRestart,
+	"tls":                  reasonRestart,
+	"http":                 reasonRestart,
+	"forward":              reasonRestart,
+	"otlp":                 reasonRestart,
+	"syslog":               reasonRestart,
+	"destinations":         reasonRestart,
+	"deliveryWorkers":      reasonRestart,
+	"queueSize":            reasonRestart,
+	"ordering":             reasonRestart,
+	"orderingKey":          reasonRestart,
+	"deliveryPolicy":       reasonRestart,
+	"recordAttempts":       reasonRestart,
+	"batchSize":            reasonRestart,
+	"batchInterval":        reasonRestart,
+	"batchLatency":         reasonRestart,
+	"skewProbeURL":         reasonRestart,
+	"skewProbeInterval":    reasonRestart,
+	"statsFile":            reasonRestart,
+	"statsInterval":        reasonRestart,
+	"statsEventInterval":   reasonRestart,
+	"statsEventChannel":    reasonRestart,
+	"captureStderr":        reasonRestart,
+	"spoolDir":             reasonRestart,
+	"spoolMaxBytes":        reasonRestart,
+	"replayWindow":         reasonRestart,
+	"maxMessageBytes":      reasonRestart,
+	"egressBudget":         reasonRestart,
+	"egressWindow":         reasonRestart,
+	"egressSampleRate":     reasonRestart,
+	"maxEventsPerSecond":   reasonRestart,
+	"burst":                reasonRestart,
+	"memoryPressure":       reasonRestart,
+	"memoryLimit":          reasonRestart,
+	"memorySampleRate":     reasonRestart,
+	"remoteConfigURL":      reasonRestart,
+	"remoteConfigKey":      reasonRestart,
+	"remoteConfigInterval": reasonRestart,
+	"flagRefreshInterval":  reasonRestart,
+	"debugSignal":          reasonRestart,
+	"trace":                reasonRestart,
+}
+
+// CompareConfigs returns the settings changed from old to new, in the order
+// of the fields of Config, marking those that can't be applied to a running
+// logger as Unsafe. Settings that can't be set in config files, such as
+// Processors, aren't compared.
+func CompareConfigs(old, new Config) []Change {
+	var changes []Change
+	o, n := reflect.ValueOf(old), reflect.ValueOf(new)
+	t := o.Type()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if len(name) == 0 || name == "-" {
+			continue
+		}
+		from, to := o.Field(i).Interface(), n.Field(i).Interface()
+		if reflect.DeepEqual(from, to) {
+			continue
+		}
+		reason, unsafe := unsafeChanges[name]
+		changes = append(changes, Change{Field: name, Old: from, New: to, Unsafe: unsafe, Reason: reason})
+	}
+	return changes
+}
+
+// formatSetting writes v as in a change of the changelog: strings, numbers
+// and durations as they are, other values as JSON
+func formatSetting(v any) string {
+	switch v := v.(type) {
+	case string, bool, int, int64, float64, fmt.Stringer:
+		return fmt.Sprint(v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+func TestCompareConfigs(t *testing.T) {
+	old := NewConfig()
+	old.LogType = "example-main"
+	old.Level = "info"
+
+	new := old
+	new.Level = "debug"
+	new.MessageVersion = 3
+	new.BatchInterval = 2 * time.Second
+	new.TLS = &TLSConfig{CAFile: "/etc/ca.pem"}
+	new.Processors = []Processor{DropAttrs("noisy")}
+
+	changes := CompareConfigs(old, new)
+	var described []string
+	for _, c := range changes {
+		described = append(described, c.String())
+	}
+	expected := []string{
+		"messageVersion: 1→3",
+		"level: info→debug",
+		`tls: null→{"caFile":"/etc/ca.pem","certFile":"","keyFile":"","insecureSkipVerify":false,"serverName":""}`,
+		"batchInterval: 100ms→2s",
+	}
+	if !reflect.DeepEqual(described, expected) {
+		t.Errorf("CompareConfigs() = %q, want %q", described, expected)
+	}
+
+	for _, c := range changes {
+		if unsafe := c.Field != "level"; c.Unsafe != unsafe || (unsafe && len(c.Reason) == 0) {
+			t.Errorf("change of %s is unsafe = %v (%s), want %v", c.Field, c.Unsafe, c.Reason, unsafe)
+		}
+	}
+	if changes := CompareConfigs(old, old); len(changes) != 0 {
+		t.Errorf("CompareConfigs() of the same config = %v, want none", changes)
+	}
+}
+
+func TestUnsafeChanges_Fields(t *testing.T) {
+	fields := map[string]bool{}
+	typ := reflect.TypeOf(Config{})
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		fields[name] = true
+	}
+	for name := range unsafeChanges {
+		if !fields[name] {
+			t.Errorf("unsafe change %s is not a setting of Config", name)
+		}
+	}
+}
+
+func TestInitialize_StrictReconfigure(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	running = nil
+
+	r, err := loggertest.Listen(loggertest.UDP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "reconfigure"
+	cfg.LogHost = r.Host()
+	cfg.LogPort = r.Port()
+	cfg.StrictReconfigure = true
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = Shutdown(ctx)
+	}()
+
+	changed := cfg
+	changed.Level = "warn"
+	if err := Initialize(changed); err != nil {
+		t.Errorf("Initialize() of a safe change returned unexpected error: %v", err)
+	}
+
+	changed.LogPort++
+	err = Initialize(changed)
+	if err == nil || !strings.Contains(err.Error(), "logPort") {
+		t.Errorf("Initialize() of a new port = %v, want the unsafe change rejected", err)
+	}
+	if logPort != r.Port() || level != "warn" {
+		t.Errorf("the rejected config was applied: port %d, level %s", logPort, level)
+	}
+}
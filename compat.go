@@ -0,0 +1,238 @@
+package logger
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"runtime"
+	"slices"
+	"time"
+)
+
+// CompatMonologLagoon writes events as the Lagoon PHP and Drupal logs
+// handlers do, Monolog's LogstashFormatter, for pipelines and dashboards
+// built on their payloads
+const CompatMonologLagoon = "monolog-lagoon"
+
+const (
+	// monologMaxDatagram is the largest datagram Monolog's UDP socket sends,
+	// the default MaxMessageBytes of forwarded events in CompatMonologLagoon
+	monologMaxDatagram = 65023
+	// monologTimeFormat is the @timestamp of Monolog, in microseconds with
+	// the offset of the zone
+	monologTimeFormat = "2006-01-02T15:04:05.000000-07:00"
+)
+
+// monologExtraKeys are the fields the handler adds to every record, which
+// Monolog processors add to extra rather than the context of the call
+var monologExtraKeys = map[string]bool{
+	"application": true,
+	"lagoon":      true,
+	senderKey:     true,
+	schemaKey:     true,
+	ownerKey:      true,
+	FieldTraceID:  true,
+	FieldSpanID:   true,
+	truncatedKey:  true,
+	"clock":       true,
+}
+
+// monologLevel returns the name and number of the Monolog level of level.
+// slog has no NOTICE, CRITICAL, ALERT or EMERGENCY, which are the levels
+// between and above its own: slog.LevelInfo+2 is NOTICE and
+// slog.LevelError+4, +8 and +12 are CRITICAL, ALERT and EMERGENCY.
+func monologLevel(level slog.Level) (string, int) {
+	switch {
+	case level >= slog.LevelError+12:
+		return "EMERGENCY", 600
+	case level >= slog.LevelError+8:
+		return "ALERT", 550
+	case level >= slog.LevelError+4:
+		return "CRITICAL", 500
+	case level >= slog.LevelError:
+		return "ERROR", 400
+	case level >= slog.LevelWarn:
+		return "WARNING", 300
+	case level >= slog.LevelInfo+2:
+		return "NOTICE", 250
+	case level >= slog.LevelInfo:
+		return "INFO", 200
+	default:
+		return "DEBUG", 100
+	}
+}
+
+// monologHandler writes records in the layout of Monolog's LogstashFormatter:
+// the host, log type and channel in the header, the attributes of the call in
+// context and the fields of the forwarder, such as the caller, in extra
+type monologHandler struct {
+	w    io.Writer
+	opts slog.HandlerOptions
+	// host, logType and channel are taken from the static attrs
+	host, logType, channel string
+	context, extra         []slog.Attr
+	groups                 []string
+}
+
+// newMonologHandler returns a handler writing the records of opts with the
+// static attrs to w
+func newMonologHandler(w io.Writer, opts *slog.HandlerOptions, attrs []any) *monologHandler {
+	h := &monologHandler{w: w, opts: *opts}
+	for _, a := range slog.Group("", attrs...).Value.Group() {
+		switch a.Key {
+		case "host":
+			h.host = a.Value.String()
+		case "type":
+			h.logType = a.Value.String()
+		case "channel":
+			h.channel = a.Value.String()
+		case "@version":
+			// Monolog's LogstashFormatter always writes version 1
+		default:
+			h.context, h.extra = routeMonologAttr(h.context, h.extra, a)
+		}
+	}
+	return h
+}
+
+// routeMonologAttr appends a to the context of the call or extra. The groups named context
+// and extra are merged into them, so the events of Emit and SourceExtra keep
+// their shape.
+func routeMonologAttr(call, extra []slog.Attr, a slog.Attr) ([]slog.Attr, []slog.Attr) {
+	switch {
+	case a.Key == "context" && a.Value.Kind() == slog.KindGroup:
+		return append(call, a.Value.Group()...), extra
+	case a.Key == "extra" && a.Value.Kind() == slog.KindGroup:
+		return call, append(extra, a.Value.Group()...)
+	case monologExtraKeys[a.Key]:
+		if a.Key == "application" && len(a.Value.String()) == 0 {
+			return call, extra
+		}
+		return call, append(extra, a)
+	default:
+		return append(call, a), extra
+	}
+}
+
+func (h *monologHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *monologHandler) Handle(_ context.Context, r slog.Record) error {
+	timestamp := r.Time.Round(0).Format(monologTimeFormat)
+
+	var attrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "timestampOverride" {
+			timestamp = a.Value.String()
+			return true
+		}
+		attrs = append(attrs, a)
+		return true
+	})
+	for i := len(h.groups) - 1; i >= 0 && len(attrs) > 0; i-- {
+		attrs = []slog.Attr{{Key: h.groups[i], Value: slog.GroupValue(attrs...)}}
+	}
+
+	call, extra := slices.Clone(h.context), slices.Clone(h.extra)
+	if h.opts.AddSource && r.PC != 0 {
+		// the fields of Monolog's IntrospectionProcessor
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		extra = append(extra, slog.String("file", frame.File), slog.Int("line", frame.Line), slog.String("function", frame.Function))
+	}
+	for _, a := range attrs {
+		call, extra = routeMonologAttr(call, extra, a)
+	}
+
+	name, number := monologLevel(r.Level)
+	event := orderedObject{
+		{"@timestamp", timestamp},
+		{"@version", 1},
+		{"host", h.host},
+		{"message", r.Message},
+		{"type", h.logType},
+		{"channel", h.channel},
+		{"level", name},
+		{"monolog_level", number},
+	}
+	// Monolog leaves out an empty extra or context
+	for _, field := range []struct {
+		key   string
+		attrs []slog.Attr
+	}{{"extra", extra}, {"context", call}} {
+		object, err := h.encodeAttrs(field.attrs)
+		if err != nil {
+			return err
+		}
+		if len(object) > 2 {
+			event = append(event, objectField{field.key, object})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, event); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+// encodeAttrs returns attrs as a JSON object encoded by slog, so values are
+// written as they are in the Lagoon format
+func (h *monologHandler) encodeAttrs(attrs []slog.Attr) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	jh := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.LevelKey || a.Key == slog.MessageKey) {
+				return slog.Attr{}
+			}
+			if h.opts.ReplaceAttr != nil {
+				return h.opts.ReplaceAttr(groups, a)
+			}
+			return a
+		},
+	})
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "", 0)
+	r.AddAttrs(attrs...)
+	if err := jh.Handle(context.Background(), r); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+func (h *monologHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.context, c.extra = slices.Clone(h.context), slices.Clone(h.extra)
+	for _, a := range attrs {
+		for i := len(h.groups) - 1; i >= 0; i-- {
+			a = slog.Attr{Key: h.groups[i], Value: slog.GroupValue(a)}
+		}
+		c.context, c.extra = routeMonologAttr(c.context, c.extra, a)
+	}
+	return &c
+}
+
+func (h *monologHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.groups = append(slices.Clone(h.groups), name)
+	return &c
+}
+
+// forwardMaxBytes returns the size limit of the events forwarded over
+// protocol: MaxMessageBytes, or in CompatMonologLagoon the largest datagram
+// Monolog sends over UDP. Monolog cuts the JSON of a larger event, the events
+// here are fitted with MessageTruncation so they stay valid JSON.
+func forwardMaxBytes(protocol string) int {
+	if maxMessageBytes == 0 && compatMode == CompatMonologLagoon && cmp.Or(protocol, ProtocolUDP) == ProtocolUDP {
+		return monologMaxDatagram
+	}
+	return maxMessageBytes
+}
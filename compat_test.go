@@ -0,0 +1,285 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// compatRecords log the records matching the payloads captured from the
+// Lagoon PHP handlers in testdata/compat/monolog-lagoon
+var compatRecords = []struct {
+	name        string
+	application string
+	log         func(h slog.Handler) error
+}{
+	{"info", "drupal", func(h slog.Handler) error {
+		r := slog.NewRecord(goldenTime, slog.LevelInfo, "User logged in", 0)
+		r.AddAttrs(slog.Int("uid", 7), slog.Any("roles", []string{"editor"}))
+		return h.Handle(context.Background(), r)
+	}},
+	{"notice", "drupal", func(h slog.Handler) error {
+		return h.Handle(context.Background(), slog.NewRecord(goldenTime, slog.LevelInfo+2, "Cache cleared", 0))
+	}},
+	{"warning", "drupal", func(h slog.Handler) error {
+		r := slog.NewRecord(goldenTime, slog.LevelWarn, "Page not found", 0)
+		r.AddAttrs(slog.String("path", "/node/1"), slog.String("referer", "https://example.com/"))
+		return h.Handle(context.Background(), r)
+	}},
+	{"critical", "drupal", func(h slog.Handler) error {
+		r := slog.NewRecord(goldenTime, slog.LevelError+4, "Database unavailable", 0)
+		r.AddAttrs(slog.Any("exception", errors.New("PDOException: SQLSTATE[HY000] [2002] Connection refused")), slog.Float64("retry", 1.5))
+		return h.Handle(context.Background(), r)
+	}},
+	{"debug", "", func(h slog.Handler) error {
+		r := slog.NewRecord(goldenTime, slog.LevelDebug, "Queue processed", 0)
+		r.AddAttrs(slog.Int("items", 25))
+		return h.Handle(context.Background(), r)
+	}},
+}
+
+// newCompatHandler returns a handler of the golden configuration in
+// CompatMonologLagoon writing to w
+func newCompatHandler(t *testing.T, application string, w io.Writer) slog.Handler {
+	t.Helper()
+	hostname = "nginx-php-6f7d9"
+
+	cfg := NewConfig()
+	cfg.LogType = "example-main"
+	cfg.ApplicationName = application
+	cfg.AddSource = false
+	cfg.LogHost = "localhost"
+	cfg.CompatMode = CompatMonologLagoon
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() returned unexpected error: %v", err)
+	}
+	if err := config(cfg); err != nil {
+		t.Fatalf("config() returned unexpected error: %v", err)
+	}
+	return newHandler(w)
+}
+
+// orderedJSON decodes data keeping the order of the keys of its objects, so
+// events compare equal only when their fields are written in the same order
+func orderedJSON(t *testing.T, data []byte) any {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decode func() any
+	decode = func() any {
+		token, err := decoder.Token()
+		if err != nil {
+			t.Fatalf("invalid JSON %s: %v", data, err)
+		}
+		switch token {
+		case json.Delim('{'):
+			var object [][2]any
+			for decoder.More() {
+				key, _ := decoder.Token()
+				object = append(object, [2]any{key, decode()})
+			}
+			_, _ = decoder.Token()
+			return object
+		case json.Delim('['):
+			var array []any
+			for decoder.More() {
+				array = append(array, decode())
+			}
+			_, _ = decoder.Token()
+			return array
+		}
+		return token
+	}
+	return decode()
+}
+
+// TestCompatMonologLagoon_Golden compares the events of CompatMonologLagoon
+// with payloads captured from the Lagoon PHP handlers, field by field and in
+// order. PHP encodes some values differently, e.g. 1.0 for a float, so the
+// payloads are compared decoded rather than byte for byte.
+func TestCompatMonologLagoon_Golden(t *testing.T) {
+	preserveConfig(t)
+
+	for _, record := range compatRecords {
+		t.Run(record.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := record.log(newCompatHandler(t, record.application, &buf)); err != nil {
+				t.Fatalf("Handle() returned unexpected error: %v", err)
+			}
+
+			path := filepath.Join("testdata", "compat", CompatMonologLagoon, record.name+".json")
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(orderedJSON(t, buf.Bytes()), orderedJSON(t, want)) {
+				t.Errorf("event differs from the PHP payload %s\n got: %s\nwant: %s", path, buf.Bytes(), want)
+			}
+		})
+	}
+}
+
+func TestMonologLevel(t *testing.T) {
+	tests := []struct {
+		level  slog.Level
+		name   string
+		number int
+	}{
+		{slog.LevelDebug - 4, "DEBUG", 100},
+		{slog.LevelDebug, "DEBUG", 100},
+		{slog.LevelInfo, "INFO", 200},
+		{slog.LevelInfo + 2, "NOTICE", 250},
+		{slog.LevelWarn, "WARNING", 300},
+		{slog.LevelError, "ERROR", 400},
+		{slog.LevelError + 4, "CRITICAL", 500},
+		{slog.LevelError + 8, "ALERT", 550},
+		{slog.LevelError + 12, "EMERGENCY", 600},
+		{slog.LevelError + 20, "EMERGENCY", 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			if name, number := monologLevel(tt.level); name != tt.name || number != tt.number {
+				t.Errorf("monologLevel(%v) = %s, %d, want %s, %d", tt.level, name, number, tt.name, tt.number)
+			}
+		})
+	}
+}
+
+func TestCompatMonologLagoon_Extra(t *testing.T) {
+	preserveConfig(t)
+
+	var buf bytes.Buffer
+	h := newCompatHandler(t, "drupal", &buf)
+	logger := slog.New(h).With("request_id", "r-1").WithGroup("http")
+	logger.Info("request handled", "status", 200)
+
+	var event map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	context, _ := event["context"].(map[string]any)
+	if context["request_id"] != "r-1" || context["http"].(map[string]any)["status"] != float64(200) {
+		t.Errorf("context = %v, want the attrs of the call with their groups", event["context"])
+	}
+	extra, _ := event["extra"].(map[string]any)
+	if extra["application"] != "drupal" {
+		t.Errorf("extra = %v, want the application", event["extra"])
+	}
+	if _, ok := event["application"]; ok {
+		t.Errorf("application is written at the top level: %s", buf.String())
+	}
+
+	buf.Reset()
+	slog.New(h).Info("emitted", slog.Group("extra", slog.String("source_ip", "10.0.0.1")), slog.String(FieldTraceID, "4bf9"))
+	event = nil
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	extra, _ = event["extra"].(map[string]any)
+	if extra["source_ip"] != "10.0.0.1" || extra[FieldTraceID] != "4bf9" {
+		t.Errorf("extra = %v, want the extra group and the trace merged", event["extra"])
+	}
+	if _, ok := event["context"]; ok {
+		t.Errorf("an empty context is written: %s", buf.String())
+	}
+}
+
+func TestCompatMonologLagoon_Source(t *testing.T) {
+	preserveConfig(t)
+	hostname = "nginx-php-6f7d9"
+
+	cfg := NewConfig()
+	cfg.LogType = "example-main"
+	cfg.LogHost = "localhost"
+	cfg.CompatMode = CompatMonologLagoon
+	if err := config(cfg); err != nil {
+		t.Fatalf("config() returned unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	slog.New(newHandler(&buf)).Info("with caller")
+
+	var event map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	extra, _ := event["extra"].(map[string]any)
+	file, _ := extra["file"].(string)
+	function, _ := extra["function"].(string)
+	if !strings.HasSuffix(file, "compat_test.go") || extra["line"] == nil || !strings.HasSuffix(function, "TestCompatMonologLagoon_Source") {
+		t.Errorf("extra = %v, want the fields of the IntrospectionProcessor", event["extra"])
+	}
+	if _, ok := event["source"]; ok {
+		t.Errorf("source is written at the top level: %s", buf.String())
+	}
+}
+
+func TestForwardMaxBytes(t *testing.T) {
+	preserveConfig(t)
+
+	tests := []struct {
+		name            string
+		compat          string
+		maxMessageBytes int
+		protocol        string
+		expected        int
+	}{
+		{"lagoon format", "", 0, ProtocolUDP, 0},
+		{"monolog over udp", CompatMonologLagoon, 0, ProtocolUDP, monologMaxDatagram},
+		{"monolog default protocol", CompatMonologLagoon, 0, "", monologMaxDatagram},
+		{"monolog over tcp", CompatMonologLagoon, 0, ProtocolTCP, 0},
+		{"configured limit", CompatMonologLagoon, 8192, ProtocolUDP, 8192},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compatMode, maxMessageBytes = tt.compat, tt.maxMessageBytes
+			if got := forwardMaxBytes(tt.protocol); got != tt.expected {
+				t.Errorf("forwardMaxBytes(%q) = %d, want %d", tt.protocol, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_CompatMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		errMsg string
+	}{
+		{"monolog", func(c *Config) {}, ""},
+		{"unknown", func(c *Config) { c.CompatMode = "log4j" }, "unknown compatMode"},
+		{"strict schema", func(c *Config) { c.StrictSchema = true }, "strictSchema has no schema"},
+		{"syslog", func(c *Config) { c.Syslog = &SyslogConfig{} }, "without syslog"},
+		{"otlp", func(c *Config) { c.Protocol = ProtocolOTLP }, "other than forward or otlp"},
+		{"destination", func(c *Config) {
+			c.Destinations = []Destination{{Name: "relay", Host: "relay", Port: 514, Format: FormatSyslog}}
+		}, "destination relay"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.LogType = "compat-type"
+			cfg.CompatMode = CompatMonologLagoon
+			tt.modify(&cfg)
+
+			err := cfg.Validate()
+			switch {
+			case len(tt.errMsg) == 0 && err != nil:
+				t.Errorf("Validate() returned unexpected error: %v", err)
+			case len(tt.errMsg) > 0 && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Validate() = %v, want %q", err, tt.errMsg)
+			}
+		})
+	}
+}
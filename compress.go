@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"log/slog"
+	"slices"
+)
+
+// compressedEncoding names how a compressed field is encoded
+const compressedEncoding = "gzip+base64"
+
+// fieldCompression replaces large fields by their gzip compressed JSON so
+// events stay within a datagram. A compressed field becomes an object
+//
+//	{"encoding":"gzip+base64","bytes":<size of the JSON>,"data":"<base64>"}
+//
+// whose data decompresses to the JSON the field would have had.
+type fieldCompression struct {
+	// fields are the dotted paths of the attributes compressed, e.g.
+	// "extra.payload"
+	fields []string
+	// threshold is the size of the JSON above which a field is compressed
+	threshold int
+}
+
+// apply compresses the configured fields of attrs, including those inside
+// groups
+func (c fieldCompression) apply(attrs []slog.Attr) []slog.Attr {
+	if len(c.fields) == 0 {
+		return attrs
+	}
+	return c.walk(attrs, "")
+}
+
+func (c fieldCompression) walk(attrs []slog.Attr, prefix string) []slog.Attr {
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		a.Value = a.Value.Resolve()
+
+		path := a.Key
+		if len(prefix) > 0 {
+			path = prefix + "." + a.Key
+		}
+
+		switch {
+		case slices.Contains(c.fields, path):
+			a.Value = c.compress(a.Value)
+		case a.Value.Kind() == slog.KindGroup:
+			// inline groups add their members to the enclosing group
+			if len(a.Key) == 0 {
+				path = prefix
+			}
+			a.Value = slog.GroupValue(c.walk(a.Value.Group(), path)...)
+		}
+		out[i] = a
+	}
+	return out
+}
+
+// compress returns v compressed when its JSON exceeds the threshold and
+// compression makes it smaller, and v itself otherwise
+func (c fieldCompression) compress(v slog.Value) slog.Value {
+	var encoded bytes.Buffer
+	if err := encodeJSON(&encoded, jsonValue(v)); err != nil || encoded.Len() <= c.threshold {
+		return v
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(encoded.Bytes()); err != nil {
+		return v
+	}
+	if err := zw.Close(); err != nil {
+		return v
+	}
+
+	data := base64.StdEncoding.EncodeToString(compressed.Bytes())
+	if len(data) >= encoded.Len() {
+		return v
+	}
+
+	return slog.GroupValue(
+		slog.String("encoding", compressedEncoding),
+		slog.Int("bytes", encoded.Len()),
+		slog.String("data", data),
+	)
+}
+
+// jsonValue returns what encoding/json writes v as, matching the slog JSON
+// handler
+func jsonValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindGroup:
+		object := make(orderedObject, 0, len(v.Group()))
+		for _, a := range v.Group() {
+			object = append(object, objectField{a.Key, jsonValue(a.Value.Resolve())})
+		}
+		return object
+	case slog.KindDuration:
+		return int64(v.Duration())
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+	}
+	return v.Any()
+}
@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// decompress returns the JSON a compressed field was encoded from
+func decompress(t *testing.T, field map[string]any) string {
+	t.Helper()
+	if field["encoding"] != compressedEncoding {
+		t.Fatalf("encoding = %v, want %q", field["encoding"], compressedEncoding)
+	}
+	data, err := base64.StdEncoding.DecodeString(field["data"].(string))
+	if err != nil {
+		t.Fatalf("data is not base64: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("data is not gzip: %v", err)
+	}
+	decoded, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := int(field["bytes"].(float64)); got != len(decoded) {
+		t.Errorf("bytes = %d, want %d", got, len(decoded))
+	}
+	return string(decoded)
+}
+
+func TestFieldCompression(t *testing.T) {
+	large := strings.Repeat("payload ", 100)
+	c := fieldCompression{fields: []string{"extra.payload", "body"}, threshold: 64}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	logger.LogAttrs(context.Background(), slog.LevelInfo, "compressed", c.apply([]slog.Attr{
+		slog.Group("extra", slog.String("payload", large), slog.String("other", large)),
+		slog.Any("body", map[string]string{"text": large}),
+		slog.String("payload", large),
+	})...)
+
+	var event struct {
+		Extra   map[string]any `json:"extra"`
+		Body    map[string]any `json:"body"`
+		Payload string         `json:"payload"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := decompress(t, event.Extra["payload"].(map[string]any)), `"`+large+`"`; got != want {
+		t.Errorf("extra.payload decompressed to %q, want %q", got, want)
+	}
+	if got, want := decompress(t, event.Body), `{"text":"`+large+`"}`; got != want {
+		t.Errorf("body decompressed to %q, want %q", got, want)
+	}
+	if event.Extra["other"] != large {
+		t.Error("extra.other was compressed without being configured")
+	}
+	if event.Payload != large {
+		t.Error("payload was compressed although only extra.payload is configured")
+	}
+}
+
+func TestFieldCompression_Small(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		value     string
+	}{
+		{"below threshold", 1024, strings.Repeat("x", 1000)},
+		// a short value grows by the gzip header when compressed
+		{"incompressible", 16, "b3JkZXItNDItcGF5bG9hZA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fieldCompression{fields: []string{"payload"}, threshold: tt.threshold}
+			attrs := c.apply([]slog.Attr{slog.String("payload", tt.value)})
+			if got := attrs[0].Value.String(); got != tt.value {
+				t.Errorf("apply() = %q, want the value unchanged", got)
+			}
+		})
+	}
+}
+
+func TestFieldCompression_Disabled(t *testing.T) {
+	attrs := []slog.Attr{slog.String("payload", strings.Repeat("x", 4096))}
+	if got := (fieldCompression{threshold: 0}).apply(attrs); got[0].Value.Kind() != slog.KindString {
+		t.Errorf("apply() without fields = %v, want the value unchanged", got[0].Value)
+	}
+}
+
+func TestHandler_CompressFields(t *testing.T) {
+	preserveConfig(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "test"
+	cfg.CompressFields = []string{"extra.payload"}
+	cfg.CompressThreshold = 64
+	if err := config(cfg); err != nil {
+		t.Fatalf("config() returned unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	large := strings.Repeat("payload ", 100)
+	slog.New(newHandler(&buf)).WithGroup("extra").Info("compressed", "payload", large)
+
+	var event struct {
+		Extra map[string]map[string]any `json:"extra"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decompress(t, event.Extra["payload"]), `"`+large+`"`; got != want {
+		t.Errorf("extra.payload decompressed to %q, want %q", got, want)
+	}
+}
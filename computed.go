@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// computedFuncs are the functions of the templates of ComputedAttrs, besides
+// those built into text/template
+var computedFuncs = template.FuncMap{
+	"env":     os.Getenv,
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"replace": strings.ReplaceAll,
+	"default": func(fallback, value any) any {
+		if value == nil || fmt.Sprint(value) == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// computedAttr is an attribute of ComputedAttrs and its parsed template
+type computedAttr struct {
+	key      string
+	template *template.Template
+}
+
+// computedAttrs are the attributes of ComputedAttrs in the order of their
+// keys, so they are written in the same order for every record
+type computedAttrs []computedAttr
+
+// parseComputedAttrs parses the templates of fields
+func parseComputedAttrs(fields map[string]string) (computedAttrs, error) {
+	computed := make(computedAttrs, 0, len(fields))
+	for key, text := range fields {
+		if len(key) == 0 {
+			return nil, errors.New("computedAttrs must not have an empty name")
+		}
+		t, err := template.New(key).Funcs(computedFuncs).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("computedAttrs: %w", err)
+		}
+		computed = append(computed, computedAttr{key: key, template: t})
+	}
+	slices.SortFunc(computed, func(a, b computedAttr) int { return strings.Compare(a.key, b.key) })
+	return computed, nil
+}
+
+// computedRecord is the data of the templates of ComputedAttrs
+type computedRecord struct {
+	Message string
+	Level   string
+	Time    time.Time
+	attrs   []slog.Attr
+}
+
+// Attr returns the value of the attribute at path, a key such as
+// "http.status_code" or a dotted path into groups such as "context.uid",
+// or an empty string when the record has none
+func (r computedRecord) Attr(path string) any {
+	for _, a := range r.attrs {
+		if a.Key == path {
+			return a.Value.Resolve().Any()
+		}
+	}
+	keys := strings.Split(path, ".")
+	for _, a := range r.attrs {
+		if value, ok := lookupAttr(a, keys); ok {
+			return value.Any()
+		}
+	}
+	return ""
+}
+
+// apply appends the attributes computed for the record with msg, level,
+// time and attrs to attrs. Templates failing or evaluating to an empty
+// string add nothing.
+func (c computedAttrs) apply(msg string, level slog.Level, t time.Time, attrs []slog.Attr) []slog.Attr {
+	if len(c) == 0 {
+		return attrs
+	}
+	data := computedRecord{Message: msg, Level: levelString(level), Time: t, attrs: attrs}
+	var buf bytes.Buffer
+	for _, a := range c {
+		buf.Reset()
+		if err := a.template.Execute(&buf, data); err != nil || buf.Len() == 0 {
+			continue
+		}
+		attrs = append(attrs, slog.String(a.key, buf.String()))
+	}
+	return attrs
+}
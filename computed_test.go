@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_ComputedAttrs(t *testing.T) {
+	preserveConfig(t)
+	t.Setenv("COMPUTED_TIER", "")
+	computedFields = map[string]string{
+		"tier":         `{{env "COMPUTED_TIER" | default "web"}}`,
+		"status_class": `{{with .Attr "http.status_code"}}{{printf "%.1s" (print .)}}xx{{end}}`,
+		"customer":     `{{.Attr "context.customer" | lower}}`,
+		"severity":     `{{.Level | lower}}`,
+		"missing":      `{{.Attr "nope"}}`,
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(newHandler(&buf)).With(HTTPStatus(503))
+	logger.Warn("upstream failed", slog.Group("context", slog.String("customer", "ACME")))
+
+	var event map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	expected := map[string]any{"tier": "web", "status_class": "5xx", "customer": "acme", "severity": "warn"}
+	for key, value := range expected {
+		if event[key] != value {
+			t.Errorf("%s = %v, want %v", key, event[key], value)
+		}
+	}
+	if _, ok := event["missing"]; ok {
+		t.Errorf("an empty computed attribute is written: %s", buf.String())
+	}
+}
+
+func TestConfigValidate_ComputedAttrs(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		errMsg string
+	}{
+		{"valid", map[string]string{"tier": `{{env "TIER"}}`}, ""},
+		{"unknown function", map[string]string{"tier": `{{tier}}`}, `function "tier" not defined`},
+		{"unclosed action", map[string]string{"tier": `{{.Message`}, "computedAttrs"},
+		{"empty name", map[string]string{"": "web"}, "empty name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.LogType = "computed-type"
+			cfg.ComputedAttrs = tt.fields
+
+			err := cfg.Validate()
+			switch {
+			case len(tt.errMsg) == 0 && err != nil:
+				t.Errorf("Validate() returned unexpected error: %v", err)
+			case len(tt.errMsg) > 0 && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Validate() = %v, want %q", err, tt.errMsg)
+			}
+		})
+	}
+}
@@ -1,41 +1,472 @@
 package logger
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"net/url"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	AddSource       bool
-	ApplicationName string
-	LogChannel      string
-	LogHost         string
-	LogPort         int
-	LogType         string
-	MessageVersion  int
+	AddSource bool `json:"addSource"`
+	// SourceFormat is how the caller is written with AddSource, one of
+	// SourceGroup, SourceFlat or SourceExtra. Empty follows MessageVersion:
+	// SourceGroup for version 1 and SourceExtra from version 2.
+	SourceFormat string `json:"sourceFormat"`
+	// SourceSkip are package path prefixes, e.g. "github.com/acme/logutil",
+	// whose frames are skipped when finding the caller with AddSource, so
+	// calls through wrappers report the code calling the wrapper. "vendor"
+	// skips every vendored package and "runtime" the Go runtime.
+	SourceSkip      []string `json:"sourceSkip"`
+	ApplicationName string   `json:"applicationName"`
+	LogChannel      string   `json:"logChannel"`
+	LogHost         string   `json:"logHost"`
+	LogPort         int      `json:"logPort"`
+	LogType         string   `json:"logType"`
+	// FallbackHosts are tried in order when LogHost is unreachable, as
+	// "host" on LogPort or "host:port", or socket paths with ProtocolUnix.
+	// While a fallback is in use LogHost is probed every FailbackInterval
+	// and the forwarder switches back once it is reachable.
+	FallbackHosts    []string      `json:"fallbackHosts"`
+	FailbackInterval time.Duration `json:"failbackInterval"`
+	// SenderID is added to every event as sender_id, so loss and duplication
+	// can be analysed per sender, e.g. the pod UID from the downward API.
+	// Without it an ID is generated and persisted to SenderIDFile, which
+	// keeps it across restarts when the file is on a persistent volume.
+	SenderID     string `json:"senderId"`
+	SenderIDFile string `json:"senderIdFile"`
+	// SchemaField adds the module version of the emitter to every event as
+	// schema, e.g. "go-lagoon-log-forwarder@v1.4.0", so consumers of a
+	// fleet mid-upgrade can tell which layout an event was written with
+	SchemaField bool `json:"schemaField"`
+	// LagoonMetadata adds a lagoon group with the project, environment,
+	// branch and environment type read from the Lagoon environment variables,
+	// and derives an empty LogType from them
+	LagoonMetadata bool   `json:"lagoonMetadata"`
+	MessageVersion int    `json:"messageVersion"`
+	Level          string `json:"level"` // minimum level logged, e.g. "info" or "warn", "" logs everything
+	// StdoutLevel and ForwardLevel additionally filter the records written
+	// to stdout and forwarded to the endpoint, e.g. debug records on stdout
+	// while only "info" and above reach Logstash. Empty filters nothing.
+	StdoutLevel  string `json:"stdoutLevel"`
+	ForwardLevel string `json:"forwardLevel"`
+	// StdoutFormat is the encoding of stdout, one of FormatJSON (default),
+	// FormatText or FormatPretty for local development. Forwarded records
+	// are always Lagoon JSON.
+	StdoutFormat string `json:"stdoutFormat"`
+	// LevelRoutes sends the records of a level, e.g. "debug", or of a level
+	// and above, e.g. "error+", only to the sinks listed: SinkStdout,
+	// SinkForwarder or the name of a destination. The route of a single
+	// level applies before those of levels and above, the highest first.
+	// Records without a route are written to every sink.
+	LevelRoutes map[string][]string `json:"levelRoutes"`
+	// Schedule overrides Level and samples records during recurring windows,
+	// the first active one applies. Times are in ScheduleTimezone, local time
+	// when empty.
+	Schedule         []ScheduleWindow `json:"schedule"`
+	ScheduleTimezone string           `json:"scheduleTimezone"`
+	Protocol         string           `json:"protocol"`        // one of ProtocolUDP (default), ProtocolTCP, ProtocolUnix, ProtocolHTTP, ProtocolForward, ProtocolOTLP or a registered Transport
+	WriteTimeout     time.Duration    `json:"writeTimeout"`    // fails TCP writes and HTTP requests that stall for longer, 0 waits forever
+	TLS              *TLSConfig       `json:"tls,omitempty"`   // secures the TCP connection or uses HTTPS, nil sends plain text
+	HTTP             *HTTPConfig      `json:"http,omitempty"`  // configures ProtocolHTTP
+	Forward          *ForwardConfig   `json:"forward"`         // configures ProtocolForward
+	OTLP             *OTLPConfig      `json:"otlp"`            // configures ProtocolOTLP
+	Syslog           *SyslogConfig    `json:"syslog"`          // wraps forwarded events in RFC 5424 messages, nil forwards plain JSON
+	Destinations     []Destination    `json:"destinations"`    // endpoints events are forwarded to besides LogHost
+	Resolver         Resolver         `json:"-"`               // looks up LogHost before dialling, e.g. a CacheResolver; nil leaves it to the dialer
+	Clock            Clock            `json:"-"`               // schedules reconnects and retries, nil uses the system clock
+	DeliveryWorkers  int              `json:"deliveryWorkers"` // 0 writes synchronously from the logging goroutine
+	QueueSize        int              `json:"queueSize"`       // records buffered per delivery worker
+	Ordering         string           `json:"ordering"`        // one of OrderingStrict, OrderingKeyed or OrderingUnordered (default)
+	OrderingKey      string           `json:"orderingKey"`     // attribute hashed in OrderingKeyed mode, e.g. "context.request_id"
+	DeliveryPolicy   string           `json:"deliveryPolicy"`  // one of DeliveryBestEffort (default), DeliveryAtMostOnce or DeliveryAtLeastOnce
+	// RecordAttempts adds delivery.attempts and delivery.first_attempt_at to
+	// JSON events a delivery worker retries, so consumers can tell delayed
+	// events from late ones. It requires DeliveryWorkers.
+	RecordAttempts bool `json:"recordAttempts"`
+	// BatchSize coalesces up to that many forwarded records into one
+	// newline-delimited write, flushed after BatchInterval at the latest.
+	// Over UDP a batch fits a single datagram. 0 or 1 writes every record.
+	// BatchLatency makes batching adaptive: the size, up to BatchSize, and
+	// the interval are retuned every second to the observed throughput and
+	// CPU usage, so records are written within it.
+	BatchSize     int           `json:"batchSize"`
+	BatchInterval time.Duration `json:"batchInterval"`
+	BatchLatency  time.Duration `json:"batchLatency"`
+	// SkewProbeURL is requested periodically to measure the local clock skew
+	// from its Date header, which is added to every event as clock.skew_ms
+	SkewProbeURL      string        `json:"skewProbeURL"`
+	SkewProbeInterval time.Duration `json:"skewProbeInterval"`
+	// StatsFile persists the totals of the records the sinks wrote and
+	// dropped every StatsInterval and on Shutdown, adding to the totals it
+	// already holds, so they cover the life of a pod across crashes and
+	// restarts. Empty keeps them for the current process only.
+	StatsFile     string        `json:"statsFile"`
+	StatsInterval time.Duration `json:"statsInterval"`
+	// StatsEventInterval logs the records the sinks wrote and dropped
+	// during every interval as an event on StatsEventChannel, for clusters
+	// without a metrics pipeline. 0 disables the events.
+	StatsEventInterval time.Duration `json:"statsEventInterval"`
+	StatsEventChannel  string        `json:"statsEventChannel"`
+	// CaptureStderr redirects the stderr of the process into records, so
+	// the writes of libraries and the runtime reach the endpoint too. It is
+	// only supported on Linux. StdLogLevel is the level of the output of
+	// the log package, which Initialize sends to the logger, "info" when
+	// empty.
+	CaptureStderr bool   `json:"captureStderr"`
+	StdLogLevel   string `json:"stdLogLevel"`
+	// StrictReconfigure rejects an Initialize or NewHandler before Shutdown
+	// whose config makes unsafe changes to the running one, see
+	// CompareConfigs, rather than warning about them
+	StrictReconfigure bool `json:"strictReconfigure"`
+	// ExitTimeout bounds how long Exit and Fatal wait for the queued
+	// records to be flushed before the process terminates, 5s when 0
+	ExitTimeout time.Duration `json:"exitTimeout"`
+	// SpoolDir buffers forwarded records on disk while the endpoint is
+	// unreachable, up to SpoolMaxBytes, and replays them in order once it
+	// is reached, also after a restart. Empty discards them.
+	SpoolDir      string `json:"spoolDir"`
+	SpoolMaxBytes int64  `json:"spoolMaxBytes"`
+	// ReplayWindow remembers the spooled records a replay delivered for the
+	// window, so a replay repeated after a crash skips them instead of
+	// delivering them twice. 0 disables it.
+	ReplayWindow time.Duration `json:"replayWindow"`
+	MaxAttrs     int           `json:"maxAttrs"`     // attributes kept per record, 0 keeps all
+	MaxAttrDepth int           `json:"maxAttrDepth"` // group nesting kept per record, 0 keeps all
+	// MaxMessageBytes limits the size of a forwarded event, so it isn't
+	// silently dropped as an oversized datagram. MessageTruncation is how an
+	// event is fitted: TruncateMessage (default), TruncateAttrs or
+	// TruncateSplit. 0 forwards events of any size.
+	MaxMessageBytes   int    `json:"maxMessageBytes"`
+	MessageTruncation string `json:"messageTruncation"`
+	// ControlChars is how control characters and ANSI escapes in messages
+	// are written: ControlKeep (default), ControlStrip or ControlEscape
+	ControlChars string `json:"controlChars"`
+	// StrictSchema checks every JSON event against the schema of its
+	// MessageVersion before writing it, for CI and integration tests. An
+	// invalid event fails the logging call and is reported to Diagnostics
+	// instead of being written.
+	StrictSchema bool `json:"strictSchema"`
+	// Profiling labels the CPU samples of encoding, writing, flushing and
+	// delivering events with pprof labels, lagoon_log_phase and
+	// lagoon_log_sink, and wraps them in runtime/trace regions, so profiles
+	// of busy services attribute the cost of logging. It costs a few
+	// allocations per event.
+	Profiling bool `json:"profiling"`
+	// CompatMode writes JSON events in the layout of another Lagoon logs
+	// handler. CompatMonologLagoon matches the PHP and Drupal handlers, for
+	// pipelines and dashboards shared with them. Empty writes the Lagoon
+	// format of MessageVersion.
+	CompatMode string `json:"compatMode"`
+	// Processors change or drop every record in order before it is encoded.
+	// They can't be set in config files.
+	Processors []Processor `json:"-"`
+	// OnLevel calls a function with every record at or above its level once
+	// the record is written, e.g. to count errors in a metric or page on
+	// LevelCritical, without wrapping every logging call. A record reaching
+	// several levels calls each function, the lowest level first. They are
+	// called from every logging goroutine, so they must be safe for
+	// concurrent use, and can't be set in config files.
+	OnLevel map[slog.Level]func(slog.Record) `json:"-"`
+	// ComputedAttrs are attributes added to every record, evaluated from a
+	// text/template per record after the processors ran, e.g.
+	// {"tier": "{{env \"TIER\" | default \"web\"}}"}. Templates read
+	// .Message, .Level, .Time and (.Attr "http.status_code"). Results are
+	// strings, and empty ones are left out.
+	ComputedAttrs map[string]string `json:"computedAttrs"`
+	// Values passed with slog.Any are walked by reflection within these
+	// limits, keeping exported fields only
+	MaxValueFields int  `json:"maxValueFields"` // fields, entries or elements kept per value, 0 keeps all
+	MaxValueDepth  int  `json:"maxValueDepth"`  // nesting kept per value, 0 keeps all
+	PreferStringer bool `json:"preferStringer"` // write values implementing fmt.Stringer as their String()
+	// MaxStringRunes cuts the message and string attributes to that many
+	// characters, 0 keeps all. NormalizeStrings, e.g. norm.NFC.String of
+	// golang.org/x/text, is applied to them before and can't be set in config
+	// files. With either set, invalid UTF-8 is replaced by U+FFFD first.
+	MaxStringRunes   int                 `json:"maxStringRunes"`
+	NormalizeStrings func(string) string `json:"-"`
+	// SpanContext returns the IDs of the trace and span in the context of a
+	// logging call, added to its record as trace_id and span_id so a log line
+	// leads to its trace, e.g. in Kibana. It adapts a tracer such as
+	// OpenTelemetry, and can't be set in config files. Empty IDs are left out.
+	SpanContext func(ctx context.Context) (traceID, spanID string) `json:"-"`
+	// CompressFields are the dotted paths of attributes, e.g. "extra.payload",
+	// written gzip compressed when their JSON exceeds CompressThreshold bytes
+	CompressFields    []string `json:"compressFields"`
+	CompressThreshold int      `json:"compressThreshold"`
+	// EgressBudget caps the bytes forwarded per EgressWindow, 0 is unlimited.
+	// Over budget only one in EgressSampleRate records is forwarded, 0 drops
+	// them all, and a summary of the dropped records is logged when the
+	// window ends.
+	EgressBudget     int64         `json:"egressBudget"`
+	EgressWindow     time.Duration `json:"egressWindow"`
+	EgressSampleRate int           `json:"egressSampleRate"`
+	// MaxEventsPerSecond limits the events forwarded, in bursts of up to
+	// Burst, which defaults to MaxEventsPerSecond. Events beyond it are
+	// dropped and counted in a single warning forwarded once the limit allows
+	// again. 0 forwards every event.
+	MaxEventsPerSecond int `json:"maxEventsPerSecond"`
+	Burst              int `json:"burst"`
+	// MemoryPressure throttles the logger while the memory held by the Go
+	// runtime exceeds this share of MemoryLimit, e.g. 0.9: records below warn
+	// are sampled one in MemorySampleRate and delivery queues shrink to a
+	// tenth, losing records rather than getting the process OOM killed.
+	// A MemoryLimit of 0 uses the cgroup limit, or else GOMEMLIMIT. A
+	// MemoryPressure of 0 disables the monitor.
+	MemoryPressure   float64 `json:"memoryPressure"`
+	MemoryLimit      int64   `json:"memoryLimit"`
+	MemorySampleRate int     `json:"memorySampleRate"`
+	// RemoteConfigURL is polled every RemoteConfigInterval for a RemoteOverrides
+	// document signed with RemoteConfigKey, which overrides the level,
+	// sampling and endpoint. Empty disables remote configuration.
+	RemoteConfigURL      string        `json:"remoteConfigURL"`
+	RemoteConfigKey      string        `json:"remoteConfigKey"`
+	RemoteConfigInterval time.Duration `json:"remoteConfigInterval"`
+	// Flags is consulted every FlagRefreshInterval for the FlagLevel and
+	// FlagSampleRate feature flags, which override Level and the schedule.
+	// It can't be set in config files.
+	Flags               FlagProvider  `json:"-"`
+	FlagRefreshInterval time.Duration `json:"flagRefreshInterval"`
+	// Diagnostics receives the forwarder's own warnings, such as a failed
+	// connection to the endpoint, apart from the application's records. It
+	// can't be set in config files. Nil writes them as text to stderr.
+	Diagnostics slog.Handler `json:"-"`
+	// DebugSignal names a signal, "SIGHUP" or "SIGUSR1", toggling debug
+	// records on and off at runtime like SetLevel. Empty leaves signals alone.
+	DebugSignal string `json:"debugSignal"`
+	// Trace writes the decisions taken by Initialize, such as the endpoint
+	// resolved, the dial attempts and the sinks chosen, to the diagnostics.
+	// Setting LAGOON_LOGS_DEBUG=true has the same effect.
+	Trace bool `json:"trace"`
+	// Faults injects delivery failures for resilience testing, nil disables
+	// it. It is set in code only, so a config file can't degrade delivery.
+	Faults *Faults `json:"-"`
 }
 
+// Ordering modes trade delivery order for throughput when DeliveryWorkers is set
+const (
+	// OrderingStrict delivers every record through a single worker in order
+	OrderingStrict = "strict"
+	// OrderingKeyed keeps records sharing the OrderingKey attribute in order
+	OrderingKeyed = "key"
+	// OrderingUnordered spreads records over all workers for maximum parallelism
+	OrderingUnordered = "unordered"
+)
+
+// Delivery policies of the forwarder sink
+const (
+	// DeliveryBestEffort retries a failed write a few times before dropping
+	// the record, and drops records when the queue is full
+	DeliveryBestEffort = "best-effort"
+	// DeliveryAtMostOnce writes each record once and never retries, so a
+	// record is never duplicated but may be lost
+	DeliveryAtMostOnce = "at-most-once"
+	// DeliveryAtLeastOnce retries a record until it is written and blocks
+	// callers while the queue is full, so a record is only lost when
+	// Shutdown gives up, but may be duplicated when a failed write had
+	// partially reached the endpoint. It requires DeliveryWorkers.
+	DeliveryAtLeastOnce = "at-least-once"
+)
+
 // NewConfig returns a Config struct with default values
 func NewConfig() Config {
 	return Config{
-		AddSource:       true,
-		ApplicationName: "",
-		LogChannel:      "LagoonLogs",
-		LogHost:         "", // Will default to localhost in validation
-		LogPort:         5140,
-		LogType:         "", // Required - must be set by user
-		MessageVersion:  1,
+		AddSource:            true,
+		SourceFormat:         "",
+		SourceSkip:           nil,
+		ApplicationName:      "",
+		LogChannel:           "LagoonLogs",
+		LogHost:              buildLogHost, // Will default to localhost in validation when empty
+		LogPort:              defaultLogPort(),
+		LogType:              "", // Required - must be set by user
+		FallbackHosts:        nil,
+		FailbackInterval:     30 * time.Second,
+		SenderID:             "",
+		SenderIDFile:         "",
+		LagoonMetadata:       false,
+		SchemaField:          false,
+		MessageVersion:       1,
+		StrictSchema:         false,
+		Profiling:            false,
+		CompatMode:           "",
+		Level:                "debug",
+		StdoutLevel:          "",
+		ForwardLevel:         "",
+		StdoutFormat:         FormatJSON,
+		Schedule:             nil,
+		ScheduleTimezone:     "",
+		Protocol:             ProtocolUDP,
+		WriteTimeout:         5 * time.Second,
+		TLS:                  nil,
+		HTTP:                 nil,
+		Forward:              nil,
+		OTLP:                 nil,
+		Syslog:               nil,
+		Destinations:         nil,
+		Resolver:             nil,
+		Clock:                nil,
+		DeliveryWorkers:      0,
+		QueueSize:            1000,
+		Ordering:             OrderingUnordered,
+		BatchSize:            0,
+		BatchInterval:        100 * time.Millisecond,
+		BatchLatency:         0,
+		OrderingKey:          "",
+		DeliveryPolicy:       DeliveryBestEffort,
+		RecordAttempts:       false,
+		SkewProbeURL:         "",
+		SkewProbeInterval:    5 * time.Minute,
+		SpoolDir:             "",
+		SpoolMaxBytes:        64 << 20,
+		ReplayWindow:         0,
+		StatsFile:            "",
+		StatsInterval:        time.Minute,
+		StatsEventInterval:   0,
+		StatsEventChannel:    "lagoon-log-forwarder-stats",
+		CaptureStderr:        false,
+		StdLogLevel:          "",
+		StrictReconfigure:    false,
+		ExitTimeout:          defaultExitTimeout,
+		MaxAttrs:             128,
+		MaxAttrDepth:         8,
+		MaxMessageBytes:      0,
+		MessageTruncation:    TruncateMessage,
+		ControlChars:         ControlKeep,
+		Processors:           nil,
+		OnLevel:              nil,
+		ComputedAttrs:        nil,
+		LevelRoutes:          nil,
+		MaxValueFields:       64,
+		MaxValueDepth:        8,
+		PreferStringer:       false,
+		MaxStringRunes:       0,
+		NormalizeStrings:     nil,
+		SpanContext:          nil,
+		CompressFields:       nil,
+		CompressThreshold:    1024,
+		EgressBudget:         0,
+		EgressWindow:         time.Minute,
+		EgressSampleRate:     100,
+		MaxEventsPerSecond:   0,
+		Burst:                0,
+		MemoryPressure:       0,
+		MemoryLimit:          0,
+		MemorySampleRate:     10,
+		RemoteConfigURL:      "",
+		RemoteConfigKey:      "",
+		RemoteConfigInterval: time.Minute,
+		Flags:                nil,
+		FlagRefreshInterval:  30 * time.Second,
+		Diagnostics:          nil,
+		DebugSignal:          "",
+		Trace:                false,
+		Faults:               nil,
 	}
 }
 
 func config(cfg Config) error {
+	// a rejected config leaves the applied one in place
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
 	addSource = cfg.AddSource
+	sourceFormat = cfg.SourceFormat
+	sourceSkip = cfg.SourceSkip
 	applicationName = cfg.ApplicationName
 	logChannel = cfg.LogChannel
 	logHost = cfg.LogHost
 	logPort = cfg.LogPort
-	logType = cfg.LogType
+	fallbackHosts = cfg.FallbackHosts
+	failbackInterval = cfg.FailbackInterval
+	senderID = cfg.SenderID
+	senderIDFile = cfg.SenderIDFile
+	lagoonMetadata = cfg.LagoonMetadata
+	schemaField = cfg.SchemaField
+	logType = prefixLogType(cfg.resolvedLogType())
 	messageVersion = cfg.MessageVersion
+	strictSchema = cfg.StrictSchema
+	profiling.Store(cfg.Profiling)
+	compatMode = cfg.CompatMode
+	level = cfg.Level
+	stdoutLevel = cfg.StdoutLevel
+	forwardLevel = cfg.ForwardLevel
+	stdoutFormat = cfg.StdoutFormat
+	scheduleWindows = cfg.Schedule
+	scheduleTimezone = cfg.ScheduleTimezone
+	protocol = cfg.Protocol
+	writeTimeout = cfg.WriteTimeout
+	tlsSettings = cfg.TLS
+	httpSettings = cfg.HTTP
+	fluentSettings = cfg.Forward
+	otlpSettings = cfg.OTLP
+	syslogSettings = cfg.Syslog
+	destinations = cfg.Destinations
+	resolver = cfg.Resolver
+	retryClock.Store(clockHolder{cfg.Clock})
+	deliveryWorkers = cfg.DeliveryWorkers
+	queueSize = cfg.QueueSize
+	batchSize = cfg.BatchSize
+	batchInterval = cfg.BatchInterval
+	batchLatency = cfg.BatchLatency
+	ordering = cfg.Ordering
+	orderingKey = cfg.OrderingKey
+	deliveryPolicy = cfg.DeliveryPolicy
+	recordAttempts = cfg.RecordAttempts
+	skewProbeURL = cfg.SkewProbeURL
+	skewProbeInterval = cfg.SkewProbeInterval
+	spoolDir = cfg.SpoolDir
+	spoolMaxBytes = cfg.SpoolMaxBytes
+	replayWindow = cfg.ReplayWindow
+	statsFile = cfg.StatsFile
+	statsInterval = cfg.StatsInterval
+	statsEventInterval = cfg.StatsEventInterval
+	statsEventChannel = cfg.StatsEventChannel
+	captureStderrOutput = cfg.CaptureStderr
+	stdLogLevel = cfg.StdLogLevel
+	strictReconfigure = cfg.StrictReconfigure
+	exitTimeout = cfg.ExitTimeout
+	maxAttrs = cfg.MaxAttrs
+	maxAttrDepth = cfg.MaxAttrDepth
+	maxMessageBytes = cfg.MaxMessageBytes
+	messageTruncation = cfg.MessageTruncation
+	controlChars = cfg.ControlChars
+	processors = cfg.Processors
+	onLevel = maps.Clone(cfg.OnLevel)
+	computedFields = maps.Clone(cfg.ComputedAttrs)
+	levelRouting = maps.Clone(cfg.LevelRoutes)
+	maxValueFields = cfg.MaxValueFields
+	maxValueDepth = cfg.MaxValueDepth
+	preferStringer = cfg.PreferStringer
+	maxStringRunes = cfg.MaxStringRunes
+	normalizeStrings = cfg.NormalizeStrings
+	spanContext = cfg.SpanContext
+	compressFields = cfg.CompressFields
+	compressThreshold = cfg.CompressThreshold
+	egressBudget = cfg.EgressBudget
+	egressWindow = cfg.EgressWindow
+	egressSampleRate = cfg.EgressSampleRate
+	maxEventsPerSecond = cfg.MaxEventsPerSecond
+	burst = cfg.Burst
+	memoryPressure = cfg.MemoryPressure
+	memoryLimit = cfg.MemoryLimit
+	memorySampleRate = cfg.MemorySampleRate
+	remoteConfigURL = cfg.RemoteConfigURL
+	remoteConfigKey = cfg.RemoteConfigKey
+	remoteConfigInterval = cfg.RemoteConfigInterval
+	flagProvider = cfg.Flags
+	flagRefreshInterval = cfg.FlagRefreshInterval
+	setDiagnostics(cfg.Diagnostics)
+	debugSignal = cfg.DebugSignal
+	tracing.Store(traceRequested(cfg))
+	faults = cfg.Faults
+	traceConfig()
 	return validate()
 }
 
@@ -43,14 +474,484 @@ func validate() error {
 
 	// validate logstashHost
 	if len(logHost) == 0 {
-		slog.Warn(
+		diag().Warn(
 			"log.host is not supplied and will default to localhost",
 		)
 	}
 
-	if len(logType) == 0 {
+	if faults != nil {
+		diag().Warn("fault injection is enabled, log delivery will be degraded on purpose")
+	}
+
+	return current().Validate()
+}
+
+// Validate checks the Config for errors without applying it
+func (c Config) Validate() error {
+
+	if len(c.resolvedLogType()) == 0 {
 		return errors.New("logType is required")
 	}
 
+	if _, err := ParseLevel(c.Level); err != nil {
+		return err
+	}
+
+	if _, err := parseSinkLevel(c.StdoutLevel); err != nil {
+		return fmt.Errorf("stdoutLevel: %w", err)
+	}
+
+	if _, err := parseSinkLevel(c.ForwardLevel); err != nil {
+		return fmt.Errorf("forwardLevel: %w", err)
+	}
+
+	if _, err := newSchedule(slog.LevelDebug, c.Schedule, c.ScheduleTimezone); err != nil {
+		return err
+	}
+
+	switch c.StdoutFormat {
+	case "", FormatJSON, FormatText, FormatPretty:
+	default:
+		return fmt.Errorf("unknown stdoutFormat %q", c.StdoutFormat)
+	}
+
+	switch c.SourceFormat {
+	case "", SourceGroup, SourceFlat, SourceExtra:
+	default:
+		return fmt.Errorf("unknown sourceFormat %q", c.SourceFormat)
+	}
+	for _, prefix := range c.SourceSkip {
+		if len(prefix) == 0 {
+			return errors.New("sourceSkip prefixes must not be empty")
+		}
+	}
+	if c.StrictSchema && !slices.Contains(schemaVersions, c.MessageVersion) {
+		return fmt.Errorf("strictSchema: no schema for messageVersion %d", c.MessageVersion)
+	}
+
+	switch c.CompatMode {
+	case "":
+	case CompatMonologLagoon:
+		if c.StrictSchema {
+			return fmt.Errorf("strictSchema has no schema for compatMode %s", c.CompatMode)
+		}
+		// syslog frames and the forward and otlp protocols are built from
+		// Lagoon events
+		if c.Syslog != nil || c.Protocol == ProtocolForward || c.Protocol == ProtocolOTLP {
+			return fmt.Errorf("compatMode %s requires a protocol other than forward or otlp, without syslog", c.CompatMode)
+		}
+		for _, d := range c.Destinations {
+			if d.Format == FormatSyslog || d.Protocol == ProtocolForward || d.Protocol == ProtocolOTLP {
+				return fmt.Errorf("destination %s: compatMode %s requires a protocol other than forward or otlp and a format other than syslog", d.Name, c.CompatMode)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown compatMode %q", c.CompatMode)
+	}
+
+	switch c.Protocol {
+	case "", ProtocolUDP, ProtocolTCP, ProtocolHTTP, ProtocolForward, ProtocolOTLP:
+	case ProtocolUnix:
+		if len(c.LogHost) == 0 {
+			return errors.New("logHost must be the socket path with protocol unix")
+		}
+	default:
+		if _, ok := registeredTransport(c.Protocol); !ok {
+			return fmt.Errorf("unknown protocol %q", c.Protocol)
+		}
+	}
+
+	for _, host := range c.FallbackHosts {
+		if _, err := parseFallbackHost(host, c.Protocol, c.LogPort); err != nil {
+			return err
+		}
+	}
+	if len(c.FallbackHosts) > 0 && c.FailbackInterval <= 0 {
+		return errors.New("failbackInterval must be positive when fallbackHosts are set")
+	}
+
+	if len(c.SenderID) > 0 && len(c.SenderIDFile) > 0 {
+		return errors.New("senderId and senderIdFile are mutually exclusive")
+	}
+
+	if c.WriteTimeout < 0 {
+		return errors.New("writeTimeout must not be negative")
+	}
+
+	if c.TLS != nil {
+		if builtinProtocols[c.Protocol] && c.Protocol != ProtocolTCP && c.Protocol != ProtocolHTTP && c.Protocol != ProtocolForward && c.Protocol != ProtocolOTLP {
+			return errors.New("tls requires protocol tcp, http, forward, otlp or a registered transport")
+		}
+		if err := c.TLS.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.HTTP != nil {
+		if c.Protocol != ProtocolHTTP {
+			return errors.New("http settings require protocol http")
+		}
+		if err := c.HTTP.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Forward != nil {
+		if c.Protocol != ProtocolForward {
+			return errors.New("forward settings require protocol forward")
+		}
+		if err := c.Forward.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.OTLP != nil {
+		if c.Protocol != ProtocolOTLP {
+			return errors.New("otlp settings require protocol otlp")
+		}
+		if err := c.OTLP.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Syslog != nil {
+		// the forward and otlp protocols carry JSON events
+		if c.Protocol == ProtocolForward || c.Protocol == ProtocolOTLP {
+			return errors.New("syslog requires a protocol other than forward or otlp")
+		}
+		if err := c.Syslog.validate(); err != nil {
+			return err
+		}
+		// a datagram carries a single syslog message
+		if c.BatchSize > 1 && c.Protocol != ProtocolTCP {
+			return errors.New("syslog batches require protocol tcp")
+		}
+	}
+
+	names := map[string]bool{}
+	for _, d := range c.Destinations {
+		if err := d.validate(); err != nil {
+			return err
+		}
+		if len(d.DeliveryPolicy) > 0 && c.QueueSize < 1 {
+			return fmt.Errorf("destination %s: queueSize must be positive with a deliveryPolicy", d.Name)
+		}
+		if names[d.Name] {
+			return fmt.Errorf("duplicate destination %s", d.Name)
+		}
+		names[d.Name] = true
+	}
+	if _, err := parseLevelRoutes(c.LevelRoutes, c.sinkNames()); err != nil {
+		return err
+	}
+
+	if c.DeliveryWorkers < 0 {
+		return errors.New("deliveryWorkers must not be negative")
+	}
+
+	if c.DeliveryWorkers > 0 && c.QueueSize < 1 {
+		return errors.New("queueSize must be positive when deliveryWorkers is set")
+	}
+
+	switch c.Ordering {
+	case "", OrderingStrict, OrderingUnordered:
+	case OrderingKeyed:
+		if len(c.OrderingKey) == 0 {
+			return errors.New("orderingKey is required for key ordering")
+		}
+	default:
+		return fmt.Errorf("unknown ordering %q", c.Ordering)
+	}
+
+	if c.BatchSize < 0 {
+		return errors.New("batchSize must not be negative")
+	}
+	if c.BatchLatency < 0 {
+		return errors.New("batchLatency must not be negative")
+	}
+	if c.BatchLatency > 0 && c.BatchSize <= 1 {
+		return errors.New("batchLatency requires batchSize")
+	}
+
+	if c.BatchSize > 1 {
+		if c.BatchInterval <= 0 {
+			return errors.New("batchInterval must be positive when batchSize is set")
+		}
+		// a batch is routed as a whole, so records of different keys could
+		// overtake each other
+		if c.Ordering == OrderingKeyed && c.DeliveryWorkers > 0 {
+			return errors.New("batchSize cannot be combined with key ordering")
+		}
+	}
+
+	switch c.DeliveryPolicy {
+	case "", DeliveryBestEffort, DeliveryAtMostOnce:
+	case DeliveryAtLeastOnce:
+		if c.DeliveryWorkers == 0 {
+			return errors.New("at-least-once delivery requires deliveryWorkers")
+		}
+	default:
+		return fmt.Errorf("unknown deliveryPolicy %q", c.DeliveryPolicy)
+	}
+	if c.RecordAttempts && c.DeliveryWorkers == 0 {
+		return errors.New("recordAttempts requires deliveryWorkers")
+	}
+
+	if len(c.SkewProbeURL) > 0 && c.SkewProbeInterval <= 0 {
+		return errors.New("skewProbeInterval must be positive when skewProbeURL is set")
+	}
+
+	if len(c.SpoolDir) > 0 && c.SpoolMaxBytes <= 0 {
+		return errors.New("spoolMaxBytes must be positive when spoolDir is set")
+	}
+	if c.ReplayWindow < 0 {
+		return errors.New("replayWindow must not be negative")
+	}
+	if c.ReplayWindow > 0 && len(c.SpoolDir) == 0 {
+		return errors.New("replayWindow requires spoolDir")
+	}
+
+	if len(c.StatsFile) > 0 && c.StatsInterval <= 0 {
+		return errors.New("statsInterval must be positive when statsFile is set")
+	}
+	if c.StatsEventInterval < 0 {
+		return errors.New("statsEventInterval must not be negative")
+	}
+	if c.StatsEventInterval > 0 && len(c.StatsEventChannel) == 0 {
+		return errors.New("statsEventChannel must not be empty when statsEventInterval is set")
+	}
+
+	if c.ExitTimeout < 0 {
+		return errors.New("exitTimeout must not be negative")
+	}
+
+	if c.CaptureStderr && runtime.GOOS != "linux" {
+		return errors.New("captureStderr is only supported on linux")
+	}
+	if _, err := ParseLevel(c.StdLogLevel); err != nil {
+		return fmt.Errorf("stdLogLevel: %w", err)
+	}
+
+	if c.MaxAttrs < 0 || c.MaxAttrDepth < 0 {
+		return errors.New("maxAttrs and maxAttrDepth must not be negative")
+	}
+
+	if c.MaxMessageBytes < 0 {
+		return errors.New("maxMessageBytes must not be negative")
+	}
+
+	switch c.MessageTruncation {
+	case "", TruncateMessage, TruncateAttrs, TruncateSplit:
+	default:
+		return fmt.Errorf("unknown messageTruncation %q", c.MessageTruncation)
+	}
+
+	switch c.ControlChars {
+	case "", ControlKeep, ControlStrip, ControlEscape:
+	default:
+		return fmt.Errorf("unknown controlChars %q", c.ControlChars)
+	}
+
+	for _, p := range c.Processors {
+		if p == nil {
+			return errors.New("processors must not be nil")
+		}
+	}
+	for level, fn := range c.OnLevel {
+		if fn == nil {
+			return fmt.Errorf("onLevel of %s must not be nil", levelString(level))
+		}
+	}
+	if _, err := parseComputedAttrs(c.ComputedAttrs); err != nil {
+		return err
+	}
+
+	if c.MaxValueFields < 0 || c.MaxValueDepth < 0 {
+		return errors.New("maxValueFields and maxValueDepth must not be negative")
+	}
+
+	if c.MaxStringRunes < 0 {
+		return errors.New("maxStringRunes must not be negative")
+	}
+
+	if c.CompressThreshold < 0 {
+		return errors.New("compressThreshold must not be negative")
+	}
+
+	if c.EgressBudget < 0 || c.EgressSampleRate < 0 {
+		return errors.New("egressBudget and egressSampleRate must not be negative")
+	}
+
+	if c.EgressBudget > 0 && c.EgressWindow <= 0 {
+		return errors.New("egressWindow must be positive when egressBudget is set")
+	}
+
+	if c.MaxEventsPerSecond < 0 || c.Burst < 0 {
+		return errors.New("maxEventsPerSecond and burst must not be negative")
+	}
+
+	if c.MemoryPressure < 0 || c.MemoryPressure > 1 {
+		return fmt.Errorf("memoryPressure %v must be between 0 and 1", c.MemoryPressure)
+	}
+	if c.MemoryLimit < 0 || c.MemorySampleRate < 0 {
+		return errors.New("memoryLimit and memorySampleRate must not be negative")
+	}
+
+	if len(c.RemoteConfigURL) > 0 {
+		if u, err := url.Parse(c.RemoteConfigURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			return fmt.Errorf("remoteConfigURL must be an http or https URL")
+		}
+		if len(c.RemoteConfigKey) == 0 {
+			return errors.New("remoteConfigKey is required with remoteConfigURL")
+		}
+		if c.RemoteConfigInterval <= 0 {
+			return errors.New("remoteConfigInterval must be positive when remoteConfigURL is set")
+		}
+	}
+
+	if c.Flags != nil && c.FlagRefreshInterval <= 0 {
+		return errors.New("flagRefreshInterval must be positive when flags are set")
+	}
+
+	if _, ok := debugSignals[c.DebugSignal]; len(c.DebugSignal) > 0 && !ok {
+		return fmt.Errorf("unsupported debugSignal %q", c.DebugSignal)
+	}
+
+	if f := c.Faults; f != nil {
+		if f.DropPercent < 0 || f.DropPercent > 100 {
+			return errors.New("faults.dropPercent must be between 0 and 100")
+		}
+		if f.Delay < 0 || f.FailEveryNthDial < 0 {
+			return errors.New("faults.delay and faults.failEveryNthDial must not be negative")
+		}
+	}
+
 	return nil
 }
+
+// ParseLevel parses a level name as the level settings of Config accept it,
+// such as "info", "WARN+2" or "critical", an empty name being the lowest level
+func ParseLevel(name string) (slog.Level, error) {
+	if len(name) == 0 {
+		return slog.LevelDebug, nil
+	}
+	if strings.EqualFold(name, levelString(LevelCritical)) {
+		return LevelCritical, nil
+	}
+
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return l, fmt.Errorf("invalid level: %w", err)
+	}
+	return l, nil
+}
+
+// parseSinkLevel parses the level of a sink, nil when name is empty so the
+// sink keeps every record
+func parseSinkLevel(name string) (slog.Leveler, error) {
+	if len(name) == 0 {
+		return nil, nil
+	}
+
+	l, err := ParseLevel(name)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// current returns the Config that is currently applied to the package
+func current() Config {
+	return Config{
+		AddSource:            addSource,
+		SourceFormat:         sourceFormat,
+		SourceSkip:           sourceSkip,
+		ApplicationName:      applicationName,
+		LogChannel:           logChannel,
+		LogHost:              logHost,
+		LogPort:              logPort,
+		FallbackHosts:        fallbackHosts,
+		FailbackInterval:     failbackInterval,
+		SenderID:             senderID,
+		SenderIDFile:         senderIDFile,
+		LogType:              logType,
+		LagoonMetadata:       lagoonMetadata,
+		SchemaField:          schemaField,
+		MessageVersion:       messageVersion,
+		StrictSchema:         strictSchema,
+		Profiling:            profiling.Load(),
+		CompatMode:           compatMode,
+		Level:                level,
+		StdoutLevel:          stdoutLevel,
+		ForwardLevel:         forwardLevel,
+		StdoutFormat:         stdoutFormat,
+		Schedule:             scheduleWindows,
+		ScheduleTimezone:     scheduleTimezone,
+		Protocol:             protocol,
+		WriteTimeout:         writeTimeout,
+		TLS:                  tlsSettings,
+		HTTP:                 httpSettings,
+		Forward:              fluentSettings,
+		OTLP:                 otlpSettings,
+		Syslog:               syslogSettings,
+		Destinations:         destinations,
+		Resolver:             resolver,
+		Clock:                configuredClock(),
+		DeliveryWorkers:      deliveryWorkers,
+		QueueSize:            queueSize,
+		BatchSize:            batchSize,
+		BatchInterval:        batchInterval,
+		BatchLatency:         batchLatency,
+		Ordering:             ordering,
+		OrderingKey:          orderingKey,
+		DeliveryPolicy:       deliveryPolicy,
+		RecordAttempts:       recordAttempts,
+		SkewProbeURL:         skewProbeURL,
+		SkewProbeInterval:    skewProbeInterval,
+		SpoolDir:             spoolDir,
+		SpoolMaxBytes:        spoolMaxBytes,
+		ReplayWindow:         replayWindow,
+		StatsFile:            statsFile,
+		StatsInterval:        statsInterval,
+		StatsEventInterval:   statsEventInterval,
+		StatsEventChannel:    statsEventChannel,
+		CaptureStderr:        captureStderrOutput,
+		StdLogLevel:          stdLogLevel,
+		StrictReconfigure:    strictReconfigure,
+		ExitTimeout:          exitTimeout,
+		MaxAttrs:             maxAttrs,
+		MaxAttrDepth:         maxAttrDepth,
+		MaxMessageBytes:      maxMessageBytes,
+		MessageTruncation:    messageTruncation,
+		ControlChars:         controlChars,
+		Processors:           processors,
+		OnLevel:              maps.Clone(onLevel),
+		ComputedAttrs:        maps.Clone(computedFields),
+		LevelRoutes:          maps.Clone(levelRouting),
+		MaxValueFields:       maxValueFields,
+		MaxValueDepth:        maxValueDepth,
+		PreferStringer:       preferStringer,
+		MaxStringRunes:       maxStringRunes,
+		NormalizeStrings:     normalizeStrings,
+		SpanContext:          spanContext,
+		CompressFields:       compressFields,
+		CompressThreshold:    compressThreshold,
+		EgressBudget:         egressBudget,
+		EgressWindow:         egressWindow,
+		EgressSampleRate:     egressSampleRate,
+		MaxEventsPerSecond:   maxEventsPerSecond,
+		Burst:                burst,
+		MemoryPressure:       memoryPressure,
+		MemoryLimit:          memoryLimit,
+		MemorySampleRate:     memorySampleRate,
+		RemoteConfigURL:      remoteConfigURL,
+		RemoteConfigKey:      remoteConfigKey,
+		RemoteConfigInterval: remoteConfigInterval,
+		Flags:                flagProvider,
+		FlagRefreshInterval:  flagRefreshInterval,
+		Diagnostics:          diagnosticsHandler(),
+		DebugSignal:          debugSignal,
+		Trace:                tracing.Load(),
+		Faults:               faults,
+	}
+}
@@ -0,0 +1,195 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// LoadConfigFile reads a JSON config file on top of the NewConfig defaults.
+// Environment variable references (${VAR}) in string values are expanded once
+// the file is parsed, $$ writing a literal ${, and unknown keys are rejected
+// so typos are caught early.
+func LoadConfigFile(path string) (Config, error) {
+	cfg := NewConfig()
+
+	data, err := os.ReadFile(path) // #nosec G304 -- path is supplied by the operator
+	if err != nil {
+		return cfg, fmt.Errorf("read config file: %w", err)
+	}
+
+	return parseConfig(cfg, data)
+}
+
+func parseConfig(cfg Config, data []byte) (Config, error) {
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("parse config file: %w", err)
+	}
+
+	// values are expanded after decoding, so one holding quotes or
+	// backslashes can't break the JSON around it
+	expandEnv(reflect.ValueOf(&cfg).Elem())
+
+	return cfg, nil
+}
+
+// expandEnv expands the environment variable references in the strings of v,
+// following structs, pointers, slices and maps. Fields that config files
+// can't set are left alone.
+func expandEnv(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(expandVars(v.String()))
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			expandEnv(v.Elem())
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			if t.Field(i).IsExported() && t.Field(i).Tag.Get("json") != "-" {
+				expandEnv(v.Field(i))
+			}
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			expandEnv(v.Index(i))
+		}
+	case reflect.Map:
+		// map values aren't addressable, each is expanded in a copy
+		iter := v.MapRange()
+		for iter.Next() {
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			expandEnv(value)
+			v.SetMapIndex(iter.Key(), value)
+		}
+	}
+}
+
+// expandVars replaces the ${VAR} references of s with the value of the
+// environment variable VAR, and $$ with $. Any other $ is kept, so secrets
+// and templates using $ variables are read as written.
+func expandVars(s string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+	var b strings.Builder
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '$')
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:i])
+		s = s[i:]
+		switch {
+		case strings.HasPrefix(s, "$$"):
+			b.WriteByte('$')
+			s = s[2:]
+		case strings.HasPrefix(s, "${"):
+			end := strings.IndexByte(s, '}')
+			if end < 0 {
+				b.WriteString(s)
+				return b.String()
+			}
+			b.WriteString(os.Getenv(s[2:end]))
+			s = s[end+1:]
+		default:
+			b.WriteByte('$')
+			s = s[1:]
+		}
+	}
+	return b.String()
+}
+
+// jsonDuration is a time.Duration written to config files as a string such as
+// "30s", and read from either a string or integer nanoseconds
+type jsonDuration time.Duration
+
+func (d jsonDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		parsed, err := time.ParseDuration(text)
+		if err != nil {
+			return err
+		}
+		*d = jsonDuration(parsed)
+		return nil
+	}
+
+	var nanoseconds int64
+	if err := json.Unmarshal(data, &nanoseconds); err != nil {
+		return fmt.Errorf("duration %s is neither a string such as \"30s\" nor integer nanoseconds", data)
+	}
+	*d = jsonDuration(nanoseconds)
+	return nil
+}
+
+// jsonFields returns c in the form read from and written to config files:
+// the fields of c, with its durations shadowed by jsonDurations pointing at
+// them
+func (c *Config) jsonFields() any {
+	type plain Config
+
+	return &struct {
+		*plain
+		FailbackInterval     *jsonDuration `json:"failbackInterval"`
+		WriteTimeout         *jsonDuration `json:"writeTimeout"`
+		BatchInterval        *jsonDuration `json:"batchInterval"`
+		BatchLatency         *jsonDuration `json:"batchLatency"`
+		SkewProbeInterval    *jsonDuration `json:"skewProbeInterval"`
+		StatsInterval        *jsonDuration `json:"statsInterval"`
+		StatsEventInterval   *jsonDuration `json:"statsEventInterval"`
+		ExitTimeout          *jsonDuration `json:"exitTimeout"`
+		ReplayWindow         *jsonDuration `json:"replayWindow"`
+		EgressWindow         *jsonDuration `json:"egressWindow"`
+		RemoteConfigInterval *jsonDuration `json:"remoteConfigInterval"`
+		FlagRefreshInterval  *jsonDuration `json:"flagRefreshInterval"`
+	}{
+		plain:                (*plain)(c),
+		FailbackInterval:     (*jsonDuration)(&c.FailbackInterval),
+		WriteTimeout:         (*jsonDuration)(&c.WriteTimeout),
+		BatchInterval:        (*jsonDuration)(&c.BatchInterval),
+		BatchLatency:         (*jsonDuration)(&c.BatchLatency),
+		SkewProbeInterval:    (*jsonDuration)(&c.SkewProbeInterval),
+		StatsInterval:        (*jsonDuration)(&c.StatsInterval),
+		StatsEventInterval:   (*jsonDuration)(&c.StatsEventInterval),
+		ExitTimeout:          (*jsonDuration)(&c.ExitTimeout),
+		ReplayWindow:         (*jsonDuration)(&c.ReplayWindow),
+		EgressWindow:         (*jsonDuration)(&c.EgressWindow),
+		RemoteConfigInterval: (*jsonDuration)(&c.RemoteConfigInterval),
+		FlagRefreshInterval:  (*jsonDuration)(&c.FlagRefreshInterval),
+	}
+}
+
+// MarshalJSON encodes the Config with durations written as strings such as
+// "30s" so config files stay readable
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.jsonFields())
+}
+
+// UnmarshalJSON decodes onto the existing values of c, accepting durations
+// either as strings such as "30s" or as integer nanoseconds. Unknown keys
+// are rejected.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(c.jsonFields())
+}
@@ -0,0 +1,362 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	t.Setenv("TEST_LOG_HOST", "logstash.example.com")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{"logType": "test-type", "logHost": "${TEST_LOG_HOST}", "logPort": 5150}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() returned unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		actual   interface{}
+		expected interface{}
+	}{
+		{"LogType", cfg.LogType, "test-type"},
+		{"LogHost", cfg.LogHost, "logstash.example.com"},
+		{"LogPort", cfg.LogPort, 5150},
+		{"LogChannel", cfg.LogChannel, "LagoonLogs"},
+		{"AddSource", cfg.AddSource, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.actual != tt.expected {
+				t.Errorf("LoadConfigFile().%s = %v, want %v", tt.name, tt.actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		content  string
+		contains string
+	}{
+		{"unknown field", `{"logType": "x", "logHots": "typo"}`, "unknown field"},
+		{"invalid json", `{"logType": `, "parse config file"},
+		{"wrong type", `{"logPort": "5140"}`, "parse config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			_, err := LoadConfigFile(path)
+			if err == nil {
+				t.Fatal("LoadConfigFile() should return error")
+			}
+			if !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("LoadConfigFile() error = %q, want it to contain %q", err.Error(), tt.contains)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadConfigFile(filepath.Join(dir, "missing.json"))
+		if err == nil || !strings.Contains(err.Error(), "read config file") {
+			t.Errorf("LoadConfigFile() error = %v, want read config file error", err)
+		}
+	})
+}
+
+func TestLoadConfigFile_EnvValues(t *testing.T) {
+	t.Setenv("TEST_LOG_CHANNEL", `say "hi" \ bye", "logPort": 1`)
+
+	cfg, err := parseConfig(NewConfig(), []byte(`{"logType": "x", "logChannel": "${TEST_LOG_CHANNEL}", "fallbackHosts": ["${TEST_LOG_CHANNEL}"]}`))
+	if err != nil {
+		t.Fatalf("parseConfig() returned unexpected error: %v", err)
+	}
+	want := `say "hi" \ bye", "logPort": 1`
+	if cfg.LogChannel != want || len(cfg.FallbackHosts) != 1 || cfg.FallbackHosts[0] != want {
+		t.Errorf("LogChannel = %q, FallbackHosts = %q, want the value verbatim", cfg.LogChannel, cfg.FallbackHosts)
+	}
+	if cfg.LogPort != NewConfig().LogPort {
+		t.Errorf("LogPort = %d, want the value unable to add keys", cfg.LogPort)
+	}
+}
+
+func TestLoadConfigFile_LiteralDollars(t *testing.T) {
+	t.Setenv("TEST_LOG_HOST", "logs.example.com")
+	t.Setenv("sw0rd", "expanded")
+
+	data := `{
+		"logType": "x",
+		"logHost": "${TEST_LOG_HOST}",
+		"logChannel": "$${TEST_LOG_HOST}",
+		"protocol": "http",
+		"http": {"username": "shop", "password": "pa$sw0rd"},
+		"computedAttrs": {"tier": "{{$t := .Message}}{{$t}}"}
+	}`
+	cfg, err := parseConfig(NewConfig(), []byte(data))
+	if err != nil {
+		t.Fatalf("parseConfig() returned unexpected error: %v", err)
+	}
+	if cfg.LogHost != "logs.example.com" {
+		t.Errorf("LogHost = %q, want the ${} reference expanded", cfg.LogHost)
+	}
+	if cfg.LogChannel != "${TEST_LOG_HOST}" {
+		t.Errorf("LogChannel = %q, want $$ read as a literal $", cfg.LogChannel)
+	}
+	if cfg.HTTP.Password != "pa$sw0rd" {
+		t.Errorf("HTTP.Password = %q, want the password as written", cfg.HTTP.Password)
+	}
+	if got := cfg.ComputedAttrs["tier"]; got != "{{$t := .Message}}{{$t}}" {
+		t.Errorf("ComputedAttrs[tier] = %q, want the template as written", got)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() returned unexpected error: %v", err)
+	}
+}
+
+func TestConfigJSON_DurationFields(t *testing.T) {
+	durationType := reflect.TypeOf(time.Duration(0))
+
+	// every duration is written as a string and read back
+	cfg := NewConfig()
+	v := reflect.ValueOf(&cfg).Elem()
+	for i := range v.NumField() {
+		if v.Type().Field(i).Type == durationType {
+			v.Field(i).SetInt(int64(i+1) * int64(time.Second))
+		}
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("json.Marshal() returned unexpected error: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("json.Unmarshal() returned unexpected error: %v", err)
+	}
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if field.Type != durationType {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if _, ok := fields[name].(string); !ok {
+			t.Errorf("%s = %v, want a string", name, fields[name])
+		}
+	}
+	var roundTrip Config
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("json.Unmarshal() returned unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(durations(roundTrip), durations(cfg)) {
+		t.Errorf("durations after a round trip = %v, want %v", durations(roundTrip), durations(cfg))
+	}
+
+	// keys match case insensitively and integers keep their precision
+	cfg, err = parseConfig(NewConfig(), []byte(`{"logType": "x", "SkewProbeInterval": "2s", "spoolMaxBytes": 9007199254740993}`))
+	if err != nil {
+		t.Fatalf("parseConfig() returned unexpected error: %v", err)
+	}
+	if cfg.SkewProbeInterval != 2*time.Second || cfg.SpoolMaxBytes != 9007199254740993 {
+		t.Errorf("SkewProbeInterval = %v, SpoolMaxBytes = %d, want 2s and 9007199254740993", cfg.SkewProbeInterval, cfg.SpoolMaxBytes)
+	}
+}
+
+// durations returns the durations of cfg in the order of its fields
+func durations(cfg Config) []time.Duration {
+	var out []time.Duration
+	v := reflect.ValueOf(cfg)
+	for i := range v.NumField() {
+		if d, ok := v.Field(i).Interface().(time.Duration); ok {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := NewConfig()
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should return error when LogType is empty")
+	}
+
+	cfg.LogType = "test-type"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() returned unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{"unknown level", func(c *Config) { c.Level = "loud" }},
+		{"invalid schedule days", func(c *Config) { c.Schedule = []ScheduleWindow{{Days: "weekdays"}} }},
+		{"invalid schedule time", func(c *Config) { c.Schedule = []ScheduleWindow{{Start: "9am"}} }},
+		{"invalid schedule level", func(c *Config) { c.Schedule = []ScheduleWindow{{Level: "loud"}} }},
+		{"negative schedule sample rate", func(c *Config) { c.Schedule = []ScheduleWindow{{SampleRate: -1}} }},
+		{"unknown schedule timezone", func(c *Config) { c.ScheduleTimezone = "Mars/Olympus" }},
+		{"unknown protocol", func(c *Config) { c.Protocol = "sctp" }},
+		{"unix without socket path", func(c *Config) { c.Protocol = ProtocolUnix; c.LogHost = "" }},
+		{"unknown syslog facility", func(c *Config) { c.Syslog = &SyslogConfig{Facility: "local9"} }},
+		{"invalid syslog sd-id", func(c *Config) { c.Syslog = &SyslogConfig{SDID: "lagoon 1"} }},
+		{"syslog batches over udp", func(c *Config) { c.Syslog = &SyslogConfig{}; c.BatchSize = 10 }},
+		{"destination without name", func(c *Config) { c.Destinations = []Destination{{Host: "logs", Port: 514}} }},
+		{"destination without port", func(c *Config) { c.Destinations = []Destination{{Name: "syslog", Host: "logs"}} }},
+		{"destination with unknown format", func(c *Config) {
+			c.Destinations = []Destination{{Name: "syslog", Host: "logs", Port: 514, Format: "pretty"}}
+		}},
+		{"destination with unknown level", func(c *Config) {
+			c.Destinations = []Destination{{Name: "syslog", Host: "logs", Port: 514, Level: "loud"}}
+		}},
+		{"destination tls over udp", func(c *Config) {
+			c.Destinations = []Destination{{Name: "syslog", Host: "logs", Port: 514, TLS: &TLSConfig{}}}
+		}},
+		{"duplicate destinations", func(c *Config) {
+			c.Destinations = []Destination{{Name: "syslog", Host: "a", Port: 514}, {Name: "syslog", Host: "b", Port: 514}}
+		}},
+		{"destination with unknown delivery policy", func(c *Config) {
+			c.Destinations = []Destination{{Name: "syslog", Host: "logs", Port: 514, DeliveryPolicy: "exactly-once"}}
+		}},
+		{"destination delivery policy without queue", func(c *Config) {
+			c.QueueSize = 0
+			c.Destinations = []Destination{{Name: "syslog", Host: "logs", Port: 514, DeliveryPolicy: DeliveryAtLeastOnce}}
+		}},
+		{"empty fallback host", func(c *Config) { c.FallbackHosts = []string{""} }},
+		{"invalid fallback port", func(c *Config) { c.FallbackHosts = []string{"logs-b:99999"} }},
+		{"fallback without failback interval", func(c *Config) {
+			c.FallbackHosts = []string{"logs-b"}
+			c.FailbackInterval = 0
+		}},
+		{"sender ID and file", func(c *Config) { c.SenderID = "pod-uid"; c.SenderIDFile = "/data/sender-id" }},
+		{"negative write timeout", func(c *Config) { c.WriteTimeout = -time.Second }},
+		{"tls over udp", func(c *Config) { c.TLS = &TLSConfig{} }},
+		{"tls certificate without key", func(c *Config) {
+			c.Protocol = ProtocolTCP
+			c.TLS = &TLSConfig{CertFile: "client.pem"}
+		}},
+		{"http settings over tcp", func(c *Config) { c.Protocol = ProtocolTCP; c.HTTP = &HTTPConfig{} }},
+		{"http path without slash", func(c *Config) { c.Protocol = ProtocolHTTP; c.HTTP = &HTTPConfig{Path: "_bulk"} }},
+		{"http basic auth and bearer token", func(c *Config) {
+			c.Protocol = ProtocolHTTP
+			c.HTTP = &HTTPConfig{Username: "elastic", BearerToken: "token"}
+		}},
+		{"forward settings over tcp", func(c *Config) { c.Protocol = ProtocolTCP; c.Forward = &ForwardConfig{} }},
+		{"forward tag with whitespace", func(c *Config) { c.Protocol = ProtocolForward; c.Forward = &ForwardConfig{Tag: "lagoon logs"} }},
+		{"syslog over forward", func(c *Config) { c.Protocol = ProtocolForward; c.Syslog = &SyslogConfig{} }},
+		{"otlp settings over http", func(c *Config) { c.Protocol = ProtocolHTTP; c.OTLP = &OTLPConfig{} }},
+		{"unknown otlp transport", func(c *Config) { c.Protocol = ProtocolOTLP; c.OTLP = &OTLPConfig{Transport: "thrift"} }},
+		{"otlp path over grpc", func(c *Config) {
+			c.Protocol = ProtocolOTLP
+			c.OTLP = &OTLPConfig{Transport: OTLPGRPC, Path: "/v1/logs"}
+		}},
+		{"syslog over otlp", func(c *Config) { c.Protocol = ProtocolOTLP; c.Syslog = &SyslogConfig{} }},
+		{"negative workers", func(c *Config) { c.DeliveryWorkers = -1 }},
+		{"workers without queue", func(c *Config) { c.DeliveryWorkers = 2; c.QueueSize = 0 }},
+		{"unknown ordering", func(c *Config) { c.Ordering = "fifo" }},
+		{"key ordering without key", func(c *Config) { c.Ordering = OrderingKeyed }},
+		{"negative batch size", func(c *Config) { c.BatchSize = -1 }},
+		{"batch without interval", func(c *Config) { c.BatchSize = 10; c.BatchInterval = 0 }},
+		{"negative batch latency", func(c *Config) { c.BatchSize = 10; c.BatchLatency = -time.Second }},
+		{"batch latency without batch size", func(c *Config) { c.BatchLatency = 50 * time.Millisecond }},
+		{"batch with key ordering", func(c *Config) {
+			c.BatchSize = 10
+			c.DeliveryWorkers = 2
+			c.Ordering = OrderingKeyed
+			c.OrderingKey = "k"
+		}},
+		{"unknown delivery policy", func(c *Config) { c.DeliveryPolicy = "exactly-once" }},
+		{"at-least-once without workers", func(c *Config) { c.DeliveryPolicy = DeliveryAtLeastOnce }},
+		{"record attempts without workers", func(c *Config) { c.RecordAttempts = true }},
+		{"negative attribute limit", func(c *Config) { c.MaxAttrs = -1 }},
+		{"negative message size", func(c *Config) { c.MaxMessageBytes = -1 }},
+		{"unknown message truncation", func(c *Config) { c.MessageTruncation = "drop" }},
+		{"unknown control chars", func(c *Config) { c.ControlChars = "remove" }},
+		{"nil processor", func(c *Config) { c.Processors = []Processor{nil} }},
+		{"negative string runes", func(c *Config) { c.MaxStringRunes = -1 }},
+		{"negative value limit", func(c *Config) { c.MaxValueDepth = -1 }},
+		{"negative compress threshold", func(c *Config) { c.CompressThreshold = -1 }},
+		{"negative egress budget", func(c *Config) { c.EgressBudget = -1 }},
+		{"egress budget without window", func(c *Config) { c.EgressBudget = 1 << 20; c.EgressWindow = 0 }},
+		{"negative event rate", func(c *Config) { c.MaxEventsPerSecond = -1 }},
+		{"negative burst", func(c *Config) { c.Burst = -1 }},
+		{"memory pressure above one", func(c *Config) { c.MemoryPressure = 1.5 }},
+		{"negative memory limit", func(c *Config) { c.MemoryLimit = -1 }},
+		{"negative memory sample rate", func(c *Config) { c.MemorySampleRate = -1 }},
+		{"remote config url without key", func(c *Config) { c.RemoteConfigURL = "https://config.example.com/logs" }},
+		{"remote config url not http", func(c *Config) { c.RemoteConfigURL = "ftp://example.com"; c.RemoteConfigKey = "secret" }},
+		{"flags without refresh interval", func(c *Config) { c.Flags = FlagProviderFunc(nil); c.FlagRefreshInterval = 0 }},
+		{"unknown source format", func(c *Config) { c.SourceFormat = "nested" }},
+		{"empty source skip prefix", func(c *Config) { c.SourceSkip = []string{""} }},
+		{"unknown stdout format", func(c *Config) { c.StdoutFormat = "yaml" }},
+		{"invalid forward level", func(c *Config) { c.ForwardLevel = "loud" }},
+		{"spool dir without max bytes", func(c *Config) { c.SpoolDir = "/tmp/spool"; c.SpoolMaxBytes = 0 }},
+		{"unknown debug signal", func(c *Config) { c.DebugSignal = "SIGKILL" }},
+		{"drop percent out of range", func(c *Config) { c.Faults = &Faults{DropPercent: 150} }},
+		{"negative fault delay", func(c *Config) { c.Faults = &Faults{Delay: -time.Second} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := cfg
+			tt.modify(&invalid)
+			if err := invalid.Validate(); err == nil {
+				t.Error("Validate() should return error")
+			}
+		})
+	}
+}
+
+func TestConfigJSON_Durations(t *testing.T) {
+	cfg, err := parseConfig(NewConfig(), []byte(`{"logType": "x", "skewProbeInterval": "90s"}`))
+	if err != nil {
+		t.Fatalf("parseConfig() returned unexpected error: %v", err)
+	}
+	if cfg.SkewProbeInterval != 90*time.Second {
+		t.Errorf("SkewProbeInterval = %v, want 90s", cfg.SkewProbeInterval)
+	}
+
+	cfg, err = parseConfig(NewConfig(), []byte(`{"logType": "x", "skewProbeInterval": 1000000000}`))
+	if err != nil {
+		t.Fatalf("parseConfig() returned unexpected error: %v", err)
+	}
+	if cfg.SkewProbeInterval != time.Second {
+		t.Errorf("SkewProbeInterval = %v, want 1s", cfg.SkewProbeInterval)
+	}
+
+	if _, err := parseConfig(NewConfig(), []byte(`{"skewProbeInterval": "soon"}`)); err == nil {
+		t.Error("parseConfig() should return error for an invalid duration")
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("json.Marshal() returned unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"skewProbeInterval":"1s"`) {
+		t.Errorf("json.Marshal() = %s, want durations as strings", data)
+	}
+
+	var roundTrip Config
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("json.Unmarshal() returned unexpected error: %v", err)
+	}
+	if roundTrip.SkewProbeInterval != time.Second || roundTrip.LogType != "x" {
+		t.Errorf("round trip = %+v", roundTrip)
+	}
+}
@@ -3,7 +3,9 @@ package logger
 import (
 	"bytes"
 	"log/slog"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewConfig(t *testing.T) {
@@ -16,12 +18,93 @@ func TestNewConfig(t *testing.T) {
 		expected interface{}
 	}{
 		{"AddSource", cfg.AddSource, true},
+		{"SourceFormat", cfg.SourceFormat, ""},
+		{"SourceSkip", len(cfg.SourceSkip), 0},
 		{"ApplicationName", cfg.ApplicationName, ""},
 		{"LogChannel", cfg.LogChannel, "LagoonLogs"},
 		{"LogHost", cfg.LogHost, ""},
 		{"LogPort", cfg.LogPort, 5140},
 		{"LogType", cfg.LogType, ""},
+		{"FallbackHosts", len(cfg.FallbackHosts), 0},
+		{"FailbackInterval", cfg.FailbackInterval, 30 * time.Second},
+		{"SenderID", cfg.SenderID, ""},
+		{"SenderIDFile", cfg.SenderIDFile, ""},
+		{"LagoonMetadata", cfg.LagoonMetadata, false},
+		{"SchemaField", cfg.SchemaField, false},
 		{"MessageVersion", cfg.MessageVersion, 1},
+		{"StrictSchema", cfg.StrictSchema, false},
+		{"Profiling", cfg.Profiling, false},
+		{"CompatMode", cfg.CompatMode, ""},
+		{"Level", cfg.Level, "debug"},
+		{"StdoutLevel", cfg.StdoutLevel, ""},
+		{"ForwardLevel", cfg.ForwardLevel, ""},
+		{"StdoutFormat", cfg.StdoutFormat, FormatJSON},
+		{"Schedule", len(cfg.Schedule), 0},
+		{"ScheduleTimezone", cfg.ScheduleTimezone, ""},
+		{"Protocol", cfg.Protocol, ProtocolUDP},
+		{"WriteTimeout", cfg.WriteTimeout, 5 * time.Second},
+		{"TLS", cfg.TLS, (*TLSConfig)(nil)},
+		{"HTTP", cfg.HTTP, (*HTTPConfig)(nil)},
+		{"Forward", cfg.Forward, (*ForwardConfig)(nil)},
+		{"OTLP", cfg.OTLP, (*OTLPConfig)(nil)},
+		{"Syslog", cfg.Syslog, (*SyslogConfig)(nil)},
+		{"Destinations", len(cfg.Destinations), 0},
+		{"Resolver", cfg.Resolver, nil},
+		{"Clock", cfg.Clock, nil},
+		{"DeliveryWorkers", cfg.DeliveryWorkers, 0},
+		{"QueueSize", cfg.QueueSize, 1000},
+		{"BatchSize", cfg.BatchSize, 0},
+		{"BatchInterval", cfg.BatchInterval, 100 * time.Millisecond},
+		{"BatchLatency", cfg.BatchLatency, time.Duration(0)},
+		{"Ordering", cfg.Ordering, OrderingUnordered},
+		{"OrderingKey", cfg.OrderingKey, ""},
+		{"DeliveryPolicy", cfg.DeliveryPolicy, DeliveryBestEffort},
+		{"RecordAttempts", cfg.RecordAttempts, false},
+		{"SkewProbeURL", cfg.SkewProbeURL, ""},
+		{"SkewProbeInterval", cfg.SkewProbeInterval, 5 * time.Minute},
+		{"SpoolDir", cfg.SpoolDir, ""},
+		{"SpoolMaxBytes", cfg.SpoolMaxBytes, int64(64 << 20)},
+		{"ReplayWindow", cfg.ReplayWindow, time.Duration(0)},
+		{"StatsFile", cfg.StatsFile, ""},
+		{"StatsInterval", cfg.StatsInterval, time.Minute},
+		{"StatsEventInterval", cfg.StatsEventInterval, time.Duration(0)},
+		{"StatsEventChannel", cfg.StatsEventChannel, "lagoon-log-forwarder-stats"},
+		{"ExitTimeout", cfg.ExitTimeout, 5 * time.Second},
+		{"CaptureStderr", cfg.CaptureStderr, false},
+		{"StdLogLevel", cfg.StdLogLevel, ""},
+		{"StrictReconfigure", cfg.StrictReconfigure, false},
+		{"MaxAttrs", cfg.MaxAttrs, 128},
+		{"MaxAttrDepth", cfg.MaxAttrDepth, 8},
+		{"MaxMessageBytes", cfg.MaxMessageBytes, 0},
+		{"MessageTruncation", cfg.MessageTruncation, TruncateMessage},
+		{"ControlChars", cfg.ControlChars, ControlKeep},
+		{"Processors", len(cfg.Processors), 0},
+		{"OnLevel", len(cfg.OnLevel), 0},
+		{"ComputedAttrs", len(cfg.ComputedAttrs), 0},
+		{"LevelRoutes", len(cfg.LevelRoutes), 0},
+		{"MaxValueFields", cfg.MaxValueFields, 64},
+		{"MaxValueDepth", cfg.MaxValueDepth, 8},
+		{"PreferStringer", cfg.PreferStringer, false},
+		{"MaxStringRunes", cfg.MaxStringRunes, 0},
+		{"CompressFields", len(cfg.CompressFields), 0},
+		{"CompressThreshold", cfg.CompressThreshold, 1024},
+		{"EgressBudget", cfg.EgressBudget, int64(0)},
+		{"EgressWindow", cfg.EgressWindow, time.Minute},
+		{"EgressSampleRate", cfg.EgressSampleRate, 100},
+		{"MaxEventsPerSecond", cfg.MaxEventsPerSecond, 0},
+		{"Burst", cfg.Burst, 0},
+		{"MemoryPressure", cfg.MemoryPressure, 0.0},
+		{"MemoryLimit", cfg.MemoryLimit, int64(0)},
+		{"MemorySampleRate", cfg.MemorySampleRate, 10},
+		{"RemoteConfigURL", cfg.RemoteConfigURL, ""},
+		{"RemoteConfigKey", cfg.RemoteConfigKey, ""},
+		{"RemoteConfigInterval", cfg.RemoteConfigInterval, time.Minute},
+		{"Flags", cfg.Flags, nil},
+		{"FlagRefreshInterval", cfg.FlagRefreshInterval, 30 * time.Second},
+		{"Diagnostics", cfg.Diagnostics, nil},
+		{"DebugSignal", cfg.DebugSignal, ""},
+		{"Trace", cfg.Trace, false},
+		{"Faults", cfg.Faults, (*Faults)(nil)},
 	}
 
 	for _, tt := range tests {
@@ -115,11 +198,7 @@ func TestValidate_ValidConfig(t *testing.T) {
 	logHost = "valid.example.com"
 	logType = "valid-type"
 
-	// Capture log output
-	var logOutput bytes.Buffer
-	handler := slog.NewTextHandler(&logOutput, &slog.HandlerOptions{})
-	logger := slog.New(handler)
-	slog.SetDefault(logger)
+	logOutput := captureDiagnostics(t)
 
 	err := validate()
 	if err != nil {
@@ -127,7 +206,7 @@ func TestValidate_ValidConfig(t *testing.T) {
 	}
 
 	// Check that no warnings were logged for logHost
-	if bytes.Contains(logOutput.Bytes(), []byte("log.host is not supplied")) {
+	if strings.Contains(logOutput.String(), "log.host is not supplied") {
 		t.Error("validate() should not warn when logHost is provided")
 	}
 }
@@ -147,11 +226,7 @@ func TestValidate_EmptyLogHost(t *testing.T) {
 	logHost = ""
 	logType = "valid-type"
 
-	// Capture log output
-	var logOutput bytes.Buffer
-	handler := slog.NewTextHandler(&logOutput, &slog.HandlerOptions{})
-	logger := slog.New(handler)
-	slog.SetDefault(logger)
+	logOutput := captureDiagnostics(t)
 
 	err := validate()
 	if err != nil {
@@ -159,7 +234,7 @@ func TestValidate_EmptyLogHost(t *testing.T) {
 	}
 
 	// Check that warning was logged for empty logHost
-	if !bytes.Contains(logOutput.Bytes(), []byte("log.host is not supplied")) {
+	if !strings.Contains(logOutput.String(), "log.host is not supplied") {
 		t.Error("validate() should warn when logHost is empty")
 	}
 }
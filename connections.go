@@ -0,0 +1,262 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithLongPoll logs an extra record when a request whose path matches one of
+// patterns starts, so long polls and streams appear while they are open
+// rather than only once they finish
+func WithLongPoll(patterns ...string) MiddlewareOption {
+	return func(m *middleware) { m.longPolls = append(m.longPolls, patterns...) }
+}
+
+// isLongPoll reports whether path is served by a long poll route
+func (m *middleware) isLongPoll(path string) bool {
+	for _, pattern := range m.longPolls {
+		if matchRoute(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hijack takes over the connection of an upgraded request, logging when it
+// opens and, with its duration and traffic, when it closes. The access record
+// is not logged for hijacked requests.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.hijacked = true
+
+	tracked := &trackedConn{Conn: conn, access: w.access}
+	if strings.EqualFold(w.access.request.Header.Get("Upgrade"), "websocket") {
+		// a handshake response not sent yet is written to the connection
+		// ahead of the first frame
+		tracked.in, tracked.out = &frameScanner{}, &frameScanner{handshake: w.status == 0}
+	}
+
+	// bytes the server already buffered are read before the connection
+	var buffered []byte
+	if brw.Reader.Buffered() > 0 {
+		peeked, _ := brw.Reader.Peek(brw.Reader.Buffered())
+		buffered = append(buffered, peeked...)
+		tracked.observeRead(buffered)
+	}
+	reader := bufio.NewReader(io.MultiReader(bytes.NewReader(buffered), tracked))
+	brw = bufio.NewReadWriter(reader, bufio.NewWriter(tracked))
+
+	w.access.log(slog.LevelInfo, "Connection opened", w.access.connectionAttrs(nil)...)
+	return tracked, brw, nil
+}
+
+// accessContext is what a connection needs to log its lifecycle after
+// ServeHTTP has returned
+type accessContext struct {
+	logger  *slog.Logger
+	request *http.Request
+	start   time.Time
+}
+
+func (a *accessContext) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	a.logger.LogAttrs(a.request.Context(), level, msg, attrs...)
+}
+
+// connectionAttrs returns the attributes of records about the connection,
+// with the connection group when one is given
+func (a *accessContext) connectionAttrs(connection []any) []slog.Attr {
+	attrs := []slog.Attr{
+		HTTPMethod(a.request.Method),
+		HTTPPath(a.request.URL.Path),
+		slog.String("http.upgrade", a.request.Header.Get("Upgrade")),
+	}
+	if connection != nil {
+		attrs = append(attrs, DurationMS(time.Since(a.start)), slog.Group("connection", connection...))
+	}
+	return attrs
+}
+
+// trackedConn counts the traffic of a hijacked connection and logs when it
+// is closed
+type trackedConn struct {
+	net.Conn
+	access *accessContext
+
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+	// in and out find the close frames of WebSocket traffic
+	in, out *frameScanner
+	mu      sync.Mutex
+
+	closeOnce sync.Once
+}
+
+func (c *trackedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.observeRead(p[:n])
+	return n, err
+}
+
+func (c *trackedConn) observeRead(p []byte) {
+	c.bytesIn.Add(int64(len(p)))
+	if c.in != nil {
+		c.mu.Lock()
+		c.in.observe(p)
+		c.mu.Unlock()
+	}
+}
+
+func (c *trackedConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.bytesOut.Add(int64(n))
+	if c.out != nil {
+		c.mu.Lock()
+		c.out.observe(p[:n])
+		c.mu.Unlock()
+	}
+	return n, err
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() {
+		connection := []any{
+			slog.Int64("bytes_in", c.bytesIn.Load()),
+			slog.Int64("bytes_out", c.bytesOut.Load()),
+		}
+
+		c.mu.Lock()
+		switch {
+		case c.in != nil && c.in.closeCode != 0:
+			connection = append(connection, slog.Int("close_code", c.in.closeCode), slog.String("closed_by", "client"))
+		case c.out != nil && c.out.closeCode != 0:
+			connection = append(connection, slog.Int("close_code", c.out.closeCode), slog.String("closed_by", "server"))
+		}
+		c.mu.Unlock()
+
+		c.access.log(slog.LevelInfo, "Connection closed", c.access.connectionAttrs(connection)...)
+	})
+	return err
+}
+
+// closeNoStatus is the code reported for close frames without a status,
+// as defined by RFC 6455
+const closeNoStatus = 1005
+
+// frameScanner follows the WebSocket frames of one direction of a connection
+// across reads or writes, remembering the code of the first close frame
+type frameScanner struct {
+	// handshake skips an HTTP response up to its blank line
+	handshake bool
+	tail      []byte
+
+	header    []byte
+	remaining uint64
+	closing   bool
+	mask      []byte
+	offset    int
+	payload   []byte
+	closeCode int
+}
+
+func (s *frameScanner) observe(p []byte) {
+	if s.handshake {
+		p = s.skipHandshake(p)
+	}
+
+	for len(p) > 0 {
+		if s.remaining > 0 {
+			n := int(min(s.remaining, uint64(len(p))))
+			if s.closing && len(s.payload) < 2 {
+				for _, b := range p[:min(n, 2-len(s.payload))] {
+					if s.mask != nil {
+						b ^= s.mask[s.offset%4]
+					}
+					s.payload = append(s.payload, b)
+					s.offset++
+				}
+				if len(s.payload) == 2 && s.closeCode == 0 {
+					s.closeCode = int(binary.BigEndian.Uint16(s.payload))
+				}
+			}
+			s.remaining -= uint64(n)
+			p = p[n:]
+			continue
+		}
+
+		s.header = append(s.header, p[0])
+		p = p[1:]
+		if length, ok := s.parseHeader(); ok {
+			s.remaining = length
+			if s.closing && length < 2 && s.closeCode == 0 {
+				s.closeCode = closeNoStatus
+			}
+		}
+	}
+}
+
+// skipHandshake returns the bytes of p following the end of the handshake
+// response, which may be split across writes
+func (s *frameScanner) skipHandshake(p []byte) []byte {
+	const end = "\r\n\r\n"
+
+	window := append(s.tail, p...)
+	if i := bytes.Index(window, []byte(end)); i >= 0 {
+		s.handshake, s.tail = false, nil
+		return window[i+len(end):]
+	}
+
+	s.tail = append([]byte(nil), window[max(0, len(window)-len(end)+1):]...)
+	return nil
+}
+
+// parseHeader reports the payload length once the header is complete and
+// starts the frame
+func (s *frameScanner) parseHeader() (uint64, bool) {
+	if len(s.header) < 2 {
+		return 0, false
+	}
+
+	masked := s.header[1]&0x80 != 0
+	length := uint64(s.header[1] & 0x7f)
+	size := 2
+	switch length {
+	case 126:
+		size += 2
+	case 127:
+		size += 8
+	}
+	if masked {
+		size += 4
+	}
+	if len(s.header) < size {
+		return 0, false
+	}
+
+	switch length {
+	case 126:
+		length = uint64(binary.BigEndian.Uint16(s.header[2:4]))
+	case 127:
+		length = binary.BigEndian.Uint64(s.header[2:10])
+	}
+
+	s.closing = s.header[0]&0x0f == 0x8
+	s.mask, s.offset, s.payload = nil, 0, nil
+	if masked {
+		s.mask = append([]byte(nil), s.header[size-4:size]...)
+	}
+	s.header = s.header[:0]
+	return length, true
+}
@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// frame encodes a WebSocket frame, masked with key when it is given
+func frame(opcode byte, payload []byte, key []byte) []byte {
+	out := []byte{0x80 | opcode}
+	maskBit := byte(0)
+	if key != nil {
+		maskBit = 0x80
+	}
+
+	switch {
+	case len(payload) < 126:
+		out = append(out, maskBit|byte(len(payload)))
+	default:
+		out = append(out, maskBit|126, byte(len(payload)>>8), byte(len(payload)))
+	}
+
+	if key == nil {
+		return append(out, payload...)
+	}
+	out = append(out, key...)
+	for i, b := range payload {
+		out = append(out, b^key[i%4])
+	}
+	return out
+}
+
+func TestFrameScanner(t *testing.T) {
+	key := []byte{1, 2, 3, 4}
+
+	tests := []struct {
+		name      string
+		handshake bool
+		stream    []byte
+		expected  int
+	}{
+		{"no close", false, frame(0x1, []byte("hello"), nil), 0},
+		{"close code", false, frame(0x8, []byte{0x03, 0xe8}, nil), 1000},
+		{"masked close after data", false, append(frame(0x1, bytes.Repeat([]byte("x"), 300), key), frame(0x8, []byte{0x03, 0xe9, 'b', 'y', 'e'}, key)...), 1001},
+		{"close without status", false, frame(0x8, nil, nil), closeNoStatus},
+		{"after handshake", true, append([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\n\r\n"), frame(0x8, []byte{0x0f, 0xa0}, nil)...), 4000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, chunk := range []int{len(tt.stream), 1} {
+				s := &frameScanner{handshake: tt.handshake}
+				for stream := tt.stream; len(stream) > 0; {
+					n := min(chunk, len(stream))
+					s.observe(stream[:n])
+					stream = stream[n:]
+				}
+				if s.closeCode != tt.expected {
+					t.Errorf("closeCode in chunks of %d = %d, want %d", chunk, s.closeCode, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPMiddleware_WebSocketLifecycle(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	logger := slog.New(slog.NewJSONHandler(&lockedWriter{w: &buf, mu: &mu}, nil))
+
+	closed := make(chan struct{})
+	server := httptest.NewServer(HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Hijack() returned unexpected error: %v", err)
+			return
+		}
+		go func() {
+			defer close(closed)
+			defer conn.Close()
+			_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+			_, _ = brw.Write(frame(0x1, []byte("hi"), nil))
+			_ = brw.Flush()
+
+			// wait for the close frame of the client
+			_, _ = brw.Read(make([]byte, 64))
+		}()
+	}), WithLogger(logger)))
+	defer server.Close()
+
+	client, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer client.Close()
+
+	_, _ = client.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+	reader := bufio.NewReader(client)
+	if _, err := http.ReadResponse(reader, nil); err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	_, _ = client.Write(frame(0x8, []byte{0x03, 0xe8}, []byte{9, 8, 7, 6}))
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("server connection was not closed")
+	}
+
+	mu.Lock()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	mu.Unlock()
+	if len(lines) != 2 {
+		t.Fatalf("logged %d records, want opened and closed:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+
+	var opened, closedRecord struct {
+		Message    string `json:"msg"`
+		Upgrade    string `json:"http.upgrade"`
+		DurationMS *int64 `json:"duration_ms"`
+		Connection struct {
+			BytesIn   int64  `json:"bytes_in"`
+			BytesOut  int64  `json:"bytes_out"`
+			CloseCode int    `json:"close_code"`
+			ClosedBy  string `json:"closed_by"`
+		} `json:"connection"`
+	}
+	_ = json.Unmarshal([]byte(lines[0]), &opened)
+	_ = json.Unmarshal([]byte(lines[1]), &closedRecord)
+
+	if opened.Message != "Connection opened" || opened.Upgrade != "websocket" {
+		t.Errorf("first record = %s, want the connection opened", lines[0])
+	}
+	if closedRecord.Message != "Connection closed" || closedRecord.DurationMS == nil {
+		t.Errorf("second record = %s, want the connection closed with its duration", lines[1])
+	}
+	if c := closedRecord.Connection; c.CloseCode != 1000 || c.ClosedBy != "client" || c.BytesIn != 8 || c.BytesOut == 0 {
+		t.Errorf("connection = %+v, want close code 1000 by the client and 8 bytes in", c)
+	}
+}
+
+func TestHTTPMiddleware_LongPoll(t *testing.T) {
+	var buf bytes.Buffer
+	handler := HTTPMiddleware(statusHandler(http.StatusOK),
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+		WithLongPoll("/events/*"),
+	)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events/stream", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record struct {
+			Message string `json:"msg"`
+		}
+		_ = json.Unmarshal([]byte(line), &record)
+		messages = append(messages, record.Message)
+	}
+
+	want := []string{"HTTP request started", "HTTP request", "HTTP request"}
+	if strings.Join(messages, ",") != strings.Join(want, ",") {
+		t.Errorf("messages = %v, want %v", messages, want)
+	}
+}
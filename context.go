@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// DebugContext logs at slog.LevelDebug with the logger of ctx, like
+// slog.DebugContext, adding the trace and span of ctx with SpanContext
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	logDefault(ctx, slog.LevelDebug, msg, args...)
+}
+
+// InfoContext logs at slog.LevelInfo with the logger of ctx, like
+// slog.InfoContext, adding the trace and span of ctx with SpanContext
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	logDefault(ctx, slog.LevelInfo, msg, args...)
+}
+
+// WarnContext logs at slog.LevelWarn with the logger of ctx, like
+// slog.WarnContext, adding the trace and span of ctx with SpanContext
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	logDefault(ctx, slog.LevelWarn, msg, args...)
+}
+
+// ErrorContext logs at slog.LevelError with the logger of ctx, like
+// slog.ErrorContext, adding the trace and span of ctx with SpanContext
+func ErrorContext(ctx context.Context, msg string, args ...any) {
+	logDefault(ctx, slog.LevelError, msg, args...)
+}
+
+// contextKey holds the logger of a context
+type contextKey struct{}
+
+// NewContext returns a copy of ctx whose logger adds attrs to every record,
+// e.g. the request_id and user_id of a request, so they are attached once
+// instead of threading a *slog.Logger through every call. The attrs are
+// added to those of the logger of ctx, so nested scopes accumulate them.
+func NewContext(ctx context.Context, attrs ...any) context.Context {
+	return context.WithValue(ctx, contextKey{}, FromContext(ctx).With(attrs...))
+}
+
+// FromContext returns the logger of ctx set by NewContext, or the default
+// logger when there is none
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// logDefault logs with the logger of ctx on behalf of the caller of the
+// exported function calling it, which is the source of the record
+func logDefault(ctx context.Context, level slog.Level, msg string, args ...any) {
+	logger := FromContext(ctx)
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	// skip runtime.Callers, logDefault and the exported function
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = logger.Handler().Handle(ctx, r)
+}
+
+// appendSpanAttrs adds the trace and span IDs to attrs, leaving out empty
+// ones such as those of a context without a span
+func appendSpanAttrs(attrs []slog.Attr, traceID, spanID string) []slog.Attr {
+	if len(traceID) > 0 {
+		attrs = append(attrs, slog.String(FieldTraceID, traceID))
+	}
+	if len(spanID) > 0 {
+		attrs = append(attrs, slog.String(FieldSpanID, spanID))
+	}
+	return attrs
+}
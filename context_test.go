@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// testSpanKey holds the span of a context in the tests, as a tracer would
+type testSpanKey struct{}
+
+type testSpan struct {
+	traceID, spanID string
+}
+
+func testSpanContext(ctx context.Context) (string, string) {
+	span, _ := ctx.Value(testSpanKey{}).(testSpan)
+	return span.traceID, span.spanID
+}
+
+func TestHandler_SpanContext(t *testing.T) {
+	preserveConfig(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "span-type"
+	cfg.LogHost = "localhost"
+	cfg.Level = "debug"
+	cfg.SpanContext = testSpanContext
+
+	var buf bytes.Buffer
+	h, err := NewWriterHandler(cfg, &buf)
+	if err != nil {
+		t.Fatalf("NewWriterHandler() returned unexpected error: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(h))
+	defer slog.SetDefault(previous)
+
+	traced := context.WithValue(context.Background(), testSpanKey{}, testSpan{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"})
+	tests := []struct {
+		name  string
+		log   func(ctx context.Context, msg string, args ...any)
+		level string
+	}{
+		{"debug", DebugContext, "DEBUG"},
+		{"info", InfoContext, "INFO"},
+		{"warn", WarnContext, "WARN"},
+		{"error", ErrorContext, "ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			tt.log(traced, "traced", "seq", 1)
+
+			var event map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+				t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+			}
+			if event["level"] != tt.level || event["seq"] != float64(1) {
+				t.Errorf("event = %v, want a %s record with seq", event, tt.level)
+			}
+			if event[FieldTraceID] != "4bf92f3577b34da6a3ce929d0e0e4736" || event[FieldSpanID] != "00f067aa0ba902b7" {
+				t.Errorf("trace_id = %v, span_id = %v, want the span of the context", event[FieldTraceID], event[FieldSpanID])
+			}
+			if !strings.Contains(buf.String(), "context_test.go") {
+				t.Errorf("source isn't the caller in %s", buf.String())
+			}
+		})
+	}
+
+	buf.Reset()
+	slog.InfoContext(context.Background(), "untraced")
+	if strings.Contains(buf.String(), FieldTraceID) || strings.Contains(buf.String(), FieldSpanID) {
+		t.Errorf("a context without a span added IDs: %s", buf.String())
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	preserveConfig(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "context-type"
+	cfg.LogHost = "localhost"
+
+	var buf bytes.Buffer
+	h, err := NewWriterHandler(cfg, &buf)
+	if err != nil {
+		t.Fatalf("NewWriterHandler() returned unexpected error: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(h))
+	defer slog.SetDefault(previous)
+
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("FromContext() of a context without a logger isn't the default logger")
+	}
+
+	request := NewContext(context.Background(), "request_id", "r-1")
+	user := NewContext(request, slog.String("user_id", "u-7"))
+
+	tests := []struct {
+		name     string
+		log      func()
+		expected map[string]any
+	}{
+		{"from context", func() { FromContext(request).Info("scoped") }, map[string]any{"request_id": "r-1"}},
+		{"nested", func() { FromContext(user).Info("scoped") }, map[string]any{"request_id": "r-1", "user_id": "u-7"}},
+		{"package function", func() { InfoContext(user, "scoped") }, map[string]any{"request_id": "r-1", "user_id": "u-7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			tt.log()
+
+			var event map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+				t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+			}
+			for key, want := range tt.expected {
+				if event[key] != want {
+					t.Errorf("event[%q] = %v, want %v", key, event[key], want)
+				}
+			}
+		})
+	}
+
+	buf.Reset()
+	FromContext(request).Info("outer")
+	if strings.Contains(buf.String(), "user_id") {
+		t.Errorf("a nested scope changed its parent: %s", buf.String())
+	}
+}
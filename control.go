@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Treatments of the control characters in a message, which break the grok
+// stages of some Logstash pipelines even though the JSON encoding keeps them
+// intact
+const (
+	// ControlKeep writes control characters as they are
+	ControlKeep = "keep"
+	// ControlStrip removes ANSI escape sequences and control characters,
+	// keeping newlines and tabs
+	ControlStrip = "strip"
+	// ControlEscape writes control characters as visible escapes, e.g. the
+	// ANSI colour ESC[31m as \x1b[31m
+	ControlEscape = "escape"
+)
+
+// isControl reports whether r is a control character other than a newline or
+// tab
+func isControl(r rune) bool {
+	return (r < 0x20 && r != '\n' && r != '\t') || (r >= 0x7f && r <= 0x9f)
+}
+
+// cleanMessage returns msg with its control characters treated as mode
+// describes. A message without any is returned as it is.
+func cleanMessage(msg, mode string) string {
+	if mode != ControlStrip && mode != ControlEscape {
+		return msg
+	}
+	if strings.IndexFunc(msg, isControl) < 0 {
+		return msg
+	}
+
+	var b strings.Builder
+	b.Grow(len(msg))
+	for i := 0; i < len(msg); {
+		r, width := utf8.DecodeRuneInString(msg[i:])
+		switch {
+		case !isControl(r):
+			b.WriteString(msg[i : i+width])
+		case mode == ControlStrip && r == 0x1b:
+			i += escapeSequence(msg[i:])
+			continue
+		case mode == ControlStrip:
+		case r < utf8.RuneSelf:
+			b.WriteString(`\x`)
+			if r < 0x10 {
+				b.WriteByte('0')
+			}
+			b.WriteString(strconv.FormatInt(int64(r), 16))
+		default:
+			b.WriteString(`\u00`)
+			b.WriteString(strconv.FormatInt(int64(r), 16))
+		}
+		i += width
+	}
+	return b.String()
+}
+
+// escapeSequence returns the length of the ANSI escape sequence at the start
+// of s, which starts with ESC: a CSI sequence such as a colour, an OSC
+// sequence such as a hyperlink or a single character escape. An incomplete
+// sequence runs to the end of s.
+func escapeSequence(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+
+	switch s[1] {
+	case '[':
+		// parameter and intermediate bytes up to a final byte
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+			if s[i] < 0x20 || s[i] > 0x3f {
+				return i
+			}
+		}
+		return len(s)
+	case ']':
+		// terminated by BEL or ESC \
+		for i := 2; i < len(s); i++ {
+			if s[i] == 0x07 {
+				return i + 1
+			}
+			if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	default:
+		if s[1] >= 0x40 && s[1] <= 0x5f {
+			return 2
+		}
+		return 1
+	}
+}
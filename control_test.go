@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestCleanMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		msg    string
+		expect string
+	}{
+		{"keep", ControlKeep, "\x1b[31mred\x1b[0m", "\x1b[31mred\x1b[0m"},
+		{"no control characters", ControlStrip, "plain\ttext\nline", "plain\ttext\nline"},
+		{"strip colours", ControlStrip, "\x1b[1;31mred\x1b[0m done", "red done"},
+		{"strip hyperlink", ControlStrip, "\x1b]8;;https://example.com\x07link\x1b]8;;\x1b\\", "link"},
+		{"strip controls", ControlStrip, "bell\x07 null\x00 del\x7f c1\u009b", "bell null del c1"},
+		{"strip incomplete sequence", ControlStrip, "cut\x1b[31", "cut"},
+		{"escape", ControlEscape, "\x1b[31mred\x1b[0m\x00\u0085", `\x1b[31mred\x1b[0m\x00\u0085`},
+		{"escape keeps unicode", ControlEscape, "café\x07", `café\x07`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanMessage(tt.msg, tt.mode); got != tt.expect {
+				t.Errorf("cleanMessage(%q, %q) = %q, want %q", tt.msg, tt.mode, got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestHandler_ControlChars(t *testing.T) {
+	preserveConfig(t)
+	controlChars = ControlStrip
+
+	var buf bytes.Buffer
+	slog.New(newHandler(&buf)).Info("\x1b[32mready\x1b[0m", "raw", "\x1b[32m")
+
+	if !strings.Contains(buf.String(), `"message":"ready"`) || !strings.Contains(buf.String(), `"raw":"\u001b[32m"`) {
+		t.Errorf("output = %s, want the message stripped and the attribute kept", buf.String())
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package logger
+
+import "time"
+
+// processCPUTime returns the CPU time the process has used, which is unknown
+// outside unix
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,362 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// deliveryAttempts is how often a worker tries to deliver a record
+	deliveryAttempts = 3
+	// deliveryBackoff is the delay before the first redial of a worker
+	deliveryBackoff = 100 * time.Millisecond
+	// deliveryMaxBackoff caps the exponential redial delay of a worker
+	deliveryMaxBackoff = 5 * time.Second
+)
+
+// ErrQueueFull is returned when a record is dropped because the delivery
+// queue of its worker is full
+var ErrQueueFull = errors.New("delivery queue is full")
+
+// deliveryPool fans records written to it out to a fixed number of delivery
+// workers, each with its own connection and retry state. Records routed to
+// the same worker are delivered in the order they were written.
+type deliveryPool struct {
+	// sink names the sink the pool delivers, SinkForwarder or a destination
+	sink      string
+	workers   []*deliveryWorker
+	partition func(p []byte) int
+	block     bool
+	next      atomic.Uint64
+	dropped   atomic.Uint64
+	aborted   chan struct{}
+	abortOnce sync.Once
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// deliveryWorker owns a queue and the connection its records are written to
+type deliveryWorker struct {
+	sink     string
+	queue    chan []byte
+	dial     func() (io.WriteCloser, error)
+	conn     io.WriteCloser
+	attempts int // 0 retries until the record is written
+	// annotate adds the delivery fields to records written again
+	annotate bool
+	failures int
+	// spill takes the records given up on instead of dropping them, and lost
+	// is told when the attempts at one were exhausted, when they are set
+	spill   func(p []byte) bool
+	lost    func(err error)
+	dropped *atomic.Uint64
+	aborted chan struct{}
+	clock   Clock
+}
+
+// newDeliveryPool starts workers delivering through connections opened with
+// dial. Each worker buffers up to queueSize records.
+func newDeliveryPool(workers, queueSize int, dial func() (io.WriteCloser, error)) *deliveryPool {
+	return newPolicyPool(DeliveryBestEffort, workers, queueSize, dial)
+}
+
+// newPolicyPool starts a pool whose workers implement the delivery policy
+func newPolicyPool(policy string, workers, queueSize int, dial func() (io.WriteCloser, error)) *deliveryPool {
+	pool := &deliveryPool{sink: SinkForwarder, aborted: make(chan struct{})}
+	pool.partition = pool.roundRobin
+
+	attempts := deliveryAttempts
+	switch policy {
+	case DeliveryAtMostOnce:
+		attempts = 1
+	case DeliveryAtLeastOnce:
+		attempts = 0
+		pool.block = true
+	}
+
+	for i := 0; i < workers; i++ {
+		worker := &deliveryWorker{
+			sink:     pool.sink,
+			queue:    make(chan []byte, queueSize),
+			dial:     dial,
+			attempts: attempts,
+			dropped:  &pool.dropped,
+			aborted:  pool.aborted,
+			clock:    schedulerClock(),
+		}
+		pool.workers = append(pool.workers, worker)
+
+		pool.wg.Add(1)
+		go func() {
+			defer pool.wg.Done()
+			worker.run()
+		}()
+	}
+
+	return pool
+}
+
+// newOrderedPool starts a pool whose partitioning implements the ordering
+// mode: a single worker for strict ordering, a hash of the key attribute for
+// keyed ordering and round robin otherwise
+func newOrderedPool(mode, key, policy string, workers, queueSize int, dial func() (io.WriteCloser, error)) *deliveryPool {
+	switch mode {
+	case OrderingStrict:
+		return newPolicyPool(policy, 1, queueSize, dial)
+	case OrderingKeyed:
+		pool := newPolicyPool(policy, workers, queueSize, dial)
+		pool.partition = keyPartition(key)
+		return pool
+	default:
+		return newPolicyPool(policy, workers, queueSize, dial)
+	}
+}
+
+// keyPartition hashes the value of the attribute at the dotted path key, so
+// records sharing a value are delivered by the same worker. Records without
+// the attribute share a partition.
+func keyPartition(key string) func([]byte) int {
+	path := strings.Split(key, ".")
+
+	return func(record []byte) int {
+		hash := fnv.New32a()
+		_, _ = hash.Write(lookupJSON(record, path))
+		return int(hash.Sum32() & 0x7fffffff)
+	}
+}
+
+// lookupJSON returns the raw JSON value at path in the encoded object
+func lookupJSON(data []byte, path []string) []byte {
+	for _, key := range path {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(data, &object); err != nil {
+			return nil
+		}
+		data = object[key]
+	}
+	return data
+}
+
+func (p *deliveryPool) roundRobin([]byte) int {
+	return int(p.next.Add(1) % uint64(len(p.workers)))
+}
+
+// Write queues a copy of b for delivery, as slog handlers reuse their
+// buffers. A full queue drops the record, or blocks the caller under the
+// at-least-once policy.
+func (p *deliveryPool) Write(b []byte) (int, error) {
+	return p.writeContext(context.Background(), b)
+}
+
+// writeContext is Write, except that a caller blocked by a full queue drops
+// the record and returns the error of ctx once it is done
+func (p *deliveryPool) writeContext(ctx context.Context, b []byte) (int, error) {
+	if err := p.enqueue(ctx, b, p.block); err != nil {
+		p.dropped.Add(1)
+		countDropped(p.sink, 1)
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// enqueue queues a copy of b for its worker. A full queue fails with
+// ErrQueueFull, or with wait set, once ctx is done or the pool aborted.
+// Under memory pressure a queue is full at a tenth of its capacity.
+func (p *deliveryPool) enqueue(ctx context.Context, b []byte, wait bool) error {
+	record := append([]byte(nil), b...)
+	worker := p.workers[p.partition(record)%len(p.workers)]
+
+	// under memory pressure records are dropped rather than buffered
+	if !wait && underPressure() && len(worker.queue) >= max(cap(worker.queue)/pressureQueueShare, 1) {
+		return ErrQueueFull
+	}
+
+	select {
+	case worker.queue <- record:
+		return nil
+	default:
+	}
+
+	if wait {
+		select {
+		case worker.queue <- record:
+			return nil
+		case <-p.aborted:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return ErrQueueFull
+}
+
+// Close stops accepting records and waits for the workers to drain their
+// queues. Writes after Close panic, so the pool must be detached first.
+func (p *deliveryPool) Close() error {
+	p.closeOnce.Do(func() {
+		for _, worker := range p.workers {
+			close(worker.queue)
+		}
+	})
+	p.wg.Wait()
+	return nil
+}
+
+// abort makes workers give up on undeliverable records so Close returns
+// promptly, used when Shutdown runs out of time
+func (p *deliveryPool) abort() {
+	p.abortOnce.Do(func() { close(p.aborted) })
+}
+
+// Dropped returns the number of records that could not be delivered
+func (p *deliveryPool) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// named makes the pool count its drops and errors for the sink name. It must
+// be called before records are written.
+func (p *deliveryPool) named(name string) {
+	p.sink = name
+	for _, w := range p.workers {
+		w.sink = name
+	}
+}
+
+// recordAttempts makes the workers add the delivery fields to the records
+// they retry. It must be called before records are written.
+func (p *deliveryPool) recordAttempts() {
+	for _, w := range p.workers {
+		w.annotate = true
+	}
+}
+
+// spillTo makes the workers hand the records they give up on to spill, when
+// it is set, and report the error exhausting the attempts at one to lost. It
+// must be called before records are written.
+func (p *deliveryPool) spillTo(spill func([]byte) bool, lost func(error)) {
+	for _, w := range p.workers {
+		w.spill = spill
+		w.lost = lost
+	}
+}
+
+func (w *deliveryWorker) run() {
+	for record := range w.queue {
+		_, delivering := startPhase(context.Background(), phaseDeliver, w.sink)
+		w.deliver(record)
+		delivering.end()
+	}
+	if w.conn != nil {
+		_ = w.conn.Close()
+	}
+}
+
+// deliver writes record, redialling with exponential backoff between failed
+// attempts, and gives up on it once the attempts are exhausted or the pool
+// aborted
+func (w *deliveryWorker) deliver(record []byte) {
+	var first time.Time
+	var err error
+	for attempt := 0; w.attempts == 0 || attempt < w.attempts; attempt++ {
+		if attempt > 0 || w.failures > 0 {
+			select {
+			case <-w.clock.After(w.backoff()):
+			case <-w.aborted:
+				w.giveUp(record)
+				return
+			}
+		}
+		if attempt == 0 {
+			first = w.clock.Now()
+		}
+
+		if w.conn == nil {
+			var conn io.WriteCloser
+			conn, err = w.dial()
+			if err != nil {
+				// dialling the forwarder records its own errors
+				w.failures++
+				continue
+			}
+			w.conn = conn
+		}
+
+		data := record
+		if attempt > 0 && w.annotate {
+			data = withDeliveryFields(record, attempt+1, first)
+		}
+		if _, err = w.conn.Write(data); err != nil {
+			recordError(w.sink, OpWrite, err)
+			w.failures++
+			_ = w.conn.Close()
+			w.conn = nil
+			continue
+		}
+
+		w.failures = 0
+		return
+	}
+
+	if w.lost != nil {
+		w.lost(err)
+	}
+	w.giveUp(record)
+}
+
+// giveUp spills record, or drops it without a spill
+func (w *deliveryWorker) giveUp(record []byte) {
+	if w.spill != nil && w.spill(record) {
+		return
+	}
+	w.dropped.Add(1)
+	countDropped(w.sink, 1)
+}
+
+// backoff is the delay before the next attempt given consecutive failures
+func (w *deliveryWorker) backoff() time.Duration {
+	if w.failures == 0 {
+		return 0
+	}
+	delay := deliveryBackoff << min(w.failures-1, 16)
+	return min(delay, deliveryMaxBackoff)
+}
+
+// withDeliveryFields returns a copy of the JSON lines in data with a
+// delivery group recording the attempts and when the first one was made, so
+// consumers can tell delayed events from late ones. Lines that aren't JSON
+// objects are copied unchanged.
+func withDeliveryFields(data []byte, attempts int, first time.Time) []byte {
+	fields := `"delivery":{"attempts":` + strconv.Itoa(attempts) + `,"first_attempt_at":"` + first.UTC().Format(time.RFC3339Nano) + `"}`
+
+	out := make([]byte, 0, len(data)+len(fields)+8)
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i+1]
+		}
+		data = data[len(line):]
+
+		body := bytes.TrimRight(line, " \r\n")
+		if len(body) < 2 || body[0] != '{' || body[len(body)-1] != '}' {
+			out = append(out, line...)
+			continue
+		}
+		out = append(out, body[:len(body)-1]...)
+		if inner := bytes.TrimSpace(body[1 : len(body)-1]); len(inner) > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, fields...)
+		out = append(out, '}')
+		out = append(out, line[len(body):]...)
+	}
+	return out
+}
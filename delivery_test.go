@@ -0,0 +1,621 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingConn collects delivered records and can be told to fail
+type recordingConn struct {
+	mu      sync.Mutex
+	records []string
+	fail    int
+	closed  bool
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail > 0 {
+		c.fail--
+		return 0, errors.New("write failed")
+	}
+	c.records = append(c.records, string(p))
+	return len(p), nil
+}
+
+func (c *recordingConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *recordingConn) delivered() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.records...)
+}
+
+func TestDeliveryPool_DeliversAll(t *testing.T) {
+	conn := &recordingConn{}
+	var dials int
+	var mu sync.Mutex
+	pool := newDeliveryPool(4, 100, func() (io.WriteCloser, error) {
+		mu.Lock()
+		dials++
+		mu.Unlock()
+		return conn, nil
+	})
+
+	const records = 200
+	var wg sync.WaitGroup
+	for i := 0; i < records; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				if _, err := pool.Write([]byte(fmt.Sprintf("record-%03d", i))); err == nil {
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Close() returned unexpected error: %v", err)
+	}
+
+	delivered := conn.delivered()
+	if len(delivered) != records {
+		t.Fatalf("delivered %d records, want %d", len(delivered), records)
+	}
+	sort.Strings(delivered)
+	for i, record := range delivered {
+		if want := fmt.Sprintf("record-%03d", i); record != want {
+			t.Fatalf("delivered[%d] = %q, want %q", i, record, want)
+		}
+	}
+	if dials != 4 {
+		t.Errorf("workers dialled %d connections, want one each (4)", dials)
+	}
+}
+
+func TestDeliveryPool_CopiesBuffer(t *testing.T) {
+	conn := &recordingConn{}
+	pool := newDeliveryPool(1, 10, func() (io.WriteCloser, error) { return conn, nil })
+
+	buf := []byte("first")
+	if _, err := pool.Write(buf); err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+	copy(buf, "XXXXX")
+	pool.Close()
+
+	if got := conn.delivered(); len(got) != 1 || got[0] != "first" {
+		t.Errorf("delivered %v, want [first]", got)
+	}
+}
+
+func TestDeliveryPool_PartitionOrdering(t *testing.T) {
+	conn := &recordingConn{}
+	pool := newDeliveryPool(3, 100, func() (io.WriteCloser, error) { return conn, nil })
+	pool.partition = func([]byte) int { return 1 }
+
+	for i := 0; i < 50; i++ {
+		if _, err := pool.Write([]byte(fmt.Sprintf("%02d", i))); err != nil {
+			t.Fatalf("Write() returned unexpected error: %v", err)
+		}
+	}
+	pool.Close()
+
+	for i, record := range conn.delivered() {
+		if want := fmt.Sprintf("%02d", i); record != want {
+			t.Fatalf("delivered[%d] = %q, want %q; records of one partition must stay ordered", i, record, want)
+		}
+	}
+}
+
+func TestDeliveryPool_RetriesAndRedials(t *testing.T) {
+	conn := &recordingConn{fail: 1}
+	dials := 0
+	pool := newDeliveryPool(1, 10, func() (io.WriteCloser, error) {
+		dials++
+		if dials == 1 {
+			return nil, errors.New("dial failed")
+		}
+		return conn, nil
+	})
+
+	if _, err := pool.Write([]byte("retried")); err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+	pool.Close()
+
+	if got := conn.delivered(); len(got) != 1 || got[0] != "retried" {
+		t.Errorf("delivered %v, want [retried]", got)
+	}
+	if dials != 3 {
+		t.Errorf("worker dialled %d times, want 3 (failed dial, failed write, success)", dials)
+	}
+	if pool.Dropped() != 0 {
+		t.Errorf("Dropped() = %d, want 0", pool.Dropped())
+	}
+}
+
+func TestDeliveryPool_DropsAfterAttempts(t *testing.T) {
+	pool := newDeliveryPool(1, 10, func() (io.WriteCloser, error) {
+		return nil, errors.New("unreachable")
+	})
+
+	if _, err := pool.Write([]byte("lost")); err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+	pool.Close()
+
+	if pool.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", pool.Dropped())
+	}
+}
+
+func TestDeliveryPool_QueueFull(t *testing.T) {
+	block := make(chan struct{})
+	pool := newDeliveryPool(1, 1, func() (io.WriteCloser, error) {
+		<-block
+		return &recordingConn{}, nil
+	})
+
+	var full bool
+	for i := 0; i < 10; i++ {
+		if _, err := pool.Write([]byte("x")); errors.Is(err, ErrQueueFull) {
+			full = true
+			break
+		}
+	}
+	close(block)
+	pool.Close()
+
+	if !full {
+		t.Error("Write() should return ErrQueueFull when the worker queue is full")
+	}
+	if pool.Dropped() == 0 {
+		t.Error("Dropped() should count records rejected by a full queue")
+	}
+}
+
+func TestDeliveryWorkerBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{0, 0},
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{10, 5 * time.Second},
+		{100, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		w := &deliveryWorker{failures: tt.failures}
+		if got := w.backoff(); got != tt.expected {
+			t.Errorf("backoff() with %d failures = %v, want %v", tt.failures, got, tt.expected)
+		}
+	}
+}
+
+func TestSwitchWriter(t *testing.T) {
+	w := &switchWriter{}
+	if n, err := w.Write([]byte("discarded")); n != 9 || err != nil {
+		t.Errorf("Write() without destination = %d, %v; want 9, nil", n, err)
+	}
+
+	conn := &recordingConn{}
+	if previous := w.set(conn); previous != nil {
+		t.Errorf("set() returned %v, want nil", previous)
+	}
+	w.Write([]byte("kept"))
+	if previous := w.set(nil); previous != conn {
+		t.Errorf("set() returned %v, want previous destination", previous)
+	}
+	if got := conn.delivered(); len(got) != 1 || got[0] != "kept" {
+		t.Errorf("delivered %v, want [kept]", got)
+	}
+}
+
+func TestInitialize_DeliveryWorkers(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	receiver, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen on udp: %v", err)
+	}
+	defer receiver.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "pool-type"
+	cfg.LogHost = "127.0.0.1"
+	cfg.LogPort = receiver.LocalAddr().(*net.UDPAddr).Port
+	cfg.DeliveryWorkers = 2
+
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+	if _, ok := forwarder.w.(*deliveryPool); !ok {
+		t.Fatalf("forwarder = %T, want *deliveryPool", forwarder.w)
+	}
+
+	slog.Info("pooled record")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() returned unexpected error: %v", err)
+	}
+
+	buf := make([]byte, 65535)
+	receiver.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := receiver.Read(buf)
+	if err != nil {
+		t.Fatalf("no record delivered before Shutdown returned: %v", err)
+	}
+	if !containsAll(string(buf[:n]), `"message":"pooled record"`, `"type":"pool-type"`) {
+		t.Errorf("delivered record = %q", buf[:n])
+	}
+}
+
+func TestShutdown_Timeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	forwarder.set(closerFunc(func() error { <-block; return nil }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() = %v, want deadline exceeded", err)
+	}
+}
+
+// closerFunc is a destination whose Close runs a function
+type closerFunc func() error
+
+func (f closerFunc) Write(p []byte) (int, error) { return len(p), nil }
+func (f closerFunc) Close() error                { return f() }
+
+func TestKeyPartition(t *testing.T) {
+	partition := keyPartition("context.request_id")
+
+	a1 := partition([]byte(`{"message":"one","context":{"request_id":"a"}}`))
+	a2 := partition([]byte(`{"message":"two","context":{"request_id":"a"}}`))
+	b := partition([]byte(`{"message":"three","context":{"request_id":"b"}}`))
+	none1 := partition([]byte(`{"message":"four"}`))
+	none2 := partition([]byte(`not json`))
+
+	if a1 != a2 {
+		t.Errorf("records with the same key got partitions %d and %d", a1, a2)
+	}
+	if a1 == b {
+		t.Logf("keys a and b hash to the same partition %d", a1)
+	}
+	if none1 != none2 {
+		t.Errorf("records without the key got partitions %d and %d, want a shared one", none1, none2)
+	}
+	if a1 < 0 || b < 0 || none1 < 0 {
+		t.Error("partitions must not be negative")
+	}
+}
+
+func TestNewOrderedPool(t *testing.T) {
+	dial := func() (io.WriteCloser, error) { return &recordingConn{}, nil }
+
+	tests := []struct {
+		mode    string
+		key     string
+		workers int
+	}{
+		{OrderingStrict, "", 1},
+		{OrderingUnordered, "", 4},
+		{OrderingKeyed, "request_id", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			pool := newOrderedPool(tt.mode, tt.key, DeliveryBestEffort, 4, 10, dial)
+			defer pool.Close()
+			if len(pool.workers) != tt.workers {
+				t.Errorf("newOrderedPool(%q) started %d workers, want %d", tt.mode, len(pool.workers), tt.workers)
+			}
+		})
+	}
+}
+
+func TestDeliveryPool_KeyedOrdering(t *testing.T) {
+	conn := &recordingConn{}
+	pool := newOrderedPool(OrderingKeyed, "request_id", DeliveryBestEffort, 4, 100, func() (io.WriteCloser, error) { return conn, nil })
+
+	for i := 0; i < 40; i++ {
+		record := fmt.Sprintf(`{"request_id":"req-%d","seq":%d}`, i%3, i)
+		if _, err := pool.Write([]byte(record)); err != nil {
+			t.Fatalf("Write() returned unexpected error: %v", err)
+		}
+	}
+	pool.Close()
+
+	last := map[string]int{}
+	for _, record := range conn.delivered() {
+		var event struct {
+			RequestID string `json:"request_id"`
+			Seq       int    `json:"seq"`
+		}
+		if err := json.Unmarshal([]byte(record), &event); err != nil {
+			t.Fatalf("delivered record is not JSON: %v", err)
+		}
+		if previous, ok := last[event.RequestID]; ok && previous > event.Seq {
+			t.Fatalf("records for %s delivered out of order: %d after %d", event.RequestID, event.Seq, previous)
+		}
+		last[event.RequestID] = event.Seq
+	}
+}
+
+// partialConn records every write but reports the first fail of them as
+// failed, like a stream connection that broke after sending the record
+type partialConn struct {
+	recordingConn
+}
+
+func (c *partialConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records = append(c.records, string(p))
+	if c.fail > 0 {
+		c.fail--
+		return len(p) / 2, errors.New("connection reset")
+	}
+	return len(p), nil
+}
+
+func TestDeliveryPolicy_Retries(t *testing.T) {
+	tests := []struct {
+		policy    string
+		fail      int
+		delivered int
+		dropped   uint64
+	}{
+		{DeliveryAtMostOnce, 1, 0, 1},
+		{DeliveryBestEffort, 2, 1, 0},
+		{DeliveryBestEffort, 3, 0, 1},
+		{DeliveryAtLeastOnce, 3, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%d failures", tt.policy, tt.fail), func(t *testing.T) {
+			conn := &recordingConn{fail: tt.fail}
+			pool := newPolicyPool(tt.policy, 1, 10, func() (io.WriteCloser, error) { return conn, nil })
+
+			if _, err := pool.Write([]byte("record")); err != nil {
+				t.Fatalf("Write() returned unexpected error: %v", err)
+			}
+			pool.Close()
+
+			if got := len(conn.delivered()); got != tt.delivered {
+				t.Errorf("delivered %d records, want %d", got, tt.delivered)
+			}
+			if pool.Dropped() != tt.dropped {
+				t.Errorf("Dropped() = %d, want %d", pool.Dropped(), tt.dropped)
+			}
+		})
+	}
+}
+
+func TestDeliveryPolicy_Duplicates(t *testing.T) {
+	tests := []struct {
+		policy   string
+		received int
+	}{
+		{DeliveryAtMostOnce, 1},
+		{DeliveryAtLeastOnce, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			conn := &partialConn{recordingConn{fail: 1}}
+			pool := newPolicyPool(tt.policy, 1, 10, func() (io.WriteCloser, error) { return conn, nil })
+
+			if _, err := pool.Write([]byte("record")); err != nil {
+				t.Fatalf("Write() returned unexpected error: %v", err)
+			}
+			pool.Close()
+
+			if got := len(conn.delivered()); got != tt.received {
+				t.Errorf("endpoint received %d copies, want %d", got, tt.received)
+			}
+		})
+	}
+}
+
+func TestDeliveryPolicy_AtLeastOnceBlocksWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	conn := &recordingConn{}
+	pool := newPolicyPool(DeliveryAtLeastOnce, 1, 1, func() (io.WriteCloser, error) {
+		<-block
+		return conn, nil
+	})
+
+	const records = 5
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for i := 0; i < records; i++ {
+			if _, err := pool.Write([]byte(fmt.Sprintf("record-%d", i))); err != nil {
+				t.Errorf("Write() returned unexpected error: %v", err)
+			}
+		}
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("Write() should block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(block)
+	<-written
+	pool.Close()
+
+	if got := len(conn.delivered()); got != records {
+		t.Errorf("delivered %d records, want %d", got, records)
+	}
+	if pool.Dropped() != 0 {
+		t.Errorf("Dropped() = %d, want 0", pool.Dropped())
+	}
+}
+
+func TestDeliveryPolicy_AtLeastOnceHonorsContext(t *testing.T) {
+	block := make(chan struct{})
+	pool := newPolicyPool(DeliveryAtLeastOnce, 1, 1, func() (io.WriteCloser, error) {
+		<-block
+		return &recordingConn{}, nil
+	})
+	defer func() {
+		close(block)
+		pool.Close()
+	}()
+
+	// the worker holds one record while dialling, the queue the other
+	for i := 0; i < 2; i++ {
+		if _, err := pool.Write([]byte("queued")); err != nil {
+			t.Fatalf("Write() returned unexpected error: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := pool.writeContext(ctx, []byte("late")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("writeContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("writeContext() returned after %v, want once the context is done", elapsed)
+	}
+	if pool.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", pool.Dropped())
+	}
+}
+
+func TestDeliveryPolicy_AbortStopsRetrying(t *testing.T) {
+	pool := newPolicyPool(DeliveryAtLeastOnce, 1, 1, func() (io.WriteCloser, error) {
+		return nil, errors.New("unreachable")
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := pool.Write([]byte("stuck")); err != nil {
+			t.Fatalf("Write() returned unexpected error: %v", err)
+		}
+	}
+
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := pool.Write([]byte("waiting"))
+		blocked <- err
+	}()
+
+	select {
+	case <-blocked:
+		t.Fatal("Write() should block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// once aborted the write either fails or is queued and dropped by the
+	// worker, depending on which becomes ready first
+	pool.abort()
+	<-blocked
+
+	closed := make(chan struct{})
+	go func() {
+		pool.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close() should return once the pool is aborted")
+	}
+
+	if pool.Dropped() != 3 {
+		t.Errorf("Dropped() = %d, want 3", pool.Dropped())
+	}
+}
+
+func TestDeliveryPool_RecordAttempts(t *testing.T) {
+	conn := &recordingConn{fail: 1}
+	pool := newDeliveryPool(1, 10, func() (io.WriteCloser, error) { return conn, nil })
+	pool.recordAttempts()
+
+	before := time.Now()
+	pool.Write([]byte(`{"message":"retried"}` + "\n"))
+	pool.Write([]byte(`{"message":"first"}` + "\n"))
+	pool.Close()
+
+	got := conn.delivered()
+	if len(got) != 2 {
+		t.Fatalf("delivered %v, want two records", got)
+	}
+
+	var retried struct {
+		Message  string `json:"message"`
+		Delivery struct {
+			Attempts       int       `json:"attempts"`
+			FirstAttemptAt time.Time `json:"first_attempt_at"`
+		} `json:"delivery"`
+	}
+	if err := json.Unmarshal([]byte(got[0]), &retried); err != nil {
+		t.Fatalf("retried record %q is not JSON: %v", got[0], err)
+	}
+	if retried.Message != "retried" || retried.Delivery.Attempts != 2 {
+		t.Errorf("retried record = %q, want message retried on attempt 2", got[0])
+	}
+	if first := retried.Delivery.FirstAttemptAt; first.Before(before.Truncate(time.Millisecond)) || first.After(time.Now()) {
+		t.Errorf("first_attempt_at = %v, want the time of the first attempt", first)
+	}
+	if got[1] != `{"message":"first"}`+"\n" {
+		t.Errorf("record delivered on the first attempt = %q, want it unchanged", got[1])
+	}
+}
+
+func TestWithDeliveryFields(t *testing.T) {
+	first := time.Date(2026, 10, 15, 9, 30, 0, 12e6, time.UTC)
+	fields := `"delivery":{"attempts":3,"first_attempt_at":"2026-10-15T09:30:00.012Z"}`
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"object", `{"a":1}`, `{"a":1,` + fields + `}`},
+		{"empty object", `{}`, `{` + fields + `}`},
+		{"batch", "{\"a\":1}\n{\"b\":2}\n", "{\"a\":1," + fields + "}\n{\"b\":2," + fields + "}\n"},
+		{"text", "level=INFO msg=hello\n", "level=INFO msg=hello\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(withDeliveryFields([]byte(tt.data), 3, first)); got != tt.expected {
+				t.Errorf("withDeliveryFields(%q) = %q, want %q", tt.data, got, tt.expected)
+			}
+		})
+	}
+}
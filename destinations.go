@@ -0,0 +1,227 @@
+package logger
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// Destination is an endpoint events are forwarded to besides LogHost, such
+// as a regional syslog collector. It fails independently of the others: an
+// unreachable destination is reconnected in the background while the rest
+// keep receiving events.
+type Destination struct {
+	Name     string `json:"name"`     // identifies the destination in diagnostics
+	Host     string `json:"host"`     // the socket path with ProtocolUnix
+	Port     int    `json:"port"`     // unused with ProtocolUnix
+	Protocol string `json:"protocol"` // one of ProtocolUDP (default), ProtocolTCP, ProtocolUnix, ProtocolHTTP, ProtocolForward, ProtocolOTLP or a registered Transport
+	Format   string `json:"format"`   // one of FormatJSON (default), FormatText or FormatSyslog
+	Level    string `json:"level"`    // minimum level forwarded, empty forwards every record
+	// DeliveryPolicy is one of DeliveryBestEffort, DeliveryAtMostOnce or
+	// DeliveryAtLeastOnce, delivering through a worker of its own. Empty
+	// writes events directly and discards them while reconnecting.
+	DeliveryPolicy string         `json:"deliveryPolicy,omitempty"`
+	TLS            *TLSConfig     `json:"tls,omitempty"`
+	HTTP           *HTTPConfig    `json:"http,omitempty"`
+	Forward        *ForwardConfig `json:"forward,omitempty"`
+	OTLP           *OTLPConfig    `json:"otlp,omitempty"`
+}
+
+// endpointSettings returns the settings of the endpoint of d
+func (d Destination) endpointSettings() endpointSettings {
+	return endpointSettings{tls: d.TLS, http: d.HTTP, forward: d.Forward, otlp: d.OTLP}
+}
+
+func (d Destination) validate() error {
+	if len(d.Name) == 0 {
+		return errors.New("destinations must have a name")
+	}
+	if len(d.Host) == 0 {
+		return fmt.Errorf("destination %s: host is required", d.Name)
+	}
+
+	switch d.Protocol {
+	case "", ProtocolUDP, ProtocolTCP, ProtocolHTTP, ProtocolForward, ProtocolOTLP:
+		if d.Port <= 0 || d.Port > 65535 {
+			return fmt.Errorf("destination %s: invalid port %d", d.Name, d.Port)
+		}
+	case ProtocolUnix:
+	default:
+		if _, ok := registeredTransport(d.Protocol); !ok {
+			return fmt.Errorf("destination %s: unknown protocol %q", d.Name, d.Protocol)
+		}
+	}
+
+	switch d.Format {
+	case "", FormatJSON, FormatText, FormatSyslog:
+	default:
+		return fmt.Errorf("destination %s: unknown format %q", d.Name, d.Format)
+	}
+	// the forward and otlp protocols carry JSON events
+	if (d.Protocol == ProtocolForward || d.Protocol == ProtocolOTLP) && d.Format != "" && d.Format != FormatJSON {
+		return fmt.Errorf("destination %s: protocol %s requires format json", d.Name, d.Protocol)
+	}
+
+	if _, err := parseSinkLevel(d.Level); err != nil {
+		return fmt.Errorf("destination %s: %w", d.Name, err)
+	}
+	switch d.DeliveryPolicy {
+	case "", DeliveryBestEffort, DeliveryAtMostOnce, DeliveryAtLeastOnce:
+	default:
+		return fmt.Errorf("destination %s: unknown deliveryPolicy %q", d.Name, d.DeliveryPolicy)
+	}
+
+	if d.TLS != nil {
+		if builtinProtocols[d.Protocol] && d.Protocol != ProtocolTCP && d.Protocol != ProtocolHTTP && d.Protocol != ProtocolForward && d.Protocol != ProtocolOTLP {
+			return fmt.Errorf("destination %s: tls requires protocol tcp, http, forward, otlp or a registered transport", d.Name)
+		}
+		if err := d.TLS.validate(); err != nil {
+			return fmt.Errorf("destination %s: %w", d.Name, err)
+		}
+	}
+	if d.HTTP != nil {
+		if d.Protocol != ProtocolHTTP {
+			return fmt.Errorf("destination %s: http settings require protocol http", d.Name)
+		}
+		if err := d.HTTP.validate(); err != nil {
+			return fmt.Errorf("destination %s: %w", d.Name, err)
+		}
+	}
+	if d.Forward != nil {
+		if d.Protocol != ProtocolForward {
+			return fmt.Errorf("destination %s: forward settings require protocol forward", d.Name)
+		}
+		if err := d.Forward.validate(); err != nil {
+			return fmt.Errorf("destination %s: %w", d.Name, err)
+		}
+	}
+	if d.OTLP != nil {
+		if d.Protocol != ProtocolOTLP {
+			return fmt.Errorf("destination %s: otlp settings require protocol otlp", d.Name)
+		}
+		if err := d.OTLP.validate(); err != nil {
+			return fmt.Errorf("destination %s: %w", d.Name, err)
+		}
+	}
+	return nil
+}
+
+var (
+	destinationsMu sync.Mutex
+	// destinationWriters are the connections of the destinations, closed by
+	// Shutdown
+	destinationWriters []*destinationWriter
+)
+
+// newDestinationSink returns the sink forwarding to d, connected within ctx
+// or else in the background. A destination with a delivery policy is
+// delivered by a worker dialling its own connection instead.
+func newDestinationSink(ctx context.Context, d Destination) sink {
+	timeout, lookup, size := writeTimeout, resolver, queueSize
+	w := &destinationWriter{
+		name: d.Name,
+		dial: func(ctx context.Context) (net.Conn, error) {
+			conn, err := dialEndpointContext(ctx, d.Protocol, d.Host, d.Port, timeout, d.endpointSettings(), lookup)
+			recordError(d.Name, OpDial, err)
+			return conn, err
+		},
+	}
+
+	if len(d.DeliveryPolicy) > 0 {
+		w.pool = newPolicyPool(d.DeliveryPolicy, 1, size, func() (io.WriteCloser, error) {
+			return w.dial(context.Background())
+		})
+		w.pool.named(d.Name)
+		w.target.set(w.pool)
+	} else if conn, err := w.dial(ctx); err != nil {
+		w.redial("Failed to connect to log destination, reconnecting in the background", err)
+	} else {
+		w.target.set(&synchronizedUDPWriter{conn: conn})
+	}
+
+	destinationsMu.Lock()
+	destinationWriters = append(destinationWriters, w)
+	destinationsMu.Unlock()
+
+	// the level was validated when the config was applied
+	level, _ := parseSinkLevel(d.Level)
+	format := cmp.Or(d.Format, FormatJSON)
+	var out io.Writer = w
+	if format == FormatSyslog {
+		out = syslogWriter(w, d.Protocol)
+	}
+	return sink{name: d.Name, w: out, format: format, level: level, maxBytes: forwardMaxBytes(d.Protocol)}
+}
+
+// destinationWriter writes to the connection of a destination, discarding
+// writes while it reconnects after a failure, or queues them for the
+// delivery worker of its pool
+type destinationWriter struct {
+	name   string
+	dial   func(ctx context.Context) (net.Conn, error)
+	target switchWriter
+	// pool delivers the events under the delivery policy of the
+	// destination, nil without one
+	pool *deliveryPool
+	// reconnecting is set while the connection is being replaced
+	reconnecting atomic.Bool
+	// closed is set by Shutdown, after which it never reconnects
+	closed atomic.Bool
+}
+
+func (w *destinationWriter) Write(p []byte) (int, error) {
+	return w.writeContext(context.Background(), p)
+}
+
+func (w *destinationWriter) writeContext(ctx context.Context, p []byte) (int, error) {
+	n, err := w.target.writeContext(ctx, p)
+	// the worker of a pool reconnects by itself
+	if err != nil && ctx.Err() == nil && w.pool == nil {
+		w.redial("Failed to write to log destination, reconnecting", err)
+	}
+	return n, err
+}
+
+// redial reports the failure with msg, detaches the connection and
+// reconnects in the background, unless that is already under way
+func (w *destinationWriter) redial(msg string, err error) {
+	if w.closed.Load() || !w.reconnecting.CompareAndSwap(false, true) {
+		return
+	}
+	diag().Warn(msg, "destination", w.name, "error", err)
+	closeWriter(w.target.set(nil))
+
+	goBackground(func(ctx context.Context) {
+		defer w.reconnecting.Store(false)
+		reconnect(ctx, &w.target, func() (net.Conn, error) { return w.dial(ctx) }, func(conn net.Conn) io.Writer {
+			return &synchronizedUDPWriter{conn: conn}
+		})
+	})
+}
+
+// closeDestinations closes the connections of every destination and returns
+// the delivery pools of those with a delivery policy, detached but still
+// delivering their queues, for Shutdown to close within its deadline
+func closeDestinations() []*deliveryPool {
+	destinationsMu.Lock()
+	writers := destinationWriters
+	destinationWriters = nil
+	destinationsMu.Unlock()
+
+	var pools []*deliveryPool
+	for _, w := range writers {
+		w.closed.Store(true)
+		if w.pool != nil {
+			w.target.set(nil)
+			pools = append(pools, w.pool)
+			continue
+		}
+		closeWriter(w.target.set(nil))
+	}
+	return pools
+}
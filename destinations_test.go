@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+func TestInitialize_Destinations(t *testing.T) {
+	preserveConfig(t)
+	fastReconnect(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	primary, err := loggertest.Listen(loggertest.UDP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer primary.Close()
+	audit, err := loggertest.Listen(loggertest.TCP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer audit.Close()
+
+	// reserve a port nothing listens on yet
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "destinations-type"
+	cfg.LogHost = primary.Host()
+	cfg.LogPort = primary.Port()
+	cfg.Destinations = []Destination{
+		{Name: "audit", Host: audit.Host(), Port: audit.Port(), Protocol: ProtocolTCP, Level: "warn"},
+		{Name: "regional", Host: "127.0.0.1", Port: downPort, Protocol: ProtocolTCP},
+	}
+	diagnosed := &capturedDiagnostics{}
+	cfg.Diagnostics = slog.NewJSONHandler(diagnosed, nil)
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+	if !diagnosed.wait("Failed to connect to log destination", time.Second) {
+		t.Error("unreachable destination was not reported")
+	}
+
+	slog.Info("routine")
+	slog.Warn("suspicious")
+
+	if !primary.Wait(2, time.Second) {
+		t.Fatalf("primary received %d records, want 2", primary.Count())
+	}
+	if !audit.Wait(1, time.Second) {
+		t.Fatalf("audit received %d records, want 1", audit.Count())
+	}
+	time.Sleep(50 * time.Millisecond)
+	if events := audit.Events(); len(events) != 1 || events[0]["message"] != "suspicious" || events[0]["type"] != "destinations-type" {
+		t.Errorf("audit received %v, want the warning in the Lagoon format", events)
+	}
+
+	regional, err := loggertest.ListenAddr(loggertest.TCP, net.JoinHostPort("127.0.0.1", strconv.Itoa(downPort)))
+	if err != nil {
+		t.Skipf("port %d was taken before the destination came up: %v", downPort, err)
+	}
+	defer regional.Close()
+	if !diagnosed.wait("Connected to log endpoint", 2*time.Second) {
+		t.Fatal("destination did not reconnect once it came up")
+	}
+	slog.Info("after reconnect")
+	if !regional.Wait(1, time.Second) {
+		t.Fatalf("regional received %d records, want 1", regional.Count())
+	}
+}
+
+func TestInitialize_DestinationPolicy(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	primary, err := loggertest.Listen(loggertest.UDP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer primary.Close()
+
+	// reserve a port nothing listens on yet
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "destinations-type"
+	cfg.LogHost = primary.Host()
+	cfg.LogPort = primary.Port()
+	cfg.Destinations = []Destination{
+		{Name: "audit", Host: "127.0.0.1", Port: downPort, Protocol: ProtocolTCP, DeliveryPolicy: DeliveryAtLeastOnce},
+	}
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+
+	// the event is queued for the worker rather than discarded while the
+	// destination is down
+	slog.Info("logged while down")
+	time.Sleep(150 * time.Millisecond)
+
+	audit, err := loggertest.ListenAddr(loggertest.TCP, net.JoinHostPort("127.0.0.1", strconv.Itoa(downPort)))
+	if err != nil {
+		t.Skipf("port %d was taken before the destination came up: %v", downPort, err)
+	}
+	defer audit.Close()
+	if !audit.Wait(1, 5*time.Second) {
+		t.Fatalf("audit received %d records, want the queued one", audit.Count())
+	}
+
+	slog.Info("logged before shutdown")
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() returned unexpected error: %v", err)
+	}
+	if !audit.Wait(2, time.Second) {
+		t.Fatalf("audit received %d records, want the queue delivered by Shutdown", audit.Count())
+	}
+	if events := audit.Events(); events[0]["message"] != "logged while down" || events[1]["message"] != "logged before shutdown" {
+		t.Errorf("audit received %v, want both events in order", events)
+	}
+}
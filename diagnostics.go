@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+// diagnostics logs the forwarder's own warnings, such as a failed
+// connection, apart from the records of the application: the default slog
+// logger may not be set up yet, or forward to the failing endpoint itself
+var diagnostics atomic.Pointer[slog.Logger]
+
+// stderrDiagnostics is the diagnostics logger unless Config.Diagnostics is set
+var stderrDiagnostics = slog.New(slog.NewTextHandler(diagnosticsOutput{}, nil)).With("logger", "lagoon-log-forwarder")
+
+// diag returns the logger of the forwarder's diagnostics
+func diag() *slog.Logger {
+	if l := diagnostics.Load(); l != nil {
+		return l
+	}
+	return stderrDiagnostics
+}
+
+// setDiagnostics sends the diagnostics to h, or to stderr when h is nil
+func setDiagnostics(h slog.Handler) {
+	if h == nil {
+		diagnostics.Store(nil)
+		return
+	}
+	diagnostics.Store(slog.New(h))
+}
+
+// diagnosticsHandler returns the handler set with setDiagnostics
+func diagnosticsHandler() slog.Handler {
+	if l := diagnostics.Load(); l != nil {
+		return l.Handler()
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// capturedDiagnostics collects the diagnostics logged during a test
+type capturedDiagnostics struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (c *capturedDiagnostics) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(p)
+}
+
+func (c *capturedDiagnostics) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
+
+// wait reports whether s was logged before the timeout
+func (c *capturedDiagnostics) wait(s string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for !strings.Contains(c.String(), s) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+// captureDiagnostics logs the diagnostics as JSON to the returned capture
+// until the test ends
+func captureDiagnostics(t *testing.T) *capturedDiagnostics {
+	t.Helper()
+	previous := diagnosticsHandler()
+	t.Cleanup(func() { setDiagnostics(previous) })
+
+	captured := &capturedDiagnostics{}
+	setDiagnostics(slog.NewJSONHandler(captured, nil))
+	return captured
+}
+
+func TestDiagnostics_SeparateFromDefault(t *testing.T) {
+	preserveConfig(t)
+
+	var application bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&application, nil)))
+	defer slog.SetDefault(previous)
+
+	var diagnosed bytes.Buffer
+	cfg := NewConfig()
+	cfg.LogType = "diagnostics-type"
+	cfg.LogHost = ""
+	cfg.Diagnostics = slog.NewJSONHandler(&diagnosed, nil)
+	if _, err := NewWriterHandler(cfg, &bytes.Buffer{}); err != nil {
+		t.Fatalf("NewWriterHandler() returned unexpected error: %v", err)
+	}
+
+	if !strings.Contains(diagnosed.String(), "log.host is not supplied") {
+		t.Errorf("diagnostics = %q, want the log host warning", diagnosed.String())
+	}
+	if application.Len() > 0 {
+		t.Errorf("default logger received %q, want no diagnostics", application.String())
+	}
+	if current().Diagnostics == nil {
+		t.Error("current() should return the applied Diagnostics")
+	}
+
+	cfg.Diagnostics = nil
+	if err := config(cfg); err != nil {
+		t.Fatalf("config() returned unexpected error: %v", err)
+	}
+	if diag() != stderrDiagnostics {
+		t.Error("diag() should write to stderr without Diagnostics")
+	}
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Sinks whose egress is accounted
+const (
+	SinkStdout    = "stdout"
+	SinkForwarder = "forwarder"
+)
+
+// EgressStats is the traffic of a sink during one accounting window
+type EgressStats struct {
+	Sink           string
+	Start          time.Time
+	Bytes          int64 // bytes written to the sink
+	Records        int64
+	DroppedBytes   int64 // bytes not written because the budget was exceeded
+	DroppedRecords int64
+}
+
+var (
+	egressMu     sync.Mutex
+	egressMeters []*egressMeter
+)
+
+// Egress returns the traffic of every sink during the last complete
+// accounting window
+func Egress() []EgressStats {
+	egressMu.Lock()
+	meters := egressMeters
+	egressMu.Unlock()
+
+	stats := make([]EgressStats, 0, len(meters))
+	for _, m := range meters {
+		m.mu.Lock()
+		stats = append(stats, m.last)
+		m.mu.Unlock()
+	}
+	return stats
+}
+
+// egressMeter counts the bytes written to a sink per window. Once a window's
+// budget is exceeded only one in sampleRate records is written until the
+// window ends, the others are dropped.
+type egressMeter struct {
+	w          io.Writer
+	budget     int64
+	sampleRate int
+
+	mu      sync.Mutex
+	current EgressStats
+	last    EgressStats
+	over    int
+}
+
+func newEgressMeter(sink string, w io.Writer, budget int64, sampleRate int) *egressMeter {
+	return &egressMeter{
+		w:          w,
+		budget:     budget,
+		sampleRate: sampleRate,
+		current:    EgressStats{Sink: sink, Start: time.Now()},
+	}
+}
+
+func (m *egressMeter) Write(p []byte) (int, error) {
+	return m.writeContext(context.Background(), p)
+}
+
+func (m *egressMeter) writeContext(ctx context.Context, p []byte) (int, error) {
+	m.mu.Lock()
+	if m.budget > 0 && m.current.Bytes+int64(len(p)) > m.budget {
+		m.over++
+		if m.sampleRate == 0 || m.over%m.sampleRate != 0 {
+			m.current.DroppedBytes += int64(len(p))
+			m.current.DroppedRecords++
+			m.mu.Unlock()
+			countDropped(m.current.Sink, 1)
+			return len(p), nil
+		}
+	}
+	m.mu.Unlock()
+
+	n, err := writeContext(ctx, m.w, p)
+
+	// records discarded while the forwarder is disconnected never leave
+	// the process
+	if d, ok := m.w.(interface{ discarding() bool }); ok && d.discarding() {
+		return n, err
+	}
+
+	m.mu.Lock()
+	m.current.Bytes += int64(n)
+	m.current.Records++
+	sink := m.current.Sink
+	m.mu.Unlock()
+	if err == nil {
+		countWrite(sink, n)
+	}
+	return n, err
+}
+
+// roll starts a new window at now and returns the one that ended
+func (m *egressMeter) roll(now time.Time) EgressStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.last = m.current
+	m.current = EgressStats{Sink: m.last.Sink, Start: now}
+	m.over = 0
+	return m.last
+}
+
+// meterEgress rolls the windows of meters every window until ctx is done,
+// logging a summary of the records a sink dropped over its budget
+func meterEgress(ctx context.Context, window time.Duration, meters ...*egressMeter) {
+	egressMu.Lock()
+	egressMeters = meters
+	egressMu.Unlock()
+
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, m := range meters {
+				if stats := m.roll(now); stats.DroppedRecords > 0 {
+					diag().Warn("Egress budget exceeded, records were sampled",
+						slog.Group("egress",
+							slog.String("sink", stats.Sink),
+							slog.Int64("budget_bytes", m.budget),
+							slog.Int64("bytes", stats.Bytes),
+							slog.Int64("records", stats.Records),
+							slog.Int64("dropped_bytes", stats.DroppedBytes),
+							slog.Int64("dropped_records", stats.DroppedRecords),
+						),
+					)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEgressMeter(t *testing.T) {
+	var buf bytes.Buffer
+	m := newEgressMeter(SinkForwarder, &buf, 0, 0)
+
+	for i := 0; i < 3; i++ {
+		if _, err := m.Write([]byte("0123456789")); err != nil {
+			t.Fatalf("Write() returned unexpected error: %v", err)
+		}
+	}
+
+	stats := m.roll(time.Now())
+	if stats.Bytes != 30 || stats.Records != 3 {
+		t.Errorf("roll() = %d bytes in %d records, want 30 bytes in 3 records", stats.Bytes, stats.Records)
+	}
+	if stats.DroppedRecords != 0 {
+		t.Errorf("DroppedRecords = %d without a budget, want 0", stats.DroppedRecords)
+	}
+	if next := m.roll(time.Now()); next.Bytes != 0 {
+		t.Errorf("next window Bytes = %d, want 0", next.Bytes)
+	}
+}
+
+func TestEgressMeter_Budget(t *testing.T) {
+	tests := []struct {
+		name        string
+		sampleRate  int
+		wantRecords int64
+	}{
+		{"drop all", 0, 5},
+		{"sample one in four", 4, 5 + 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			m := newEgressMeter(SinkForwarder, &buf, 50, tt.sampleRate)
+
+			// the first five records fit the budget
+			for i := 0; i < 21; i++ {
+				if n, err := m.Write([]byte("0123456789")); n != 10 || err != nil {
+					t.Fatalf("Write() = %d, %v, want 10, nil", n, err)
+				}
+			}
+
+			stats := m.roll(time.Now())
+			if stats.Records != tt.wantRecords {
+				t.Errorf("Records = %d, want %d", stats.Records, tt.wantRecords)
+			}
+			if got := int64(buf.Len()); got != stats.Bytes {
+				t.Errorf("Bytes = %d, want the %d bytes written", stats.Bytes, got)
+			}
+			if stats.DroppedRecords != 21-tt.wantRecords || stats.DroppedBytes != 10*stats.DroppedRecords {
+				t.Errorf("dropped %d bytes in %d records, want %d records", stats.DroppedBytes, stats.DroppedRecords, 21-tt.wantRecords)
+			}
+
+			// a new window starts with the whole budget
+			buf.Reset()
+			m.Write([]byte("0123456789"))
+			if buf.Len() != 10 {
+				t.Error("record dropped at the start of a new window")
+			}
+		})
+	}
+}
+
+func TestEgressMeter_Disconnected(t *testing.T) {
+	m := newEgressMeter(SinkForwarder, &switchWriter{}, 0, 0)
+	m.Write([]byte("0123456789"))
+
+	if stats := m.roll(time.Now()); stats.Bytes != 0 {
+		t.Errorf("Bytes = %d while disconnected, want 0", stats.Bytes)
+	}
+}
+
+func TestMeterEgress(t *testing.T) {
+	logged := captureDiagnostics(t)
+
+	var buf bytes.Buffer
+	stdout := newEgressMeter(SinkStdout, &bytes.Buffer{}, 0, 0)
+	forwarded := newEgressMeter(SinkForwarder, &buf, 10, 0)
+	forwarded.Write([]byte("0123456789"))
+	forwarded.Write([]byte("0123456789"))
+	stdout.Write([]byte("0123456789"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		meterEgress(ctx, 50*time.Millisecond, stdout, forwarded)
+		close(done)
+	}()
+	// stop between the first and second window ends
+	time.Sleep(75 * time.Millisecond)
+	cancel()
+	<-done
+
+	stats := Egress()
+	if len(stats) != 2 || stats[0].Sink != SinkStdout || stats[1].Sink != SinkForwarder {
+		t.Fatalf("Egress() = %+v, want stdout and forwarder", stats)
+	}
+	if stats[0].Bytes != 10 || stats[1].Bytes != 10 || stats[1].DroppedRecords != 1 {
+		t.Errorf("Egress() = %+v, want 10 bytes per sink and 1 forwarded record dropped", stats)
+	}
+
+	line, _, _ := strings.Cut(logged.String(), "\n")
+	var summary struct {
+		Msg    string
+		Egress map[string]any
+	}
+	if err := json.Unmarshal([]byte(line), &summary); err != nil {
+		t.Fatalf("no summary was logged: %v", err)
+	}
+	if summary.Egress["sink"] != SinkForwarder || summary.Egress["dropped_records"] != float64(1) {
+		t.Errorf("summary = %+v, want 1 forwarder record dropped", summary)
+	}
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// contextWriter is implemented by the writers of the emit path that may
+// block, so a logging call gives up once its context is done
+type contextWriter interface {
+	writeContext(ctx context.Context, p []byte) (int, error)
+}
+
+// writeContext writes p to w, bounded by ctx when w supports it
+func writeContext(ctx context.Context, w io.Writer, p []byte) (int, error) {
+	if cw, ok := w.(contextWriter); ok {
+		return cw.writeContext(ctx, p)
+	}
+	return w.Write(p)
+}
+
+// serialWriter serializes writes to a writer that may not be safe for
+// concurrent use, such as one passed to NewWriterHandler
+type serialWriter struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func (s *serialWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// Formats records are encoded in for a sink
+const (
+	// FormatJSON is the Lagoon JSON event
+	FormatJSON = "json"
+	// FormatText is slog's key=value text, with the Lagoon fields
+	FormatText = "text"
+	// FormatPretty is a colourised line for reading in a terminal, without
+	// the static Lagoon fields
+	FormatPretty = "pretty"
+)
+
+// encoder renders a record in the format of its handler into buf, so the
+// encoded record is written outside of any lock
+type encoder struct {
+	format  string
+	buf     bytes.Buffer
+	handler slog.Handler
+}
+
+// maxPooledBuffer is the largest encoder buffer kept for reuse, so a single
+// huge record doesn't pin its memory
+const maxPooledBuffer = 64 << 10
+
+// newEncoders returns a pool of encoders formatting records in format with
+// opts and the static attrs. The JSON, text and syslog encoders preformat the
+// static attrs once, when the pool creates them, by adding them to their slog
+// handler with With, so a record only encodes its own attributes. The Monolog
+// layout merges them into the context and extra of every record instead, and
+// the pretty format leaves them out.
+func newEncoders(format string, opts *slog.HandlerOptions, attrs []any) *sync.Pool {
+	// the frame and compat mode are taken from the config the handler is
+	// created with
+	var frame syslogFrame
+	if format == FormatSyslog {
+		frame = newSyslogFrame()
+	}
+	monolog := format == FormatJSON && compatMode == CompatMonologLagoon
+
+	pool := &sync.Pool{}
+	pool.New = func() any {
+		e := &encoder{format: format}
+		if monolog {
+			e.handler = newMonologHandler(&e.buf, opts, attrs)
+			return e
+		}
+		switch format {
+		case FormatText:
+			e.handler = slog.New(slog.NewTextHandler(&e.buf, opts)).With(attrs...).Handler()
+		case FormatPretty:
+			e.handler = newPrettyHandler(&e.buf, opts)
+		case FormatSyslog:
+			e.handler = newSyslogHandler(&e.buf, opts, attrs, frame)
+		default:
+			e.handler = slog.New(slog.NewJSONHandler(&e.buf, opts)).With(attrs...).Handler()
+		}
+		return e
+	}
+	return pool
+}
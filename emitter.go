@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"path"
+	"runtime/debug"
+	"sync"
+)
+
+// schemaKey is the attribute identifying the module version that wrote an
+// event
+const schemaKey = "schema"
+
+// modulePath is the path of the module in the build info of binaries
+const modulePath = "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+
+// emitterSchema returns the schema field of the events, the module name and
+// the version it was built at, "devel" when built from a work tree
+var emitterSchema = sync.OnceValue(func() string {
+	version := ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = moduleVersion(info)
+	}
+	if len(version) == 0 || version == "(devel)" {
+		version = "devel"
+	}
+	return path.Base(modulePath) + "@" + version
+})
+
+// moduleVersion returns the version of the module in info, the main module
+// for the binaries of this repository or a dependency otherwise
+func moduleVersion(info *debug.BuildInfo) string {
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+		if dep.Replace != nil && len(dep.Replace.Version) > 0 {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+	return ""
+}
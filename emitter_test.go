@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestModuleVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		info debug.BuildInfo
+		want string
+	}{
+		{"main module", debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "v1.4.0"}}, "v1.4.0"},
+		{"dependency", debug.BuildInfo{
+			Main: debug.Module{Path: "example.com/shop"},
+			Deps: []*debug.Module{{Path: "example.com/other", Version: "v0.1.0"}, {Path: modulePath, Version: "v1.3.2"}},
+		}, "v1.3.2"},
+		{"replaced", debug.BuildInfo{
+			Main: debug.Module{Path: "example.com/shop"},
+			Deps: []*debug.Module{{Path: modulePath, Version: "v1.3.2", Replace: &debug.Module{Path: "example.com/fork", Version: "v1.3.3-fork"}}},
+		}, "v1.3.3-fork"},
+		{"missing", debug.BuildInfo{Main: debug.Module{Path: "example.com/shop"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := moduleVersion(&tt.info); got != tt.want {
+				t.Errorf("moduleVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaField(t *testing.T) {
+	preserveConfig(t)
+
+	schema := func() (string, bool) {
+		for _, a := range DefaultAttrs() {
+			if a.Key == schemaKey {
+				return a.Value.String(), true
+			}
+		}
+		return "", false
+	}
+
+	schemaField = false
+	if value, ok := schema(); ok {
+		t.Errorf("schema = %q without SchemaField, want none", value)
+	}
+
+	schemaField = true
+	value, ok := schema()
+	if !ok {
+		t.Fatal("no schema with SchemaField")
+	}
+	// tests run in the work tree of the module
+	if !strings.HasPrefix(value, "go-lagoon-log-forwarder@") || value != emitterSchema() {
+		t.Errorf("schema = %q, want the module and its version", value)
+	}
+}
@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envVar sets a Config field from the environment variable name
+type envVar struct {
+	name string
+	set  func(cfg *Config, value string) error
+}
+
+// envVars are the environment variables read by FromEnv
+var envVars = []envVar{
+	{"LOGGER_HOST", envString(func(c *Config) *string { return &c.LogHost })},
+	{"LOGGER_PORT", envInt(func(c *Config) *int { return &c.LogPort })},
+	{"LOGGER_FALLBACK_HOSTS", envStrings(func(c *Config) *[]string { return &c.FallbackHosts })},
+	{"LOGGER_FAILBACK_INTERVAL", envDuration(func(c *Config) *time.Duration { return &c.FailbackInterval })},
+	{"LOGGER_SENDER_ID", envString(func(c *Config) *string { return &c.SenderID })},
+	{"LOGGER_SENDER_ID_FILE", envString(func(c *Config) *string { return &c.SenderIDFile })},
+	{"LOGGER_SCHEMA_FIELD", envBool(func(c *Config) *bool { return &c.SchemaField })},
+	{"LOGGER_PROTOCOL", envString(func(c *Config) *string { return &c.Protocol })},
+	{"LOGGER_TYPE", envString(func(c *Config) *string { return &c.LogType })},
+	{"LOGGER_CHANNEL", envString(func(c *Config) *string { return &c.LogChannel })},
+	{"LOGGER_APP_NAME", envString(func(c *Config) *string { return &c.ApplicationName })},
+	{"LOGGER_LEVEL", envString(func(c *Config) *string { return &c.Level })},
+	{"LOGGER_STDOUT_LEVEL", envString(func(c *Config) *string { return &c.StdoutLevel })},
+	{"LOGGER_FORWARD_LEVEL", envString(func(c *Config) *string { return &c.ForwardLevel })},
+	{"LOGGER_STDOUT_FORMAT", envString(func(c *Config) *string { return &c.StdoutFormat })},
+	{"LOGGER_ADD_SOURCE", envBool(func(c *Config) *bool { return &c.AddSource })},
+	{"LOGGER_SOURCE_FORMAT", envString(func(c *Config) *string { return &c.SourceFormat })},
+	{"LOGGER_MESSAGE_VERSION", envInt(func(c *Config) *int { return &c.MessageVersion })},
+	{"LOGGER_STRICT_SCHEMA", envBool(func(c *Config) *bool { return &c.StrictSchema })},
+	{"LOGGER_PROFILING", envBool(func(c *Config) *bool { return &c.Profiling })},
+	{"LOGGER_COMPAT_MODE", envString(func(c *Config) *string { return &c.CompatMode })},
+	{"LOGGER_CONTROL_CHARS", envString(func(c *Config) *string { return &c.ControlChars })},
+	{"LOGGER_WRITE_TIMEOUT", envDuration(func(c *Config) *time.Duration { return &c.WriteTimeout })},
+	{"LOGGER_DELIVERY_WORKERS", envInt(func(c *Config) *int { return &c.DeliveryWorkers })},
+	{"LOGGER_QUEUE_SIZE", envInt(func(c *Config) *int { return &c.QueueSize })},
+	{"LOGGER_BATCH_SIZE", envInt(func(c *Config) *int { return &c.BatchSize })},
+	{"LOGGER_BATCH_INTERVAL", envDuration(func(c *Config) *time.Duration { return &c.BatchInterval })},
+	{"LOGGER_BATCH_LATENCY", envDuration(func(c *Config) *time.Duration { return &c.BatchLatency })},
+	{"LOGGER_DELIVERY_POLICY", envString(func(c *Config) *string { return &c.DeliveryPolicy })},
+	{"LOGGER_RECORD_ATTEMPTS", envBool(func(c *Config) *bool { return &c.RecordAttempts })},
+	{"LOGGER_SPOOL_DIR", envString(func(c *Config) *string { return &c.SpoolDir })},
+	{"LOGGER_SPOOL_MAX_BYTES", envInt64(func(c *Config) *int64 { return &c.SpoolMaxBytes })},
+	{"LOGGER_REPLAY_WINDOW", envDuration(func(c *Config) *time.Duration { return &c.ReplayWindow })},
+	{"LOGGER_STATS_FILE", envString(func(c *Config) *string { return &c.StatsFile })},
+	{"LOGGER_STATS_INTERVAL", envDuration(func(c *Config) *time.Duration { return &c.StatsInterval })},
+	{"LOGGER_STATS_EVENT_INTERVAL", envDuration(func(c *Config) *time.Duration { return &c.StatsEventInterval })},
+	{"LOGGER_STATS_EVENT_CHANNEL", envString(func(c *Config) *string { return &c.StatsEventChannel })},
+	{"LOGGER_CAPTURE_STDERR", envBool(func(c *Config) *bool { return &c.CaptureStderr })},
+	{"LOGGER_STD_LOG_LEVEL", envString(func(c *Config) *string { return &c.StdLogLevel })},
+	{"LOGGER_STRICT_RECONFIGURE", envBool(func(c *Config) *bool { return &c.StrictReconfigure })},
+	{"LOGGER_EXIT_TIMEOUT", envDuration(func(c *Config) *time.Duration { return &c.ExitTimeout })},
+	{"LOGGER_DEBUG_SIGNAL", envString(func(c *Config) *string { return &c.DebugSignal })},
+	{"LOGGER_REMOTE_CONFIG_URL", envString(func(c *Config) *string { return &c.RemoteConfigURL })},
+	{"LOGGER_REMOTE_CONFIG_KEY", envString(func(c *Config) *string { return &c.RemoteConfigKey })},
+	{traceEnv, envBool(func(c *Config) *bool { return &c.Trace })},
+}
+
+// NewConfigFromEnv returns the NewConfig defaults with the LOGGER_*
+// environment variables applied, see Config.FromEnv
+func NewConfigFromEnv() (Config, error) {
+	return NewConfig().FromEnv()
+}
+
+// FromEnv returns c with the LOGGER_* environment variables that are set
+// applied on top, e.g. LOGGER_HOST, LOGGER_PORT or LOGGER_TYPE. Without
+// LOGGER_TYPE, an empty log type follows Lagoon's <project>-<environment>
+// namespace naming when the Lagoon variables are set.
+func (c Config) FromEnv() (Config, error) {
+	var applied []string
+	for _, v := range envVars {
+		value, ok := os.LookupEnv(v.name)
+		if !ok {
+			continue
+		}
+		if err := v.set(&c, value); err != nil {
+			return c, fmt.Errorf("%s: %w", v.name, err)
+		}
+		applied = append(applied, v.name)
+	}
+
+	lagoon := len(c.LogType) == 0
+	if lagoon {
+		c.LogType = lagoonLogType()
+	}
+	traceEnvironment(c, applied, lagoon)
+	return c, nil
+}
+
+func envString(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func envStrings(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); len(v) > 0 {
+				values = append(values, v)
+			}
+		}
+		*field(c) = values
+		return nil
+	}
+}
+
+func envInt(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func envInt64(field func(*Config) *int64) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func envBool(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func envDuration(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*field(c) = d
+		return nil
+	}
+}
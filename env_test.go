@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewConfigFromEnv(t *testing.T) {
+	t.Setenv("LOGGER_HOST", "logs.example.com")
+	t.Setenv("LOGGER_PORT", "5141")
+	t.Setenv("LOGGER_PROTOCOL", ProtocolTCP)
+	t.Setenv("LOGGER_TYPE", "shop-main")
+	t.Setenv("LOGGER_CHANNEL", "Channel")
+	t.Setenv("LOGGER_APP_NAME", "api")
+	t.Setenv("LOGGER_ADD_SOURCE", "true")
+	t.Setenv("LOGGER_WRITE_TIMEOUT", "5s")
+	t.Setenv("LOGGER_FALLBACK_HOSTS", "logs-b.example.com, logs-c.example.com:5142")
+
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("NewConfigFromEnv() returned unexpected error: %v", err)
+	}
+
+	want := NewConfig()
+	want.LogHost = "logs.example.com"
+	want.LogPort = 5141
+	want.Protocol = ProtocolTCP
+	want.LogType = "shop-main"
+	want.LogChannel = "Channel"
+	want.ApplicationName = "api"
+	want.AddSource = true
+	want.WriteTimeout = 5 * time.Second
+	want.FallbackHosts = []string{"logs-b.example.com", "logs-c.example.com:5142"}
+
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("NewConfigFromEnv() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestConfigFromEnv_KeepsUnset(t *testing.T) {
+	t.Setenv("LOGGER_PORT", "5141")
+
+	base := NewConfig()
+	base.LogHost = "configured"
+	base.LogType = "configured-type"
+	cfg, err := base.FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv() returned unexpected error: %v", err)
+	}
+	if cfg.LogHost != "configured" || cfg.LogType != "configured-type" {
+		t.Errorf("FromEnv() = %+v, want unset variables to keep %+v", cfg, base)
+	}
+	if cfg.LogPort != 5141 {
+		t.Errorf("LogPort = %d, want 5141", cfg.LogPort)
+	}
+}
+
+func TestConfigFromEnv_LagoonLogType(t *testing.T) {
+	t.Setenv("LAGOON_PROJECT", "shop")
+	t.Setenv("LAGOON_ENVIRONMENT", "main")
+
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("NewConfigFromEnv() returned unexpected error: %v", err)
+	}
+	if cfg.LogType != "shop-main" {
+		t.Errorf("LogType = %q, want %q", cfg.LogType, "shop-main")
+	}
+
+	t.Setenv("LOGGER_TYPE", "explicit")
+	if cfg, _ = NewConfigFromEnv(); cfg.LogType != "explicit" {
+		t.Errorf("LogType = %q, want LOGGER_TYPE to take precedence", cfg.LogType)
+	}
+}
+
+func TestConfigFromEnv_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"LOGGER_PORT", "udp"},
+		{"LOGGER_ADD_SOURCE", "sometimes"},
+		{"LOGGER_WRITE_TIMEOUT", "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+			if _, err := NewConfigFromEnv(); err == nil {
+				t.Errorf("NewConfigFromEnv() with %s=%q expected error", tt.name, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package logger_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	logger "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+// exampleConfig returns the config forwarding to receiver. The records are
+// only routed to the forwarder so the output of the examples is just what
+// they print.
+func exampleConfig(receiver *loggertest.Receiver) logger.Config {
+	cfg := logger.NewConfig()
+	cfg.LogHost = receiver.Host()
+	cfg.LogPort = receiver.Port()
+	cfg.LogType = "example"
+	cfg.ApplicationName = "shop"
+	cfg.LevelRoutes = map[string][]string{"debug+": {logger.SinkForwarder}}
+	return cfg
+}
+
+func ExampleInitialize() {
+	receiver, err := loggertest.Listen(loggertest.UDP)
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = receiver.Close() }()
+
+	if err := logger.Initialize(exampleConfig(receiver)); err != nil {
+		panic(err)
+	}
+	defer func() { _ = logger.Shutdown(context.Background()) }()
+
+	slog.Info("Order placed", "order_id", "1001")
+
+	receiver.Wait(1, 5*time.Second)
+	event := receiver.Events()[0]
+	fmt.Println(event["message"], event["order_id"], event["type"])
+	// Output: Order placed 1001 example
+}
+
+func ExampleNewHandler() {
+	receiver, err := loggertest.Listen(loggertest.UDP)
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = receiver.Close() }()
+
+	handler, err := logger.NewHandler(exampleConfig(receiver))
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = logger.Shutdown(context.Background()) }()
+
+	// the default logger is left alone, the handler is used explicitly
+	log := slog.New(handler).With("component", "billing")
+	log.Warn("Payment retried", "attempt", 2)
+
+	receiver.Wait(1, 5*time.Second)
+	event := receiver.Events()[0]
+	fmt.Println(event["message"], event["component"], event["attempt"])
+	// Output: Payment retried billing 2
+}
+
+func Example_httpMiddleware() {
+	receiver, err := loggertest.Listen(loggertest.UDP)
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = receiver.Close() }()
+
+	if err := logger.Initialize(exampleConfig(receiver)); err != nil {
+		panic(err)
+	}
+	defer func() { _ = logger.Shutdown(context.Background()) }()
+
+	handler := logger.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the logger of the request adds its request_id
+		logger.FromContext(r.Context()).Info("Listing products")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/products", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	receiver.Wait(2, 5*time.Second)
+	for _, event := range receiver.Events() {
+		fmt.Println(event["message"], event[logger.FieldRequestID], event[logger.FieldHTTPPath])
+	}
+	// Output:
+	// Listing products req-42 <nil>
+	// HTTP request req-42 /products
+}
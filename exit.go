@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// defaultExitTimeout bounds the flush of Exit when ExitTimeout is 0
+const defaultExitTimeout = 5 * time.Second
+
+var (
+	// osExit terminates the process, replaced by tests
+	osExit = os.Exit
+
+	exitMu    sync.Mutex
+	exitHooks []func()
+)
+
+// OnExit registers fn to be called by Exit before the records are flushed,
+// so the records it logs are delivered too. The functions are called in the
+// reverse order of their registration, like deferred calls.
+func OnExit(fn func()) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitHooks = append(exitHooks, fn)
+}
+
+// Exit terminates the process with code once the functions registered with
+// OnExit returned and Shutdown flushed the queued records, waiting at most
+// ExitTimeout. Deferred functions are not run, like with os.Exit, which it
+// replaces wherever an application terminates:
+//
+//	cli.OsExiter = logger.Exit
+func Exit(code int) {
+	exitMu.Lock()
+	hooks := slices.Clone(exitHooks)
+	exitMu.Unlock()
+
+	for _, fn := range slices.Backward(hooks) {
+		fn()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cmp.Or(exitTimeout, defaultExitTimeout))
+	defer cancel()
+	if err := Shutdown(ctx); err != nil {
+		diag().Warn("Records may be lost on exit", "error", err)
+	}
+	osExit(code)
+}
+
+// Fatal logs msg and args at LevelCritical with the default logger and
+// terminates the process with Exit(1), so the final error is delivered
+func Fatal(msg string, args ...any) {
+	logger := slog.Default()
+	if logger.Enabled(context.Background(), LevelCritical) {
+		var pcs [1]uintptr
+		runtime.Callers(2, pcs[:]) // skip runtime.Callers and Fatal
+		r := slog.NewRecord(time.Now(), LevelCritical, msg, pcs[0])
+		r.Add(args...)
+		if err := logger.Handler().Handle(context.Background(), r); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", msg, err)
+		}
+	}
+	Exit(1)
+}
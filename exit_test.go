@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+// stubExit records the code of Exit instead of terminating the test binary
+// and forgets the functions registered with OnExit
+func stubExit(t *testing.T) *int {
+	t.Helper()
+	code := -1
+	osExit = func(c int) { code = c }
+	previous := slog.Default()
+	t.Cleanup(func() {
+		osExit = os.Exit
+		slog.SetDefault(previous)
+		exitMu.Lock()
+		exitHooks = nil
+		exitMu.Unlock()
+	})
+	return &code
+}
+
+// initializeTCP initializes the logger forwarding to a TCP receiver
+func initializeTCP(t *testing.T) *loggertest.Receiver {
+	t.Helper()
+	preserveConfig(t)
+	once = sync.Once{}
+
+	r, err := loggertest.Listen(loggertest.TCP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+
+	cfg := NewConfig()
+	cfg.LogType = "exit"
+	cfg.LogHost = r.Host()
+	cfg.LogPort = r.Port()
+	cfg.Protocol = ProtocolTCP
+	cfg.ExitTimeout = 2 * time.Second
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+	return r
+}
+
+func TestExit(t *testing.T) {
+	code := stubExit(t)
+	r := initializeTCP(t)
+
+	var order []string
+	OnExit(func() { order = append(order, "first") })
+	OnExit(func() {
+		order = append(order, "second")
+		slog.Info("closing connections")
+	})
+	slog.Info("terminating")
+	Exit(3)
+
+	if *code != 3 {
+		t.Errorf("exit code = %d, want 3", *code)
+	}
+	if strings.Join(order, ",") != "second,first" {
+		t.Errorf("OnExit functions called in order %v, want the last registered first", order)
+	}
+	// the records were flushed by the time the process would terminate
+	r.Wait(2, time.Second)
+	if events := r.Events(); len(events) != 2 || events[1]["message"] != "closing connections" {
+		t.Errorf("received %v, want the records logged before and during Exit", events)
+	}
+}
+
+func TestFatal(t *testing.T) {
+	code := stubExit(t)
+	r := initializeTCP(t)
+	addSource = true
+
+	Fatal("Failed to start", Err(errors.New("address in use")))
+
+	if *code != 1 {
+		t.Errorf("exit code = %d, want 1", *code)
+	}
+	r.Wait(1, time.Second)
+	events := r.Events()
+	if len(events) != 1 {
+		t.Fatalf("received %d events, want the final error", len(events))
+	}
+	event := events[0]
+	if event["level"] != "CRITICAL" || event[FieldError] != "address in use" {
+		t.Errorf("event = %v, want the error at CRITICAL", event)
+	}
+	if source, _ := event["source"].(map[string]any); source == nil || !strings.HasSuffix(source["file"].(string), "exit_test.go") {
+		t.Errorf("source = %v, want the caller of Fatal", event["source"])
+	}
+}
@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// endpoint is a host and port the forwarder can connect to, the port is 0
+// for a socket path
+type endpoint struct {
+	host string
+	port int
+}
+
+func (e endpoint) String() string {
+	if e.port == 0 {
+		return e.host
+	}
+	return net.JoinHostPort(e.host, strconv.Itoa(e.port))
+}
+
+// parseFallbackHost returns the endpoint of an entry of FallbackHosts, on
+// port unless it names one
+func parseFallbackHost(host, protocol string, port int) (endpoint, error) {
+	if len(host) == 0 {
+		return endpoint{}, errors.New("fallbackHosts must not be empty")
+	}
+	if protocol == ProtocolUnix {
+		return endpoint{host: host}, nil
+	}
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		n, err := strconv.Atoi(p)
+		if err != nil || n <= 0 || n > 65535 {
+			return endpoint{}, fmt.Errorf("invalid port in fallback host %q", host)
+		}
+		return endpoint{host: h, port: n}, nil
+	}
+	return endpoint{host: host, port: port}, nil
+}
+
+// failover connects to the first reachable of the primary endpoint and its
+// fallbacks, starting from the one reached last
+type failover struct {
+	// endpoints are the primary followed by the fallbacks
+	endpoints []endpoint
+	dial      func(ctx context.Context, e endpoint) (net.Conn, error)
+	// active is the index of the endpoint reached last
+	active atomic.Int32
+}
+
+// forwarderEndpoints are the endpoints of the forwarder, nil without
+// FallbackHosts
+var forwarderEndpoints atomic.Pointer[failover]
+
+// newFailover returns the failover of the applied config, nil when there are
+// no fallback hosts
+func newFailover() *failover {
+	if len(fallbackHosts) == 0 {
+		return nil
+	}
+
+	network, timeout, settings, lookup := protocol, writeTimeout, forwarderSettings(), resolver
+	f := &failover{
+		endpoints: []endpoint{{host: logHost, port: logPort}},
+		dial: func(ctx context.Context, e endpoint) (net.Conn, error) {
+			return dialEndpointContext(ctx, network, e.host, e.port, timeout, settings, lookup)
+		},
+	}
+	if network == ProtocolUnix {
+		f.endpoints[0].port = 0
+	}
+	for _, host := range fallbackHosts {
+		// the hosts were validated when the config was applied
+		e, _ := parseFallbackHost(host, network, logPort)
+		f.endpoints = append(f.endpoints, e)
+	}
+	return f
+}
+
+// dialContext connects to the endpoint reached last or, when it is
+// unreachable, the ones following it in turn
+func (f *failover) dialContext(ctx context.Context) (net.Conn, error) {
+	start := int(f.active.Load())
+	var errs []error
+	for i := range f.endpoints {
+		index := (start + i) % len(f.endpoints)
+		e := f.endpoints[index]
+		conn, err := f.dial(ctx, e)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e, err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		if previous := f.active.Swap(int32(index)); int(previous) != index {
+			if index == 0 {
+				diag().Info("Failed back to primary log endpoint", "endpoint", e.String())
+			} else {
+				diag().Warn("Failed over to fallback log endpoint", "endpoint", e.String(), "error", errors.Join(errs...))
+			}
+		}
+		return conn, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// failback probes the primary endpoint every interval while a fallback is
+// connected, and attaches the destination built on a connection to it to
+// target once it is reachable
+func (f *failover) failback(ctx context.Context, interval time.Duration, target *switchWriter, destination func(net.Conn) io.Writer) {
+	clock := schedulerClock()
+	for {
+		select {
+		case <-clock.After(interval):
+		case <-ctx.Done():
+			return
+		}
+
+		// while nothing is connected the reconnect loop reaches the primary
+		if f.active.Load() == 0 || target.discarding() {
+			continue
+		}
+		conn, err := f.dial(ctx, f.endpoints[0])
+		if err != nil {
+			continue
+		}
+
+		f.active.Store(0)
+		w := destination(conn)
+		previous, ok := target.attach(ctx, w)
+		if !ok {
+			closeWriter(w)
+			return
+		}
+		// records still queued for the fallback are delivered there
+		closeWriter(previous)
+		diag().Info("Failed back to primary log endpoint", "endpoint", f.endpoints[0].String())
+	}
+}
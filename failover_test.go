@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+func TestParseFallbackHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		protocol string
+		expected endpoint
+		wantErr  bool
+	}{
+		{"logs-b", ProtocolUDP, endpoint{"logs-b", 5140}, false},
+		{"logs-b:5142", ProtocolTCP, endpoint{"logs-b", 5142}, false},
+		{"[::1]:5142", ProtocolTCP, endpoint{"::1", 5142}, false},
+		{"/run/logs-b.sock", ProtocolUnix, endpoint{"/run/logs-b.sock", 0}, false},
+		{"logs-b:0", ProtocolTCP, endpoint{}, true},
+		{"logs-b:http", ProtocolTCP, endpoint{}, true},
+		{"", ProtocolUDP, endpoint{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseFallbackHost(tt.host, tt.protocol, 5140)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseFallbackHost(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("parseFallbackHost(%q) = %+v, want %+v", tt.host, got, tt.expected)
+		}
+	}
+}
+
+func TestFailover_DialContext(t *testing.T) {
+	var mu sync.Mutex
+	down := map[string]bool{"primary": true}
+	var dialled []string
+	f := &failover{
+		endpoints: []endpoint{{host: "primary"}, {host: "b"}, {host: "c"}},
+		dial: func(_ context.Context, e endpoint) (net.Conn, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dialled = append(dialled, e.host)
+			if down[e.host] {
+				return nil, errors.New("unreachable")
+			}
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		},
+	}
+
+	dial := func() []string {
+		t.Helper()
+		dialled = nil
+		conn, err := f.dialContext(context.Background())
+		if err != nil {
+			return append(dialled, "error")
+		}
+		conn.Close()
+		return dialled
+	}
+
+	if got := dial(); !slices.Equal(got, []string{"primary", "b"}) {
+		t.Errorf("dialled %v, want the primary then the first fallback", got)
+	}
+	down["b"] = true
+	if got := dial(); !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("dialled %v, want the active fallback then the next one", got)
+	}
+	down["c"] = true
+	if got := dial(); !slices.Equal(got, []string{"c", "primary", "b", "error"}) {
+		t.Errorf("dialled %v, want every endpoint starting at the active one", got)
+	}
+	down["primary"] = false
+	if got := dial(); !slices.Equal(got, []string{"c", "primary"}) || f.active.Load() != 0 {
+		t.Errorf("dialled %v with active %d, want to wrap around to the primary", got, f.active.Load())
+	}
+}
+
+func TestInitialize_FailsOverAndBack(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	// reserve a port nothing listens on yet
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	fallback, err := loggertest.Listen(loggertest.TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fallback.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "failover-type"
+	cfg.Protocol = ProtocolTCP
+	cfg.LogHost = "127.0.0.1"
+	cfg.LogPort = port
+	cfg.FallbackHosts = []string{fallback.Addr()}
+	cfg.FailbackInterval = 20 * time.Millisecond
+	diagnosed := &capturedDiagnostics{}
+	cfg.Diagnostics = slog.NewJSONHandler(diagnosed, nil)
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+
+	slog.Info("on fallback")
+	if !fallback.Wait(1, time.Second) {
+		t.Fatalf("fallback received %d records, want 1", fallback.Count())
+	}
+	if !diagnosed.wait("Failed over to fallback log endpoint", time.Second) {
+		t.Error("failing over was not reported")
+	}
+
+	primary, err := loggertest.ListenAddr(loggertest.TCP, listener.Addr().String())
+	if err != nil {
+		t.Skipf("port %d was taken before the primary came up: %v", port, err)
+	}
+	defer primary.Close()
+
+	if !diagnosed.wait("Failed back to primary log endpoint", 2*time.Second) {
+		t.Fatal("forwarder did not fail back once the primary came up")
+	}
+	slog.Info("on primary")
+	if !primary.Wait(1, time.Second) {
+		t.Fatalf("primary received %d records after failing back, want 1", primary.Count())
+	}
+	if got := primary.Events()[0]["message"]; got != "on primary" {
+		t.Errorf("message = %v, want %q", got, "on primary")
+	}
+	if fallback.Count() != 1 {
+		t.Errorf("fallback received %d records, want only the one logged before failing back", fallback.Count())
+	}
+}
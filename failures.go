@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// maxRecentErrors is the number of delivery errors kept for RecentErrors
+const maxRecentErrors = 100
+
+// Operations failing in a DeliveryError
+const (
+	OpDial  = "dial"
+	OpWrite = "write"
+)
+
+// DeliveryError is a failure to connect or write to a sink
+type DeliveryError struct {
+	Time  time.Time `json:"time"`
+	Sink  string    `json:"sink"` // SinkStdout, SinkForwarder or the name of a destination
+	Op    string    `json:"op"`   // OpDial or OpWrite
+	Error string    `json:"error"`
+}
+
+// recentErrors is a ring of the last maxRecentErrors delivery errors
+var recentErrors struct {
+	sync.Mutex
+	entries [maxRecentErrors]DeliveryError
+	// next is the index of the slot written next
+	next  int
+	count int
+}
+
+// RecentErrors returns the last n delivery errors, newest first, so health
+// endpoints and support tooling can report why records aren't arriving. At
+// most the last 100 are kept, for the life of the process.
+func RecentErrors(n int) []DeliveryError {
+	recentErrors.Lock()
+	defer recentErrors.Unlock()
+
+	n = min(n, recentErrors.count)
+	if n <= 0 {
+		return nil
+	}
+	out := make([]DeliveryError, n)
+	for i := range out {
+		out[i] = recentErrors.entries[(recentErrors.next-1-i+maxRecentErrors)%maxRecentErrors]
+	}
+	return out
+}
+
+// recordError remembers that op on sink failed with err. Records dropped
+// from a full delivery queue are counted by the pool instead.
+func recordError(sink, op string, err error) {
+	if err == nil || errors.Is(err, ErrQueueFull) {
+		return
+	}
+	if op == OpWrite {
+		countersOf(sink).errors.Add(1)
+	}
+
+	recentErrors.Lock()
+	defer recentErrors.Unlock()
+	recentErrors.entries[recentErrors.next] = DeliveryError{Time: time.Now(), Sink: sink, Op: op, Error: err.Error()}
+	recentErrors.next = (recentErrors.next + 1) % maxRecentErrors
+	recentErrors.count = min(recentErrors.count+1, maxRecentErrors)
+}
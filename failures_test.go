@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// resetRecentErrors forgets the delivery errors recorded before and during t
+func resetRecentErrors(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		recentErrors.Lock()
+		recentErrors.next, recentErrors.count = 0, 0
+		recentErrors.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestRecentErrors(t *testing.T) {
+	resetRecentErrors(t)
+
+	if got := RecentErrors(10); got != nil {
+		t.Errorf("RecentErrors() = %v before any error, want nil", got)
+	}
+
+	recordError(SinkForwarder, OpWrite, ErrQueueFull)
+	recordError(SinkForwarder, OpWrite, nil)
+	if got := RecentErrors(10); got != nil {
+		t.Errorf("RecentErrors() = %v, want full queues and successes ignored", got)
+	}
+
+	for i := 0; i < maxRecentErrors+5; i++ {
+		recordError("audit", OpDial, fmt.Errorf("failure %d", i))
+	}
+	got := RecentErrors(3)
+	if len(got) != 3 {
+		t.Fatalf("RecentErrors(3) returned %d errors", len(got))
+	}
+	for i, e := range got {
+		want := fmt.Sprintf("failure %d", maxRecentErrors+4-i)
+		if e.Error != want || e.Sink != "audit" || e.Op != OpDial || e.Time.IsZero() {
+			t.Errorf("RecentErrors(3)[%d] = %+v, want %q on audit", i, e, want)
+		}
+	}
+	if got := RecentErrors(1000); len(got) != maxRecentErrors {
+		t.Errorf("RecentErrors(1000) returned %d errors, want the last %d", len(got), maxRecentErrors)
+	}
+	if got := RecentErrors(0); got != nil {
+		t.Errorf("RecentErrors(0) = %v, want nil", got)
+	}
+}
+
+func TestRecentErrors_Sinks(t *testing.T) {
+	preserveConfig(t)
+	fastReconnect(t)
+	resetRecentErrors(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	// reserve a port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "errors-type"
+	cfg.LogHost = "127.0.0.1"
+	cfg.LogPort = downPort
+	cfg.Protocol = ProtocolTCP
+	cfg.Destinations = []Destination{{Name: "regional", Host: "127.0.0.1", Port: downPort, Protocol: ProtocolTCP}}
+	if _, err := NewHandler(cfg); err != nil {
+		t.Fatalf("NewHandler() returned unexpected error: %v", err)
+	}
+
+	sinks := map[string]bool{}
+	for _, e := range RecentErrors(maxRecentErrors) {
+		if e.Op == OpDial {
+			sinks[e.Sink] = true
+		}
+	}
+	if !sinks[SinkForwarder] || !sinks["regional"] {
+		t.Errorf("dial errors recorded for %v, want the forwarder and regional", sinks)
+	}
+}
+
+func TestRecentErrors_Handler(t *testing.T) {
+	resetRecentErrors(t)
+	h := newSinkHandler(sink{name: "audit", w: &failingWriter{}})
+
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "lost", 0)); err == nil {
+		t.Fatal("Handle() returned no error for a failing sink")
+	}
+	got := RecentErrors(1)
+	if len(got) != 1 || got[0].Sink != "audit" || got[0].Op != OpWrite || got[0].Error != "endpoint went away" {
+		t.Errorf("RecentErrors(1) = %+v, want the write error of audit", got)
+	}
+}
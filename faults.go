@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Faults injects failures into the forwarder so applications and tests can
+// check how they behave when the log endpoint misbehaves. Decisions are taken
+// from a generator seeded with Seed, so a run can be repeated exactly. It is
+// meant for testing only and must not be set in production.
+type Faults struct {
+	DropPercent      float64       `json:"dropPercent"`      // share of writes silently discarded, 0 to 100
+	Delay            time.Duration `json:"delay"`            // added before every write
+	FailEveryNthDial int           `json:"failEveryNthDial"` // fail every Nth connection a delivery worker opens, other dials are left alone
+	Seed             uint64        `json:"seed"`
+}
+
+// faultInjector applies Faults to the connections of the forwarder. A nil
+// injector leaves them untouched.
+type faultInjector struct {
+	faults Faults
+	mu     sync.Mutex
+	rand   *rand.Rand
+	dials  int
+}
+
+// injectedFault is the error of an operation failed on purpose
+type injectedFault string
+
+func (e injectedFault) Error() string { return "injected fault: " + string(e) }
+
+func newFaultInjector(faults *Faults) *faultInjector {
+	if faults == nil {
+		return nil
+	}
+	return &faultInjector{
+		faults: *faults,
+		rand:   rand.New(rand.NewPCG(faults.Seed, faults.Seed)),
+	}
+}
+
+// dial wraps dial so every Nth call fails and the connections it opens are
+// faulty
+func (f *faultInjector) dial(dial func() (io.WriteCloser, error)) func() (io.WriteCloser, error) {
+	if f == nil {
+		return dial
+	}
+
+	return func() (io.WriteCloser, error) {
+		f.mu.Lock()
+		f.dials++
+		fail := f.faults.FailEveryNthDial > 0 && f.dials%f.faults.FailEveryNthDial == 0
+		f.mu.Unlock()
+
+		if fail {
+			return nil, injectedFault("dial failed")
+		}
+
+		conn, err := dial()
+		if err != nil {
+			return nil, err
+		}
+		return f.conn(conn), nil
+	}
+}
+
+// conn wraps conn so its writes are delayed and dropped
+func (f *faultInjector) conn(conn io.WriteCloser) io.WriteCloser {
+	if f == nil {
+		return conn
+	}
+	return &faultyConn{WriteCloser: conn, faults: f}
+}
+
+// drop reports whether the next write should be discarded
+func (f *faultInjector) drop() bool {
+	if f.faults.DropPercent <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rand.Float64()*100 < f.faults.DropPercent
+}
+
+// faultyConn is a connection whose writes go through a faultInjector
+type faultyConn struct {
+	io.WriteCloser
+	faults *faultInjector
+}
+
+func (c *faultyConn) Write(p []byte) (int, error) {
+	if c.faults.faults.Delay > 0 {
+		time.Sleep(c.faults.faults.Delay)
+	}
+	if c.faults.drop() {
+		// like a datagram lost on the network, the caller believes it was sent
+		return len(p), nil
+	}
+	return c.WriteCloser.Write(p)
+}
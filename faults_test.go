@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFaultInjector_Nil(t *testing.T) {
+	var injector *faultInjector
+	conn := &recordingConn{}
+
+	if got := injector.conn(conn); got != io.WriteCloser(conn) {
+		t.Error("nil injector should return the connection unchanged")
+	}
+
+	dial := injector.dial(func() (io.WriteCloser, error) { return conn, nil })
+	if got, err := dial(); err != nil || got != io.WriteCloser(conn) {
+		t.Errorf("nil injector dial() = %v, %v; want the connection unchanged", got, err)
+	}
+}
+
+func TestFaultInjector_DropPercent(t *testing.T) {
+	pattern := func(seed uint64) string {
+		conn := &recordingConn{}
+		faulty := newFaultInjector(&Faults{DropPercent: 30, Seed: seed}).conn(conn)
+		for i := 0; i < 1000; i++ {
+			if _, err := faulty.Write([]byte(fmt.Sprint(i))); err != nil {
+				t.Fatalf("Write() returned unexpected error: %v", err)
+			}
+		}
+		return fmt.Sprint(conn.delivered())
+	}
+
+	if pattern(1) != pattern(1) {
+		t.Error("the same seed should drop the same writes")
+	}
+	if pattern(1) == pattern(2) {
+		t.Error("different seeds should drop different writes")
+	}
+
+	conn := &recordingConn{}
+	faulty := newFaultInjector(&Faults{DropPercent: 30, Seed: 1}).conn(conn)
+	for i := 0; i < 1000; i++ {
+		_, _ = faulty.Write([]byte("x"))
+	}
+	if delivered := len(conn.delivered()); delivered < 650 || delivered > 750 {
+		t.Errorf("delivered %d of 1000 writes, want about 700", delivered)
+	}
+}
+
+func TestFaultInjector_Delay(t *testing.T) {
+	faulty := newFaultInjector(&Faults{Delay: 20 * time.Millisecond}).conn(&recordingConn{})
+
+	start := time.Now()
+	if _, err := faulty.Write([]byte("x")); err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Write() took %v, want at least 20ms", elapsed)
+	}
+}
+
+func TestFaultInjector_FailEveryNthDial(t *testing.T) {
+	dial := newFaultInjector(&Faults{FailEveryNthDial: 3}).dial(func() (io.WriteCloser, error) {
+		return &recordingConn{}, nil
+	})
+
+	var failed []int
+	for i := 1; i <= 9; i++ {
+		if _, err := dial(); err != nil {
+			var fault injectedFault
+			if !errors.As(err, &fault) {
+				t.Errorf("dial() error = %v, want an injected fault", err)
+			}
+			failed = append(failed, i)
+		}
+	}
+
+	if fmt.Sprint(failed) != "[3 6 9]" {
+		t.Errorf("failed dials = %v, want [3 6 9]", failed)
+	}
+}
+
+func TestFaultInjector_DeliveryPool(t *testing.T) {
+	conn := &recordingConn{}
+	dial := newFaultInjector(&Faults{FailEveryNthDial: 1}).dial(func() (io.WriteCloser, error) {
+		return conn, nil
+	})
+	pool := newPolicyPool(DeliveryAtMostOnce, 1, 10, dial)
+
+	if _, err := pool.Write([]byte("lost")); err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+	pool.Close()
+
+	if got := conn.delivered(); len(got) != 0 {
+		t.Errorf("delivered %v, want nothing when every dial fails", got)
+	}
+	if pool.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", pool.Dropped())
+	}
+}
+
+func TestConfigJSON_Faults(t *testing.T) {
+	if _, err := parseConfig(NewConfig(), []byte(`{"logType": "x", "faults": {"dropPercent": 5}}`)); err == nil {
+		t.Error("parseConfig() should reject faults in a config file")
+	}
+
+	cfg := NewConfig()
+	cfg.Faults = &Faults{DropPercent: 5}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("json.Marshal() returned unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), "faults") {
+		t.Errorf("json.Marshal() = %s, want no faults", data)
+	}
+}
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+const (
+	// fieldsMaxDepth bounds the nesting of the fields passed to Emit, so a
+	// map containing itself can't recurse forever
+	fieldsMaxDepth = 16
+	// emptyFieldKey replaces an empty key of the fields passed to Emit
+	emptyFieldKey = "_empty"
+)
+
+// Emit logs msg at level with the default logger, the fields grouped under
+// extra. It bridges dynamically shaped data such as the metadata of a webhook
+// payload: keys are sorted, nested maps become groups, and values JSON can't
+// carry, such as functions, channels and NaN, are written as strings, so the
+// record is never lost to an encoding error.
+func Emit(level slog.Level, msg string, fields map[string]any) {
+	ctx := context.Background()
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+	if len(fields) == 0 {
+		logDefault(ctx, level, msg)
+		return
+	}
+	logDefault(ctx, level, msg, slog.Attr{Key: "extra", Value: slog.GroupValue(fieldAttrs(fields, 0)...)})
+}
+
+// fieldAttrs returns the attributes of fields sorted by key
+func fieldAttrs(fields map[string]any, depth int) []slog.Attr {
+	keys := slices.Sorted(maps.Keys(fields))
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, key := range keys {
+		value := fields[key]
+		if len(key) == 0 {
+			key = emptyFieldKey
+		}
+		attrs = append(attrs, slog.Attr{Key: key, Value: fieldValue(value, depth)})
+	}
+	return attrs
+}
+
+// fieldValue returns the value of a field, a group for a non-empty map so
+// processors such as Redact reach its keys
+func fieldValue(v any, depth int) slog.Value {
+	if m, ok := v.(map[string]any); ok && len(m) > 0 {
+		if depth >= fieldsMaxDepth {
+			return slog.StringValue(depthMarker)
+		}
+		return slog.GroupValue(fieldAttrs(m, depth+1)...)
+	}
+	policy := valuePolicy{maxDepth: fieldsMaxDepth - depth}
+	return slog.AnyValue(finiteFloats(policy.encode(reflect.ValueOf(v))))
+}
+
+// finiteFloats replaces the NaN and infinite floats of an encoded value,
+// which JSON has no numbers for, with their names
+func finiteFloats(v any) any {
+	switch v := v.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	case orderedObject:
+		for i := range v {
+			v[i].value = finiteFloats(v[i].value)
+		}
+	case []any:
+		for i := range v {
+			v[i] = finiteFloats(v[i])
+		}
+	}
+	return v
+}
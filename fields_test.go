@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+)
+
+// emitted returns the event Emit writes for fields with the default logger
+// set to a Lagoon handler
+func emitted(t *testing.T, level slog.Level, fields map[string]any) (map[string]any, string) {
+	t.Helper()
+	preserveConfig(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "emit-type"
+	cfg.LogHost = "localhost"
+	cfg.Level = "info"
+
+	var buf bytes.Buffer
+	h, err := NewWriterHandler(cfg, &buf)
+	if err != nil {
+		t.Fatalf("NewWriterHandler() returned unexpected error: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(h))
+	defer slog.SetDefault(previous)
+
+	Emit(level, "webhook received", fields)
+	if buf.Len() == 0 {
+		return nil, ""
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	return event, buf.String()
+}
+
+func TestEmit(t *testing.T) {
+	cyclic := map[string]any{"name": "loop"}
+	cyclic["self"] = cyclic
+
+	event, raw := emitted(t, slog.LevelWarn, map[string]any{
+		"repository": "lagoon/site",
+		"sender":     map[string]any{"login": "octocat", "id": 1},
+		"":           "blank key",
+		"commits":    []any{map[string]any{"b": 2, "a": 1}, "next"},
+		"empty":      map[string]any{},
+		"ratio":      math.NaN(),
+		"callback":   func() {},
+		"cyclic":     cyclic,
+	})
+	if event == nil {
+		t.Fatal("Emit() wrote nothing")
+	}
+	if event["message"] != "webhook received" || event["level"] != "WARN" || event["type"] != "emit-type" {
+		t.Errorf("event = %v, want the Lagoon event of the message", event)
+	}
+
+	extra, _ := event["extra"].(map[string]any)
+	if extra["repository"] != "lagoon/site" || extra[emptyFieldKey] != "blank key" {
+		t.Errorf("extra = %v, want the fields", extra)
+	}
+	if sender, _ := extra["sender"].(map[string]any); sender["login"] != "octocat" || sender["id"] != float64(1) {
+		t.Errorf("sender = %v, want the nested map", extra["sender"])
+	}
+	if ratio := extra["ratio"]; ratio != "NaN" {
+		t.Errorf("ratio = %v, want NaN as a string", ratio)
+	}
+	if callback, _ := extra["callback"].(string); !strings.HasPrefix(callback, "[unsupported") {
+		t.Errorf("callback = %v, want the unsupported marker", extra["callback"])
+	}
+	if !strings.Contains(raw, depthMarker) {
+		t.Errorf("cyclic map isn't bounded in %s", raw)
+	}
+	if !strings.Contains(raw, `"callback":`) || strings.Index(raw, `"callback":`) > strings.Index(raw, `"commits":`) {
+		t.Errorf("fields aren't sorted in %s", raw)
+	}
+	if !strings.Contains(raw, `[{"a":1,"b":2},"next"]`) {
+		t.Errorf("maps inside slices aren't sorted in %s", raw)
+	}
+	if !strings.Contains(raw, `"empty":{}`) {
+		t.Errorf("empty map isn't kept in %s", raw)
+	}
+	if !strings.Contains(raw, "fields_test.go") {
+		t.Errorf("source isn't the caller of Emit in %s", raw)
+	}
+}
+
+func TestEmit_Disabled(t *testing.T) {
+	if event, raw := emitted(t, slog.LevelDebug, map[string]any{"skipped": true}); event != nil {
+		t.Errorf("Emit() below the level wrote %s", raw)
+	}
+}
@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Feature flags consulted through the FlagProvider
+const (
+	// FlagLevel is the minimum level logged, e.g. "debug"
+	FlagLevel = "lagoon-logs-level"
+	// FlagSampleRate keeps one in this many records below warn, e.g. "10"
+	FlagSampleRate = "lagoon-logs-sample-rate"
+)
+
+// FlagTarget describes the service flags are evaluated for, so flags can be
+// targeted at a project, environment or application
+type FlagTarget struct {
+	Project     string
+	Environment string
+	Application string
+	Type        string
+}
+
+// FlagProvider evaluates feature flags, typically an adapter around an
+// Unleash or LaunchDarkly client. Flag returns the value of the flag name for
+// target, and false when the flag is not defined for it. It is called from a
+// background goroutine every FlagRefreshInterval, never while logging.
+type FlagProvider interface {
+	Flag(ctx context.Context, name string, target FlagTarget) (string, bool)
+}
+
+// FlagProviderFunc adapts a function to a FlagProvider
+type FlagProviderFunc func(ctx context.Context, name string, target FlagTarget) (string, bool)
+
+func (f FlagProviderFunc) Flag(ctx context.Context, name string, target FlagTarget) (string, bool) {
+	return f(ctx, name, target)
+}
+
+// flagTarget returns the target of the running service
+func flagTarget() FlagTarget {
+	return FlagTarget{
+		Project:     os.Getenv("LAGOON_PROJECT"),
+		Environment: lagoonEnvironment(),
+		Application: applicationName,
+		Type:        logType,
+	}
+}
+
+// flagController caches the flags of a provider, refreshing them every
+// interval
+type flagController struct {
+	provider FlagProvider
+	target   FlagTarget
+	interval time.Duration
+	// invalid remembers the invalid values warned about
+	invalid map[string]string
+}
+
+// run refreshes the flags until ctx is done, then clears them
+func (c *flagController) run(ctx context.Context) {
+	defer func() {
+		flagLevel.Store(nil)
+		flagSampleRate.Store(0)
+	}()
+
+	for {
+		c.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.interval):
+		}
+	}
+}
+
+func (c *flagController) refresh(ctx context.Context) {
+	if value, ok := c.provider.Flag(ctx, FlagLevel, c.target); ok {
+		if level, err := ParseLevel(value); err == nil {
+			flagLevel.Store(&level)
+			delete(c.invalid, FlagLevel)
+		} else {
+			c.warnInvalid(FlagLevel, value)
+		}
+	} else {
+		flagLevel.Store(nil)
+	}
+
+	if value, ok := c.provider.Flag(ctx, FlagSampleRate, c.target); ok {
+		if rate, err := strconv.Atoi(value); err == nil && rate >= 0 {
+			flagSampleRate.Store(int64(rate))
+			delete(c.invalid, FlagSampleRate)
+		} else {
+			c.warnInvalid(FlagSampleRate, value)
+		}
+	} else {
+		flagSampleRate.Store(0)
+	}
+}
+
+// warnInvalid warns about an invalid flag value once, keeping the last valid
+// value in place
+func (c *flagController) warnInvalid(name, value string) {
+	if c.invalid == nil {
+		c.invalid = map[string]string{}
+	}
+	if previous, ok := c.invalid[name]; ok && previous == value {
+		return
+	}
+	c.invalid[name] = value
+	diag().Warn("Ignoring invalid feature flag value", "flag", name, "value", value)
+}
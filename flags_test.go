@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testFlags is a FlagProvider serving fixed values and recording the targets
+// it was asked for
+type testFlags struct {
+	mu      sync.Mutex
+	values  map[string]string
+	targets []FlagTarget
+}
+
+func (f *testFlags) Flag(_ context.Context, name string, target FlagTarget) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.targets = append(f.targets, target)
+	value, ok := f.values[name]
+	return value, ok
+}
+
+func (f *testFlags) set(name, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[name] = value
+}
+
+// resetFlags clears the flag overrides once t ends
+func resetFlags(t *testing.T) {
+	t.Cleanup(func() {
+		flagLevel.Store(nil)
+		flagSampleRate.Store(0)
+	})
+}
+
+func TestFlagController_Refresh(t *testing.T) {
+	resetFlags(t)
+	flags := &testFlags{values: map[string]string{FlagLevel: "warn", FlagSampleRate: "5"}}
+	c := &flagController{provider: flags}
+
+	c.refresh(context.Background())
+	leveler := overrideLeveler{slog.LevelInfo}
+	if got := leveler.Level(); got != slog.LevelWarn {
+		t.Errorf("Level() = %v, want %v", got, slog.LevelWarn)
+	}
+	if got := flagSampleRate.Load(); got != 5 {
+		t.Errorf("sample rate = %d, want 5", got)
+	}
+
+	// invalid values keep the last valid one
+	flags.set(FlagLevel, "loud")
+	flags.set(FlagSampleRate, "often")
+	c.refresh(context.Background())
+	if got := leveler.Level(); got != slog.LevelWarn {
+		t.Errorf("Level() after an invalid value = %v, want %v", got, slog.LevelWarn)
+	}
+	if got := flagSampleRate.Load(); got != 5 {
+		t.Errorf("sample rate after an invalid value = %d, want 5", got)
+	}
+
+	// flags no longer defined are cleared
+	flags.mu.Lock()
+	flags.values = map[string]string{}
+	flags.mu.Unlock()
+	c.refresh(context.Background())
+	if got := leveler.Level(); got != slog.LevelInfo {
+		t.Errorf("Level() without flags = %v, want %v", got, slog.LevelInfo)
+	}
+	if got := flagSampleRate.Load(); got != 0 {
+		t.Errorf("sample rate without flags = %d, want 0", got)
+	}
+}
+
+func TestOverridePrecedence(t *testing.T) {
+	resetFlags(t)
+	resetRemote(t)
+
+	flag, remote := slog.LevelWarn, slog.LevelError
+	flagLevel.Store(&flag)
+	leveler := overrideLeveler{slog.LevelDebug}
+	if got := leveler.Level(); got != slog.LevelWarn {
+		t.Errorf("Level() = %v, want the flag level %v", got, slog.LevelWarn)
+	}
+
+	remoteLevel.Store(&remote)
+	if got := leveler.Level(); got != slog.LevelError {
+		t.Errorf("Level() = %v, want the remote level %v", got, slog.LevelError)
+	}
+
+	flagSampleRate.Store(1000)
+	remoteSampleRate.Store(1)
+	for i := 0; i < 10; i++ {
+		if !overrideKeep(slog.LevelInfo) {
+			t.Fatal("overrideKeep() sampled with a remote rate of 1")
+		}
+	}
+}
+
+func TestHandler_Flags(t *testing.T) {
+	preserveConfig(t)
+	resetFlags(t)
+	t.Setenv("LAGOON_PROJECT", "shop")
+	t.Setenv("LAGOON_ENVIRONMENT", "main")
+
+	var buf strings.Builder
+	flags := &testFlags{values: map[string]string{FlagLevel: "error"}}
+
+	cfg := NewConfig()
+	cfg.LogType = "shop-main"
+	cfg.ApplicationName = "api"
+	cfg.Flags = flags
+	if err := config(cfg); err != nil {
+		t.Fatalf("config() returned unexpected error: %v", err)
+	}
+
+	c := &flagController{provider: flags, target: flagTarget()}
+	c.refresh(context.Background())
+
+	logger := slog.New(newHandler(&buf))
+	logger.Warn("filtered by the flag")
+	logger.Error("kept")
+	if strings.Contains(buf.String(), "filtered by the flag") || !strings.Contains(buf.String(), "kept") {
+		t.Errorf("output = %q, want only the error record", buf.String())
+	}
+
+	want := FlagTarget{Project: "shop", Environment: "main", Application: "api", Type: "shop-main"}
+	if got := flags.targets[0]; got != want {
+		t.Errorf("target = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,317 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultForwardTag is the tag of forwarded events without ForwardConfig
+	defaultForwardTag = "lagoon"
+	// forwardAckTimeout bounds waiting for the ack of a chunk
+	forwardAckTimeout = 30 * time.Second
+)
+
+// ForwardConfig configures ProtocolForward, which sends every write, a single
+// event or a batch with BatchSize, as one Forward mode message of the Fluent
+// forward protocol. Set TLS for a forward input with TLS enabled.
+type ForwardConfig struct {
+	Tag        string `json:"tag"`        // routes the events in Fluentd, "lagoon" when empty
+	RequireAck bool   `json:"requireAck"` // waits for the aggregator to acknowledge every message
+}
+
+func (c *ForwardConfig) validate() error {
+	if strings.ContainsAny(c.Tag, " \t\r\n") {
+		return fmt.Errorf("forward.tag %q must not contain whitespace", c.Tag)
+	}
+	return nil
+}
+
+// dialForward returns a connection to the forward input at host:port, over
+// TLS when settings are given
+func dialForward(ctx context.Context, host string, port int, writeTimeout time.Duration, settings *TLSConfig, fluent *ForwardConfig, resolver Resolver) (net.Conn, error) {
+	conn, err := dialEndpointContext(ctx, ProtocolTCP, host, port, writeTimeout, endpointSettings{tls: settings}, resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &forwardConn{Conn: conn, tag: defaultForwardTag}
+	if fluent != nil {
+		c.tag = cmp.Or(fluent.Tag, defaultForwardTag)
+		c.ack = fluent.RequireAck
+	}
+	if c.ack {
+		c.responses = bufio.NewReader(conn)
+	}
+	return c, nil
+}
+
+// forwardConn encodes the newline-delimited JSON events written to it as
+// Forward mode messages
+type forwardConn struct {
+	net.Conn
+	tag string
+	ack bool
+	// responses reads the acks of the aggregator
+	responses *bufio.Reader
+}
+
+// Write sends the events in p as one message. With acks, it fails when the
+// aggregator doesn't acknowledge the message in time, and closes the
+// connection, so a late ack can't be taken for the one of the next message.
+func (c *forwardConn) Write(p []byte) (int, error) {
+	var entries [][]byte
+	for line := range bytes.Lines(p) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		entries = append(entries, forwardEntry(line))
+	}
+	if len(entries) == 0 {
+		return len(p), nil
+	}
+
+	var chunk string
+	if c.ack {
+		id := make([]byte, 16)
+		_, _ = rand.Read(id)
+		chunk = base64.StdEncoding.EncodeToString(id)
+	}
+
+	msg := packArray(nil, 3)
+	msg = packString(msg, c.tag)
+	msg = packArray(msg, len(entries))
+	for _, e := range entries {
+		msg = append(msg, e...)
+	}
+	options := 1
+	if c.ack {
+		options = 2
+	}
+	msg = packMap(msg, options)
+	msg = packInt(packString(msg, "size"), int64(len(entries)))
+	if c.ack {
+		msg = packString(packString(msg, "chunk"), chunk)
+	}
+
+	if _, err := c.Conn.Write(msg); err != nil {
+		return 0, err
+	}
+	if !c.ack {
+		return len(p), nil
+	}
+
+	if err := c.awaitAck(chunk); err != nil {
+		_ = c.Conn.Close()
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// awaitAck reads the response to the message sent as chunk
+func (c *forwardConn) awaitAck(chunk string) error {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(forwardAckTimeout)); err != nil {
+		return err
+	}
+	response, err := unpackStringMap(c.responses)
+	if err != nil {
+		return fmt.Errorf("read forward ack: %w", err)
+	}
+	if response["ack"] != chunk {
+		return errors.New("forward ack does not match the chunk sent")
+	}
+	return nil
+}
+
+// forwardEntry returns the [time, record] entry of the JSON event line. A
+// line that isn't a JSON object is sent as the message of a record.
+func forwardEntry(line []byte) []byte {
+	var record map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(&record); err != nil || record == nil {
+		record = map[string]any{"message": string(line)}
+	}
+
+	t := time.Now()
+	if s, ok := record["@timestamp"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			t = parsed
+		}
+	}
+
+	entry := packArray(nil, 2)
+	entry = packEventTime(entry, t)
+	return pack(entry, record)
+}
+
+// pack appends the MessagePack encoding of v, a value decoded from JSON with
+// numbers kept as json.Number
+func pack(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return packInt(b, i)
+		}
+		f, _ := v.Float64()
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(f))
+	case string:
+		return packString(b, v)
+	case []any:
+		b = packArray(b, len(v))
+		for _, item := range v {
+			b = pack(b, item)
+		}
+		return b
+	case map[string]any:
+		b = packMap(b, len(v))
+		// sorted so the same event is always encoded the same
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			b = pack(packString(b, key), v[key])
+		}
+		return b
+	default:
+		return packString(b, fmt.Sprint(v))
+	}
+}
+
+func packInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i < 128:
+		return append(b, byte(i))
+	case i >= -32 && i < 0:
+		return append(b, byte(i))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+	}
+}
+
+func packString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n < 1<<8:
+		b = append(b, 0xd9, byte(n))
+	case n < 1<<16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func packArray(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n < 1<<16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+func packMap(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n < 1<<16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+// packEventTime appends t as the EventTime extension, which keeps the
+// nanoseconds of the timestamp
+func packEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Unix()))
+	return binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+}
+
+// unpackStringMap reads a map of strings, such as the ack of a chunk
+func unpackStringMap(r *bufio.Reader) (map[string]string, error) {
+	head, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	switch {
+	case head&0xf0 == 0x80:
+		n = int(head & 0x0f)
+	case head == 0xde:
+		var size [2]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint16(size[:]))
+	default:
+		return nil, fmt.Errorf("unexpected response type 0x%02x", head)
+	}
+
+	m := make(map[string]string, n)
+	for range n {
+		key, err := unpackString(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := unpackString(r)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+func unpackString(r *bufio.Reader) (string, error) {
+	head, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	var n int
+	switch {
+	case head&0xe0 == 0xa0:
+		n = int(head & 0x1f)
+	case head == 0xd9, head == 0xda, head == 0xdb:
+		size := make([]byte, 1<<(head-0xd9))
+		if _, err := io.ReadFull(r, size); err != nil {
+			return "", err
+		}
+		for _, s := range size {
+			n = n<<8 | int(s)
+		}
+	default:
+		return "", fmt.Errorf("unexpected string type 0x%02x", head)
+	}
+
+	s := make([]byte, n)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
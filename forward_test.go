@@ -0,0 +1,278 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+// unpack decodes the MessagePack value pack encodes, with EventTime as a
+// time.Time
+func unpack(r *bufio.Reader) (any, error) {
+	head, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	readN := func(n int) ([]byte, error) {
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	collection := func(n int, isMap bool) (any, error) {
+		if !isMap {
+			items := make([]any, n)
+			for i := range items {
+				if items[i], err = unpack(r); err != nil {
+					return nil, err
+				}
+			}
+			return items, nil
+		}
+		m := map[string]any{}
+		for range n {
+			key, err := unpack(r)
+			if err != nil {
+				return nil, err
+			}
+			if m[key.(string)], err = unpack(r); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+
+	switch {
+	case head < 0x80:
+		return int64(head), nil
+	case head >= 0xe0:
+		return int64(int8(head)), nil
+	case head&0xe0 == 0xa0:
+		b, err := readN(int(head & 0x1f))
+		return string(b), err
+	case head&0xf0 == 0x90:
+		return collection(int(head&0x0f), false)
+	case head&0xf0 == 0x80:
+		return collection(int(head&0x0f), true)
+	}
+	switch head {
+	case 0xc0:
+		return nil, nil
+	case 0xc2, 0xc3:
+		return head == 0xc3, nil
+	case 0xd3:
+		b, err := readN(8)
+		return int64(binary.BigEndian.Uint64(b)), err
+	case 0xcb:
+		b, err := readN(8)
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), err
+	case 0xd9, 0xda:
+		size, err := readN(int(head-0xd9) + 1)
+		if err != nil {
+			return nil, err
+		}
+		n := 0
+		for _, s := range size {
+			n = n<<8 | int(s)
+		}
+		b, err := readN(n)
+		return string(b), err
+	case 0xdc, 0xde:
+		size, err := readN(2)
+		if err != nil {
+			return nil, err
+		}
+		return collection(int(binary.BigEndian.Uint16(size)), head == 0xde)
+	case 0xd7:
+		b, err := readN(9)
+		if err != nil || b[0] != 0 {
+			return nil, fmt.Errorf("unexpected extension %v", b)
+		}
+		return time.Unix(int64(binary.BigEndian.Uint32(b[1:5])), int64(binary.BigEndian.Uint32(b[5:]))), nil
+	}
+	return nil, fmt.Errorf("unexpected type 0x%02x", head)
+}
+
+func TestPack(t *testing.T) {
+	long := string(bytes.Repeat([]byte("x"), 300))
+	tests := []struct {
+		name  string
+		value string
+		want  any
+	}{
+		{"null", `null`, nil},
+		{"bool", `true`, true},
+		{"small int", `7`, int64(7)},
+		{"negative int", `-5`, int64(-5)},
+		{"large int", `1700000000000`, int64(1700000000000)},
+		{"float", `1.5`, 1.5},
+		{"string", `"hello"`, "hello"},
+		{"long string", `"` + long + `"`, long},
+		{"array", `[1,"a",[]]`, []any{int64(1), "a", []any{}}},
+		{"object", `{"b":{"c":false},"a":null}`, map[string]any{"a": nil, "b": map[string]any{"c": false}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := json.NewDecoder(bytes.NewReader([]byte(tt.value)))
+			decoder.UseNumber()
+			var v any
+			if err := decoder.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := unpack(bufio.NewReader(bytes.NewReader(pack(nil, v))))
+			if err != nil {
+				t.Fatalf("unpack() returned unexpected error: %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("pack(%s) decodes to %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// forwardInput accepts one connection and hands the messages it reads to
+// messages, answering each with the ack returned by respond, if any. It drops
+// the connection when respond returns nil.
+func forwardInput(t *testing.T, respond func(chunk string) []byte) (int, chan []any) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	messages := make(chan []any, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			v, err := unpack(r)
+			if err != nil {
+				return
+			}
+			msg := v.([]any)
+			messages <- msg
+			options := msg[2].(map[string]any)
+			if chunk, ok := options["chunk"].(string); ok && respond != nil {
+				ack := respond(chunk)
+				if ack == nil {
+					return
+				}
+				conn.Write(ack)
+			}
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port, messages
+}
+
+func receiveForward(t *testing.T, messages chan []any) []any {
+	t.Helper()
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no forward message received")
+		return nil
+	}
+}
+
+func TestForwardConn_Write(t *testing.T) {
+	port, messages := forwardInput(t, nil)
+	conn, err := dialForward(t.Context(), "127.0.0.1", port, time.Second, nil, &ForwardConfig{Tag: "lagoon.drupal"}, nil)
+	if err != nil {
+		t.Fatalf("dialForward() returned unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	batch := "{\"@timestamp\":\"2024-05-01T10:00:00.123456789Z\",\"message\":\"first\",\"seq\":1}\nnot json\n"
+	if n, err := conn.Write([]byte(batch)); err != nil || n != len(batch) {
+		t.Fatalf("Write() = %d, %v, want %d bytes written", n, err, len(batch))
+	}
+
+	msg := receiveForward(t, messages)
+	if msg[0] != "lagoon.drupal" {
+		t.Errorf("tag = %v, want lagoon.drupal", msg[0])
+	}
+	entries := msg[1].([]any)
+	if len(entries) != 2 {
+		t.Fatalf("message has %d entries, want 2", len(entries))
+	}
+	first := entries[0].([]any)
+	if want := time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.UTC); !first[0].(time.Time).Equal(want) {
+		t.Errorf("time = %v, want %v", first[0], want)
+	}
+	if record := first[1].(map[string]any); record["message"] != "first" || record["seq"] != int64(1) {
+		t.Errorf("record = %v, want the first event", record)
+	}
+	if record := entries[1].([]any)[1].(map[string]any); record["message"] != "not json" {
+		t.Errorf("record = %v, want the line as the message", record)
+	}
+	if size := msg[2].(map[string]any)["size"]; size != int64(2) {
+		t.Errorf("size = %v, want 2", size)
+	}
+}
+
+func TestForwardConn_Ack(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(chunk string) []byte
+		fails   bool
+	}{
+		{"acknowledged", func(chunk string) []byte {
+			return pack(nil, map[string]any{"ack": chunk})
+		}, false},
+		{"wrong chunk", func(string) []byte {
+			return pack(nil, map[string]any{"ack": "other"})
+		}, true},
+		{"closed", func(string) []byte { return nil }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, _ := forwardInput(t, tt.respond)
+			conn, err := dialForward(t.Context(), "127.0.0.1", port, time.Second, nil, &ForwardConfig{RequireAck: true}, nil)
+			if err != nil {
+				t.Fatalf("dialForward() returned unexpected error: %v", err)
+			}
+			defer conn.Close()
+
+			_, err = conn.Write([]byte("{\"message\":\"acked\"}\n"))
+			if (err != nil) != tt.fails {
+				t.Errorf("Write() error = %v, want failure %v", err, tt.fails)
+			}
+		})
+	}
+}
+
+func TestDial_Forward(t *testing.T) {
+	port, messages := forwardInput(t, nil)
+
+	cfg := NewConfig()
+	cfg.Protocol = ProtocolForward
+	cfg.LogHost = "127.0.0.1"
+	cfg.LogPort = port
+	w, err := Dial(cfg)
+	if err != nil {
+		t.Fatalf("Dial() returned unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte(`{"message":"dialled"}` + "\n")); err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+	if msg := receiveForward(t, messages); msg[0] != defaultForwardTag {
+		t.Errorf("tag = %v, want %s", msg[0], defaultForwardTag)
+	}
+}
@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// goldenVersions are the message versions whose wire format is frozen
+var goldenVersions = []int{1, 3}
+
+// goldenTime is the timestamp of every golden record
+var goldenTime = time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.UTC)
+
+// goldenRecords are representative records, logged through a handler built
+// from the golden configuration
+var goldenRecords = []struct {
+	name string
+	log  func(h slog.Handler) error
+}{
+	{"message", func(h slog.Handler) error {
+		return h.Handle(context.Background(), slog.NewRecord(goldenTime, slog.LevelInfo, "hello world", 0))
+	}},
+	{"attrs", func(h slog.Handler) error {
+		r := slog.NewRecord(goldenTime, slog.LevelWarn, "attribute types", 0)
+		r.AddAttrs(
+			slog.String("string", "value"),
+			slog.Int("int", -42),
+			slog.Uint64("uint", 42),
+			slog.Float64("float", 1.5),
+			slog.Bool("bool", true),
+			slog.Duration("duration", 1500*time.Millisecond),
+			slog.Time("started_at", goldenTime.Add(time.Hour)),
+			slog.Any("nil", nil),
+			slog.Any("slice", []string{"a", "b"}),
+			slog.Any("map", map[string]int{"b": 2, "a": 1}),
+		)
+		return h.Handle(context.Background(), r)
+	}},
+	{"groups", func(h slog.Handler) error {
+		scoped := h.WithAttrs([]slog.Attr{slog.String("request_id", "abc-123")}).WithGroup("http")
+		r := slog.NewRecord(goldenTime, slog.LevelInfo, "request handled", 0)
+		r.AddAttrs(slog.Int("status", 200), slog.Group("route", slog.String("path", "/users/{id}")))
+		return scoped.Handle(context.Background(), r)
+	}},
+	{"error", func(h slog.Handler) error {
+		r := slog.NewRecord(goldenTime, slog.LevelError, "request failed", 0)
+		r.AddAttrs(slog.Any("error", errors.New("connection refused")))
+		return h.Handle(context.Background(), r)
+	}},
+	{"debug", func(h slog.Handler) error {
+		return h.Handle(context.Background(), slog.NewRecord(goldenTime, slog.LevelDebug, "low level detail", 0))
+	}},
+	{"timestamp_override", func(h slog.Handler) error {
+		r := slog.NewRecord(goldenTime, slog.LevelInfo, "historic event", 0)
+		r.AddAttrs(slog.String("timestampOverride", "2020-01-01T00:00:00Z"))
+		return h.Handle(context.Background(), r)
+	}},
+}
+
+// TestGolden freezes the exact bytes of every golden record per message
+// version. Intended format changes are recorded with
+//
+//	go test -run TestGolden -update .
+func TestGolden(t *testing.T) {
+	preserveConfig(t)
+	hostname = "golden-host"
+
+	for _, version := range goldenVersions {
+		for _, record := range goldenRecords {
+			t.Run(fmt.Sprintf("v%d/%s", version, record.name), func(t *testing.T) {
+				cfg := NewConfig()
+				cfg.LogType = "golden-type"
+				cfg.ApplicationName = "golden-app"
+				cfg.AddSource = false
+				cfg.LogHost = "localhost"
+				cfg.MessageVersion = version
+				if err := config(cfg); err != nil {
+					t.Fatalf("config() returned unexpected error: %v", err)
+				}
+
+				var buf bytes.Buffer
+				if err := record.log(newHandler(&buf)); err != nil {
+					t.Fatalf("Handle() returned unexpected error: %v", err)
+				}
+
+				path := filepath.Join("testdata", "golden", fmt.Sprintf("v%d", version), record.name+".json")
+				if *update {
+					if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				want, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("read golden file: %v (run with -update to create it)", err)
+				}
+				if !bytes.Equal(buf.Bytes(), want) {
+					t.Errorf("wire format of %s changed\n got: %s\nwant: %s", path, buf.Bytes(), want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// goroutineStore holds the []slog.Attr set on goroutines by ID
+	goroutineStore sync.Map
+	// goroutinesSet counts the goroutines with attributes, so records of
+	// applications not using them never look up their goroutine
+	goroutinesSet atomic.Int64
+)
+
+// goroutineID returns the ID of the calling goroutine, read from the header
+// of its stack trace since the runtime doesn't expose it
+func goroutineID() uint64 {
+	var buf [64]byte
+	stack := buf[:runtime.Stack(buf[:], false)]
+	stack = bytes.TrimPrefix(stack, []byte("goroutine "))
+	stack, _, _ = bytes.Cut(stack, []byte(" "))
+	id, _ := strconv.ParseUint(string(stack), 10, 64)
+	return id
+}
+
+// SetGoroutineAttrs adds attrs to every record logged by the calling
+// goroutine, until the returned function restores its previous attributes.
+// A worker can so set the ID of a job once for code that doesn't get a
+// context to pass to NewContext:
+//
+//	defer logger.SetGoroutineAttrs("job_id", job.ID)()
+//
+// The attributes are not inherited by goroutines the goroutine starts, see
+// Go. The restore function must be called on the same goroutine.
+func SetGoroutineAttrs(attrs ...any) (restore func()) {
+	return setGoroutineAttrs(goroutineID(), slog.Group("", attrs...).Value.Group())
+}
+
+// setGoroutineAttrs adds attrs to those of goroutine id
+func setGoroutineAttrs(id uint64, attrs []slog.Attr) (restore func()) {
+	previous := goroutineAttrsOf(id)
+	storeGoroutineAttrs(id, append(slices.Clip(previous), attrs...))
+	return func() { storeGoroutineAttrs(id, previous) }
+}
+
+// storeGoroutineAttrs replaces the attributes of goroutine id, forgetting
+// the goroutine when there are none
+func storeGoroutineAttrs(id uint64, attrs []slog.Attr) {
+	if len(attrs) == 0 {
+		if _, loaded := goroutineStore.LoadAndDelete(id); loaded {
+			goroutinesSet.Add(-1)
+		}
+		return
+	}
+	if _, loaded := goroutineStore.Swap(id, attrs); !loaded {
+		goroutinesSet.Add(1)
+	}
+}
+
+// goroutineAttrsOf returns the attributes of goroutine id, which must not
+// be modified
+func goroutineAttrsOf(id uint64) []slog.Attr {
+	if attrs, ok := goroutineStore.Load(id); ok {
+		return attrs.([]slog.Attr)
+	}
+	return nil
+}
+
+// GoroutineAttrs returns a copy of the attributes set on the calling
+// goroutine with SetGoroutineAttrs
+func GoroutineAttrs() []slog.Attr {
+	if goroutinesSet.Load() == 0 {
+		return nil
+	}
+	return slices.Clone(goroutineAttrsOf(goroutineID()))
+}
+
+// Go runs fn in a new goroutine carrying the attributes of the calling
+// goroutine, as a worker pool would hand a job to a worker
+func Go(fn func()) {
+	attrs := GoroutineAttrs()
+	go func() {
+		if len(attrs) > 0 {
+			defer setGoroutineAttrs(goroutineID(), attrs)()
+		}
+		fn()
+	}()
+}
+
+// ContextWithGoroutineAttrs returns a copy of ctx whose logger adds the
+// attributes of the calling goroutine, so they reach code running on other
+// goroutines that logs with FromContext or the Context functions
+func ContextWithGoroutineAttrs(ctx context.Context) context.Context {
+	attrs := GoroutineAttrs()
+	if len(attrs) == 0 {
+		return ctx
+	}
+	args := make([]any, len(attrs))
+	for i, a := range attrs {
+		args[i] = a
+	}
+	return NewContext(ctx, args...)
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSetGoroutineAttrs(t *testing.T) {
+	preserveConfig(t)
+
+	var buf bytes.Buffer
+	logger := slog.New(newSinkHandler(sink{w: &buf}))
+
+	restore := SetGoroutineAttrs("job_id", "job-1")
+	inner := SetGoroutineAttrs(slog.String("step", "resize"))
+	logger.WithGroup("image").Info("processing", "width", 800)
+	inner()
+	logger.Info("done")
+	restore()
+	logger.Info("idle")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d records, want 3: %s", len(lines), buf.String())
+	}
+	for i, want := range []string{
+		`"job_id":"job-1","step":"resize","image":{"width":800}`,
+		`"job_id":"job-1"}`,
+	} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("record %d = %s, want %s", i, lines[i], want)
+		}
+	}
+	if strings.Contains(lines[2], "job_id") {
+		t.Errorf("record after restore = %s, want no goroutine attributes", lines[2])
+	}
+	if n := goroutinesSet.Load(); n != 0 {
+		t.Errorf("%d goroutines still have attributes after restore", n)
+	}
+}
+
+func TestSetGoroutineAttrs_OtherGoroutines(t *testing.T) {
+	preserveConfig(t)
+
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	logger := slog.New(newSinkHandler(sink{w: &lockedWriter{w: &buf, mu: &mu}}))
+	defer SetGoroutineAttrs("job_id", "job-1")()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		logger.Info("plain goroutine")
+	}()
+	Go(func() {
+		defer wg.Done()
+		logger.Info("started with Go")
+	})
+	wg.Wait()
+
+	events := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event struct {
+			Message string `json:"message"`
+			JobID   string `json:"job_id"`
+		}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		events[event.Message] = event.JobID
+	}
+	want := map[string]string{"plain goroutine": "", "started with Go": "job-1"}
+	for msg, jobID := range want {
+		if events[msg] != jobID {
+			t.Errorf("%q has job_id %q, want %q", msg, events[msg], jobID)
+		}
+	}
+}
+
+func TestContextWithGoroutineAttrs(t *testing.T) {
+	if ctx := context.Background(); ContextWithGoroutineAttrs(ctx) != ctx {
+		t.Error("ContextWithGoroutineAttrs() without attributes returned a new context")
+	}
+
+	defer SetGoroutineAttrs("job_id", "job-1")()
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, nil)
+	previous := slog.Default()
+	slog.SetDefault(slog.New(h))
+	defer slog.SetDefault(previous)
+
+	FromContext(ContextWithGoroutineAttrs(context.Background())).Info("handed off")
+	if !strings.Contains(buf.String(), `"job_id":"job-1"`) {
+		t.Errorf("record = %s, want the attributes of the goroutine", buf.String())
+	}
+}
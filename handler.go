@@ -0,0 +1,333 @@
+package logger
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// handler wraps the Lagoon JSON handler and resolves WithAttrs and WithGroup
+// itself, so that attributes computed per record can be added at the top
+// level of the event regardless of the groups a logger has opened
+type handler struct {
+	leveler slog.Leveler
+	// encoders format records in the format of each sink before they are
+	// written to it with the context of the logging call
+	encoders map[string]*sync.Pool
+	sinks    []sink
+	scope    []groupOrAttrs
+	limits   attrLimits
+	values   valuePolicy
+	strings  stringPolicy
+	compress fieldCompression
+	// schedule samples records during its windows, nil keeps all
+	schedule *schedule
+	// source is the representation of the caller added by the handler, empty
+	// when slog adds it or AddSource is off
+	source string
+	// frames moves the source past the frames it skips, nil reports the
+	// caller of the logger
+	frames frameFilter
+	// truncation is the strategy fitting events into the size limit of a
+	// sink, TruncateMessage when empty
+	truncation string
+	// control is the treatment of control characters in messages,
+	// ControlKeep when empty
+	control string
+	// processors change or drop records before anything else is done
+	processors []Processor
+	// hooks are called with the records at or above their levels once they
+	// are written
+	hooks levelHooks
+	// computed are the attributes evaluated per record after the processors
+	computed computedAttrs
+	// routes choose the sinks of the records of some levels
+	routes levelRoutes
+	// spans returns the trace and span IDs of the context of a record, nil
+	// adds none
+	spans func(ctx context.Context) (traceID, spanID string)
+	// strict checks JSON events against the schema of their version before
+	// they are written
+	strict bool
+}
+
+// groupOrAttrs is a single WithGroup or WithAttrs call
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// sink is a destination of the handler, filtering records by its own level
+// on top of the handler's
+type sink struct {
+	// name identifies the sink in RecentErrors, empty records no errors
+	name string
+	w    io.Writer
+	// format is the encoding written to w, FormatJSON when empty
+	format string
+	// level is the minimum level written to w, nil writes every record
+	level slog.Leveler
+	// maxBytes is the size limit of an event written to w, 0 is unlimited
+	maxBytes int
+	// limit drops events written to w beyond its rate, nil writes all
+	limit *rateLimiter
+}
+
+func (s sink) accepts(level slog.Level) bool {
+	return s.level == nil || level >= s.level.Level()
+}
+
+// newJSONHandler returns a handler writing records formatted with opts and
+// the static attrs to the sinks accepting their level
+func newJSONHandler(sinks []sink, opts *slog.HandlerOptions, attrs []any) *handler {
+	var leveler slog.Leveler = slog.LevelInfo
+	if opts.Level != nil {
+		leveler = opts.Level
+	}
+
+	serialized := make([]sink, len(sinks))
+	encoders := map[string]*sync.Pool{}
+	for i, s := range sinks {
+		// writers of the emit path are safe for concurrent use, others are
+		// serialized like slog would
+		if _, ok := s.w.(contextWriter); !ok {
+			s.w = &serialWriter{w: s.w}
+		}
+		if len(s.format) == 0 {
+			s.format = FormatJSON
+		}
+		if _, ok := encoders[s.format]; !ok {
+			encoders[s.format] = newEncoders(s.format, opts, attrs)
+		}
+		serialized[i] = s
+	}
+	return &handler{leveler: leveler, encoders: encoders, sinks: serialized}
+}
+
+// Enabled only reads levels, so it can guard hot paths without allocating
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	if level < h.leveler.Level() {
+		return false
+	}
+	for _, s := range h.sinks {
+		if s.accepts(level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if !overrideKeep(r.Level) || (h.schedule != nil && !h.schedule.keep(r.Level)) {
+		return nil
+	}
+	attrs := h.resolve(r)
+	if len(h.processors) > 0 {
+		var keep bool
+		if r, attrs, keep = process(ctx, h.processors, r, attrs); !keep {
+			return nil
+		}
+	}
+	attrs = h.computed.apply(r.Message, r.Level, r.Time, attrs)
+
+	pc := r.PC
+	if h.frames != nil && pc != 0 {
+		pc = h.frames.caller(pc)
+	}
+	out := slog.NewRecord(r.Time, r.Level, h.strings.apply(cleanMessage(r.Message, h.control)), pc)
+	base := recordAttrs()
+	out.AddAttrs(base...)
+
+	attrs, dropped := h.limits.apply(h.strings.applyAttrs(h.values.applyAttrs(attrs)))
+	attrs = h.compress.apply(attrs)
+	if len(h.source) > 0 && pc != 0 {
+		attrs = addSourceAttrs(attrs, h.source, pc)
+	}
+	if team := ownerOf(pc); len(team) > 0 {
+		attrs = append(attrs, slog.String(ownerKey, team))
+	}
+	if h.spans != nil {
+		traceID, spanID := h.spans(ctx)
+		attrs = appendSpanAttrs(attrs, traceID, spanID)
+	}
+	if dropped > 0 {
+		attrs = append(attrs, slog.Int(truncatedKey, dropped))
+	}
+	out.AddAttrs(attrs...)
+
+	// each format is encoded once, for the first sink written in it
+	var encoded []*encoder
+	defer func() {
+		for _, e := range encoded {
+			if e.buf.Cap() <= maxPooledBuffer {
+				h.encoders[e.format].Put(e)
+			}
+		}
+	}()
+
+	// sinks fail independently, a blocked one gives up once ctx is done
+	var err error
+	for _, s := range h.sinks {
+		if !s.accepts(r.Level) || !h.routes.allows(r.Level, s.name) {
+			continue
+		}
+		allowed, limited := s.limit.allow()
+		if !allowed {
+			countDropped(s.name, 1)
+			continue
+		}
+		if limited > 0 {
+			// the events dropped are summarized once the bucket refills
+			if summary, serr := h.encodeRecord(ctx, s.format, droppedRecord(limited)); serr == nil {
+				_, _ = writeContext(ctx, s.w, summary)
+			}
+		}
+		ectx, encoding := startPhase(ctx, phaseEncode, s.name)
+		e, eerr := h.encode(ectx, s.format, out, &encoded)
+		if eerr != nil {
+			encoding.end()
+			err = cmp.Or(err, eerr)
+			continue
+		}
+		events := [][]byte{e.buf.Bytes()}
+		if s.maxBytes > 0 && e.buf.Len() > s.maxBytes {
+			events, eerr = h.fit(ectx, s.format, s.maxBytes, oversized{record: out, base: base, attrs: attrs})
+		}
+		encoding.end()
+		if eerr != nil {
+			err = cmp.Or(err, eerr)
+			continue
+		}
+
+		wctx, writing := startPhase(ctx, phaseWrite, s.name)
+		for _, event := range events {
+			if h.strict && s.format == FormatJSON {
+				if verr := ValidateEvent(event); verr != nil {
+					diag().Error("Event violates the schema, it is not written", "sink", s.name, "error", verr)
+					err = cmp.Or(err, verr)
+					continue
+				}
+			}
+			_, werr := writeContext(wctx, s.w, event)
+			if werr != nil && ctx.Err() == nil && len(s.name) > 0 {
+				recordError(s.name, OpWrite, werr)
+			}
+			err = cmp.Or(err, werr)
+		}
+		writing.end()
+	}
+
+	if h.hooks.match(r.Level) {
+		// hooks get the attributes of the record without the Lagoon fields
+		hooked := slog.NewRecord(r.Time, r.Level, out.Message, pc)
+		hooked.AddAttrs(attrs...)
+		h.hooks.call(hooked)
+	}
+	return err
+}
+
+// encode returns r encoded in format, reusing the encoding of an earlier
+// sink when there is one in encoded
+func (h *handler) encode(ctx context.Context, format string, r slog.Record, encoded *[]*encoder) (*encoder, error) {
+	for _, e := range *encoded {
+		if e.format == format {
+			return e, nil
+		}
+	}
+
+	e := h.encoders[format].Get().(*encoder)
+	e.buf.Reset()
+	if err := e.handler.Handle(ctx, r); err != nil {
+		// the encoder is dropped so later sinks don't reuse its partial
+		// encoding
+		return nil, err
+	}
+	*encoded = append(*encoded, e)
+	return e, nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{attrs: cloneAttrs(attrs)})
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if len(name) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+// with returns a copy of h with g appended to its scope. The scope slice is
+// copied so handlers derived from the same parent never share it.
+func (h *handler) with(g groupOrAttrs) *handler {
+	scope := make([]groupOrAttrs, len(h.scope), len(h.scope)+1)
+	copy(scope, h.scope)
+	c := *h
+	c.scope = append(scope, g)
+	return &c
+}
+
+// cloneAttrs returns a copy of attrs and the members of their groups. The
+// scope of a handler is shared by every logger derived from it, so it keeps
+// its own copy rather than a slice the caller may reuse, e.g. a buffer of
+// attributes appended to for each derived logger.
+func cloneAttrs(attrs []slog.Attr) []slog.Attr {
+	clone := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		if a.Value.Kind() == slog.KindGroup {
+			a.Value = slog.GroupValue(cloneAttrs(a.Value.Group())...)
+		}
+		clone[i] = a
+	}
+	return clone
+}
+
+// resolve nests the record attributes inside the handler's scope, giving the
+// same structure slog would produce from the original WithAttrs and
+// WithGroup calls
+func (h *handler) resolve(r slog.Record) []slog.Attr {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	for i := len(h.scope) - 1; i >= 0; i-- {
+		g := h.scope[i]
+		if len(g.group) > 0 {
+			if len(attrs) == 0 {
+				continue
+			}
+			attrs = []slog.Attr{{Key: g.group, Value: slog.GroupValue(attrs...)}}
+			continue
+		}
+		attrs = append(append(make([]slog.Attr, 0, len(g.attrs)+len(attrs)), g.attrs...), attrs...)
+	}
+
+	// the attributes of the goroutine come before those of the logger, as
+	// if it was created with them
+	if goroutinesSet.Load() > 0 {
+		if g := goroutineAttrsOf(goroutineID()); len(g) > 0 {
+			attrs = append(slices.Clip(g), attrs...)
+		}
+	}
+
+	return attrs
+}
+
+// recordAttrs returns the attributes computed for every record
+func recordAttrs() []slog.Attr {
+	var attrs []slog.Attr
+
+	if skew, ok := clockSkew(); ok {
+		attrs = append(attrs, slog.Group("clock", slog.Int64("skew_ms", skew.Milliseconds())))
+	}
+
+	return attrs
+}
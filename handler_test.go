@@ -0,0 +1,338 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// dropTime removes the time so outputs can be compared
+func dropTime(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.TimeKey {
+		return slog.Attr{}
+	}
+	return a
+}
+
+func TestHandler_MatchesSlogScoping(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(*slog.Logger) *slog.Logger
+		args  []any
+	}{
+		{"no scope", func(l *slog.Logger) *slog.Logger { return l }, []any{"a", 1}},
+		{"attrs", func(l *slog.Logger) *slog.Logger { return l.With("a", 1) }, []any{"b", 2}},
+		{"group", func(l *slog.Logger) *slog.Logger { return l.WithGroup("g") }, []any{"b", 2}},
+		{"attrs then group", func(l *slog.Logger) *slog.Logger { return l.With("a", 1).WithGroup("g").With("c", 3) }, []any{"b", 2}},
+		{"nested groups", func(l *slog.Logger) *slog.Logger { return l.WithGroup("g1").With("a", 1).WithGroup("g2") }, []any{"b", 2}},
+		{"empty group", func(l *slog.Logger) *slog.Logger { return l.With("a", 1).WithGroup("g") }, nil},
+		{"empty nested group", func(l *slog.Logger) *slog.Logger { return l.WithGroup("g1").With("a", 1).WithGroup("g2") }, nil},
+		{"record group", func(l *slog.Logger) *slog.Logger { return l.WithGroup("g") }, []any{slog.Group("r", "x", 1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &slog.HandlerOptions{ReplaceAttr: dropTime}
+
+			var want, got bytes.Buffer
+			tt.build(slog.New(slog.NewJSONHandler(&want, opts))).Info("msg", tt.args...)
+			tt.build(slog.New(newJSONHandler([]sink{{w: &got}}, opts, nil))).Info("msg", tt.args...)
+
+			if got.String() != want.String() {
+				t.Errorf("handler output = %s, want %s", got.String(), want.String())
+			}
+		})
+	}
+}
+
+func TestHandler_DerivedScopesAreIndependent(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	base := slog.New(newJSONHandler([]sink{{w: &lockedWriter{w: &buf, mu: &mu}}}, &slog.HandlerOptions{ReplaceAttr: dropTime}, nil)).With("shared", 1)
+
+	a := base.With("only", "a")
+	b := base.With("only", "b")
+	a.Info("from a")
+	b.Info("from b")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if !strings.Contains(lines[0], `"only":"a"`) || strings.Contains(lines[0], `"only":"b"`) {
+		t.Errorf("logger a output = %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"only":"b"`) || strings.Contains(lines[1], `"only":"a"`) {
+		t.Errorf("logger b output = %s", lines[1])
+	}
+}
+
+func TestHandler_WithAttrsKeepsItsCopy(t *testing.T) {
+	var buf bytes.Buffer
+	h := newJSONHandler([]sink{{w: &buf}}, &slog.HandlerOptions{ReplaceAttr: dropTime}, nil)
+
+	// a caller reusing its buffer of attributes for the next derived logger
+	members := []slog.Attr{slog.String("id", "r-1")}
+	attrs := []slog.Attr{slog.String("user", "alice"), slog.Attr{Key: "request", Value: slog.GroupValue(members...)}}
+	derived := slog.New(h.WithAttrs(attrs))
+	attrs[0] = slog.String("user", "bob")
+	members[0] = slog.String("id", "r-2")
+
+	derived.Info("msg")
+	want := `{"level":"INFO","msg":"msg","user":"alice","request":{"id":"r-1"}}`
+	if strings.TrimSpace(buf.String()) != want {
+		t.Errorf("handler output = %s, want %s", buf.String(), want)
+	}
+}
+
+func TestHandler_ConcurrentDerivation(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	h := newJSONHandler([]sink{{w: &lockedWriter{w: &buf, mu: &mu}}}, &slog.HandlerOptions{ReplaceAttr: dropTime}, nil)
+	previous := slog.Default()
+	slog.SetDefault(slog.New(h).With("shared", 1).WithGroup("scope"))
+	defer slog.SetDefault(previous)
+
+	const workers, records = 16, 50
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range records {
+				logger := slog.Default().With("worker", worker).WithGroup("g").With("n", n)
+				logger.Info("derived", "check", fmt.Sprintf("%d/%d", worker, n))
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != workers*records {
+		t.Fatalf("got %d lines, want %d", len(lines), workers*records)
+	}
+	for _, line := range lines {
+		var record struct {
+			Shared int `json:"shared"`
+			Scope  struct {
+				Worker int `json:"worker"`
+				G      struct {
+					N     int    `json:"n"`
+					Check string `json:"check"`
+				} `json:"g"`
+			} `json:"scope"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		scope := record.Scope
+		if record.Shared != 1 || fmt.Sprintf("%d/%d", scope.Worker, scope.G.N) != scope.G.Check {
+			t.Errorf("attributes of another logger in %s", line)
+		}
+	}
+}
+
+func TestHandler_RecordAttrsAtTopLevel(t *testing.T) {
+	defer resetSkew()
+	measuredSkew.Store(&skewMeasurement{offset: 1500 * time.Millisecond})
+
+	var buf bytes.Buffer
+	logger := slog.New(newJSONHandler([]sink{{w: &buf}}, &slog.HandlerOptions{ReplaceAttr: dropTime}, nil))
+	logger.WithGroup("g").Info("msg", "a", 1)
+
+	want := `{"level":"INFO","msg":"msg","clock":{"skew_ms":1500},"g":{"a":1}}`
+	if strings.TrimSpace(buf.String()) != want {
+		t.Errorf("handler output = %s, want %s", buf.String(), want)
+	}
+}
+
+func TestHandler_SinkLevels(t *testing.T) {
+	var stdout, forwarded bytes.Buffer
+	h := newJSONHandler([]sink{
+		{w: &stdout},
+		{w: &forwarded, level: slog.LevelInfo},
+	}, &slog.HandlerOptions{Level: slog.LevelDebug}, nil)
+	logger := slog.New(h)
+
+	logger.Debug("local only")
+	logger.Info("everywhere")
+
+	if !strings.Contains(stdout.String(), "local only") || !strings.Contains(stdout.String(), "everywhere") {
+		t.Errorf("stdout = %q, want both records", stdout.String())
+	}
+	if strings.Contains(forwarded.String(), "local only") || !strings.Contains(forwarded.String(), "everywhere") {
+		t.Errorf("forwarded = %q, want only the info record", forwarded.String())
+	}
+
+	h = newJSONHandler([]sink{{w: &stdout, level: slog.LevelWarn}}, &slog.HandlerOptions{Level: slog.LevelDebug}, nil)
+	if h.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("Enabled() = true for a level no sink accepts")
+	}
+}
+
+func TestHandler_SinkFormats(t *testing.T) {
+	var stdout, forwarded bytes.Buffer
+	h := newJSONHandler([]sink{
+		{w: &stdout, format: FormatText},
+		{w: &forwarded},
+	}, &slog.HandlerOptions{ReplaceAttr: dropTime}, []any{slog.String("type", "t")})
+	slog.New(h).Info("msg", "a", 1)
+
+	if got, want := stdout.String(), "level=INFO msg=msg type=t a=1\n"; got != want {
+		t.Errorf("text sink = %q, want %q", got, want)
+	}
+	if got, want := forwarded.String(), `{"level":"INFO","msg":"msg","type":"t","a":1}`+"\n"; got != want {
+		t.Errorf("json sink = %q, want %q", got, want)
+	}
+}
+
+// failOnce fails the first record encoded by any encoder sharing failed,
+// after writing part of it
+type failOnce struct {
+	slog.Handler
+	buf    *bytes.Buffer
+	failed *bool
+}
+
+func (h *failOnce) Handle(ctx context.Context, r slog.Record) error {
+	if !*h.failed {
+		*h.failed = true
+		h.buf.WriteString("partial")
+		return errors.New("encode failed")
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func TestHandler_EncodeErrors(t *testing.T) {
+	var first, forwarded, last bytes.Buffer
+	h := newJSONHandler([]sink{
+		{w: &first, format: FormatText},
+		{w: &forwarded},
+		{w: &last, format: FormatText},
+	}, &slog.HandlerOptions{ReplaceAttr: dropTime}, nil)
+	var failed bool
+	h.encoders[FormatText].New = func() any {
+		e := &encoder{format: FormatText}
+		e.handler = &failOnce{Handler: slog.NewTextHandler(&e.buf, &slog.HandlerOptions{ReplaceAttr: dropTime}), buf: &e.buf, failed: &failed}
+		return e
+	}
+
+	err := h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0))
+	if err == nil || err.Error() != "encode failed" {
+		t.Errorf("Handle() error = %v, want the encoding error", err)
+	}
+	if first.Len() != 0 {
+		t.Errorf("failed sink = %q, want nothing written", first.String())
+	}
+	// the sinks after the failed one are still written, without reusing its
+	// partial encoding
+	if !strings.Contains(forwarded.String(), `"msg":"msg"`) {
+		t.Errorf("json sink = %q, want the record", forwarded.String())
+	}
+	if got, want := last.String(), "level=INFO msg=msg\n"; got != want {
+		t.Errorf("text sink = %q, want %q", got, want)
+	}
+}
+
+func TestHandler_HonorsContext(t *testing.T) {
+	block := make(chan struct{})
+	pool := newPolicyPool(DeliveryAtLeastOnce, 1, 1, func() (io.WriteCloser, error) {
+		<-block
+		return &recordingConn{}, nil
+	})
+	defer func() {
+		close(block)
+		pool.Close()
+	}()
+
+	h := newJSONHandler([]sink{{w: io.Discard}, {w: pool}}, &slog.HandlerOptions{}, nil)
+	logger := slog.New(h)
+	logger.Info("in flight")
+	logger.Info("queued")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "blocked", 0))
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Handle() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Handle() should return once the context is done")
+	}
+	if pool.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", pool.Dropped())
+	}
+}
+
+// lockedWriter serializes writes to a shared buffer
+type lockedWriter struct {
+	w  *bytes.Buffer
+	mu *sync.Mutex
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// countedValue counts how often it is resolved
+type countedValue struct{ n *atomic.Int64 }
+
+func (v countedValue) LogValue() slog.Value {
+	v.n.Add(1)
+	return slog.StringValue("static")
+}
+
+func TestNewEncoders_PreformatsStaticAttrs(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatText, FormatSyslog} {
+		t.Run(format, func(t *testing.T) {
+			var resolved atomic.Int64
+			pool := newEncoders(format, &slog.HandlerOptions{}, []any{"static", countedValue{&resolved}})
+			e := pool.Get().(*encoder)
+
+			for i := 0; i < 3; i++ {
+				e.buf.Reset()
+				if err := e.handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0)); err != nil {
+					t.Fatalf("Handle() returned unexpected error: %v", err)
+				}
+				if !strings.Contains(e.buf.String(), "static") {
+					t.Errorf("record %d = %q, want the static attr", i, e.buf.String())
+				}
+			}
+			if n := resolved.Load(); n != 1 {
+				t.Errorf("static attr encoded %d times for 3 records, want once per encoder", n)
+			}
+		})
+	}
+}
+
+func BenchmarkHandler(b *testing.B) {
+	preserveConfig(b)
+	lagoonMetadata = true
+	h := newSinkHandler(sink{w: io.Discard}, sink{w: io.Discard, format: FormatText})
+	logger := slog.New(h)
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info("request handled", "status", 200)
+		}
+	})
+}
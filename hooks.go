@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+)
+
+// levelHook is a function of OnLevel and its level
+type levelHook struct {
+	level slog.Level
+	fn    func(slog.Record)
+}
+
+// levelHooks are the functions of OnLevel, the lowest level first
+type levelHooks []levelHook
+
+// newLevelHooks returns the functions of onLevel ordered by level
+func newLevelHooks(onLevel map[slog.Level]func(slog.Record)) levelHooks {
+	hooks := make(levelHooks, 0, len(onLevel))
+	for level, fn := range onLevel {
+		hooks = append(hooks, levelHook{level: level, fn: fn})
+	}
+	slices.SortFunc(hooks, func(a, b levelHook) int { return cmp.Compare(a.level, b.level) })
+	return hooks
+}
+
+// match reports whether a record at level calls a hook, so the record is only
+// built for those that do
+func (hs levelHooks) match(level slog.Level) bool {
+	return len(hs) > 0 && level >= hs[0].level
+}
+
+// call calls the hooks at or below the level of r, each with its own copy
+// of r so one keeping it never sees the changes of another
+func (hs levelHooks) call(r slog.Record) {
+	for _, h := range hs {
+		if r.Level < h.level {
+			return
+		}
+		h.fn(r.Clone())
+	}
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_OnLevel(t *testing.T) {
+	preserveConfig(t)
+	var calls []string
+	var written bool
+	var buf bytes.Buffer
+	onLevel = map[slog.Level]func(slog.Record){
+		slog.LevelError: func(r slog.Record) {
+			written = buf.Len() > 0
+			calls = append(calls, "error:"+r.Message)
+		},
+		LevelCritical: func(r slog.Record) {
+			var attrs []string
+			r.Attrs(func(a slog.Attr) bool {
+				attrs = append(attrs, a.String())
+				return true
+			})
+			calls = append(calls, "critical:"+strings.Join(attrs, ","))
+		},
+	}
+
+	logger := slog.New(newHandler(&buf)).With("request_id", "r1")
+	logger.Warn("slow query")
+	logger.Error("query failed")
+	logger.Log(context.Background(), LevelCritical, "database down", "retries", 3)
+
+	expected := []string{"error:query failed", "error:database down", "critical:request_id=r1,retries=3"}
+	if strings.Join(calls, " ") != strings.Join(expected, " ") {
+		t.Errorf("hooks called as %q, want %q", calls, expected)
+	}
+	if !written {
+		t.Error("the hook was called before the record was written")
+	}
+}
+
+func TestConfigValidate_OnLevel(t *testing.T) {
+	cfg := NewConfig()
+	cfg.LogType = "hooks-type"
+	cfg.OnLevel = map[slog.Level]func(slog.Record){slog.LevelError: nil}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "onLevel of ERROR") {
+		t.Errorf("Validate() = %v, want the nil hook rejected", err)
+	}
+}
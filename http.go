@@ -0,0 +1,276 @@
+package logger
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// httpAttempts is how often a request failing with a network error, 429
+	// or a 5xx status is sent
+	httpAttempts = 3
+	// httpBackoff is the delay before the first retry of a request, doubling
+	// for every further one
+	httpBackoff = 200 * time.Millisecond
+	// httpBatchBytes caps a batch posted in one request
+	httpBatchBytes = 1 << 20
+)
+
+// bulkAction precedes every event posted to the Elasticsearch bulk API, which
+// data streams require to be a create
+var bulkAction = []byte(`{"create":{}}` + "\n")
+
+// HTTPConfig configures ProtocolHTTP, which POSTs every write, a single event
+// or a batch with BatchSize, as newline-delimited JSON. Set TLS to use HTTPS.
+type HTTPConfig struct {
+	Path        string            `json:"path"`        // request path, e.g. "/_bulk", "/" when empty
+	Headers     map[string]string `json:"headers"`     // added to every request
+	Username    string            `json:"username"`    // basic auth
+	Password    string            `json:"password"`    // basic auth
+	BearerToken string            `json:"bearerToken"` // sent as an Authorization: Bearer header
+	Gzip        bool              `json:"gzip"`        // compresses request bodies
+	Bulk        bool              `json:"bulk"`        // prefixes every event with a create action for the Elasticsearch bulk API
+}
+
+func (c *HTTPConfig) validate() error {
+	if len(c.Path) > 0 && !strings.HasPrefix(c.Path, "/") {
+		return fmt.Errorf("http.path %q must start with /", c.Path)
+	}
+	if len(c.Password) > 0 && len(c.Username) == 0 {
+		return errors.New("http.password requires http.username")
+	}
+	if len(c.Username) > 0 && len(c.BearerToken) > 0 {
+		return errors.New("http.username and http.bearerToken are mutually exclusive")
+	}
+	for name, value := range c.Headers {
+		if len(name) == 0 || strings.ContainsAny(name, " :\r\n") || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid http header %q", name)
+		}
+	}
+	return nil
+}
+
+// dialHTTP returns a connection posting to the endpoint at host:port, over
+// HTTPS when settings are given
+func dialHTTP(ctx context.Context, host string, port int, writeTimeout time.Duration, settings *TLSConfig, web *HTTPConfig, resolver Resolver) (net.Conn, error) {
+	if web == nil {
+		web = &HTTPConfig{}
+	}
+	client, endpoint, err := newHTTPClient(ctx, host, port, writeTimeout, settings, resolver)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Path = cmp.Or(web.Path, "/")
+
+	return &httpConn{
+		client:   client,
+		url:      endpoint.String(),
+		settings: *web,
+		clock:    schedulerClock(),
+	}, nil
+}
+
+// newHTTPClient returns a client of the endpoint at host:port and its URL,
+// over HTTPS when settings are given. The endpoint is reachable when a
+// connection to it can be opened, which is closed again, as the client keeps
+// its own.
+func newHTTPClient(ctx context.Context, host string, port int, writeTimeout time.Duration, settings *TLSConfig, resolver Resolver) (*http.Client, url.URL, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: tcpKeepAlive}
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if resolver == nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		// addresses are tried in the order of the resolver
+		name, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := resolver.LookupHost(ctx, name)
+		traceResolved(name, addrs, err)
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s: %w", name, err)
+		}
+		err = fmt.Errorf("resolve %s: no addresses", name)
+		for _, a := range addrs {
+			var conn net.Conn
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(a, port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+
+	transport := &http.Transport{
+		DialContext:         dial,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	endpoint := url.URL{Scheme: "http", Host: net.JoinHostPort(host, strconv.Itoa(port))}
+	if settings != nil {
+		config, err := settings.ClientConfig(host)
+		if err != nil {
+			return nil, endpoint, err
+		}
+		transport.TLSClientConfig = config
+		endpoint.Scheme = "https"
+	}
+
+	conn, err := dial(ctx, "tcp", endpoint.Host)
+	if err != nil {
+		return nil, endpoint, fmt.Errorf("dial http: %w", err)
+	}
+	if transport.TLSClientConfig != nil {
+		tlsConn := tls.Client(conn, transport.TLSClientConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, endpoint, fmt.Errorf("dial https: %w", err)
+		}
+		conn = tlsConn
+	}
+	conn.Close()
+
+	return &http.Client{Transport: transport, Timeout: writeTimeout}, endpoint, nil
+}
+
+// httpConn posts every write as a request to the endpoint. It stands in for
+// a connection, so reconnecting, failover, batching and delivery workers
+// treat HTTP like the other transports.
+type httpConn struct {
+	client   *http.Client
+	url      string
+	settings HTTPConfig
+	clock    Clock
+}
+
+// Write posts p, retrying a request failing with a network error, 429 or a
+// 5xx status with exponential backoff. Other statuses fail at once, as
+// sending the same request again would not help.
+func (c *httpConn) Write(p []byte) (int, error) {
+	body := p
+	if c.settings.Bulk {
+		body = withBulkActions(body)
+	}
+	encoding := ""
+	if c.settings.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(body)
+		_ = zw.Close()
+		body, encoding = buf.Bytes(), "gzip"
+	}
+
+	if err := retryPost(c.clock, func() (bool, error) { return c.post(body, encoding) }); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// retryPost calls post until it succeeds, fails for good or httpAttempts are
+// made, backing off exponentially on clock
+func retryPost(clock Clock, post func() (retry bool, err error)) error {
+	var err error
+	for attempt := 0; attempt < httpAttempts; attempt++ {
+		if attempt > 0 {
+			<-clock.After(httpBackoff << (attempt - 1))
+		}
+		var retry bool
+		if retry, err = post(); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// post sends one request, reporting whether a failed one may be retried
+func (c *httpConn) post(body []byte, encoding string) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if len(encoding) > 0 {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	for name, value := range c.settings.Headers {
+		req.Header.Set(name, value)
+	}
+	switch {
+	case len(c.settings.Username) > 0:
+		req.SetBasicAuth(c.settings.Username, c.settings.Password)
+	case len(c.settings.BearerToken) > 0:
+		req.Header.Set("Authorization", "Bearer "+c.settings.BearerToken)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("post %s: %w", c.url, err)
+	}
+	// the body is drained so the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	return checkStatus(c.url, resp)
+}
+
+// checkStatus returns the error of a response with a failure status, and
+// whether the request may be retried: on 429 and 5xx statuses, as the same
+// request fails again for other statuses
+func checkStatus(url string, resp *http.Response) (bool, error) {
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("post %s: %s", url, resp.Status)
+	default:
+		return false, fmt.Errorf("post %s: %s", url, resp.Status)
+	}
+}
+
+// withBulkActions returns the newline-delimited events in p, each preceded by
+// the bulk action
+func withBulkActions(p []byte) []byte {
+	out := make([]byte, 0, len(p)+bytes.Count(p, []byte("\n"))*len(bulkAction)+len(bulkAction)+1)
+	for line := range bytes.Lines(p) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		out = append(out, bulkAction...)
+		out = append(out, line...)
+		if line[len(line)-1] != '\n' {
+			out = append(out, '\n')
+		}
+	}
+	return out
+}
+
+func (c *httpConn) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// the rest of net.Conn, which requests have no use for
+
+func (c *httpConn) Read([]byte) (int, error)         { return 0, io.EOF }
+func (c *httpConn) LocalAddr() net.Addr              { return httpAddr("") }
+func (c *httpConn) RemoteAddr() net.Addr             { return httpAddr(c.url) }
+func (c *httpConn) SetDeadline(time.Time) error      { return nil }
+func (c *httpConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *httpConn) SetWriteDeadline(time.Time) error { return nil }
+
+// httpAddr is the URL of an HTTP endpoint
+type httpAddr string
+
+func (a httpAddr) Network() string { return ProtocolHTTP }
+func (a httpAddr) String() string  { return string(a) }
@@ -0,0 +1,229 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// httpRequest is a request received by a test endpoint
+type httpRequest struct {
+	path   string
+	header http.Header
+	body   string
+}
+
+// httpEndpoint serves requests with the statuses given in turn, 200 once they
+// are used up, and returns its host, port and the requests it received
+func httpEndpoint(t *testing.T, statuses ...int) (string, int, func() []httpRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []httpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		data, _ := io.ReadAll(body)
+
+		mu.Lock()
+		requests = append(requests, httpRequest{path: r.URL.Path, header: r.Header, body: string(data)})
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	n, _ := strconv.Atoi(port)
+	return host, n, func() []httpRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]httpRequest(nil), requests...)
+	}
+}
+
+func TestHTTPConn_Write(t *testing.T) {
+	host, port, requests := httpEndpoint(t)
+	conn, err := dialHTTP(context.Background(), host, port, time.Second, nil, &HTTPConfig{
+		Path:    "/logs",
+		Headers: map[string]string{"X-Tenant": "project-a"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("dialHTTP() returned unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("{\"seq\":1}\n{\"seq\":2}\n")); err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("received %d requests, want 1", len(got))
+	}
+	if got[0].path != "/logs" {
+		t.Errorf("path = %q, want /logs", got[0].path)
+	}
+	if ct := got[0].header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+	if tenant := got[0].header.Get("X-Tenant"); tenant != "project-a" {
+		t.Errorf("X-Tenant = %q, want project-a", tenant)
+	}
+	if got[0].body != "{\"seq\":1}\n{\"seq\":2}\n" {
+		t.Errorf("body = %q, want both events", got[0].body)
+	}
+}
+
+func TestHTTPConn_Settings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings HTTPConfig
+		auth     string
+		body     string
+	}{
+		{"basic auth", HTTPConfig{Username: "lagoon", Password: "secret"}, "Basic bGFnb29uOnNlY3JldA==", "{\"seq\":1}\n"},
+		{"bearer token", HTTPConfig{BearerToken: "token"}, "Bearer token", "{\"seq\":1}\n"},
+		{"gzip", HTTPConfig{Gzip: true}, "", "{\"seq\":1}\n"},
+		{"bulk", HTTPConfig{Bulk: true}, "", "{\"create\":{}}\n{\"seq\":1}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, requests := httpEndpoint(t)
+			conn, err := dialHTTP(context.Background(), host, port, time.Second, nil, &tt.settings, nil)
+			if err != nil {
+				t.Fatalf("dialHTTP() returned unexpected error: %v", err)
+			}
+			defer conn.Close()
+
+			if _, err := conn.Write([]byte("{\"seq\":1}\n")); err != nil {
+				t.Fatalf("Write() returned unexpected error: %v", err)
+			}
+			got := requests()[0]
+			if auth := got.header.Get("Authorization"); auth != tt.auth {
+				t.Errorf("Authorization = %q, want %q", auth, tt.auth)
+			}
+			if got.body != tt.body {
+				t.Errorf("body = %q, want %q", got.body, tt.body)
+			}
+		})
+	}
+}
+
+func TestHTTPConn_Retries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		requests int
+		fails    bool
+	}{
+		{"unavailable then accepted", []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}, 3, false},
+		{"unavailable throughout", []int{500, 502, 503}, httpAttempts, true},
+		{"rejected", []int{http.StatusBadRequest}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, requests := httpEndpoint(t, tt.statuses...)
+			conn, err := dialHTTP(context.Background(), host, port, time.Second, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("dialHTTP() returned unexpected error: %v", err)
+			}
+			defer conn.Close()
+
+			_, err = conn.Write([]byte("{\"seq\":1}\n"))
+			if (err != nil) != tt.fails {
+				t.Errorf("Write() error = %v, want failure %v", err, tt.fails)
+			}
+			if got := len(requests()); got != tt.requests {
+				t.Errorf("sent %d requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestDialHTTP_Unreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	if _, err := dialHTTP(context.Background(), "127.0.0.1", port, time.Second, nil, nil, nil); err == nil {
+		t.Error("dialHTTP() returned no error for a closed port")
+	}
+}
+
+func TestWithBulkActions(t *testing.T) {
+	got := withBulkActions([]byte("{\"a\":1}\n\n{\"b\":2}"))
+	want := "{\"create\":{}}\n{\"a\":1}\n{\"create\":{}}\n{\"b\":2}\n"
+	if string(got) != want {
+		t.Errorf("withBulkActions() = %q, want %q", got, want)
+	}
+}
+
+func TestInitialize_HTTP(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	host, port, requests := httpEndpoint(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "http-type"
+	cfg.LogHost = host
+	cfg.LogPort = port
+	cfg.Protocol = ProtocolHTTP
+	cfg.HTTP = &HTTPConfig{Path: "/_bulk", Bulk: true}
+	cfg.DeliveryWorkers = 1
+	cfg.BatchSize = 10
+	cfg.BatchInterval = 20 * time.Millisecond
+
+	handler, err := NewHandler(cfg)
+	if err != nil {
+		t.Fatalf("NewHandler() returned unexpected error: %v", err)
+	}
+	logger := slog.New(handler)
+	for i := 0; i < 25; i++ {
+		logger.Info("posted", "seq", i)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	events := 0
+	for time.Now().Before(deadline) {
+		events = 0
+		for _, r := range requests() {
+			events += bytes.Count([]byte(r.body), []byte(`{"create":{}}`))
+		}
+		if events >= 25 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if events != 25 {
+		t.Errorf("posted %d events, want 25", events)
+	}
+	if n := len(requests()); n >= 25 {
+		t.Errorf("posted %d requests, want batches of events", n)
+	}
+}
@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"log/slog"
+	"os"
+)
+
+// lagoonEnvironment returns the name of the Lagoon environment the process
+// runs in, or "" outside Lagoon
+func lagoonEnvironment() string {
+	if environment := os.Getenv("LAGOON_ENVIRONMENT"); len(environment) > 0 {
+		return environment
+	}
+	// older Lagoon versions only set the branch name
+	return os.Getenv("LAGOON_GIT_SAFE_BRANCH")
+}
+
+// lagoonLogType returns the namespace of the Lagoon environment the process
+// runs in, or "" outside Lagoon
+func lagoonLogType() string {
+	project, environment := os.Getenv("LAGOON_PROJECT"), lagoonEnvironment()
+	if len(project) == 0 || len(environment) == 0 {
+		return ""
+	}
+	return project + "-" + environment
+}
+
+// lagoonAttrs returns the lagoon group of the Lagoon metadata that is set
+func lagoonAttrs() slog.Attr {
+	var attrs []any
+	for _, field := range []struct{ key, value string }{
+		{"project", os.Getenv("LAGOON_PROJECT")},
+		{"environment", lagoonEnvironment()},
+		{"branch", os.Getenv("LAGOON_GIT_BRANCH")},
+		{"environment_type", os.Getenv("LAGOON_ENVIRONMENT_TYPE")},
+	} {
+		if len(field.value) > 0 {
+			attrs = append(attrs, slog.String(field.key, field.value))
+		}
+	}
+	return slog.Group("lagoon", attrs...)
+}
+
+// resolvedLogType returns the LogType, derived from the Lagoon environment
+// when it is empty and LagoonMetadata is set
+func (c Config) resolvedLogType() string {
+	if len(c.LogType) == 0 && c.LagoonMetadata {
+		return lagoonLogType()
+	}
+	return c.LogType
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setLagoonEnv sets the Lagoon environment variables of a production
+// environment
+func setLagoonEnv(t *testing.T) {
+	t.Setenv("LAGOON_PROJECT", "shop")
+	t.Setenv("LAGOON_ENVIRONMENT", "main")
+	t.Setenv("LAGOON_GIT_BRANCH", "main")
+	t.Setenv("LAGOON_ENVIRONMENT_TYPE", "production")
+}
+
+func TestLagoonLogType(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"environment", map[string]string{"LAGOON_PROJECT": "shop", "LAGOON_ENVIRONMENT": "main"}, "shop-main"},
+		{"safe branch", map[string]string{"LAGOON_PROJECT": "shop", "LAGOON_GIT_SAFE_BRANCH": "feature-x"}, "shop-feature-x"},
+		{"no environment", map[string]string{"LAGOON_PROJECT": "shop"}, ""},
+		{"outside lagoon", map[string]string{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"LAGOON_PROJECT", "LAGOON_ENVIRONMENT", "LAGOON_GIT_SAFE_BRANCH"} {
+				t.Setenv(name, tt.env[name])
+			}
+			if got := lagoonLogType(); got != tt.want {
+				t.Errorf("lagoonLogType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_LagoonMetadata(t *testing.T) {
+	cfg := NewConfig()
+	cfg.LagoonMetadata = true
+	t.Setenv("LAGOON_PROJECT", "")
+	if err := cfg.Validate(); err == nil || err.Error() != "logType is required" {
+		t.Errorf("Validate() outside Lagoon = %v, want logType is required", err)
+	}
+
+	setLagoonEnv(t)
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() returned unexpected error: %v", err)
+	}
+	if got := cfg.resolvedLogType(); got != "shop-main" {
+		t.Errorf("resolvedLogType() = %q, want %q", got, "shop-main")
+	}
+
+	// an explicit log type is kept
+	cfg.LogType = "explicit"
+	if got := cfg.resolvedLogType(); got != "explicit" {
+		t.Errorf("resolvedLogType() = %q, want %q", got, "explicit")
+	}
+}
+
+func TestHandler_LagoonMetadata(t *testing.T) {
+	preserveConfig(t)
+	setLagoonEnv(t)
+	t.Setenv("LAGOON_ENVIRONMENT_TYPE", "")
+
+	cfg := NewConfig()
+	cfg.LagoonMetadata = true
+	if err := config(cfg); err != nil {
+		t.Fatalf("config() returned unexpected error: %v", err)
+	}
+
+	var buf strings.Builder
+	slog.New(newHandler(&buf)).Info("hello")
+
+	var event map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &event); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if got := event["type"]; got != "shop-main" {
+		t.Errorf("type = %v, want shop-main", got)
+	}
+	// variables that are not set are left out
+	want := map[string]any{"project": "shop", "environment": "main", "branch": "main"}
+	if got := event["lagoon"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("lagoon = %v, want %v", got, want)
+	}
+
+	// without the option the group is left out
+	cfg.LogType = "explicit"
+	cfg.LagoonMetadata = false
+	if err := config(cfg); err != nil {
+		t.Fatalf("config() returned unexpected error: %v", err)
+	}
+	buf.Reset()
+	slog.New(newHandler(&buf)).Info("hello")
+	if strings.Contains(buf.String(), `"lagoon"`) {
+		t.Errorf("output = %q, want no lagoon group", buf.String())
+	}
+}
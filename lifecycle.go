@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// background is one generation of background goroutines, started between an
+// Initialize and its Shutdown. Each generation has its own context and wait
+// group, so a Shutdown that gave up waiting can't stop or wait on the
+// goroutines of the next Initialize.
+type background struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+var (
+	backgroundMu  sync.Mutex
+	backgroundGen *background
+)
+
+// goBackground runs fn in a goroutine until Shutdown cancels its context
+func goBackground(fn func(ctx context.Context)) {
+	backgroundMu.Lock()
+	defer backgroundMu.Unlock()
+
+	if backgroundGen == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		backgroundGen = &background{ctx: ctx, cancel: cancel}
+	}
+
+	g := backgroundGen
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn(g.ctx)
+	}()
+}
+
+// cancelBackground cancels the background goroutines without waiting and
+// returns their generation, nil when none were started. Goroutines started
+// afterwards belong to a new generation.
+func cancelBackground() *background {
+	backgroundMu.Lock()
+	g := backgroundGen
+	backgroundGen = nil
+	backgroundMu.Unlock()
+
+	if g != nil {
+		g.cancel()
+	}
+	return g
+}
+
+// wait waits for the goroutines of the generation to return
+func (g *background) wait() {
+	if g != nil {
+		g.wg.Wait()
+	}
+}
+
+// stopBackground cancels the background goroutines and waits for them
+func stopBackground() {
+	cancelBackground().wait()
+}
+
+// newBackgroundClient returns an HTTP client with its own connection pool, so
+// a background task can close its idle connections once it stops
+func newBackgroundClient() *http.Client {
+	return &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+// goroutines returns the stacks of the running goroutines other than the
+// caller's, keyed by their "goroutine N" header
+func goroutines() map[string]string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := map[string]string{}
+	// the caller's goroutine comes first
+	for _, stack := range strings.Split(string(buf), "\n\n")[1:] {
+		header, _, _ := strings.Cut(stack, " [")
+		stacks[header] = stack
+	}
+	return stacks
+}
+
+// checkLeaks fails t when goroutines started after it was called are still
+// running once cleanup has run, in the spirit of goleak without the
+// dependency. It must be called before anything registering cleanup that
+// stops goroutines, so those run first.
+func checkLeaks(t *testing.T) {
+	t.Helper()
+	before := goroutines()
+
+	t.Cleanup(func() {
+		var leaked []string
+		// goroutines may still be returning, give them a moment
+		for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			leaked = leaked[:0]
+			for header, stack := range goroutines() {
+				if _, ok := before[header]; !ok {
+					leaked = append(leaked, stack)
+				}
+			}
+			if len(leaked) == 0 || time.Now().After(deadline) {
+				break
+			}
+		}
+		for _, stack := range leaked {
+			t.Errorf("leaked goroutine:\n%s", stack)
+		}
+	})
+}
+
+func TestGoBackground(t *testing.T) {
+	checkLeaks(t)
+
+	var stopped sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		stopped.Add(1)
+		goBackground(func(ctx context.Context) {
+			defer stopped.Done()
+			<-ctx.Done()
+		})
+	}
+	stopBackground()
+	stopped.Wait()
+
+	// tasks started after stopping get a fresh context
+	started := make(chan struct{})
+	goBackground(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	})
+	<-started
+	stopBackground()
+}
+
+func TestShutdown_NoLeaks(t *testing.T) {
+	tests := []struct {
+		name      string
+		network   string
+		configure func(t *testing.T, cfg *Config, r *loggertest.Receiver)
+	}{
+		{"udp", loggertest.UDP, func(*testing.T, *Config, *loggertest.Receiver) {}},
+		{"tcp", loggertest.TCP, func(t *testing.T, cfg *Config, r *loggertest.Receiver) {
+			cfg.Protocol = ProtocolTCP
+		}},
+		{"delivery workers", loggertest.TCP, func(t *testing.T, cfg *Config, r *loggertest.Receiver) {
+			cfg.Protocol = ProtocolTCP
+			cfg.DeliveryWorkers = 4
+		}},
+		{"reconnecting", loggertest.TCP, func(t *testing.T, cfg *Config, r *loggertest.Receiver) {
+			cfg.Protocol = ProtocolTCP
+			r.Close()
+		}},
+		{"background tasks", loggertest.UDP, func(t *testing.T, cfg *Config, r *loggertest.Receiver) {
+			server := newRemoteServer(t, remoteTestKey, `{}`)
+			t.Cleanup(resetSkew)
+			cfg.EgressBudget = 1 << 20
+			cfg.RemoteConfigURL = server.URL
+			cfg.RemoteConfigKey = remoteTestKey
+			cfg.RemoteConfigInterval = 10 * time.Millisecond
+			cfg.SkewProbeURL = server.URL
+			cfg.SkewProbeInterval = 10 * time.Millisecond
+			cfg.Flags = FlagProviderFunc(func(context.Context, string, FlagTarget) (string, bool) { return "", false })
+			cfg.FlagRefreshInterval = 10 * time.Millisecond
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkLeaks(t)
+			preserveConfig(t)
+			resetRemote(t)
+			fastReconnect(t)
+			once = sync.Once{}
+
+			r, err := loggertest.Listen(tt.network)
+			if err != nil {
+				t.Fatalf("Listen() returned unexpected error: %v", err)
+			}
+			defer r.Close()
+
+			cfg := NewConfig()
+			cfg.LogType = "lifecycle"
+			cfg.LogHost = r.Host()
+			cfg.LogPort = r.Port()
+			tt.configure(t, &cfg, r)
+			if err := Initialize(cfg); err != nil {
+				t.Fatalf("Initialize() returned unexpected error: %v", err)
+			}
+
+			for i := 0; i < 10; i++ {
+				slog.Info("lifecycle", "i", i)
+			}
+			// let the background tasks run a few times
+			time.Sleep(50 * time.Millisecond)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if err := Shutdown(ctx); err != nil {
+				t.Errorf("Shutdown() returned unexpected error: %v", err)
+			}
+		})
+	}
+}
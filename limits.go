@@ -0,0 +1,67 @@
+package logger
+
+import "log/slog"
+
+const (
+	// truncatedKey reports how many attributes a record lost to MaxAttrs
+	truncatedKey = "truncated_attrs"
+	// depthMarker replaces groups nested deeper than MaxAttrDepth
+	depthMarker = "[truncated: max depth]"
+)
+
+// attrLimits bounds the attributes of a record, a zero limit keeps all
+type attrLimits struct {
+	count int
+	depth int
+}
+
+// apply returns attrs with groups nested deeper than the depth limit replaced
+// by a marker and attributes past the count limit dropped, counting groups
+// and their members alike, along with the number of attributes dropped
+func (l attrLimits) apply(attrs []slog.Attr) ([]slog.Attr, int) {
+	if l.count == 0 && l.depth == 0 {
+		return attrs, 0
+	}
+
+	w := &limitWalk{attrLimits: l}
+	return w.walk(attrs, 1), w.dropped
+}
+
+// limitWalk is the state of a single apply
+type limitWalk struct {
+	attrLimits
+	kept    int
+	dropped int
+}
+
+func (w *limitWalk) walk(attrs []slog.Attr, depth int) []slog.Attr {
+	out := make([]slog.Attr, 0, len(attrs))
+
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+
+		// inline groups add their members at the current depth
+		if a.Value.Kind() == slog.KindGroup && len(a.Key) == 0 {
+			out = append(out, w.walk(a.Value.Group(), depth)...)
+			continue
+		}
+
+		if w.count > 0 && w.kept >= w.count {
+			w.dropped++
+			continue
+		}
+		w.kept++
+
+		if a.Value.Kind() == slog.KindGroup {
+			if w.depth > 0 && depth >= w.depth {
+				a.Value = slog.StringValue(depthMarker)
+			} else {
+				a.Value = slog.GroupValue(w.walk(a.Value.Group(), depth+1)...)
+			}
+		}
+
+		out = append(out, a)
+	}
+
+	return out
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+// nested returns a group nested depth levels deep around a single attribute
+func nested(depth int) slog.Attr {
+	a := slog.String("leaf", "value")
+	for i := depth; i > 0; i-- {
+		a = slog.Group(fmt.Sprintf("level%d", i), a)
+	}
+	return a
+}
+
+func TestAttrLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   attrLimits
+		attrs    []slog.Attr
+		expected string
+		dropped  int
+	}{
+		{
+			name:     "no limits",
+			attrs:    []slog.Attr{slog.Int("a", 1), slog.Int("b", 2), nested(3)},
+			expected: "[a=1 b=2 level1=[level2=[level3=[leaf=value]]]]",
+		},
+		{
+			name:     "count",
+			limits:   attrLimits{count: 2},
+			attrs:    []slog.Attr{slog.Int("a", 1), slog.Int("b", 2), slog.Int("c", 3), slog.Int("d", 4)},
+			expected: "[a=1 b=2]",
+			dropped:  2,
+		},
+		{
+			name:     "count includes group members",
+			limits:   attrLimits{count: 3},
+			attrs:    []slog.Attr{slog.Group("g", slog.Int("a", 1), slog.Int("b", 2), slog.Int("c", 3))},
+			expected: "[g=[a=1 b=2]]",
+			dropped:  1,
+		},
+		{
+			name:     "depth",
+			limits:   attrLimits{depth: 2},
+			attrs:    []slog.Attr{slog.Int("a", 1), nested(3)},
+			expected: "[a=1 level1=[level2=" + depthMarker + "]]",
+		},
+		{
+			name:     "inline groups keep their depth",
+			limits:   attrLimits{depth: 1},
+			attrs:    []slog.Attr{slog.Group("", slog.Int("a", 1)), nested(1)},
+			expected: "[a=1 level1=" + depthMarker + "]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs, dropped := tt.limits.apply(tt.attrs)
+			if got := fmt.Sprint(attrs); got != tt.expected {
+				t.Errorf("apply() = %s, want %s", got, tt.expected)
+			}
+			if dropped != tt.dropped {
+				t.Errorf("apply() dropped %d, want %d", dropped, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestHandler_AttrLimits(t *testing.T) {
+	preserveConfig(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "limits-type"
+	cfg.LogHost = "localhost"
+	cfg.MaxAttrs = 4
+	cfg.MaxAttrDepth = 2
+
+	var buf bytes.Buffer
+	h, err := NewWriterHandler(cfg, &buf)
+	if err != nil {
+		t.Fatalf("NewWriterHandler() returned unexpected error: %v", err)
+	}
+
+	slog.New(h).With("scoped", 1).Info("limited", "a", 2, nested(4), "b", 3)
+
+	var event map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	if event["type"] != "limits-type" {
+		t.Errorf("default attributes should not count towards the limit, got type = %v", event["type"])
+	}
+	if event[truncatedKey] != float64(1) {
+		t.Errorf("%s = %v, want 1", truncatedKey, event[truncatedKey])
+	}
+	if _, ok := event["b"]; ok {
+		t.Error("attributes past the limit should be dropped")
+	}
+	level1, _ := event["level1"].(map[string]any)
+	if level1["level2"] != depthMarker {
+		t.Errorf("level1 = %v, want level2 replaced by the depth marker", event["level1"])
+	}
+}
@@ -1,27 +1,117 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
-	addSource       bool
-	applicationName string
-	hostname        string
-	logChannel      string
-	logHost         string
-	logPort         int
-	logType         string // should match namespace to create index 'application-logs-{logType}'
-	messageVersion  int
-	once            sync.Once
+	addSource            bool
+	sourceFormat         string
+	sourceSkip           []string
+	applicationName      string
+	hostname             string
+	logChannel           string
+	logHost              string
+	logPort              int
+	fallbackHosts        []string
+	failbackInterval     time.Duration
+	senderID             string
+	senderIDFile         string
+	sender               string // the resolved sender ID
+	protocol             string
+	writeTimeout         time.Duration
+	logType              string // should match namespace to create index 'application-logs-{logType}'
+	lagoonMetadata       bool
+	schemaField          bool
+	messageVersion       int
+	strictSchema         bool
+	compatMode           string
+	level                string
+	stdoutLevel          string
+	forwardLevel         string
+	stdoutFormat         string
+	scheduleWindows      []ScheduleWindow
+	scheduleTimezone     string
+	deliveryWorkers      int
+	queueSize            int
+	batchSize            int
+	batchInterval        time.Duration
+	batchLatency         time.Duration
+	ordering             string
+	orderingKey          string
+	deliveryPolicy       string
+	recordAttempts       bool
+	skewProbeURL         string
+	remoteConfigURL      string
+	remoteConfigKey      string
+	remoteConfigInterval time.Duration
+	flagProvider         FlagProvider
+	resolver             Resolver
+	flagRefreshInterval  time.Duration
+	debugSignal          string
+	skewProbeInterval    time.Duration
+	compressFields       []string
+	compressThreshold    int
+	egressBudget         int64
+	egressWindow         time.Duration
+	egressSampleRate     int
+	maxEventsPerSecond   int
+	burst                int
+	memoryPressure       float64
+	memoryLimit          int64
+	memorySampleRate     int
+	faults               *Faults
+	tlsSettings          *TLSConfig
+	httpSettings         *HTTPConfig
+	fluentSettings       *ForwardConfig
+	otlpSettings         *OTLPConfig
+	syslogSettings       *SyslogConfig
+	destinations         []Destination
+	spoolDir             string
+	spoolMaxBytes        int64
+	replayWindow         time.Duration
+	statsFile            string
+	statsInterval        time.Duration
+	statsEventInterval   time.Duration
+	statsEventChannel    string
+	captureStderrOutput  bool
+	stdLogLevel          string
+	strictReconfigure    bool
+	exitTimeout          time.Duration
+	maxAttrs             int
+	maxAttrDepth         int
+	maxMessageBytes      int
+	messageTruncation    string
+	controlChars         string
+	processors           []Processor
+	onLevel              map[slog.Level]func(slog.Record)
+	computedFields       map[string]string
+	levelRouting         map[string][]string
+	maxValueFields       int
+	maxValueDepth        int
+	preferStringer       bool
+	maxStringRunes       int
+	normalizeStrings     func(string) string
+	spanContext          func(ctx context.Context) (traceID, spanID string)
+	once                 sync.Once
+	// outputs are the stdout and forwarder sinks opened by NewHandler
+	outputs   []sink
+	forwarder = &switchWriter{}
+	// running is the config the sinks were opened with, until Shutdown
+	running *Config
 )
 
-// synchronizedUDPWriter ensures UDP writes happen serially
+// synchronizedUDPWriter ensures writes to the endpoint happen serially
 type synchronizedUDPWriter struct {
 	conn io.WriteCloser
 	mu   sync.Mutex
@@ -39,57 +129,614 @@ func (w *synchronizedUDPWriter) Close() error {
 	return w.conn.Close()
 }
 
+// lostConn is a connection written to without delivery workers, which
+// reports the first failed write to lost so the connection is redialed. The
+// records of failed writes are handed to spill.
+type lostConn struct {
+	io.WriteCloser
+	spill func(p []byte) bool
+	lost  func(err error)
+	once  sync.Once
+}
+
+func (c *lostConn) Write(p []byte) (int, error) {
+	n, err := c.WriteCloser.Write(p)
+	if err == nil {
+		return n, nil
+	}
+	c.once.Do(func() { c.lost(err) })
+	if c.spill(p) {
+		// the record is delivered with the spool, the error is only counted
+		recordError(SinkForwarder, OpWrite, err)
+		return len(p), nil
+	}
+	return n, err
+}
+
+// switchWriter forwards writes to a destination that can be replaced at
+// runtime, spooling or discarding them while no destination is set
+type switchWriter struct {
+	w io.Writer
+	// spool buffers writes while there is no destination, nil discards them.
+	// Destinations spill to it without the lock.
+	spool atomic.Pointer[spool]
+	mu    sync.RWMutex
+}
+
+func (s *switchWriter) Write(p []byte) (n int, err error) {
+	return s.writeContext(context.Background(), p)
+}
+
+func (s *switchWriter) writeContext(ctx context.Context, p []byte) (n int, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.w == nil {
+		if sp := s.spool.Load(); sp != nil {
+			return sp.Write(p)
+		}
+		return len(p), nil
+	}
+	return writeContext(ctx, s.w, p)
+}
+
+// spill spools p, which a destination failed to deliver, and reports whether
+// there was a spool to take it
+func (s *switchWriter) spill(p []byte) bool {
+	sp := s.spool.Load()
+	if sp == nil {
+		return false
+	}
+	_, _ = sp.Write(p)
+	return true
+}
+
+// discarding reports whether writes are discarded for lack of a destination
+func (s *switchWriter) discarding() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.w == nil
+}
+
+// set replaces the destination once in-flight writes have finished and
+// returns the previous one
+func (s *switchWriter) set(w io.Writer) io.Writer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.w
+	s.w = w
+	return previous
+}
+
+// detach removes w as the destination unless it was already replaced, and
+// reports whether it did
+func (s *switchWriter) detach(w io.Writer) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w != w {
+		return false
+	}
+	s.w = nil
+	return true
+}
+
+// attach sets w as the destination unless ctx is done, which Shutdown
+// cancels before it detaches the destination, and returns the previous one.
+// Spooled records are replayed to w first, so they arrive in order.
+func (s *switchWriter) attach(ctx context.Context, w io.Writer) (io.Writer, bool) {
+	sp := s.spool.Load()
+
+	replayed, err := 0, error(nil)
+	if sp != nil {
+		// most records are replayed while logging carries on spooling
+		replayed, err = sp.replay(ctx, w)
+	}
+
+	s.mu.Lock()
+	if ctx.Err() != nil {
+		s.mu.Unlock()
+		return nil, false
+	}
+	if sp != nil && err == nil {
+		var n int
+		n, err = sp.replay(ctx, w)
+		replayed += n
+	}
+	previous := s.w
+	s.w = w
+	s.mu.Unlock()
+
+	if err != nil {
+		diag().Warn("Failed to replay spooled records, they are kept for the next connection", "replayed", replayed, "error", err)
+	} else if replayed > 0 {
+		diag().Info("Replayed spooled records", "replayed", replayed, "dropped", sp.Dropped(), "skipped", sp.Skipped())
+	}
+	return previous, true
+}
+
+// setSpool sets the spool buffering writes while there is no destination
+// and returns the previous one
+func (s *switchWriter) setSpool(sp *spool) *spool {
+	return s.spool.Swap(sp)
+}
+
 // Initialize creates a multiwriter logger (udp and stdout) and sets it as the default
 // slog
 func Initialize(cfg Config) error {
+	return InitializeContext(context.Background(), cfg)
+}
+
+// InitializeContext is Initialize with resolving and connecting to the log
+// endpoint bounded by ctx. When ctx is done first, records only reach stdout
+// until the endpoint is reached in the background.
+func InitializeContext(ctx context.Context, cfg Config) error {
+
+	handler, err := newForwardingHandler(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(slog.New(handler))
+	// the log package writes to the default logger at this level
+	stdLog := slog.LevelInfo
+	if len(stdLogLevel) > 0 {
+		// the level was validated when the config was applied
+		stdLog, _ = ParseLevel(stdLogLevel)
+	}
+	slog.SetLogLoggerLevel(stdLog)
+	return nil
+}
+
+// NewHandler applies cfg and returns the Lagoon formatted JSON handler
+// writing to stdout and the log endpoint, without replacing the default slog
+// logger, so it can be wrapped by other handlers or used by libraries. The
+// endpoint is connected to once until Shutdown, handlers created meanwhile
+// share that connection.
+func NewHandler(cfg Config) (slog.Handler, error) {
+	return newForwardingHandler(context.Background(), cfg)
+}
+
+// newForwardingHandler is NewHandler with the first connection attempt
+// bounded by ctx
+func newForwardingHandler(ctx context.Context, cfg Config) (slog.Handler, error) {
 
 	hostname, _ = os.Hostname()
-	messageVersion = 3
+
+	if running != nil {
+		var unsafe []string
+		for _, c := range CompareConfigs(*running, cfg) {
+			if c.Unsafe {
+				unsafe = append(unsafe, c.String()+" ("+c.Reason+")")
+			}
+		}
+		if len(unsafe) > 0 && running.StrictReconfigure {
+			return nil, fmt.Errorf("configuration error: unsafe changes to the running logger: %s", strings.Join(unsafe, ", "))
+		}
+		if len(unsafe) > 0 {
+			diag().Warn("Unsafe changes to the running logger", "changes", unsafe)
+		}
+	}
 
 	if err := config(cfg); err != nil {
-		return fmt.Errorf("configuration error: %w", err)
+		return nil, fmt.Errorf("configuration error: %w", err)
 	}
+	sender = resolveSender()
 
 	once.Do(func() {
-		var writer io.Writer = os.Stdout
+		running = &cfg
+		injector := newFaultInjector(faults)
+		var destination func(net.Conn, func() (io.WriteCloser, error)) io.Writer
+		destination = newDestination(injector, forwarder.spill, func(w io.Writer, err error) {
+			// the write that failed may hold the forwarder's lock
+			goBackground(func(ctx context.Context) {
+				redial(ctx, forwarder, w, err, connect, func(conn net.Conn) io.Writer { return destination(conn, dialForwarder) })
+			})
+		})
 
-		udpConnection, err := connect()
+		if len(spoolDir) > 0 {
+			sp, err := openSpool(spoolDir, spoolMaxBytes)
+			if err != nil {
+				diag().Warn("Failed to open spool, records are discarded while the log endpoint is unreachable", "error", err)
+			} else {
+				if replayWindow > 0 {
+					if err := sp.protectReplays(replayWindow); err != nil {
+						diag().Warn("Failed to read the replayed records, replays after a crash may duplicate them", "error", err)
+					}
+				}
+				forwarder.setSpool(sp)
+			}
+		}
+
+		endpoints := newFailover()
+		forwarderEndpoints.Store(endpoints)
+
+		conn, err := connectContext(ctx)
 		if err != nil {
-			slog.Warn("Failed to connect to UDP endpoint, logging to stdout only", "error", err)
+			diag().Warn("Failed to connect to log endpoint, logging to stdout until it is reachable", "protocol", protocol, "error", err)
+			goBackground(func(ctx context.Context) {
+				reconnect(ctx, forwarder, connect, func(conn net.Conn) io.Writer { return destination(conn, dialForwarder) })
+			})
 		} else {
-			// Wrap UDP connection with synchronized writer to ensure serial writes
-			syncUDPWriter := &synchronizedUDPWriter{conn: udpConnection}
-			writer = io.MultiWriter(os.Stdout, syncUDPWriter)
-		}
-
-		slogger := slog.New(
-			slog.NewJSONHandler(
-				writer,
-				&slog.HandlerOptions{
-					AddSource:   addSource,
-					Level:       slog.LevelDebug,
-					ReplaceAttr: replaceAttr,
-				},
-			)).With(defaultAttrs()...)
-
-		slog.SetDefault(slogger)
+			// records spooled by a previous process are delivered first
+			forwarder.attach(context.Background(), destination(conn, dialForwarder))
+		}
+		if endpoints != nil {
+			interval := failbackInterval
+			goBackground(func(ctx context.Context) {
+				endpoints.failback(ctx, interval, forwarder, func(conn net.Conn) io.Writer { return destination(conn, dialForwarder) })
+			})
+		}
+		// the forwarder spools or discards records while not connected
+		stdout := newEgressMeter(SinkStdout, os.Stdout, 0, 0)
+		forwarded := newEgressMeter(SinkForwarder, forwarder, egressBudget, egressSampleRate)
+
+		window := egressWindow
+		if window <= 0 {
+			window = time.Minute
+		}
+		goBackground(func(ctx context.Context) { meterEgress(ctx, window, stdout, forwarded) })
+
+		if len(statsFile) > 0 {
+			if err := loadStats(statsFile); err != nil {
+				diag().Warn("Failed to read the lifetime stats, counting from the current process", "path", statsFile, "error", err)
+			}
+			path, interval := statsFile, statsInterval
+			goBackground(func(ctx context.Context) { persistStats(ctx, path, interval) })
+		}
+
+		if flagProvider != nil {
+			controller := &flagController{provider: flagProvider, target: flagTarget(), interval: flagRefreshInterval}
+			// flags apply to the first records already
+			controller.refresh(context.Background())
+			goBackground(controller.run)
+		}
+
+		if sig, ok := debugSignals[debugSignal]; ok {
+			goBackground(func(ctx context.Context) { toggleDebug(ctx, sig) })
+		}
+
+		if monitor := newMemoryMonitor(); monitor != nil {
+			goBackground(monitor.run)
+		}
+
+		if len(skewProbeURL) > 0 {
+			url, interval := skewProbeURL, skewProbeInterval
+			goBackground(func(ctx context.Context) { probeSkew(ctx, url, interval) })
+		}
+
+		// the sink levels were validated when the config was applied
+		stdoutMin, _ := parseSinkLevel(stdoutLevel)
+		forwardMin, _ := parseSinkLevel(forwardLevel)
+		format := forwardFormat()
+		var forwardTo io.Writer = forwarded
+		if format == FormatSyslog {
+			forwardTo = syslogWriter(forwarded, protocol)
+		}
+		outputs = []sink{
+			{name: SinkStdout, w: stdout, format: stdoutFormat, level: stdoutMin},
+			{name: SinkForwarder, w: forwardTo, format: format, level: forwardMin, maxBytes: forwardMaxBytes(protocol), limit: newRateLimiter(maxEventsPerSecond, burst)},
+		}
+		for _, d := range destinations {
+			outputs = append(outputs, newDestinationSink(ctx, d))
+		}
+		traceSinks(err == nil)
+
+		if len(remoteConfigURL) > 0 {
+			controller := &remoteController{
+				url:       remoteConfigURL,
+				key:       remoteConfigKey,
+				interval:  remoteConfigInterval,
+				client:    newBackgroundClient(),
+				host:      logHost,
+				port:      logPort,
+				forwardTo: newForwardTo(destination),
+				level:     level,
+				// changes are logged along with the application's records
+				changelog: newSinkHandler(outputs...),
+			}
+			goBackground(controller.run)
+		}
+
+		if captureStderrOutput {
+			captured := newSinkHandler(outputs...)
+			goBackground(func(ctx context.Context) { captureStderr(ctx, captured) })
+		}
+
+		if statsEventInterval > 0 {
+			events, interval := newChannelHandler(statsEventChannel, outputs...), statsEventInterval
+			goBackground(func(ctx context.Context) { emitStats(ctx, events, interval) })
+		}
 	})
 
-	return nil
+	return newSinkHandler(outputs...), nil
+}
+
+// forwardFormat returns the format of the forwarded events
+func forwardFormat() string {
+	if syslogSettings != nil {
+		return FormatSyslog
+	}
+	return FormatJSON
+}
+
+// newDestination returns a function building the forwarder destination on a
+// connection to an endpoint, which delivery workers reach with dial. Records
+// the destination fails to deliver are handed to spill, and the first failed
+// write, or record the workers give up on, is reported to lost with the
+// destination.
+func newDestination(injector *faultInjector, spill func(p []byte) bool, lost func(w io.Writer, err error)) func(conn net.Conn, dial func() (io.WriteCloser, error)) io.Writer {
+	workers, mode, key, policy, size := deliveryWorkers, ordering, orderingKey, deliveryPolicy, queueSize
+	network, batch, interval, latency := protocol, batchSize, batchInterval, batchLatency
+	attemptFields := recordAttempts
+
+	return func(conn net.Conn, dial func() (io.WriteCloser, error)) io.Writer {
+		// Wrap the connection with synchronized writer to ensure serial writes
+		syncUDPWriter := &synchronizedUDPWriter{conn: conn}
+
+		var w io.Writer
+		var direct *lostConn
+		var pool *deliveryPool
+		if workers == 0 {
+			direct = &lostConn{WriteCloser: syncUDPWriter, spill: spill}
+			w = injector.conn(direct)
+		} else {
+			// each worker dials its own connection, this one only proved the
+			// endpoint is reachable
+			_ = syncUDPWriter.Close()
+			pool = newOrderedPool(mode, key, policy, workers, size, injector.dial(dial))
+			if attemptFields {
+				pool.recordAttempts()
+			}
+			w = pool
+		}
+
+		if batch > 1 {
+			b := newBatchWriter(w, network, batch, interval)
+			if latency > 0 {
+				b.tuner = newBatchTuner(latency, batch)
+			}
+			w = b
+		}
+		// nothing is written before the destination is attached
+		destination := w
+		if direct != nil {
+			direct.lost = func(err error) { lost(destination, err) }
+		}
+		if pool != nil {
+			giveUp := spill
+			if policy == DeliveryAtMostOnce {
+				// a record written at most once is not written again
+				giveUp = nil
+			}
+			pool.spillTo(giveUp, func(err error) { lost(destination, err) })
+		}
+		return w
+	}
+}
+
+// newForwardTo returns a function switching the forwarder to the destination
+// built on another endpoint
+func newForwardTo(destination func(net.Conn, func() (io.WriteCloser, error)) io.Writer) func(ctx context.Context, host string, port int) error {
+	network, timeout, settings, lookup := protocol, writeTimeout, forwarderSettings(), resolver
+
+	return func(ctx context.Context, host string, port int) error {
+		dial := func() (net.Conn, error) { return dialEndpoint(network, host, port, timeout, settings, lookup) }
+		conn, err := dial()
+		if err != nil {
+			return err
+		}
+
+		w := destination(conn, serialize(dial))
+		previous, ok := forwarder.attach(ctx, w)
+		if !ok {
+			closeWriter(w)
+			return ctx.Err()
+		}
+		// records still queued for the previous endpoint are delivered there
+		closeWriter(previous)
+		return nil
+	}
+}
+
+// closeWriter closes w when it is an io.Closer
+func closeWriter(w io.Writer) {
+	if closer, ok := w.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+// Shutdown detaches the log endpoint, stops background tasks, flushes records
+// still queued for delivery and closes the connection, giving up once ctx is
+// done. Records logged afterwards only reach stdout, and Initialize may be
+// called again.
+func Shutdown(ctx context.Context) error {
+
+	// a reconnecting forwarder must not attach after it was detached, and
+	// only this generation's goroutines are waited for: a later Initialize
+	// starts its own
+	generation := cancelBackground()
+	previous := forwarder.set(nil)
+	pools := closeDestinations()
+	// records spooled meanwhile are replayed by the next Initialize
+	if sp := forwarder.setSpool(nil); sp != nil {
+		_ = sp.Close()
+	}
+	once = sync.Once{}
+	running = nil
+
+	done := make(chan error, 1)
+	go func() {
+		generation.wait()
+		for _, pool := range pools {
+			_ = pool.Close()
+		}
+		if closer, ok := previous.(io.Closer); ok {
+			done <- closer.Close()
+			return
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// stop retrying records that cannot be delivered in time
+		for _, pool := range pools {
+			pool.abort()
+		}
+		if a, ok := previous.(interface{ abort() }); ok {
+			a.abort()
+		}
+		return fmt.Errorf("shutdown: %w", ctx.Err())
+	}
+}
+
+// NewWriterHandler applies cfg and returns the Lagoon formatted JSON handler
+// writing to w, framed like the forwarded events when cfg.Syslog is set.
+// Unlike Initialize it neither connects to the UDP endpoint nor replaces the
+// default slog logger.
+func NewWriterHandler(cfg Config, w io.Writer) (slog.Handler, error) {
+
+	hostname, _ = os.Hostname()
+
+	if err := config(cfg); err != nil {
+		return nil, fmt.Errorf("configuration error: %w", err)
+	}
+	sender = resolveSender()
+
+	format := forwardFormat()
+	if format == FormatSyslog {
+		w = syslogWriter(w, protocol)
+	}
+	return newSinkHandler(sink{w: w, format: format}), nil
+}
+
+// Dial opens a serialized connection to the log endpoint described by cfg
+// without applying cfg to the package
+func Dial(cfg Config) (io.WriteCloser, error) {
+
+	conn, err := dialEndpoint(cfg.Protocol, cfg.LogHost, cfg.LogPort, cfg.WriteTimeout, cfg.endpointSettings(), cfg.Resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	return &synchronizedUDPWriter{conn: conn}, nil
+}
+
+// dialForwarder opens a serialized connection to the configured endpoint
+func dialForwarder() (io.WriteCloser, error) {
+	return serialize(connect)()
+}
+
+// serialize returns a function opening serialized connections with dial
+func serialize(dial func() (net.Conn, error)) func() (io.WriteCloser, error) {
+	return func() (io.WriteCloser, error) {
+		conn, err := dial()
+		if err != nil {
+			return nil, err
+		}
+
+		return &synchronizedUDPWriter{conn: conn}, nil
+	}
+}
+
+func newHandler(w io.Writer) slog.Handler {
+	return newSinkHandler(sink{w: w})
+}
+
+// newSinkHandler returns the Lagoon handler of the applied config writing to
+// sinks
+func newSinkHandler(sinks ...sink) slog.Handler {
+	return newChannelHandler(logChannel, sinks...)
+}
+
+// newChannelHandler returns the Lagoon handler of the applied config writing
+// to sinks on channel
+func newChannelHandler(channel string, sinks ...sink) slog.Handler {
+
+	// the level and schedule were validated when the config was applied
+	minLevel, _ := ParseLevel(level)
+	var leveler slog.Leveler = minLevel
+	var sched *schedule
+	if len(scheduleWindows) > 0 {
+		sched, _ = newSchedule(minLevel, scheduleWindows, scheduleTimezone)
+		leveler = sched
+	}
+	leveler = overrideLeveler{leveler}
+
+	// slog only writes the source as a group, other representations are
+	// added by the handler. The Monolog encoder writes its own.
+	var source string
+	if format := resolveSourceFormat(sourceFormat, messageVersion); addSource && format != SourceGroup && compatMode != CompatMonologLagoon {
+		source = format
+	}
+
+	h := newJSONHandler(sinks, &slog.HandlerOptions{
+		AddSource:   addSource && len(source) == 0,
+		Level:       leveler,
+		ReplaceAttr: replaceAttr,
+	}, staticAttrs(channel))
+	h.limits = attrLimits{count: maxAttrs, depth: maxAttrDepth}
+	h.truncation = messageTruncation
+	h.control = controlChars
+	h.processors = processors
+	h.hooks = newLevelHooks(onLevel)
+	// the templates are validated before they are applied
+	h.computed, _ = parseComputedAttrs(computedFields)
+	h.routes, _ = parseLevelRoutes(levelRouting, current().sinkNames())
+	h.values = valuePolicy{maxFields: maxValueFields, maxDepth: maxValueDepth, preferStringer: preferStringer}
+	h.strings = stringPolicy{normalize: normalizeStrings, maxRunes: maxStringRunes}
+	h.compress = fieldCompression{fields: compressFields, threshold: compressThreshold}
+	h.spans = spanContext
+	h.strict = strictSchema
+	h.schedule = sched
+	h.source = source
+	if addSource && len(sourceSkip) > 0 {
+		h.frames = frameFilter(sourceSkip)
+	}
+	return h
 }
 
 func defaultAttrs() []any {
+	return staticAttrs(logChannel)
+}
+
+// DefaultAttrs returns a copy of the static attributes the applied config
+// adds to every record: @version, application, channel, the empty context
+// and extra groups, host and type, plus the sender, lagoon and schema fields
+// when they are enabled
+func DefaultAttrs() []slog.Attr {
+	attrs := defaultAttrs()
+	static := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		static[i] = a.(slog.Attr)
+	}
+	return static
+}
+
+// staticAttrs returns the attributes of every record logged on channel
+func staticAttrs(channel string) []any {
 
-	return []any{
+	attrs := []any{
 		slog.Int("@version", messageVersion),
 		slog.String("application", applicationName),
-		slog.String("channel", logChannel),
+		slog.String("channel", channel),
 		slog.Group("context"),
 		slog.Group("extra"),
 		slog.String("host", hostname),
 		// NOTE: Refactoring will be required if we want to override this per project
 		slog.String("type", logType),
 	}
+	if len(sender) > 0 {
+		attrs = append(attrs, slog.String(senderKey, sender))
+	}
+	if lagoonMetadata {
+		attrs = append(attrs, lagoonAttrs())
+	}
+	if schemaField {
+		attrs = append(attrs, slog.String(schemaKey, emitterSchema()))
+	}
+	return attrs
 }
 
 func replaceAttr(groups []string, a slog.Attr) slog.Attr {
@@ -101,22 +748,42 @@ func replaceAttr(groups []string, a slog.Attr) slog.Attr {
 			a.Key = "@timestamp"
 		case "timestampOverride":
 			a.Key = "@timestamp"
+		case slog.LevelKey:
+			// the handlers marshal a Level with encoding/json, its name is
+			// written directly
+			if l, ok := a.Value.Any().(slog.Level); ok {
+				a.Value = slog.StringValue(levelString(l))
+			}
 		}
 	}
 	return a
 }
 
-func connect() (*net.UDPConn, error) {
+func connect() (net.Conn, error) {
+	return connectContext(context.Background())
+}
 
-	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", logHost, logPort))
-	if err != nil {
-		slog.Error("Failed to resolve udp address")
-		return nil, err
+// connectContext dials the configured endpoint, or its fallbacks, until ctx
+// is done
+func connectContext(ctx context.Context) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if f := forwarderEndpoints.Load(); f != nil {
+		conn, err = f.dialContext(ctx)
+	} else {
+		conn, err = dialEndpointContext(ctx, protocol, logHost, logPort, writeTimeout, forwarderSettings(), resolver)
 	}
+	recordError(SinkForwarder, OpDial, err)
+	return conn, err
+}
+
+func dialUDP(ctx context.Context, host string, port int) (net.Conn, error) {
 
-	con, err := net.DialUDP("udp", nil, addr)
+	// resolving the host is the only part of dialling udp that can block
+	var dialer net.Dialer
+	con, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(host, strconv.Itoa(port)))
 	if err != nil {
-		slog.Error("Failed to dial udp")
+		diag().Error("Failed to dial udp")
 		return nil, err
 	}
 
@@ -2,13 +2,18 @@ package logger
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
 )
 
 func TestDefaultAttrs(t *testing.T) {
@@ -115,6 +120,12 @@ func TestReplaceAttr(t *testing.T) {
 			input:    slog.String("timestampOverride", "2023-01-01T00:00:00Z"),
 			expected: slog.String("@timestamp", "2023-01-01T00:00:00Z"),
 		},
+		{
+			name:     "level to its name",
+			groups:   []string{},
+			input:    slog.Any("level", slog.LevelWarn+2),
+			expected: slog.String("level", "WARN+2"),
+		},
 		{
 			name:     "no change for other keys",
 			groups:   []string{},
@@ -265,6 +276,36 @@ func TestInitialize_ConfigError(t *testing.T) {
 	}
 }
 
+func TestInitialize_RejectedConfigKeepsRunning(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	cfg := NewConfig()
+	cfg.LogType = "good"
+	cfg.LogHost = "127.0.0.1"
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+	want := DefaultAttrs()
+
+	cfg.LogType = "bad"
+	cfg.Level = "loud"
+	if err := Initialize(cfg); err == nil {
+		t.Fatal("Initialize() returned no error for an invalid level")
+	}
+	if _, err := NewHandler(cfg); err == nil {
+		t.Fatal("NewHandler() returned no error for an invalid level")
+	}
+
+	if got := DefaultAttrs(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("DefaultAttrs() = %v after a rejected config, want %v", got, want)
+	}
+	if level != "debug" {
+		t.Errorf("level = %q after a rejected config, want the running %q", level, "debug")
+	}
+}
+
 func TestInitialize_ValidConfig(t *testing.T) {
 	// Save original values
 	originalHostname := hostname
@@ -335,6 +376,52 @@ func TestInitialize_OnceSemantics(t *testing.T) {
 	}
 }
 
+func TestInitializeContext_FallsBackToStdout(t *testing.T) {
+	preserveConfig(t)
+	fastReconnect(t)
+	// earlier tests may have left the forwarder attached
+	Shutdown(context.Background())
+	defer Shutdown(context.Background())
+
+	// an endpoint that accepts connections but never completes the TLS
+	// handshake stalls the first connection attempt
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	cfg := NewConfig()
+	cfg.LogType = "deadline-type"
+	cfg.Protocol = ProtocolTCP
+	cfg.TLS = &TLSConfig{InsecureSkipVerify: true}
+	cfg.LogHost = "127.0.0.1"
+	cfg.LogPort = listener.Addr().(*net.TCPAddr).Port
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := InitializeContext(ctx, cfg); err != nil {
+		t.Fatalf("InitializeContext() returned unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("InitializeContext() took %v, want to return once the context is done", elapsed)
+	}
+	if !forwarder.discarding() {
+		t.Error("forwarder is attached, want stdout only until the endpoint is reached")
+	}
+}
+
 // Test helper functions
 func TestPackageVariables(t *testing.T) {
 	// Test that package variables can be set and read
@@ -575,3 +662,263 @@ func BenchmarkUnsynchronizedUDPWriter(b *testing.B) {
 		}
 	})
 }
+
+// preserveConfig restores the package configuration when the test finishes
+func preserveConfig(t testing.TB) {
+	t.Helper()
+	original := current()
+	originalHostname, originalSender, originalRunning := hostname, sender, running
+	t.Cleanup(func() {
+		running = originalRunning
+		addSource = original.AddSource
+		sourceFormat = original.SourceFormat
+		sourceSkip = original.SourceSkip
+		applicationName = original.ApplicationName
+		logChannel = original.LogChannel
+		logHost = original.LogHost
+		logPort = original.LogPort
+		fallbackHosts = original.FallbackHosts
+		failbackInterval = original.FailbackInterval
+		senderID = original.SenderID
+		senderIDFile = original.SenderIDFile
+		lagoonMetadata = original.LagoonMetadata
+		schemaField = original.SchemaField
+		logType = original.LogType
+		messageVersion = original.MessageVersion
+		level = original.Level
+		stdoutLevel = original.StdoutLevel
+		forwardLevel = original.ForwardLevel
+		stdoutFormat = original.StdoutFormat
+		scheduleWindows = original.Schedule
+		scheduleTimezone = original.ScheduleTimezone
+		protocol = original.Protocol
+		writeTimeout = original.WriteTimeout
+		deliveryWorkers = original.DeliveryWorkers
+		queueSize = original.QueueSize
+		batchSize = original.BatchSize
+		batchInterval = original.BatchInterval
+		batchLatency = original.BatchLatency
+		ordering = original.Ordering
+		orderingKey = original.OrderingKey
+		deliveryPolicy = original.DeliveryPolicy
+		recordAttempts = original.RecordAttempts
+		skewProbeURL = original.SkewProbeURL
+		skewProbeInterval = original.SkewProbeInterval
+		spoolDir = original.SpoolDir
+		spoolMaxBytes = original.SpoolMaxBytes
+		statsFile = original.StatsFile
+		statsInterval = original.StatsInterval
+		statsEventInterval = original.StatsEventInterval
+		statsEventChannel = original.StatsEventChannel
+		exitTimeout = original.ExitTimeout
+		replayWindow = original.ReplayWindow
+		captureStderrOutput = original.CaptureStderr
+		stdLogLevel = original.StdLogLevel
+		strictReconfigure = original.StrictReconfigure
+		maxAttrs = original.MaxAttrs
+		maxAttrDepth = original.MaxAttrDepth
+		maxMessageBytes = original.MaxMessageBytes
+		messageTruncation = original.MessageTruncation
+		controlChars = original.ControlChars
+		processors = original.Processors
+		onLevel = original.OnLevel
+		computedFields = original.ComputedAttrs
+		levelRouting = original.LevelRoutes
+		maxValueFields = original.MaxValueFields
+		maxValueDepth = original.MaxValueDepth
+		preferStringer = original.PreferStringer
+		maxStringRunes = original.MaxStringRunes
+		normalizeStrings = original.NormalizeStrings
+		strictSchema = original.StrictSchema
+		profiling.Store(original.Profiling)
+		compatMode = original.CompatMode
+		spanContext = original.SpanContext
+		compressFields = original.CompressFields
+		compressThreshold = original.CompressThreshold
+		egressBudget = original.EgressBudget
+		egressWindow = original.EgressWindow
+		egressSampleRate = original.EgressSampleRate
+		maxEventsPerSecond = original.MaxEventsPerSecond
+		burst = original.Burst
+		memoryPressure = original.MemoryPressure
+		memoryLimit = original.MemoryLimit
+		memorySampleRate = original.MemorySampleRate
+		remoteConfigURL = original.RemoteConfigURL
+		remoteConfigKey = original.RemoteConfigKey
+		remoteConfigInterval = original.RemoteConfigInterval
+		flagProvider = original.Flags
+		flagRefreshInterval = original.FlagRefreshInterval
+		setDiagnostics(original.Diagnostics)
+		debugSignal = original.DebugSignal
+		tracing.Store(original.Trace)
+		faults = original.Faults
+		tlsSettings = original.TLS
+		httpSettings = original.HTTP
+		fluentSettings = original.Forward
+		otlpSettings = original.OTLP
+		syslogSettings = original.Syslog
+		destinations = original.Destinations
+		resolver = original.Resolver
+		retryClock.Store(clockHolder{original.Clock})
+		hostname = originalHostname
+		sender = originalSender
+	})
+}
+
+func TestNewWriterHandler(t *testing.T) {
+	preserveConfig(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "writer-type"
+	cfg.AddSource = false
+
+	var buf bytes.Buffer
+	handler, err := NewWriterHandler(cfg, &buf)
+	if err != nil {
+		t.Fatalf("NewWriterHandler() returned unexpected error: %v", err)
+	}
+
+	slog.New(handler).Info("hello")
+
+	for _, want := range []string{`"message":"hello"`, `"type":"writer-type"`, `"channel":"LagoonLogs"`, `"@timestamp":`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("NewWriterHandler() output missing %q in %q", want, buf.String())
+		}
+	}
+
+	cfg.LogType = ""
+	if _, err := NewWriterHandler(cfg, &buf); err == nil {
+		t.Error("NewWriterHandler() should return error for invalid config")
+	}
+}
+
+func TestNewWriterHandler_Syslog(t *testing.T) {
+	preserveConfig(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "writer-type"
+	cfg.Protocol = ProtocolTCP
+	cfg.Syslog = &SyslogConfig{}
+
+	var buf bytes.Buffer
+	handler, err := NewWriterHandler(cfg, &buf)
+	if err != nil {
+		t.Fatalf("NewWriterHandler() returned unexpected error: %v", err)
+	}
+	slog.New(handler).Info("hello")
+
+	// the events are framed like those of the forwarder, octet counted over TCP
+	length, msg, _ := strings.Cut(buf.String(), " ")
+	if length != strconv.Itoa(len(msg)) {
+		t.Errorf("length prefix = %s, want %d", length, len(msg))
+	}
+	if !strings.HasPrefix(msg, "<134>1 ") || !strings.Contains(msg, `"message":"hello"`) {
+		t.Errorf("NewWriterHandler() wrote %q, want a syslog message holding the event", msg)
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	receiver, err := loggertest.Listen(loggertest.UDP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer receiver.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "handler-type"
+	cfg.LogHost = receiver.Host()
+	cfg.LogPort = receiver.Port()
+
+	previous := slog.Default()
+	handler, err := NewHandler(cfg)
+	if err != nil {
+		t.Fatalf("NewHandler() returned unexpected error: %v", err)
+	}
+	if slog.Default() != previous {
+		t.Error("NewHandler() replaced the default logger")
+	}
+
+	// callers compose the handler with their own
+	slog.New(handler.WithAttrs([]slog.Attr{slog.String("library", "payments")})).Info("composed")
+
+	if !receiver.Wait(1, time.Second) {
+		t.Fatal("record was not forwarded")
+	}
+	event := receiver.Events()[0]
+	if event["type"] != "handler-type" || event["library"] != "payments" || event["message"] != "composed" {
+		t.Errorf("forwarded %v, want the composed Lagoon record", event)
+	}
+
+	cfg.LogType = ""
+	if _, err := NewHandler(cfg); err == nil {
+		t.Error("NewHandler() should return error for invalid config")
+	}
+}
+
+func TestDial(t *testing.T) {
+	cfg := NewConfig()
+	cfg.LogHost = "127.0.0.1"
+
+	conn, err := Dial(cfg)
+	if err != nil {
+		t.Fatalf("Dial() returned unexpected error: %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("Dial() connection failed to close: %v", err)
+	}
+
+	cfg.LogHost = "invalid-address-format:::"
+	if _, err := Dial(cfg); err == nil {
+		t.Error("Dial() should return error for invalid address")
+	}
+}
+
+// containsAll reports whether s contains every one of substrs
+func containsAll(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if !strings.Contains(s, substr) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDefaultAttrs_Snapshot(t *testing.T) {
+	preserveConfig(t)
+	messageVersion = 2
+	applicationName = "test-app"
+	logChannel = "TestChannel"
+	hostname = "test-host"
+	logType = "test-type"
+	sender = "pod-1"
+	lagoonMetadata = false
+
+	attrs := DefaultAttrs()
+	got := map[string]string{}
+	for _, a := range attrs {
+		got[a.Key] = a.Value.String()
+	}
+	want := map[string]string{
+		"@version":    "2",
+		"application": "test-app",
+		"channel":     "TestChannel",
+		"context":     "[]",
+		"extra":       "[]",
+		"host":        "test-host",
+		"type":        "test-type",
+		senderKey:     "pod-1",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("DefaultAttrs() = %v, want %v", got, want)
+	}
+
+	// the attributes are a copy
+	attrs[0] = slog.String("application", "changed")
+	if DefaultAttrs()[0].Key != "@version" {
+		t.Error("changing the result of DefaultAttrs() changed the static attributes")
+	}
+}
@@ -0,0 +1,102 @@
+package loggertest
+
+import (
+	"sync"
+	"time"
+)
+
+// ManualClock is a logger.Clock whose time only moves when the test advances
+// it, so reconnects and retries happen at the moment the test chooses
+// instead of after real backoff delays:
+//
+//	clock := loggertest.NewManualClock(time.Now())
+//	cfg.Clock = clock
+//	...
+//	clock.WaitTimers(1, time.Second) // the forwarder is backing off
+//	clock.Advance(time.Minute)       // and retries now
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []manualTimer
+	notify chan struct{}
+}
+
+// manualTimer is a pending After
+type manualTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewManualClock returns a clock standing at start
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start, notify: make(chan struct{})}
+}
+
+// Now returns the time the clock stands at
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel receiving the time once the clock is advanced by
+// d. A d of zero or less fires at once.
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, manualTimer{at: c.now.Add(d), ch: ch})
+	close(c.notify)
+	c.notify = make(chan struct{})
+	return ch
+}
+
+// Advance moves the clock forward by d, firing the timers due by then
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = pending
+}
+
+// Timers returns the number of pending timers
+func (c *ManualClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// WaitTimers waits until at least n timers are pending, reporting whether
+// they were before the timeout. Advancing the clock only fires the timers
+// already set, so tests wait for the forwarder to back off first.
+func (c *ManualClock) WaitTimers(n int, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		c.mu.Lock()
+		pending, notify := len(c.timers), c.notify
+		c.mu.Unlock()
+		if pending >= n {
+			return true
+		}
+
+		select {
+		case <-notify:
+		case <-deadline:
+			return false
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package loggertest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewManualClock(start)
+
+	now := clock.After(0)
+	soon := clock.After(time.Second)
+	later := clock.After(time.Minute)
+	select {
+	case <-now:
+	default:
+		t.Error("After(0) did not fire at once")
+	}
+	if clock.Timers() != 2 {
+		t.Fatalf("Timers() = %d, want 2", clock.Timers())
+	}
+
+	clock.Advance(30 * time.Second)
+	select {
+	case at := <-soon:
+		if !at.Equal(start.Add(30 * time.Second)) {
+			t.Errorf("timer fired at %v, want the advanced time", at)
+		}
+	default:
+		t.Error("timer due did not fire")
+	}
+	select {
+	case <-later:
+		t.Error("timer fired before it was due")
+	default:
+	}
+	if got := clock.Now(); !got.Equal(start.Add(30 * time.Second)) {
+		t.Errorf("Now() = %v, want %v", got, start.Add(30*time.Second))
+	}
+}
+
+func TestManualClock_WaitTimers(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	if clock.WaitTimers(1, 10*time.Millisecond) {
+		t.Error("WaitTimers() reported a timer nobody set")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		clock.After(time.Hour)
+	}()
+	if !clock.WaitTimers(1, time.Second) {
+		t.Error("WaitTimers() did not see the timer set")
+	}
+}
@@ -0,0 +1,315 @@
+// Package loggertest provides an in-process receiver standing in for the
+// Lagoon Logstash endpoint, so tests can assert on the events an application
+// forwards. It accepts newline delimited JSON over UDP, TCP or HTTP and can
+// simulate the endpoint going away. ManualClock steps through the forwarder's
+// reconnects and retries without real delays.
+package loggertest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Networks accepted by Listen
+const (
+	UDP  = "udp"
+	TCP  = "tcp"
+	HTTP = "http"
+)
+
+// maxDatagram is the largest UDP payload the receiver reads
+const maxDatagram = 64 * 1024
+
+// Receiver collects the events sent to it
+type Receiver struct {
+	packet net.PacketConn
+	stream net.Listener
+	server *http.Server
+
+	mu     sync.Mutex
+	events []map[string]any
+	errors int
+	down   bool
+	conns  map[net.Conn]struct{}
+	notify chan struct{}
+
+	wg sync.WaitGroup
+}
+
+// Listen starts a receiver on a random localhost port for network, one of
+// UDP, TCP or HTTP
+func Listen(network string) (*Receiver, error) {
+	return ListenAddr(network, "127.0.0.1:0")
+}
+
+// ListenAddr starts a receiver on addr, so an endpoint can come up on a port
+// the application under test already tries to reach
+func ListenAddr(network, addr string) (*Receiver, error) {
+	r := &Receiver{
+		conns:  map[net.Conn]struct{}{},
+		notify: make(chan struct{}),
+	}
+
+	switch network {
+	case UDP:
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		r.packet = conn
+		r.wg.Add(1)
+		go r.servePackets()
+	case TCP, HTTP:
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		r.stream = listener
+		r.wg.Add(1)
+		if network == TCP {
+			go r.serveStreams()
+		} else {
+			r.server = &http.Server{Handler: http.HandlerFunc(r.serveHTTP), ReadHeaderTimeout: 5 * time.Second}
+			go func() {
+				defer r.wg.Done()
+				_ = r.server.Serve(listener)
+			}()
+		}
+	default:
+		return nil, fmt.Errorf("unknown network %q", network)
+	}
+
+	return r, nil
+}
+
+// Addr returns the host:port the receiver listens on
+func (r *Receiver) Addr() string {
+	if r.packet != nil {
+		return r.packet.LocalAddr().String()
+	}
+	return r.stream.Addr().String()
+}
+
+// Host returns the host the receiver listens on, for Config.LogHost
+func (r *Receiver) Host() string {
+	host, _, _ := net.SplitHostPort(r.Addr())
+	return host
+}
+
+// Port returns the port the receiver listens on, for Config.LogPort
+func (r *Receiver) Port() int {
+	if r.packet != nil {
+		return r.packet.LocalAddr().(*net.UDPAddr).Port
+	}
+	return r.stream.Addr().(*net.TCPAddr).Port
+}
+
+// URL returns the address of an HTTP receiver as a URL
+func (r *Receiver) URL() string {
+	return "http://" + r.Addr()
+}
+
+// Events returns a copy of the events received so far
+func (r *Receiver) Events() []map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]map[string]any(nil), r.events...)
+}
+
+// Count returns the number of events received so far
+func (r *Receiver) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events)
+}
+
+// Errors returns the number of payloads that were not valid JSON
+func (r *Receiver) Errors() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.errors
+}
+
+// Wait blocks until at least n events were received and reports false when
+// timeout passes first
+func (r *Receiver) Wait(n int, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		r.mu.Lock()
+		count, notify := len(r.events), r.notify
+		r.mu.Unlock()
+
+		if count >= n {
+			return true
+		}
+
+		select {
+		case <-notify:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// SetDown simulates the endpoint becoming unavailable: UDP datagrams are
+// discarded, TCP connections are closed and refused, and HTTP requests fail
+// with 503 Service Unavailable, until SetDown(false) is called
+func (r *Receiver) SetDown(down bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.down = down
+	if down {
+		for conn := range r.conns {
+			_ = conn.Close()
+		}
+	}
+}
+
+// Flap takes the endpoint down for d
+func (r *Receiver) Flap(d time.Duration) {
+	r.SetDown(true)
+	time.Sleep(d)
+	r.SetDown(false)
+}
+
+// Close stops the receiver
+func (r *Receiver) Close() error {
+	var err error
+	switch {
+	case r.packet != nil:
+		err = r.packet.Close()
+	case r.server != nil:
+		err = r.server.Close()
+	default:
+		err = r.stream.Close()
+		r.SetDown(true)
+	}
+	r.wg.Wait()
+	return err
+}
+
+func (r *Receiver) isDown() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.down
+}
+
+func (r *Receiver) servePackets() {
+	defer r.wg.Done()
+
+	buf := make([]byte, maxDatagram)
+	for {
+		n, _, err := r.packet.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if r.isDown() {
+			continue
+		}
+		r.receive(buf[:n])
+	}
+}
+
+func (r *Receiver) serveStreams() {
+	defer r.wg.Done()
+
+	for {
+		conn, err := r.stream.Accept()
+		if err != nil {
+			return
+		}
+
+		r.mu.Lock()
+		if r.down {
+			r.mu.Unlock()
+			_ = conn.Close()
+			continue
+		}
+		r.conns[conn] = struct{}{}
+		r.mu.Unlock()
+
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.readLines(conn)
+
+			r.mu.Lock()
+			delete(r.conns, conn)
+			r.mu.Unlock()
+			_ = conn.Close()
+		}()
+	}
+}
+
+func (r *Receiver) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.isDown() {
+		http.Error(w, "receiver is down", http.StatusServiceUnavailable)
+		return
+	}
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.readLines(req.Body)
+	w.WriteHeader(http.StatusOK)
+}
+
+// readLines receives every line of src as an event
+func (r *Receiver) readLines(src io.Reader) {
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxDatagram*16)
+	for scanner.Scan() {
+		r.receive(scanner.Bytes())
+	}
+}
+
+// receive records every JSON object in payload
+func (r *Receiver) receive(payload []byte) {
+	for _, line := range bytes.Split(payload, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var event map[string]any
+		err := json.Unmarshal(line, &event)
+
+		r.mu.Lock()
+		if err != nil {
+			r.errors++
+		} else {
+			r.events = append(r.events, event)
+			close(r.notify)
+			r.notify = make(chan struct{})
+		}
+		r.mu.Unlock()
+	}
+}
+
+// Tally counts events by the value of the top level attribute key, returning
+// the number of distinct values and the number of events repeating a value
+// already seen. Events without the attribute are ignored.
+func Tally(events []map[string]any, key string) (unique, duplicates int) {
+	seen := map[string]bool{}
+	for _, event := range events {
+		value, ok := event[key]
+		if !ok {
+			continue
+		}
+		id := fmt.Sprint(value)
+		if seen[id] {
+			duplicates++
+			continue
+		}
+		seen[id] = true
+	}
+	return len(seen), duplicates
+}
@@ -0,0 +1,93 @@
+package loggertest
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// send delivers payload to r over its network
+func send(t *testing.T, network string, r *Receiver, payload string) {
+	t.Helper()
+
+	switch network {
+	case HTTP:
+		resp, err := http.Post(r.URL(), "application/x-ndjson", strings.NewReader(payload))
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		resp.Body.Close()
+	default:
+		conn, err := net.Dial(network, r.Addr())
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer conn.Close()
+		if _, err := conn.Write([]byte(payload)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+}
+
+func TestReceiver(t *testing.T) {
+	for _, network := range []string{UDP, TCP, HTTP} {
+		t.Run(network, func(t *testing.T) {
+			r, err := Listen(network)
+			if err != nil {
+				t.Fatalf("Listen(%q) returned unexpected error: %v", network, err)
+			}
+			defer r.Close()
+
+			send(t, network, r, "{\"seq\":1}\n{\"seq\":2}\nnot json\n")
+
+			if !r.Wait(2, time.Second) {
+				t.Fatalf("received %d events, want 2", r.Count())
+			}
+			if unique, duplicates := Tally(r.Events(), "seq"); unique != 2 || duplicates != 0 {
+				t.Errorf("Tally() = %d, %d; want 2, 0", unique, duplicates)
+			}
+			if r.Errors() != 1 {
+				t.Errorf("Errors() = %d, want 1", r.Errors())
+			}
+		})
+	}
+}
+
+func TestReceiver_SetDown(t *testing.T) {
+	r, err := Listen(HTTP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	r.SetDown(true)
+	resp, err := http.Post(r.URL(), "application/x-ndjson", strings.NewReader("{\"seq\":1}\n"))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status while down = %d, want 503", resp.StatusCode)
+	}
+
+	r.SetDown(false)
+	send(t, HTTP, r, "{\"seq\":2}\n")
+	if r.Count() != 1 {
+		t.Errorf("Count() = %d, want 1 event received after coming back up", r.Count())
+	}
+}
+
+func TestListen_UnknownNetwork(t *testing.T) {
+	if _, err := Listen("sctp"); err == nil {
+		t.Error("Listen() should reject unknown networks")
+	}
+}
+
+func TestTally(t *testing.T) {
+	events := []map[string]any{{"id": "a"}, {"id": "b"}, {"id": "a"}, {"other": 1}}
+	if unique, duplicates := Tally(events, "id"); unique != 2 || duplicates != 1 {
+		t.Errorf("Tally() = %d, %d; want 2, 1", unique, duplicates)
+	}
+}
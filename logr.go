@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// logrErrorKey is the key logr's slog sink writes the error of Error calls to
+const logrErrorKey = "err"
+
+// logrHandler maps the records of logr's slog sink to the Lagoon levels and
+// fields
+type logrHandler struct {
+	h       slog.Handler
+	grouped bool
+}
+
+// NewLogrHandler returns h for the loggers of controller-runtime and other
+// logr users, to be wrapped with logr.FromSlogHandler:
+//
+//	ctrl.SetLogger(logr.FromSlogHandler(logger.NewLogrHandler(handler)))
+//
+// logr writes V(n) at slog level -n. V(0) is logged at Info and every
+// higher verbosity at Debug with the verbosity as FieldVerbosity. The error
+// of Error calls is written to FieldError.
+func NewLogrHandler(h slog.Handler) slog.Handler {
+	return &logrHandler{h: h}
+}
+
+// logrLevel returns the Lagoon level of a logr record at level, and its
+// verbosity
+func logrLevel(level slog.Level) (slog.Level, int) {
+	if level >= slog.LevelInfo {
+		return level, 0
+	}
+	return slog.LevelDebug, int(-level)
+}
+
+func (h *logrHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	level, _ = logrLevel(level)
+	return h.h.Enabled(ctx, level)
+}
+
+func (h *logrHandler) Handle(ctx context.Context, r slog.Record) error {
+	level, v := logrLevel(r.Level)
+	out := slog.NewRecord(r.Time, level, r.Message, r.PC)
+	if v > 0 {
+		out.AddAttrs(slog.Int(FieldVerbosity, v))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.h.Handle(ctx, out)
+}
+
+// attr returns a with the key of logr's errors renamed at the top level
+func (h *logrHandler) attr(a slog.Attr) slog.Attr {
+	if !h.grouped && a.Key == logrErrorKey {
+		a.Key = FieldError
+	}
+	return a
+}
+
+func (h *logrHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	renamed := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		renamed[i] = h.attr(a)
+	}
+	return &logrHandler{h: h.h.WithAttrs(renamed), grouped: h.grouped}
+}
+
+func (h *logrHandler) WithGroup(name string) slog.Handler {
+	if len(name) == 0 {
+		return h
+	}
+	return &logrHandler{h: h.h.WithGroup(name), grouped: true}
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// logrRecord returns a record as logr's slog sink writes them at V(v)
+func logrRecord(v int, msg string, attrs ...slog.Attr) slog.Record {
+	r := slog.NewRecord(time.Now(), slog.Level(-v), msg, 0)
+	r.AddAttrs(attrs...)
+	return r
+}
+
+func TestLogrHandler(t *testing.T) {
+	preserveConfig(t)
+	level = "debug"
+
+	var buf bytes.Buffer
+	h := NewLogrHandler(newSinkHandler(sink{w: &buf}))
+
+	tests := []struct {
+		name    string
+		record  slog.Record
+		level   string
+		v       float64
+		wantErr bool
+	}{
+		{"V(0)", logrRecord(0, "reconciled"), "INFO", 0, false},
+		{"V(1)", logrRecord(1, "reconciling"), "DEBUG", 1, false},
+		{"V(5)", logrRecord(5, "cache synced"), "DEBUG", 5, false},
+		{"Error", func() slog.Record {
+			r := slog.NewRecord(time.Now(), slog.LevelError, "reconcile failed", 0)
+			r.AddAttrs(slog.Any(logrErrorKey, errors.New("conflict")))
+			return r
+		}(), "ERROR", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			if err := h.Handle(context.Background(), tt.record); err != nil {
+				t.Fatalf("Handle() returned unexpected error: %v", err)
+			}
+			var event map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+				t.Fatalf("invalid event %q: %v", buf.Bytes(), err)
+			}
+			if event["level"] != tt.level {
+				t.Errorf("level = %v, want %s", event["level"], tt.level)
+			}
+			if v, _ := event[FieldVerbosity].(float64); v != tt.v {
+				t.Errorf("%s = %v, want %v", FieldVerbosity, event[FieldVerbosity], tt.v)
+			}
+			if _, ok := event[FieldError]; ok != tt.wantErr {
+				t.Errorf("event = %s, want %s only for errors", buf.Bytes(), FieldError)
+			}
+			if _, ok := event[logrErrorKey]; ok {
+				t.Errorf("event = %s, want %q renamed", buf.Bytes(), logrErrorKey)
+			}
+		})
+	}
+}
+
+func TestLogrHandler_Enabled(t *testing.T) {
+	preserveConfig(t)
+	level = "info"
+
+	h := NewLogrHandler(newSinkHandler(sink{w: &bytes.Buffer{}}))
+	if !h.Enabled(context.Background(), slog.Level(0)) {
+		t.Error("V(0) is disabled at info, want it logged")
+	}
+	if h.Enabled(context.Background(), slog.Level(-1)) {
+		t.Error("V(1) is enabled at info, want it logged at debug only")
+	}
+}
+
+func TestLogrHandler_WithAttrs(t *testing.T) {
+	preserveConfig(t)
+
+	var buf bytes.Buffer
+	h := NewLogrHandler(newSinkHandler(sink{w: &buf})).
+		WithAttrs([]slog.Attr{slog.String(logrErrorKey, "stale"), slog.String("logger", "controller")}).
+		WithGroup("request").
+		WithAttrs([]slog.Attr{slog.String(logrErrorKey, "kept")})
+	if err := h.Handle(context.Background(), logrRecord(0, "reconciled")); err != nil {
+		t.Fatalf("Handle() returned unexpected error: %v", err)
+	}
+
+	var event struct {
+		Error   string            `json:"error"`
+		Logger  string            `json:"logger"`
+		Request map[string]string `json:"request"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("invalid event %q: %v", buf.Bytes(), err)
+	}
+	if event.Error != "stale" || event.Logger != "controller" || event.Request[logrErrorKey] != "kept" {
+		t.Errorf("event = %s, want err renamed at the top level only", buf.Bytes())
+	}
+}
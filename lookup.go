@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Lookup returns a processor enriching records with the fields of the table
+// entry named by the value of the key attribute, a dotted path such as
+// "context.service_id". The fields are added at the top level, so an internal
+// service ID is written with its team and owner rather than mapped in
+// Logstash. Records without the attribute or an entry are left alone.
+func Lookup(key string, table map[string]map[string]any) Processor {
+	path := strings.Split(key, ".")
+	entries := make(map[string][]slog.Attr, len(table))
+	for value, fields := range table {
+		attrs := make([]slog.Attr, 0, len(fields))
+		for name, field := range fields {
+			attrs = append(attrs, slog.Any(name, field))
+		}
+		// the fields are written in the same order for every record
+		slices.SortFunc(attrs, func(a, b slog.Attr) int { return strings.Compare(a.Key, b.Key) })
+		entries[value] = attrs
+	}
+
+	return func(_ context.Context, r slog.Record) (slog.Record, bool) {
+		var value slog.Value
+		var found bool
+		r.Attrs(func(a slog.Attr) bool {
+			value, found = lookupAttr(a, path)
+			return !found
+		})
+		if !found {
+			return r, true
+		}
+		if fields, ok := entries[value.String()]; ok {
+			r.AddAttrs(fields...)
+		}
+		return r, true
+	}
+}
+
+// lookupAttr returns the value at path below a, which must be named path[0]
+func lookupAttr(a slog.Attr, path []string) (slog.Value, bool) {
+	if a.Key != path[0] {
+		return slog.Value{}, false
+	}
+	v := a.Value.Resolve()
+	if len(path) == 1 {
+		return v, v.Kind() != slog.KindGroup
+	}
+	if v.Kind() != slog.KindGroup {
+		return slog.Value{}, false
+	}
+	for _, m := range v.Group() {
+		if value, ok := lookupAttr(m, path[1:]); ok {
+			return value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+// LookupFile returns a Lookup processor with the table read from a JSON or
+// CSV file. A JSON file is an object of entries, each an object of fields:
+//
+//	{"svc-1042": {"team": "payments", "owner": "jane"}}
+//
+// A CSV file has a header row, and the column named like the last element of
+// key holds the value every other column of the row is looked up by:
+//
+//	service_id,team,owner
+//	svc-1042,payments,jane
+//
+// The file is read once, a changed table takes a new processor.
+func LookupFile(key, path string) (Processor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	table := map[string]map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.NewDecoder(f).Decode(&table); err != nil {
+			return nil, fmt.Errorf("read lookup table %s: %w", path, err)
+		}
+	case ".csv":
+		rows, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("read lookup table %s: %w", path, err)
+		}
+		if len(rows) == 0 {
+			return nil, fmt.Errorf("read lookup table %s: no header row", path)
+		}
+		column := key[strings.LastIndex(key, ".")+1:]
+		index := slices.Index(rows[0], column)
+		if index < 0 {
+			return nil, fmt.Errorf("read lookup table %s: no column %q", path, column)
+		}
+		for _, row := range rows[1:] {
+			fields := map[string]any{}
+			for i, name := range rows[0] {
+				if i != index {
+					fields[name] = row[i]
+				}
+			}
+			table[row[index]] = fields
+		}
+	default:
+		return nil, errors.New("lookup table must be a .json or .csv file")
+	}
+
+	return Lookup(key, table), nil
+}
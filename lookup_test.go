@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLookup(t *testing.T) {
+	lookup := Lookup("context.service_id", map[string]map[string]any{
+		"svc-1042": {"team": "payments", "owner": "jane"},
+		"42":       {"team": "search"},
+	})
+
+	tests := []struct {
+		name  string
+		attrs []slog.Attr
+		want  map[string]any
+	}{
+		{"nested string", []slog.Attr{slog.Group("context", slog.String("service_id", "svc-1042"))}, map[string]any{"team": "payments", "owner": "jane"}},
+		{"nested number", []slog.Attr{slog.Group("context", slog.Int("service_id", 42))}, map[string]any{"team": "search"}},
+		{"unknown value", []slog.Attr{slog.Group("context", slog.String("service_id", "svc-1"))}, map[string]any{}},
+		{"top-level attribute", []slog.Attr{slog.String("service_id", "svc-1042")}, map[string]any{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0)
+			r.AddAttrs(tt.attrs...)
+			out, keep := lookup(context.Background(), r)
+
+			got := map[string]any{}
+			out.Attrs(func(a slog.Attr) bool {
+				if a.Value.Kind() != slog.KindGroup && a.Key != "service_id" {
+					got[a.Key] = a.Value.Any()
+				}
+				return true
+			})
+			if !keep || len(got) != len(tt.want) {
+				t.Fatalf("added %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("%s = %v, want %v", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestLookupFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"owners.json": `{"svc-1042": {"team": "payments", "owner": "jane"}}`,
+		"owners.csv":  "team,service_id,owner\npayments,svc-1042,jane\n",
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			lookup, err := LookupFile("context.service_id", path)
+			if err != nil {
+				t.Fatalf("LookupFile() returned unexpected error: %v", err)
+			}
+
+			preserveConfig(t)
+			processors = []Processor{lookup}
+			var buf bytes.Buffer
+			slog.New(newHandler(&buf)).With(slog.Group("context", slog.String("service_id", "svc-1042"))).Info("paid")
+
+			var event map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+				t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+			}
+			if event["team"] != "payments" || event["owner"] != "jane" {
+				t.Errorf("event = %v, want the team and owner added", event)
+			}
+		})
+	}
+
+	bad := map[string]string{
+		"missing.csv":  "",
+		"nocolumn.csv": "id,team\nsvc-1042,payments\n",
+		"invalid.json": "[1, 2]",
+		"owners.yaml":  "svc-1042: {}",
+	}
+	for name, content := range bad {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LookupFile("context.service_id", path); err == nil {
+			t.Errorf("LookupFile(%s) returned no error", name)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// memoryCheckInterval is how often the memory monitor measures the
+	// memory in use
+	memoryCheckInterval = time.Second
+	// pressureQueueShare divides the capacity of a delivery queue under
+	// memory pressure
+	pressureQueueShare = 10
+)
+
+// cgroupMemoryFiles are the memory limits of cgroup v2 and v1
+var cgroupMemoryFiles = []string{
+	"/sys/fs/cgroup/memory.max",
+	"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+}
+
+// pressureSampleRate is the sampling rate applied while the process is under
+// memory pressure, 0 while it isn't
+var pressureSampleRate atomic.Int64
+
+// underPressure reports whether the process is under memory pressure
+func underPressure() bool {
+	return pressureSampleRate.Load() > 0
+}
+
+// memoryMonitor throttles the logger while the memory in use exceeds a
+// share of the limit
+type memoryMonitor struct {
+	limit      int64
+	threshold  float64 // share of limit at which the pressure starts
+	sampleRate int
+	usage      func() int64
+}
+
+// newMemoryMonitor returns the monitor of the applied config, nil when it is
+// disabled or no limit is known
+func newMemoryMonitor() *memoryMonitor {
+	if memoryPressure <= 0 {
+		return nil
+	}
+	limit := memoryLimitOf(memoryLimit)
+	if limit <= 0 {
+		diag().Warn("No memory limit found, memory pressure is not monitored")
+		return nil
+	}
+	return &memoryMonitor{limit: limit, threshold: memoryPressure, sampleRate: memorySampleRate, usage: runtimeMemory}
+}
+
+// run checks the memory in use every memoryCheckInterval until ctx is done
+func (m *memoryMonitor) run(ctx context.Context) {
+	defer pressureSampleRate.Store(0)
+
+	ticker := time.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check starts the pressure once the memory in use reaches the threshold,
+// and ends it once the use is back below nine tenths of it, so the logger
+// doesn't flip at the threshold
+func (m *memoryMonitor) check() {
+	used := m.usage()
+	share := float64(used) / float64(m.limit)
+	attrs := []any{slog.Group("memory", slog.Int64("used_bytes", used), slog.Int64("limit_bytes", m.limit))}
+
+	switch {
+	case !underPressure() && share >= m.threshold:
+		pressureSampleRate.Store(int64(max(m.sampleRate, 1)))
+		diag().Warn("Memory pressure, sampling records and shrinking delivery queues", attrs...)
+	case underPressure() && share < m.threshold*0.9:
+		pressureSampleRate.Store(0)
+		diag().Info("Memory pressure relieved, logging resumed in full", attrs...)
+	}
+}
+
+// memoryLimitOf returns the limit configured, else the limit of the cgroup,
+// else GOMEMLIMIT, 0 when there is none
+func memoryLimitOf(configured int64) int64 {
+	if configured > 0 {
+		return configured
+	}
+	if limit := cgroupMemoryLimit(); limit > 0 {
+		return limit
+	}
+	if limit := debug.SetMemoryLimit(-1); limit < math.MaxInt64 {
+		return limit
+	}
+	return 0
+}
+
+// cgroupMemoryLimit returns the memory limit of the cgroup of the process, 0
+// when it is unlimited or unknown
+func cgroupMemoryLimit() int64 {
+	for _, path := range cgroupMemoryFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		// v2 reports "max" and v1 a huge number for an unlimited group
+		if err != nil || n >= 1<<62 {
+			return 0
+		}
+		return n
+	}
+	return 0
+}
+
+// runtimeMemory returns the memory the Go runtime holds from the system
+func runtimeMemory() int64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return int64(stats.Sys - stats.HeapReleased)
+}
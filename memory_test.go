@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// relievePressure ends memory pressure when the test finishes
+func relievePressure(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() { pressureSampleRate.Store(0) })
+}
+
+func TestMemoryMonitor_Check(t *testing.T) {
+	relievePressure(t)
+	used := int64(0)
+	m := &memoryMonitor{limit: 1000, threshold: 0.8, sampleRate: 10, usage: func() int64 { return used }}
+
+	steps := []struct {
+		used     int64
+		pressure bool
+	}{
+		{500, false},
+		{800, true},
+		{750, true}, // above nine tenths of the threshold
+		{710, false},
+		{790, false},
+	}
+	for _, step := range steps {
+		used = step.used
+		m.check()
+		if underPressure() != step.pressure {
+			t.Errorf("underPressure() = %v at %d of 1000 bytes, want %v", underPressure(), step.used, step.pressure)
+		}
+	}
+}
+
+func TestMemoryMonitor_SampleRate(t *testing.T) {
+	relievePressure(t)
+	m := &memoryMonitor{limit: 1000, threshold: 0.5, sampleRate: 0, usage: func() int64 { return 900 }}
+
+	m.check()
+	if got := pressureSampleRate.Load(); got != 1 {
+		t.Errorf("pressureSampleRate = %d with a sample rate of 0, want 1 so queues still shrink", got)
+	}
+}
+
+func TestCgroupMemoryLimit(t *testing.T) {
+	files := cgroupMemoryFiles
+	t.Cleanup(func() { cgroupMemoryFiles = files })
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected int64
+	}{
+		{"limited", "536870912\n", 512 << 20},
+		{"v2 unlimited", "max\n", 0},
+		{"v1 unlimited", "9223372036854771712\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cgroupMemoryFiles = []string{filepath.Join(dir, "missing"), path}
+			if got := cgroupMemoryLimit(); got != tt.expected {
+				t.Errorf("cgroupMemoryLimit() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+
+	if got := memoryLimitOf(1 << 30); got != 1<<30 {
+		t.Errorf("memoryLimitOf() = %d, want the configured limit", got)
+	}
+}
+
+func TestOverrideKeep_MemoryPressure(t *testing.T) {
+	relievePressure(t)
+	pressureSampleRate.Store(10)
+
+	kept := 0
+	for i := 0; i < 100; i++ {
+		if overrideKeep(slog.LevelInfo) {
+			kept++
+		}
+		if !overrideKeep(slog.LevelWarn) {
+			t.Fatal("overrideKeep() sampled a warning under memory pressure")
+		}
+	}
+	if kept != 10 {
+		t.Errorf("kept %d of 100 info records under memory pressure, want 10", kept)
+	}
+}
+
+func TestDeliveryPool_MemoryPressure(t *testing.T) {
+	relievePressure(t)
+	block := make(chan struct{})
+	pool := newDeliveryPool(1, 100, func() (io.WriteCloser, error) {
+		<-block
+		return &recordingConn{}, nil
+	})
+	defer pool.Close()
+	defer close(block)
+
+	pressureSampleRate.Store(10)
+	queued := 0
+	for i := 0; i < 100; i++ {
+		if _, err := pool.Write([]byte("x")); errors.Is(err, ErrQueueFull) {
+			break
+		}
+		queued++
+	}
+	// the worker may have taken one record off the queue
+	if queued < 10 || queued > 11 {
+		t.Errorf("queued %d records under memory pressure, want a tenth of the queue (10)", queued)
+	}
+}
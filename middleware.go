@@ -0,0 +1,215 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MiddlewareOption configures HTTPMiddleware
+type MiddlewareOption func(*middleware)
+
+// middleware logs an access record for every request served by next
+type middleware struct {
+	next      http.Handler
+	logger    *slog.Logger
+	slos      []routeSLO
+	levels    []routeLevel
+	probes    *probeFilter
+	buckets   []time.Duration
+	longPolls []string
+	// requestID is the header carrying the ID of a request
+	requestID string
+}
+
+// defaultRequestIDHeader is the header the ID of a request is read from and
+// returned in
+const defaultRequestIDHeader = "X-Request-ID"
+
+// routeLevel is the minimum level of the access records of the requests
+// matching pattern
+type routeLevel struct {
+	pattern  string
+	level    slog.Level
+	suppress bool
+}
+
+// HTTPMiddleware wraps next so every request it serves is logged as an
+// access record in the Lagoon format. The context of the request carries a
+// logger adding its request_id, which next reaches with FromContext.
+func HTTPMiddleware(next http.Handler, opts ...MiddlewareOption) http.Handler {
+	m := &middleware{next: next, buckets: defaultDurationBuckets, requestID: defaultRequestIDHeader}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithLogger logs access records to l instead of the default slog logger
+func WithLogger(l *slog.Logger) MiddlewareOption {
+	return func(m *middleware) { m.logger = l }
+}
+
+// WithRequestIDHeader reads the ID of a request from header instead of
+// X-Request-ID, e.g. "X-Amzn-Trace-Id"
+func WithRequestIDHeader(header string) MiddlewareOption {
+	return func(m *middleware) { m.requestID = header }
+}
+
+// WithRouteLevel only logs the access records of requests whose path matches
+// pattern when they are at least level, e.g. slog.LevelWarn to keep just the
+// failures of a busy route. Patterns are exact paths or prefixes such as
+// "/api/*", and the first matching route level or suppression applies.
+func WithRouteLevel(pattern string, level slog.Level) MiddlewareOption {
+	return func(m *middleware) { m.levels = append(m.levels, routeLevel{pattern: pattern, level: level}) }
+}
+
+// SuppressRoute never logs the access records of requests whose path matches
+// pattern, e.g. "/healthz"
+func SuppressRoute(pattern string) MiddlewareOption {
+	return func(m *middleware) { m.levels = append(m.levels, routeLevel{pattern: pattern, suppress: true}) }
+}
+
+// enabled reports whether an access record at level is logged for path
+func (m *middleware) enabled(path string, level slog.Level) bool {
+	for _, l := range m.levels {
+		if matchRoute(l.pattern, path) {
+			return !l.suppress && level >= l.level
+		}
+	}
+	return true
+}
+
+func (m *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	logger := m.logger
+	if logger == nil {
+		logger = FromContext(r.Context())
+	}
+
+	// the ID of a request without one is generated and returned, so a
+	// client can quote it
+	id := r.Header.Get(m.requestID)
+	if len(id) == 0 {
+		id = newRequestID()
+		w.Header().Set(m.requestID, id)
+	}
+	scoped := logger.With(RequestID(id))
+	r = r.WithContext(context.WithValue(r.Context(), contextKey{}, scoped))
+
+	access := &accessContext{logger: scoped, request: r, start: start}
+	rw := &responseWriter{ResponseWriter: w, access: access}
+
+	if m.isLongPoll(r.URL.Path) {
+		access.log(slog.LevelInfo, "HTTP request started", HTTPMethod(r.Method), HTTPPath(r.URL.Path))
+	}
+
+	m.next.ServeHTTP(rw, r)
+
+	if rw.hijacked {
+		// the connection logs its own lifecycle
+		return
+	}
+
+	elapsed := time.Since(start)
+	status := rw.statusCode()
+	level := accessLevel(status)
+
+	if m.probes != nil && status < 400 && m.probes.isProbe(r) {
+		m.probes.suppress(r.Context(), logger, time.Now())
+		return
+	}
+	if !m.enabled(r.URL.Path, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		HTTPMethod(r.Method),
+		HTTPPath(r.URL.Path),
+		HTTPStatus(status),
+		HTTPBytes(rw.bytes),
+		RemoteAddr(r.RemoteAddr),
+		DurationMS(elapsed),
+	}
+	if len(m.buckets) > 0 {
+		attrs = append(attrs, DurationBucket(elapsed, m.buckets))
+	}
+	if slo, ok := m.slo(r.URL.Path); ok {
+		attrs = append(attrs, slo.evaluate(status, elapsed))
+	}
+
+	scoped.LogAttrs(r.Context(), level, "HTTP request", attrs...)
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// accessLevel logs server errors as errors and client errors as warnings
+func accessLevel(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// matchRoute reports whether path matches pattern, which is either an exact
+// path or a prefix ending in "/*", e.g. "/api/*" matching "/api" and every
+// path below it
+func matchRoute(pattern, path string) bool {
+	prefix, wildcard := strings.CutSuffix(pattern, "/*")
+	if !wildcard {
+		return path == pattern
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// responseWriter records the status code and body size written by a handler
+type responseWriter struct {
+	http.ResponseWriter
+	status   int
+	bytes    int64
+	hijacked bool
+	access   *accessContext
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusCode is the status sent, which is 200 when the handler wrote nothing
+func (w *responseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
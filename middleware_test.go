@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serve runs a request for path through HTTPMiddleware wrapping next and
+// returns the access record it logged
+func serve(t *testing.T, next http.Handler, path string, opts ...MiddlewareOption) map[string]any {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	handler := HTTPMiddleware(next, append([]MiddlewareOption{WithLogger(logger)}, opts...)...)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+
+	if buf.Len() == 0 {
+		return nil
+	}
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid access record %q: %v", buf.String(), err)
+	}
+	return record
+}
+
+// statusHandler answers every request with code
+func statusHandler(code int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	})
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		next   http.Handler
+		status float64
+		level  string
+	}{
+		{"implicit ok", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), 200, "INFO"},
+		{"written body", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("ok")) }), 200, "INFO"},
+		{"client error", statusHandler(http.StatusNotFound), 404, "WARN"},
+		{"server error", statusHandler(http.StatusBadGateway), 502, "ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := serve(t, tt.next, "/users/42")
+
+			if record[FieldHTTPStatusCode] != tt.status {
+				t.Errorf("%s = %v, want %v", FieldHTTPStatusCode, record[FieldHTTPStatusCode], tt.status)
+			}
+			if record["level"] != tt.level {
+				t.Errorf("level = %v, want %v", record["level"], tt.level)
+			}
+			if record[FieldHTTPMethod] != "GET" || record[FieldHTTPPath] != "/users/42" {
+				t.Errorf("record = %v, want method and path", record)
+			}
+			if _, ok := record[FieldDurationMS]; !ok {
+				t.Errorf("record = %v, want %s", record, FieldDurationMS)
+			}
+			if record[FieldRemoteAddr] != "192.0.2.1" {
+				t.Errorf("%s = %v, want the client without its port", FieldRemoteAddr, record[FieldRemoteAddr])
+			}
+			if id, _ := record[FieldRequestID].(string); len(id) == 0 {
+				t.Errorf("record = %v, want a generated %s", record, FieldRequestID)
+			}
+		})
+	}
+
+	record := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("hello")) }), "/")
+	if record[FieldHTTPBytes] != float64(5) {
+		t.Errorf("%s = %v, want 5", FieldHTTPBytes, record[FieldHTTPBytes])
+	}
+}
+
+func TestHTTPMiddleware_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	handler := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("handling")
+	}), WithLogger(logger), WithRequestIDHeader("X-Correlation-ID"))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("X-Correlation-ID", "abc-123")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("logged %d records, want the record of the handler and the access record: %s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("invalid record %q: %v", line, err)
+		}
+		if record[FieldRequestID] != "abc-123" {
+			t.Errorf("%s = %v, want the ID of the header in %s", FieldRequestID, record[FieldRequestID], line)
+		}
+	}
+	if id := recorder.Header().Get("X-Correlation-ID"); len(id) > 0 {
+		t.Errorf("the ID of the request was returned as %q", id)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if id := recorder.Header().Get("X-Correlation-ID"); len(id) != 16 {
+		t.Errorf("generated request ID = %q, want 16 hex digits", id)
+	}
+}
+
+func TestResponseWriter_Unwrap(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	handler := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush() through the middleware returned error: %v", err)
+		}
+	}), WithLogger(slog.New(slog.DiscardHandler)))
+
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !recorder.Flushed {
+		t.Error("the wrapped writer should have been flushed")
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"/healthz", "/healthz", true},
+		{"/healthz", "/healthz/live", false},
+		{"/api/*", "/api", true},
+		{"/api/*", "/api/users", true},
+		{"/api/*", "/apiary", false},
+		{"/*", "/anything", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchRoute(tt.pattern, tt.path); got != tt.expected {
+			t.Errorf("matchRoute(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestHTTPMiddleware_RouteLevels(t *testing.T) {
+	opts := []MiddlewareOption{
+		SuppressRoute("/healthz"),
+		WithRouteLevel("/api/*", slog.LevelWarn),
+		WithRouteLevel("/admin/*", slog.LevelDebug),
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		logged bool
+	}{
+		{"/healthz", http.StatusOK, false},
+		{"/healthz", http.StatusInternalServerError, false},
+		{"/api/users", http.StatusOK, false},
+		{"/api/users", http.StatusNotFound, true},
+		{"/admin/settings", http.StatusOK, true},
+		{"/other", http.StatusOK, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %d", tt.path, tt.status), func(t *testing.T) {
+			record := serve(t, statusHandler(tt.status), tt.path, opts...)
+			if logged := record != nil; logged != tt.logged {
+				t.Errorf("access record logged = %v, want %v", logged, tt.logged)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package logger
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transports of ProtocolOTLP
+const (
+	// OTLPHTTP exports over OTLP/HTTP, to port 4318 of a collector
+	OTLPHTTP = "http"
+	// OTLPGRPC exports over OTLP/gRPC, to port 4317 of a collector
+	OTLPGRPC = "grpc"
+)
+
+const (
+	// otlpPath is the OTLP/HTTP path of the logs signal
+	otlpPath = "/v1/logs"
+	// otlpGRPCPath is the method of the gRPC logs service
+	otlpGRPCPath = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+	// otlpScope is the instrumentation scope of the exported records
+	otlpScope = "github.com/salsadigitalauorg/go-lagoon-log-forwarder"
+)
+
+// otlpResourceKeys maps the Lagoon fields of an event to the resource
+// attributes they are exported as. The fields of the lagoon group are
+// exported as lagoon.<field>.
+var otlpResourceKeys = map[string]string{
+	"application": "service.name",
+	"host":        "host.name",
+	senderKey:     "service.instance.id",
+	"type":        "lagoon.type",
+	"channel":     "lagoon.channel",
+}
+
+// grpcRetryable are the gRPC status codes of failures that may pass:
+// cancelled, deadline exceeded, resource exhausted, aborted, out of range,
+// unavailable and data loss
+var grpcRetryable = map[string]bool{"1": true, "4": true, "8": true, "10": true, "11": true, "14": true, "15": true}
+
+// OTLPConfig configures ProtocolOTLP, which exports every write, a single
+// event or a batch with BatchSize, as OpenTelemetry log records. Set TLS for
+// a collector receiving over TLS.
+type OTLPConfig struct {
+	Transport string            `json:"transport"` // one of OTLPHTTP (default) or OTLPGRPC
+	Path      string            `json:"path"`      // OTLP/HTTP path, "/v1/logs" when empty
+	Headers   map[string]string `json:"headers"`   // added to every request, e.g. for authentication
+}
+
+func (c *OTLPConfig) validate() error {
+	switch c.Transport {
+	case "", OTLPHTTP:
+	case OTLPGRPC:
+		if len(c.Path) > 0 {
+			return errors.New("otlp.path requires otlp.transport http")
+		}
+	default:
+		return fmt.Errorf("unknown otlp.transport %q", c.Transport)
+	}
+	if len(c.Path) > 0 && !strings.HasPrefix(c.Path, "/") {
+		return fmt.Errorf("otlp.path %q must start with /", c.Path)
+	}
+	for name, value := range c.Headers {
+		if len(name) == 0 || strings.ContainsAny(name, " :\r\n") || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid otlp header %q", name)
+		}
+	}
+	return nil
+}
+
+// dialOTLP returns a connection exporting to the collector at host:port,
+// over TLS when settings are given
+func dialOTLP(ctx context.Context, host string, port int, writeTimeout time.Duration, settings *TLSConfig, otlp *OTLPConfig, resolver Resolver) (net.Conn, error) {
+	if otlp == nil {
+		otlp = &OTLPConfig{}
+	}
+	client, endpoint, err := newHTTPClient(ctx, host, port, writeTimeout, settings, resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	grpc := otlp.Transport == OTLPGRPC
+	if grpc {
+		// gRPC requires HTTP/2, which collectors without TLS accept with
+		// prior knowledge
+		var protocols http.Protocols
+		if settings != nil {
+			protocols.SetHTTP2(true)
+		} else {
+			protocols.SetUnencryptedHTTP2(true)
+		}
+		client.Transport.(*http.Transport).Protocols = &protocols
+		endpoint.Path = otlpGRPCPath
+	} else {
+		endpoint.Path = cmp.Or(otlp.Path, otlpPath)
+	}
+
+	return &otlpConn{
+		httpConn: &httpConn{
+			client:   client,
+			url:      endpoint.String(),
+			settings: HTTPConfig{Headers: otlp.Headers},
+			clock:    schedulerClock(),
+		},
+		grpc: grpc,
+	}, nil
+}
+
+// otlpConn exports the newline-delimited JSON events written to it as an
+// ExportLogsServiceRequest
+type otlpConn struct {
+	*httpConn
+	grpc bool
+}
+
+// Write exports the events in p in one request, retrying it like the
+// requests of ProtocolHTTP
+func (c *otlpConn) Write(p []byte) (int, error) {
+	body := encodeLogs(p, time.Now())
+	if len(body) == 0 {
+		return len(p), nil
+	}
+
+	export := c.exportHTTP
+	if c.grpc {
+		export = c.exportGRPC
+	}
+	if err := retryPost(c.clock, func() (bool, error) { return export(body) }); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *otlpConn) exportHTTP(body []byte) (bool, error) {
+	resp, err := c.send(body, "application/x-protobuf")
+	if err != nil {
+		return true, err
+	}
+	return checkStatus(c.url, resp)
+}
+
+// exportGRPC sends body as the single message of a unary call, whose outcome
+// is the grpc-status trailer, or header when the response has no body
+func (c *otlpConn) exportGRPC(body []byte) (bool, error) {
+	frame := make([]byte, 5, 5+len(body))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(body)))
+	resp, err := c.send(append(frame, body...), "application/grpc")
+	if err != nil {
+		return true, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return checkStatus(c.url, resp)
+	}
+
+	status := cmp.Or(resp.Trailer.Get("Grpc-Status"), resp.Header.Get("Grpc-Status"))
+	if status == "0" {
+		return false, nil
+	}
+	message := cmp.Or(resp.Trailer.Get("Grpc-Message"), resp.Header.Get("Grpc-Message"))
+	return grpcRetryable[status], fmt.Errorf("export %s: grpc status %s: %s", c.url, status, message)
+}
+
+// send posts body, draining the response so the connection is reused
+func (c *otlpConn) send(body []byte, contentType string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.grpc {
+		req.Header.Set("TE", "trailers")
+	}
+	for name, value := range c.settings.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("export %s: %w", c.url, err)
+	}
+	// trailers are only set once the body is read to the end
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	return resp, nil
+}
+
+// encodeLogs returns the ExportLogsServiceRequest of the JSON event lines in
+// p, observed at now, empty when p holds none. Events are grouped by their
+// resource. A line that isn't a JSON object is exported as the body of a
+// record.
+func encodeLogs(p []byte, now time.Time) []byte {
+	var resources []string
+	records := map[string][]byte{}
+	for line := range bytes.Lines(p) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var event map[string]any
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		if err := decoder.Decode(&event); err != nil || event == nil {
+			event = map[string]any{"message": string(line)}
+		}
+
+		resource := string(otlpResource(event))
+		if _, ok := records[resource]; !ok {
+			resources = append(resources, resource)
+		}
+		records[resource] = appendProtoBytes(records[resource], 2, otlpLogRecord(event, now))
+	}
+
+	var request []byte
+	for _, resource := range resources {
+		scope := appendProtoBytes(nil, 1, appendProtoString(nil, 1, otlpScope))
+		scopeLogs := append(scope, records[resource]...)
+
+		resourceLogs := appendProtoBytes(nil, 1, []byte(resource))
+		resourceLogs = appendProtoBytes(resourceLogs, 2, scopeLogs)
+		request = appendProtoBytes(request, 1, resourceLogs)
+	}
+	return request
+}
+
+// otlpResource removes the Lagoon fields from event and returns the Resource
+// of their attributes
+func otlpResource(event map[string]any) []byte {
+	attrs := map[string]any{}
+	for field, key := range otlpResourceKeys {
+		if value, ok := event[field]; ok {
+			delete(event, field)
+			if s, ok := value.(string); !ok || len(s) > 0 {
+				attrs[key] = value
+			}
+		}
+	}
+	if group, ok := event["lagoon"].(map[string]any); ok {
+		delete(event, "lagoon")
+		for field, value := range group {
+			attrs["lagoon."+field] = value
+		}
+	}
+
+	var resource []byte
+	for _, key := range slices.Sorted(maps.Keys(attrs)) {
+		resource = appendProtoBytes(resource, 1, otlpKeyValue(key, attrs[key]))
+	}
+	return resource
+}
+
+// otlpLogRecord returns the LogRecord of an event whose Lagoon fields were
+// removed
+func otlpLogRecord(event map[string]any, now time.Time) []byte {
+	var record []byte
+	if s, ok := event["@timestamp"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			record = appendProtoFixed64(record, 1, uint64(t.UnixNano()))
+		}
+	}
+	if name, ok := event["level"].(string); ok {
+		if level, err := ParseLevel(name); err == nil {
+			// both count four steps from one severity to the next
+			record = appendProtoVarint(record, 2, uint64(min(max(9+int(level), 1), 24)))
+		}
+		record = appendProtoString(record, 3, name)
+	}
+	if message, ok := event["message"]; ok {
+		record = appendProtoBytes(record, 5, otlpAnyValue(message))
+	}
+
+	for _, key := range []string{"@timestamp", "@version", "level", "message"} {
+		delete(event, key)
+	}
+	for _, key := range slices.Sorted(maps.Keys(event)) {
+		// the context and extra groups are always there, mostly empty
+		if group, ok := event[key].(map[string]any); ok && len(group) == 0 {
+			continue
+		}
+		record = appendProtoBytes(record, 6, otlpKeyValue(key, event[key]))
+	}
+	return appendProtoFixed64(record, 11, uint64(now.UnixNano()))
+}
+
+// otlpKeyValue returns the KeyValue of an attribute
+func otlpKeyValue(key string, value any) []byte {
+	return appendProtoBytes(appendProtoString(nil, 1, key), 2, otlpAnyValue(value))
+}
+
+// otlpAnyValue returns the AnyValue of a value decoded from JSON with
+// numbers kept as json.Number
+func otlpAnyValue(v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return appendProtoString(nil, 1, v)
+	case bool:
+		if v {
+			return appendProtoVarint(nil, 2, 1)
+		}
+		return appendProtoVarint(nil, 2, 0)
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return appendProtoVarint(nil, 3, uint64(i))
+		}
+		f, _ := v.Float64()
+		return appendProtoFixed64(nil, 4, math.Float64bits(f))
+	case []any:
+		var array []byte
+		for _, item := range v {
+			array = appendProtoBytes(array, 1, otlpAnyValue(item))
+		}
+		return appendProtoBytes(nil, 5, array)
+	case map[string]any:
+		var list []byte
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			list = appendProtoBytes(list, 1, otlpKeyValue(key, v[key]))
+		}
+		return appendProtoBytes(nil, 6, list)
+	default:
+		// null is an empty value
+		return nil
+	}
+}
+
+// The protobuf wire format of the fields of OTLP messages
+
+func appendProtoTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	return binary.AppendUvarint(appendProtoTag(b, field, 0), v)
+}
+
+func appendProtoFixed64(b []byte, field int, v uint64) []byte {
+	return binary.LittleEndian.AppendUint64(appendProtoTag(b, field, 1), v)
+}
+
+func appendProtoBytes(b []byte, field int, p []byte) []byte {
+	b = binary.AppendUvarint(appendProtoTag(b, field, 2), uint64(len(p)))
+	return append(b, p...)
+}
+
+func appendProtoString(b []byte, field int, s string) []byte {
+	b = binary.AppendUvarint(appendProtoTag(b, field, 2), uint64(len(s)))
+	return append(b, s...)
+}
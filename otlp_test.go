@@ -0,0 +1,387 @@
+package logger
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// protoField is a field of a protobuf message decoded by the tests
+type protoField struct {
+	num    int
+	varint uint64 // varint and fixed64 fields
+	bytes  []byte // length-delimited fields
+}
+
+// decodeProto returns the fields of a protobuf message in order
+func decodeProto(t *testing.T, b []byte) []protoField {
+	t.Helper()
+	var fields []protoField
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("invalid tag in %x", b)
+		}
+		b = b[n:]
+		f := protoField{num: int(tag >> 3)}
+		switch tag & 7 {
+		case 0:
+			f.varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatalf("invalid varint in %x", b)
+			}
+			b = b[n:]
+		case 1:
+			f.varint, b = binary.LittleEndian.Uint64(b), b[8:]
+		case 2:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				t.Fatalf("invalid length in %x", b)
+			}
+			f.bytes, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// protoFields returns the fields numbered num of a message
+func protoFields(t *testing.T, b []byte, num int) []protoField {
+	t.Helper()
+	var out []protoField
+	for _, f := range decodeProto(t, b) {
+		if f.num == num {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// decodeAnyValue returns the Go value of an AnyValue
+func decodeAnyValue(t *testing.T, b []byte) any {
+	t.Helper()
+	for _, f := range decodeProto(t, b) {
+		switch f.num {
+		case 1:
+			return string(f.bytes)
+		case 2:
+			return f.varint == 1
+		case 3:
+			return int64(f.varint)
+		case 4:
+			return math.Float64frombits(f.varint)
+		case 5:
+			var array []any
+			for _, v := range protoFields(t, f.bytes, 1) {
+				array = append(array, decodeAnyValue(t, v.bytes))
+			}
+			return array
+		case 6:
+			return decodeKeyValues(t, f.bytes, 1)
+		}
+	}
+	return nil
+}
+
+// decodeKeyValues returns the KeyValue fields numbered num of a message
+func decodeKeyValues(t *testing.T, b []byte, num int) map[string]any {
+	t.Helper()
+	out := map[string]any{}
+	for _, kv := range protoFields(t, b, num) {
+		var key string
+		var value any
+		for _, f := range decodeProto(t, kv.bytes) {
+			switch f.num {
+			case 1:
+				key = string(f.bytes)
+			case 2:
+				value = decodeAnyValue(t, f.bytes)
+			}
+		}
+		out[key] = value
+	}
+	return out
+}
+
+// otlpResourceLogs is a ResourceLogs of an export request
+type otlpResourceLogs struct {
+	resource map[string]any
+	scope    string
+	records  [][]byte
+}
+
+// decodeLogs returns the ResourceLogs of an ExportLogsServiceRequest
+func decodeLogs(t *testing.T, request []byte) []otlpResourceLogs {
+	t.Helper()
+	var out []otlpResourceLogs
+	for _, rl := range protoFields(t, request, 1) {
+		var logs otlpResourceLogs
+		for _, f := range decodeProto(t, rl.bytes) {
+			switch f.num {
+			case 1:
+				logs.resource = decodeKeyValues(t, f.bytes, 1)
+			case 2:
+				for _, scope := range protoFields(t, f.bytes, 1) {
+					logs.scope = string(protoFields(t, scope.bytes, 1)[0].bytes)
+				}
+				for _, record := range protoFields(t, f.bytes, 2) {
+					logs.records = append(logs.records, record.bytes)
+				}
+			}
+		}
+		out = append(out, logs)
+	}
+	return out
+}
+
+func TestEncodeLogs(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 1, 0, time.UTC)
+	events := `{"@timestamp":"2024-05-01T10:00:00.5Z","@version":1,"application":"drupal","host":"web-1","type":"project-main","channel":"cron","level":"WARN","message":"slow","lagoon":{"project":"project","environment":"main"},"context":{},"extra":{"ms":1500,"ok":false,"tags":["a","b"]}}
+{"application":"drupal","host":"web-1","type":"project-main","channel":"cron","level":"ERROR","message":"failed","lagoon":{"project":"project","environment":"main"}}
+{"application":"solr","level":"DEBUG+2","message":"query","ratio":0.25}
+not json
+`
+	got := decodeLogs(t, encodeLogs([]byte(events), now))
+	if len(got) != 3 {
+		t.Fatalf("encodeLogs() returned %d resources, want 3", len(got))
+	}
+
+	drupal := got[0]
+	wantResource := map[string]any{
+		"service.name":       "drupal",
+		"host.name":          "web-1",
+		"lagoon.type":        "project-main",
+		"lagoon.channel":     "cron",
+		"lagoon.project":     "project",
+		"lagoon.environment": "main",
+	}
+	if len(drupal.resource) != len(wantResource) {
+		t.Errorf("resource = %v, want %v", drupal.resource, wantResource)
+	}
+	for key, want := range wantResource {
+		if drupal.resource[key] != want {
+			t.Errorf("resource %s = %v, want %v", key, drupal.resource[key], want)
+		}
+	}
+	if drupal.scope != otlpScope {
+		t.Errorf("scope = %q, want %q", drupal.scope, otlpScope)
+	}
+	if len(drupal.records) != 2 {
+		t.Fatalf("drupal has %d records, want both events", len(drupal.records))
+	}
+
+	record := map[int]protoField{}
+	for _, f := range decodeProto(t, drupal.records[0]) {
+		record[f.num] = f
+	}
+	if ts := time.Unix(0, int64(record[1].varint)).UTC(); !ts.Equal(time.Date(2024, 5, 1, 10, 0, 0, 5e8, time.UTC)) {
+		t.Errorf("time = %v, want the @timestamp", ts)
+	}
+	if record[2].varint != 13 || string(record[3].bytes) != "WARN" {
+		t.Errorf("severity = %d %q, want 13 WARN", record[2].varint, record[3].bytes)
+	}
+	if body := decodeAnyValue(t, record[5].bytes); body != "slow" {
+		t.Errorf("body = %v, want slow", body)
+	}
+	if observed := time.Unix(0, int64(record[11].varint)); !observed.Equal(now) {
+		t.Errorf("observed time = %v, want %v", observed, now)
+	}
+	attrs := decodeKeyValues(t, drupal.records[0], 6)
+	extra, _ := attrs["extra"].(map[string]any)
+	if len(attrs) != 1 || extra["ms"] != int64(1500) || extra["ok"] != false || len(extra["tags"].([]any)) != 2 {
+		t.Errorf("attributes = %v, want only extra", attrs)
+	}
+
+	solr := got[1]
+	if solr.resource["service.name"] != "solr" || len(solr.records) != 1 {
+		t.Fatalf("second resource = %+v, want the solr event", solr)
+	}
+	if severity := protoFields(t, solr.records[0], 2); len(severity) != 1 || severity[0].varint != 7 {
+		t.Errorf("severity of DEBUG+2 = %v, want 7", severity)
+	}
+	if ratio := decodeKeyValues(t, solr.records[0], 6)["ratio"]; ratio != 0.25 {
+		t.Errorf("ratio = %v, want 0.25", ratio)
+	}
+
+	raw := got[2]
+	if len(raw.resource) != 0 || len(raw.records) != 1 {
+		t.Fatalf("third resource = %+v, want the line without a resource", raw)
+	}
+	if body := decodeAnyValue(t, protoFields(t, raw.records[0], 5)[0].bytes); body != "not json" {
+		t.Errorf("body = %v, want the line", body)
+	}
+
+	if empty := encodeLogs([]byte("\n\n"), now); len(empty) != 0 {
+		t.Errorf("encodeLogs() of blank lines = %x, want nothing", empty)
+	}
+}
+
+func TestOTLPConn_HTTP(t *testing.T) {
+	host, port, requests := httpEndpoint(t, http.StatusServiceUnavailable)
+	conn, err := dialOTLP(context.Background(), host, port, time.Second, nil, &OTLPConfig{
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("dialOTLP() returned unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("{\"application\":\"drupal\",\"message\":\"one\"}\n{\"application\":\"drupal\",\"message\":\"two\"}\n")); err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("received %d requests, want a retry after 503", len(got))
+	}
+	r := got[1]
+	if r.path != otlpPath {
+		t.Errorf("path = %q, want %q", r.path, otlpPath)
+	}
+	if ct := r.header.Get("Content-Type"); ct != "application/x-protobuf" {
+		t.Errorf("Content-Type = %q, want application/x-protobuf", ct)
+	}
+	if auth := r.header.Get("Authorization"); auth != "Bearer token" {
+		t.Errorf("Authorization = %q, want the configured header", auth)
+	}
+	logs := decodeLogs(t, []byte(r.body))
+	if len(logs) != 1 || len(logs[0].records) != 2 {
+		t.Errorf("exported %+v, want both events of one resource", logs)
+	}
+}
+
+// grpcCollector serves the logs service over h2c, answering calls with the
+// grpc statuses given in turn, 0 once they are used up, and returns its port
+// and the messages it received
+func grpcCollector(t *testing.T, statuses ...string) (int, func() [][]byte) {
+	t.Helper()
+	var mu sync.Mutex
+	var messages [][]byte
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.URL.Path != otlpGRPCPath || r.Header.Get("Content-Type") != "application/grpc" {
+			http.Error(w, "not a logs export", http.StatusBadRequest)
+			return
+		}
+		frame, _ := io.ReadAll(r.Body)
+		if len(frame) < 5 || int(binary.BigEndian.Uint32(frame[1:5])) != len(frame)-5 {
+			http.Error(w, "invalid frame", http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		messages = append(messages, frame[5:])
+		status := "0"
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.WriteHeader(http.StatusOK)
+		// an empty ExportLogsServiceResponse
+		_, _ = w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set("Grpc-Status", status)
+		w.Header().Set("Grpc-Message", "status "+status)
+	}))
+	server.Config.Protocols = &http.Protocols{}
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	t.Cleanup(server.Close)
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	n, _ := strconv.Atoi(port)
+	return n, func() [][]byte {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([][]byte(nil), messages...)
+	}
+}
+
+func TestOTLPConn_GRPC(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		calls    int
+		fails    bool
+	}{
+		{"accepted", nil, 1, false},
+		{"unavailable then accepted", []string{"14"}, 2, false},
+		{"invalid argument", []string{"3"}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, messages := grpcCollector(t, tt.statuses...)
+			conn, err := dialOTLP(context.Background(), "127.0.0.1", port, time.Second, nil, &OTLPConfig{Transport: OTLPGRPC}, nil)
+			if err != nil {
+				t.Fatalf("dialOTLP() returned unexpected error: %v", err)
+			}
+			defer conn.Close()
+
+			_, err = conn.Write([]byte("{\"application\":\"drupal\",\"level\":\"INFO\",\"message\":\"exported\"}\n"))
+			if (err != nil) != tt.fails {
+				t.Errorf("Write() error = %v, want failure %v", err, tt.fails)
+			}
+			got := messages()
+			if len(got) != tt.calls {
+				t.Fatalf("collector received %d calls, want %d", len(got), tt.calls)
+			}
+			logs := decodeLogs(t, got[0])
+			if len(logs) != 1 || logs[0].resource["service.name"] != "drupal" || len(logs[0].records) != 1 {
+				t.Errorf("exported %+v, want the drupal event", logs)
+			}
+		})
+	}
+}
+
+func TestInitialize_OTLP(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	host, port, requests := httpEndpoint(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "otlp-type"
+	cfg.LogHost = host
+	cfg.LogPort = port
+	cfg.Protocol = ProtocolOTLP
+	cfg.OTLP = &OTLPConfig{}
+
+	handler, err := NewHandler(cfg)
+	if err != nil {
+		t.Fatalf("NewHandler() returned unexpected error: %v", err)
+	}
+	slog.New(handler).Info("exported", "seq", 1)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(requests()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("received %d requests, want 1", len(got))
+	}
+	logs := decodeLogs(t, []byte(got[0].body))
+	if len(logs) != 1 || len(logs[0].records) != 1 {
+		t.Fatalf("exported %+v, want one event", logs)
+	}
+	if logs[0].resource["lagoon.type"] != "otlp-type" {
+		t.Errorf("resource = %v, want the log type", logs[0].resource)
+	}
+	if seq := decodeKeyValues(t, logs[0].records[0], 6)["seq"]; seq != int64(1) {
+		t.Errorf("seq = %v, want 1", seq)
+	}
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// Levels and sampling rates set at runtime, which take precedence over the
+// configured level and schedule. A level set with SetLevel wins over the
+// remote configuration, which wins over feature flags.
+var (
+	// levelVar is the level set with SetLevel or the debug signal, used
+	// while levelSet
+	levelVar slog.LevelVar
+	levelSet atomic.Bool
+
+	// remoteLevel is the level override of the remote configuration, nil
+	// when there is none
+	remoteLevel      atomic.Pointer[slog.Level]
+	remoteSampleRate atomic.Int64
+	// flagLevel is the level of the FlagLevel feature flag, nil when it is
+	// not defined
+	flagLevel      atomic.Pointer[slog.Level]
+	flagSampleRate atomic.Int64
+
+	overrideSampled atomic.Uint64
+)
+
+// overrideLeveler applies the runtime level overrides to the level of a
+// handler
+type overrideLeveler struct {
+	slog.Leveler
+}
+
+func (l overrideLeveler) Level() slog.Level {
+	if levelSet.Load() {
+		return levelVar.Level()
+	}
+	if level := remoteLevel.Load(); level != nil {
+		return *level
+	}
+	if level := flagLevel.Load(); level != nil {
+		return *level
+	}
+	return l.Leveler.Level()
+}
+
+// overrideKeep reports whether a record at level is kept by the runtime
+// sampling rate or memory pressure. Warnings and errors are never sampled.
+func overrideKeep(level slog.Level) bool {
+	rate := remoteSampleRate.Load()
+	if rate == 0 {
+		rate = flagSampleRate.Load()
+	}
+	// memory pressure only ever samples more
+	rate = max(rate, pressureSampleRate.Load())
+	if rate <= 1 || level >= slog.LevelWarn {
+		return true
+	}
+	return overrideSampled.Add(1)%uint64(rate) == 1
+}
+
+// SetLevel sets the minimum level logged at runtime, e.g. to raise the
+// verbosity of a live service. It overrides the configured level, schedule,
+// remote configuration and feature flags until ResetLevel.
+func SetLevel(level slog.Level) {
+	levelVar.Set(level)
+	levelSet.Store(true)
+}
+
+// ResetLevel undoes SetLevel, so the configured level applies again
+func ResetLevel() {
+	levelSet.Store(false)
+}
+
+// Enabled reports whether the default logger writes records at level, with
+// the runtime overrides and destination levels applied. It doesn't allocate,
+// so hot paths can guard building expensive attributes with it:
+//
+//	if logger.Enabled(ctx, slog.LevelDebug) {
+//		slog.DebugContext(ctx, "cache state", "entries", cache.Dump())
+//	}
+func Enabled(ctx context.Context, level slog.Level) bool {
+	return slog.Default().Enabled(ctx, level)
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestSetLevel(t *testing.T) {
+	t.Cleanup(ResetLevel)
+
+	leveler := overrideLeveler{slog.LevelInfo}
+	remote := slog.LevelError
+	remoteLevel.Store(&remote)
+	t.Cleanup(func() { remoteLevel.Store(nil) })
+
+	SetLevel(slog.LevelDebug)
+	if got := leveler.Level(); got != slog.LevelDebug {
+		t.Errorf("Level() = %v after SetLevel(), want %v over the remote level", got, slog.LevelDebug)
+	}
+
+	ResetLevel()
+	if got := leveler.Level(); got != slog.LevelError {
+		t.Errorf("Level() = %v after ResetLevel(), want %v", got, slog.LevelError)
+	}
+
+	remoteLevel.Store(nil)
+	if got := leveler.Level(); got != slog.LevelInfo {
+		t.Errorf("Level() = %v, want the configured %v", got, slog.LevelInfo)
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	t.Cleanup(ResetLevel)
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	h := newJSONHandler([]sink{{w: io.Discard}}, &slog.HandlerOptions{Level: overrideLeveler{slog.LevelInfo}}, nil)
+	slog.SetDefault(slog.New(h))
+
+	ctx := context.Background()
+	if Enabled(ctx, slog.LevelDebug) || !Enabled(ctx, slog.LevelInfo) {
+		t.Error("Enabled() should follow the configured level")
+	}
+	SetLevel(slog.LevelDebug)
+	if !Enabled(ctx, slog.LevelDebug) {
+		t.Error("Enabled() should follow SetLevel")
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { Enabled(ctx, slog.LevelDebug) }); allocs != 0 {
+		t.Errorf("Enabled() allocated %v times, want 0", allocs)
+	}
+}
+
+func BenchmarkEnabled(b *testing.B) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+	h := newJSONHandler([]sink{{w: io.Discard}, {w: io.Discard, level: slog.LevelWarn}}, &slog.HandlerOptions{Level: overrideLeveler{slog.LevelInfo}}, nil)
+	slog.SetDefault(slog.New(h))
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Enabled(ctx, slog.LevelDebug)
+	}
+}
@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ownerKey is the attribute naming the team owning the caller
+const ownerKey = "owner"
+
+// ownership maps package paths to the teams owning them
+type ownership struct {
+	teams map[string]string
+	// byPC caches the owner of every pc looked up, "" for none
+	byPC sync.Map
+}
+
+// owners is the registered ownership, nil until RegisterOwners is called
+var owners atomic.Pointer[ownership]
+
+// ownersMu serializes registrations, so none is lost
+var ownersMu sync.Mutex
+
+// RegisterOwners maps package paths to the teams owning them, e.g.
+// "github.com/acme/shop/payments" to "payments". Records logged from a
+// package, or a package below it, carry the team as owner; the longest
+// registered path wins. Registering a path again replaces its team, and an
+// empty team removes it.
+func RegisterOwners(teams map[string]string) {
+	ownersMu.Lock()
+	defer ownersMu.Unlock()
+
+	next := &ownership{teams: map[string]string{}}
+	if current := owners.Load(); current != nil {
+		for pkg, team := range current.teams {
+			next.teams[pkg] = team
+		}
+	}
+	for pkg, team := range teams {
+		pkg = strings.TrimSuffix(pkg, "/")
+		if len(team) == 0 {
+			delete(next.teams, pkg)
+			continue
+		}
+		next.teams[pkg] = team
+	}
+	owners.Store(next)
+}
+
+// ownerOf returns the team owning the code at pc, "" when none does
+func ownerOf(pc uintptr) string {
+	o := owners.Load()
+	if o == nil || len(o.teams) == 0 || pc == 0 {
+		return ""
+	}
+	if team, ok := o.byPC.Load(pc); ok {
+		return team.(string)
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	team := o.lookup(framePackage(frame.Function))
+	o.byPC.Store(pc, team)
+	return team
+}
+
+// lookup returns the team of the longest registered path pkg is in
+func (o *ownership) lookup(pkg string) string {
+	for {
+		if team, ok := o.teams[pkg]; ok {
+			return team
+		}
+		slash := strings.LastIndexByte(pkg, '/')
+		if slash < 0 {
+			return ""
+		}
+		pkg = pkg[:slash]
+	}
+}
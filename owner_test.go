@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestOwnership_Lookup(t *testing.T) {
+	o := &ownership{teams: map[string]string{
+		"github.com/acme/shop":          "platform",
+		"github.com/acme/shop/payments": "payments",
+	}}
+
+	tests := map[string]string{
+		"github.com/acme/shop":                  "platform",
+		"github.com/acme/shop/payments":         "payments",
+		"github.com/acme/shop/payments/refunds": "payments",
+		"github.com/acme/shop/search":           "platform",
+		"github.com/acme/shopfront":             "",
+		"main":                                  "",
+	}
+	for pkg, want := range tests {
+		if got := o.lookup(pkg); got != want {
+			t.Errorf("lookup(%q) = %q, want %q", pkg, got, want)
+		}
+	}
+}
+
+func TestHandler_Owner(t *testing.T) {
+	t.Cleanup(func() { owners.Store(nil) })
+
+	var buf bytes.Buffer
+	logger := slog.New(newHandler(&buf))
+	logger.Info("before")
+	if strings.Contains(buf.String(), `"owner"`) {
+		t.Errorf("output = %s, want no owner before registration", buf.String())
+	}
+
+	RegisterOwners(map[string]string{"github.com/salsadigitalauorg/": "observability"})
+	buf.Reset()
+	logger.Info("after")
+	if !strings.Contains(buf.String(), `"owner":"observability"`) {
+		t.Errorf("output = %s, want the owner of the caller", buf.String())
+	}
+
+	RegisterOwners(map[string]string{"github.com/salsadigitalauorg": ""})
+	buf.Reset()
+	logger.Info("removed")
+	if strings.Contains(buf.String(), `"owner"`) {
+		t.Errorf("output = %s, want no owner once removed", buf.String())
+	}
+}
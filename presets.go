@@ -0,0 +1,47 @@
+package logger
+
+import "fmt"
+
+// Project types with presets matching Lagoon's standard stacks
+const (
+	ProjectTypeDrupal  = "drupal"
+	ProjectTypeLaravel = "laravel"
+	ProjectTypeNode    = "node"
+	ProjectTypeGolang  = "golang"
+)
+
+// projectPreset holds the settings a project type changes from NewConfig
+type projectPreset struct {
+	applicationName string
+	level           string
+	addSource       bool
+}
+
+var projectPresets = map[string]projectPreset{
+	// the lagoon_logs Drupal module forwards watchdog messages from notice up
+	ProjectTypeDrupal: {applicationName: "drupal", level: "info"},
+	// Laravel logs everything down to debug unless LOG_LEVEL says otherwise
+	ProjectTypeLaravel: {applicationName: "laravel", level: "debug"},
+	ProjectTypeNode:    {applicationName: "node", level: "info"},
+	ProjectTypeGolang:  {applicationName: "golang", level: "info", addSource: true},
+}
+
+// NewConfigForProjectType returns the default configuration for a service of
+// a Lagoon project of the given type. The log type follows Lagoon's
+// <project>-<environment> namespace naming when the LAGOON_PROJECT and
+// LAGOON_ENVIRONMENT variables are set, and must be set by the caller
+// otherwise.
+func NewConfigForProjectType(projectType string) (Config, error) {
+	preset, ok := projectPresets[projectType]
+	if !ok {
+		return Config{}, fmt.Errorf("unknown project type %q", projectType)
+	}
+
+	cfg := NewConfig()
+	cfg.ApplicationName = preset.applicationName
+	cfg.LogChannel = "LagoonLogs"
+	cfg.Level = preset.level
+	cfg.AddSource = preset.addSource
+	cfg.LogType = lagoonLogType()
+	return cfg, nil
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestNewConfigForProjectType(t *testing.T) {
+	t.Setenv("LAGOON_PROJECT", "")
+	t.Setenv("LAGOON_ENVIRONMENT", "")
+	t.Setenv("LAGOON_GIT_SAFE_BRANCH", "")
+
+	tests := []struct {
+		projectType string
+		application string
+		level       string
+		addSource   bool
+	}{
+		{ProjectTypeDrupal, "drupal", "info", false},
+		{ProjectTypeLaravel, "laravel", "debug", false},
+		{ProjectTypeNode, "node", "info", false},
+		{ProjectTypeGolang, "golang", "info", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.projectType, func(t *testing.T) {
+			cfg, err := NewConfigForProjectType(tt.projectType)
+			if err != nil {
+				t.Fatalf("NewConfigForProjectType() returned unexpected error: %v", err)
+			}
+			if cfg.ApplicationName != tt.application {
+				t.Errorf("ApplicationName = %q, want %q", cfg.ApplicationName, tt.application)
+			}
+			if cfg.Level != tt.level {
+				t.Errorf("Level = %q, want %q", cfg.Level, tt.level)
+			}
+			if cfg.AddSource != tt.addSource {
+				t.Errorf("AddSource = %v, want %v", cfg.AddSource, tt.addSource)
+			}
+			if cfg.LogChannel != "LagoonLogs" {
+				t.Errorf("LogChannel = %q, want %q", cfg.LogChannel, "LagoonLogs")
+			}
+			if cfg.LogType != "" {
+				t.Errorf("LogType = %q, want empty outside Lagoon", cfg.LogType)
+			}
+
+			cfg.LogType = "test"
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("Validate() returned unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewConfigForProjectType_Unknown(t *testing.T) {
+	if _, err := NewConfigForProjectType("wordpress"); err == nil {
+		t.Error("NewConfigForProjectType() expected error for unknown project type")
+	}
+}
+
+func TestNewConfigForProjectType_LogType(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		branch      string
+		want        string
+	}{
+		{"environment", "main", "", "shop-main"},
+		{"branch fallback", "", "feature-x", "shop-feature-x"},
+		{"environment wins", "main", "feature-x", "shop-main"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LAGOON_PROJECT", "shop")
+			t.Setenv("LAGOON_ENVIRONMENT", tt.environment)
+			t.Setenv("LAGOON_GIT_SAFE_BRANCH", tt.branch)
+
+			cfg, err := NewConfigForProjectType(ProjectTypeNode)
+			if err != nil {
+				t.Fatalf("NewConfigForProjectType() returned unexpected error: %v", err)
+			}
+			if cfg.LogType != tt.want {
+				t.Errorf("LogType = %q, want %q", cfg.LogType, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevel(t *testing.T) {
+	preserveConfig(t)
+
+	tests := []struct {
+		level string
+		logs  map[slog.Level]bool
+	}{
+		{"", map[slog.Level]bool{slog.LevelDebug: true, slog.LevelInfo: true}},
+		{"info", map[slog.Level]bool{slog.LevelDebug: false, slog.LevelInfo: true}},
+		{"WARN", map[slog.Level]bool{slog.LevelInfo: false, slog.LevelWarn: true, slog.LevelError: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.LogType = "test"
+			cfg.Level = tt.level
+			if err := config(cfg); err != nil {
+				t.Fatalf("config() returned unexpected error: %v", err)
+			}
+
+			var buf bytes.Buffer
+			h := newHandler(&buf)
+			for level, want := range tt.logs {
+				if got := h.Enabled(context.Background(), level); got != want {
+					t.Errorf("Enabled(%v) = %v, want %v", level, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ANSI colours of the pretty format
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+)
+
+// prettyHandler writes records as colourised lines for development, e.g.
+//
+//	10:30:00.000 INFO  User authenticated user_id=12345 http.method=POST
+//
+// Groups are flattened into dotted keys.
+type prettyHandler struct {
+	w      io.Writer
+	opts   slog.HandlerOptions
+	prefix string
+	attrs  []byte
+}
+
+func newPrettyHandler(w io.Writer, opts *slog.HandlerOptions) *prettyHandler {
+	return &prettyHandler{w: w, opts: *opts}
+}
+
+func (h *prettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *prettyHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := make([]byte, 0, 256)
+
+	if !r.Time.IsZero() {
+		buf = append(buf, ansiDim...)
+		buf = r.Time.Round(0).AppendFormat(buf, "15:04:05.000")
+		buf = append(buf, ansiReset...)
+		buf = append(buf, ' ')
+	}
+
+	buf = append(buf, levelColour(r.Level)...)
+	buf = append(buf, fmt.Sprintf("%-5s", levelString(r.Level))...)
+	buf = append(buf, ansiReset...)
+	buf = append(buf, ' ')
+	buf = append(buf, r.Message...)
+
+	buf = append(buf, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		buf = appendPrettyAttr(buf, h.prefix, a)
+		return true
+	})
+
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		buf = append(buf, ' ')
+		buf = append(buf, ansiDim...)
+		buf = append(buf, frame.File...)
+		buf = append(buf, ':')
+		buf = strconv.AppendInt(buf, int64(frame.Line), 10)
+		buf = append(buf, ansiReset...)
+	}
+
+	buf = append(buf, '\n')
+	_, err := h.w.Write(buf)
+	return err
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append([]byte(nil), h.attrs...)
+	for _, a := range attrs {
+		c.attrs = appendPrettyAttr(c.attrs, h.prefix, a)
+	}
+	return &c
+}
+
+func (h *prettyHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+func levelColour(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return ansiRed
+	case level >= slog.LevelWarn:
+		return ansiYellow
+	case level >= slog.LevelInfo:
+		return ansiGreen
+	default:
+		return ansiBlue
+	}
+}
+
+// appendPrettyAttr appends a as " key=value", flattening groups into dotted
+// keys under prefix
+func appendPrettyAttr(buf []byte, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return buf
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if len(a.Key) > 0 {
+			prefix += a.Key + "."
+		}
+		for _, member := range a.Value.Group() {
+			buf = appendPrettyAttr(buf, prefix, member)
+		}
+		return buf
+	}
+
+	buf = append(buf, ' ')
+	buf = append(buf, ansiDim...)
+	buf = append(buf, prefix...)
+	buf = append(buf, a.Key...)
+	buf = append(buf, '=')
+	buf = append(buf, ansiReset...)
+
+	var text string
+	switch a.Value.Kind() {
+	case slog.KindTime:
+		text = a.Value.Time().Format(time.RFC3339Nano)
+	default:
+		text = a.Value.String()
+	}
+	if needsQuoting(text) {
+		return strconv.AppendQuote(buf, text)
+	}
+	return append(buf, text...)
+}
+
+func needsQuoting(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	return strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r)
+	}) >= 0
+}
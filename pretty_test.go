@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestPrettyHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := newPrettyHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+
+	r := slog.NewRecord(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC), slog.LevelWarn, "disk almost full", 0)
+	r.AddAttrs(slog.Int("percent", 93), slog.Group("volume", slog.String("path", "/app files")))
+	if err := h.WithAttrs([]slog.Attr{slog.String("service", "api")}).WithGroup("check").Handle(context.Background(), r); err != nil {
+		t.Fatalf("Handle() returned unexpected error: %v", err)
+	}
+
+	want := ansiDim + "10:30:00.000" + ansiReset + " " + ansiYellow + "WARN " + ansiReset + " disk almost full" +
+		" " + ansiDim + "service=" + ansiReset + "api" +
+		" " + ansiDim + "check.percent=" + ansiReset + "93" +
+		" " + ansiDim + "check.volume.path=" + ansiReset + `"/app files"` + "\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
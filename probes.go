@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultProbeSummaryInterval is how often suppressed probes are summarized
+// when WithProbeSuppression is given no interval
+const defaultProbeSummaryInterval = 5 * time.Minute
+
+// probeFilter recognizes health probe requests and counts the ones it
+// suppressed since the last summary
+type probeFilter struct {
+	paths    []string
+	interval time.Duration
+
+	mu         sync.Mutex
+	suppressed int
+	since      time.Time
+}
+
+// WithProbeSuppression replaces the access records of successful health
+// probes, recognized by the kube-probe user agent or by a path matching one
+// of paths, with a summary logged at most once per interval. Failed probes
+// are still logged individually.
+func WithProbeSuppression(interval time.Duration, paths ...string) MiddlewareOption {
+	if interval <= 0 {
+		interval = defaultProbeSummaryInterval
+	}
+	return func(m *middleware) { m.probes = &probeFilter{paths: paths, interval: interval} }
+}
+
+// isProbe reports whether r is a health probe
+func (f *probeFilter) isProbe(r *http.Request) bool {
+	if strings.HasPrefix(r.UserAgent(), "kube-probe/") {
+		return true
+	}
+	for _, pattern := range f.paths {
+		if matchRoute(pattern, r.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// suppress counts a suppressed probe and logs the summary once the interval
+// since the previous one has passed
+func (f *probeFilter) suppress(ctx context.Context, logger *slog.Logger, now time.Time) {
+	f.mu.Lock()
+	if f.since.IsZero() {
+		f.since = now
+	}
+	f.suppressed++
+
+	elapsed := now.Sub(f.since)
+	if elapsed < f.interval {
+		f.mu.Unlock()
+		return
+	}
+
+	suppressed := f.suppressed
+	f.suppressed, f.since = 0, now
+	f.mu.Unlock()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "Suppressed health probe requests",
+		slog.Group("probe", slog.Int("suppressed", suppressed)),
+		DurationMS(elapsed),
+	)
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbeFilter_IsProbe(t *testing.T) {
+	filter := &probeFilter{paths: []string{"/healthz", "/status/*"}}
+
+	tests := []struct {
+		name      string
+		path      string
+		userAgent string
+		expected  bool
+	}{
+		{"kube-probe", "/", "kube-probe/1.29", true},
+		{"configured path", "/healthz", "curl/8.0", true},
+		{"configured prefix", "/status/ready", "", true},
+		{"regular request", "/users", "Mozilla/5.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Header.Set("User-Agent", tt.userAgent)
+			if got := filter.isProbe(r); got != tt.expected {
+				t.Errorf("isProbe() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProbeFilter_Summary(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	filter := &probeFilter{interval: time.Minute}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		filter.suppress(context.Background(), logger, start.Add(time.Duration(i)*time.Second))
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("summary logged before the interval passed: %s", buf.String())
+	}
+
+	filter.suppress(context.Background(), logger, start.Add(time.Minute))
+
+	var summary struct {
+		Message string `json:"msg"`
+		Probe   struct {
+			Suppressed int `json:"suppressed"`
+		} `json:"probe"`
+		DurationMS int64 `json:"duration_ms"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &summary); err != nil {
+		t.Fatalf("invalid summary %q: %v", buf.String(), err)
+	}
+	if summary.Probe.Suppressed != 11 {
+		t.Errorf("probe.suppressed = %d, want 11", summary.Probe.Suppressed)
+	}
+	if summary.DurationMS != time.Minute.Milliseconds() {
+		t.Errorf("duration_ms = %d, want %d", summary.DurationMS, time.Minute.Milliseconds())
+	}
+
+	buf.Reset()
+	filter.suppress(context.Background(), logger, start.Add(time.Minute+time.Second))
+	if buf.Len() != 0 {
+		t.Errorf("the count should restart after a summary, got %s", buf.String())
+	}
+}
+
+func TestHTTPMiddleware_ProbeSuppression(t *testing.T) {
+	var buf bytes.Buffer
+	handler := HTTPMiddleware(statusHandler(http.StatusOK),
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+		WithProbeSuppression(time.Hour, "/healthz"),
+	)
+	failing := HTTPMiddleware(statusHandler(http.StatusServiceUnavailable),
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+		WithProbeSuppression(time.Hour, "/healthz"),
+	)
+
+	for i := 0; i < 5; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	}
+	if buf.Len() != 0 {
+		t.Errorf("successful probes should be suppressed, got %s", buf.String())
+	}
+
+	failing.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Errorf("logged %d records, want the failed probe and the regular request:\n%s", lines, buf.String())
+	}
+}
@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// Processor changes a record before it is encoded, for example to add the
+// build SHA, and returns false to drop it, for example a health check. The
+// record holds the message and the attributes of the logging call and of
+// With, nested in the groups of the logger as they are written; the Lagoon
+// fields are added after the processors ran. A processor is called from
+// every logging goroutine, so it must be safe for concurrent use.
+type Processor func(ctx context.Context, r slog.Record) (slog.Record, bool)
+
+// DropAttrs returns a processor removing the top-level attributes with one of
+// the keys, such as noisy ones added by a library
+func DropAttrs(keys ...string) Processor {
+	return func(_ context.Context, r slog.Record) (slog.Record, bool) {
+		found := false
+		r.Attrs(func(a slog.Attr) bool {
+			found = slices.Contains(keys, a.Key)
+			return !found
+		})
+		if !found {
+			return r, true
+		}
+
+		out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		r.Attrs(func(a slog.Attr) bool {
+			if !slices.Contains(keys, a.Key) {
+				out.AddAttrs(a)
+			}
+			return true
+		})
+		return out, true
+	}
+}
+
+// process runs the processors in order on r with the resolved attrs instead
+// of its own, returning the record they produced and its attributes or false
+// as soon as one drops it
+func process(ctx context.Context, processors []Processor, r slog.Record, attrs []slog.Attr) (slog.Record, []slog.Attr, bool) {
+	r = slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.AddAttrs(attrs...)
+	for _, p := range processors {
+		// every processor gets its own copy, so one keeping the record it
+		// was given never sees the changes of the next
+		var keep bool
+		if r, keep = p(ctx, r.Clone()); !keep {
+			return r, nil, false
+		}
+	}
+
+	attrs = make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return r, attrs, true
+}
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_Processors(t *testing.T) {
+	preserveConfig(t)
+	var order []string
+	processors = []Processor{
+		func(_ context.Context, r slog.Record) (slog.Record, bool) {
+			order = append(order, "enrich")
+			r.AddAttrs(slog.String("build_sha", "abc123"))
+			return r, true
+		},
+		func(_ context.Context, r slog.Record) (slog.Record, bool) {
+			order = append(order, "filter")
+			return r, r.Message != "health check"
+		},
+		DropAttrs("noisy", "request_id"),
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(newHandler(&buf)).With("request_id", "r1")
+	logger.Info("health check")
+	if buf.Len() != 0 {
+		t.Errorf("output = %s, want the record dropped", buf.String())
+	}
+	if strings.Join(order, ",") != "enrich,filter" {
+		t.Errorf("processors ran as %v, want in order until the record is dropped", order)
+	}
+
+	logger.Info("handled", "noisy", true, "status", 200)
+	out := buf.String()
+	for _, want := range []string{`"build_sha":"abc123"`, `"status":200`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output = %s, want %s", out, want)
+		}
+	}
+	if strings.Contains(out, "noisy") || strings.Contains(out, "request_id") {
+		t.Errorf("output = %s, want noisy and request_id dropped", out)
+	}
+}
+
+func TestProcess_CopiesRecord(t *testing.T) {
+	attrs := []slog.Attr{slog.Int("a", 1), slog.Int("b", 2), slog.Int("c", 3), slog.Int("d", 4), slog.Int("e", 5), slog.Int("f", 6)}
+	var kept slog.Record
+	processors := []Processor{
+		func(_ context.Context, r slog.Record) (slog.Record, bool) {
+			kept = r
+			return r, true
+		},
+		func(_ context.Context, r slog.Record) (slog.Record, bool) {
+			r.AddAttrs(slog.Bool("x", true))
+			return r, true
+		},
+	}
+	r, out, keep := process(context.Background(), processors, slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0), attrs)
+
+	if !keep || r.NumAttrs() != 7 || len(out) != 7 || out[6].Key != "x" {
+		t.Fatalf("process() = %d attrs %v, %v, want x added", r.NumAttrs(), out, keep)
+	}
+	if kept.NumAttrs() != 6 {
+		t.Errorf("record kept by the first processor has %d attrs, want 6", kept.NumAttrs())
+	}
+}
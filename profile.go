@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"runtime/pprof"
+	runtrace "runtime/trace"
+	"sync/atomic"
+)
+
+// Phases of logging a record, the values of the phase label of CPU profiles
+// and the names of the trace regions after regionPrefix
+const (
+	// phaseEncode encodes a record in the format of a sink
+	phaseEncode = "encode"
+	// phaseWrite writes the events of a record to a sink
+	phaseWrite = "write"
+	// phaseFlush writes a batch of events
+	phaseFlush = "flush"
+	// phaseDeliver writes an event from a delivery worker, retries included
+	phaseDeliver = "deliver"
+)
+
+const (
+	// phaseLabel and sinkLabel are the pprof labels of the samples taken
+	// during a phase
+	phaseLabel = "lagoon_log_phase"
+	sinkLabel  = "lagoon_log_sink"
+	// regionPrefix starts the names of the runtime/trace regions of the phases
+	regionPrefix = "lagoon-log-forwarder/"
+)
+
+// profiling is Config.Profiling, read by background writers too
+var profiling atomic.Bool
+
+// phase is a profiled phase of logging a record, the zero phase when
+// profiling is off
+type phase struct {
+	ctx    context.Context
+	region *runtrace.Region
+}
+
+// startPhase labels the CPU samples of the calling goroutine with phase and
+// sink, and starts the trace region of phase, when profiling is on. It
+// returns ctx with the labels, for the calls made during the phase.
+func startPhase(ctx context.Context, name, sink string) (context.Context, phase) {
+	if !profiling.Load() {
+		return ctx, phase{}
+	}
+
+	labels := pprof.Labels(phaseLabel, name)
+	if len(sink) > 0 {
+		labels = pprof.Labels(phaseLabel, name, sinkLabel, sink)
+	}
+	labeled := pprof.WithLabels(ctx, labels)
+	pprof.SetGoroutineLabels(labeled)
+	return labeled, phase{ctx: ctx, region: runtrace.StartRegion(ctx, regionPrefix+name)}
+}
+
+// end ends the trace region and restores the labels of the context the phase
+// started with, as pprof.Do does
+func (p phase) end() {
+	if p.region == nil {
+		return
+	}
+	p.region.End()
+	pprof.SetGoroutineLabels(p.ctx)
+}
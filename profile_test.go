@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime/pprof"
+	runtrace "runtime/trace"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// labelWriter records the profiling labels of the context of every write
+type labelWriter struct {
+	mu     sync.Mutex
+	labels []string
+}
+
+func (w *labelWriter) Write(p []byte) (int, error) {
+	return w.writeContext(context.Background(), p)
+}
+
+func (w *labelWriter) writeContext(ctx context.Context, p []byte) (int, error) {
+	phase, _ := pprof.Label(ctx, phaseLabel)
+	sink, _ := pprof.Label(ctx, sinkLabel)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.labels = append(w.labels, phase+"/"+sink)
+	return len(p), nil
+}
+
+func (w *labelWriter) written() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.labels)
+}
+
+func TestProfiling_Labels(t *testing.T) {
+	preserveConfig(t)
+
+	w := &labelWriter{}
+	logger := slog.New(newSinkHandler(sink{name: SinkForwarder, w: w}))
+
+	profiling.Store(false)
+	logger.Info("unprofiled")
+	profiling.Store(true)
+	logger.Info("profiled")
+
+	want := []string{"/", phaseWrite + "/" + SinkForwarder}
+	if got := w.written(); !slices.Equal(got, want) {
+		t.Errorf("labels of the writes = %q, want %q", got, want)
+	}
+
+	// the labels of the caller are kept during and restored after a phase
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("route", "/cart"))
+	labeled, p := startPhase(ctx, phaseEncode, "")
+	if route, _ := pprof.Label(labeled, "route"); route != "/cart" {
+		t.Errorf("route label during the phase = %q, want the label of the caller", route)
+	}
+	if sink, ok := pprof.Label(labeled, sinkLabel); ok {
+		t.Errorf("sink label = %q without a sink, want none", sink)
+	}
+	p.end()
+
+	batch := newBatchWriter(w, ProtocolTCP, 2, time.Hour)
+	_, _ = batch.Write([]byte("{}\n"))
+	_, _ = batch.Write([]byte("{}\n"))
+	if got := w.written(); len(got) != 3 || got[2] != phaseFlush+"/" {
+		t.Errorf("labels of the writes = %q, want the batch flushed in the flush phase", got)
+	}
+}
+
+func TestProfiling_TraceRegions(t *testing.T) {
+	preserveConfig(t)
+	if runtrace.IsEnabled() {
+		t.Skip("the test binary is already traced")
+	}
+
+	logger := slog.New(newSinkHandler(sink{name: SinkStdout, w: &labelWriter{}}))
+	profiling.Store(true)
+
+	var buf bytes.Buffer
+	if err := runtrace.Start(&buf); err != nil {
+		t.Fatalf("failed to start the trace: %v", err)
+	}
+	logger.Info("traced")
+	runtrace.Stop()
+
+	for _, name := range []string{phaseEncode, phaseWrite} {
+		if !bytes.Contains(buf.Bytes(), []byte(regionPrefix+name)) {
+			t.Errorf("trace has no %s region", regionPrefix+name)
+		}
+	}
+}
+
+func BenchmarkHandler_Profiling(b *testing.B) {
+	preserveConfig(b)
+	profiling.Store(true)
+	logger := slog.New(newSinkHandler(sink{w: &labelWriter{}}))
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info("request handled", "status", 200)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// droppedEventsKey is the attribute of the summary of rate limited events
+const droppedEventsKey = "dropped_events"
+
+// rateLimiter is a token bucket limiting the events written to a sink, so a
+// log storm can't saturate the socket or Logstash
+type rateLimiter struct {
+	rate  float64 // tokens added per second
+	burst float64 // tokens the bucket holds
+
+	mu      sync.Mutex
+	tokens  float64
+	last    time.Time
+	dropped int
+	now     func() time.Time
+}
+
+// newRateLimiter returns a limiter of rate events per second in bursts of up
+// to burst, at least one. A rate of 0 returns nil, which allows every event.
+func newRateLimiter(rate, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = rate
+	}
+	return &rateLimiter{rate: float64(rate), burst: float64(burst), tokens: float64(burst), last: time.Now(), now: time.Now}
+}
+
+// allow reports whether an event may be written, and how many were dropped
+// since the last one allowed
+func (l *rateLimiter) allow() (bool, int) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	if l.tokens < 1 {
+		l.dropped++
+		return false, 0
+	}
+	l.tokens--
+
+	dropped := l.dropped
+	l.dropped = 0
+	return true, dropped
+}
+
+// droppedRecord returns the record summarizing n events dropped by the rate
+// limiter
+func droppedRecord(n int) slog.Record {
+	r := slog.NewRecord(time.Now(), slog.LevelWarn, fmt.Sprintf("%d events dropped due to rate limiting", n), 0)
+	r.AddAttrs(slog.Int(droppedEventsKey, n))
+	return r
+}
@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	l := newRateLimiter(2, 3)
+	l.now = func() time.Time { return now }
+	l.last = now
+
+	var allowed int
+	for range 5 {
+		if ok, _ := l.allow(); ok {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("allowed %d events of a burst, want 3", allowed)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, dropped := l.allow(); !ok || dropped != 2 {
+		t.Errorf("allow() after refilling = %v, %d, want true and 2 dropped", ok, dropped)
+	}
+	if ok, dropped := l.allow(); ok || dropped != 0 {
+		t.Errorf("allow() on an empty bucket = %v, %d, want false", ok, dropped)
+	}
+
+	if ok, _ := (*rateLimiter)(nil).allow(); !ok {
+		t.Error("nil limiter dropped an event")
+	}
+	if newRateLimiter(0, 10) != nil {
+		t.Error("newRateLimiter(0) is not nil")
+	}
+}
+
+func TestHandler_RateLimit(t *testing.T) {
+	now := time.Now()
+	limit := newRateLimiter(1, 2)
+	limit.now = func() time.Time { return now }
+	limit.last = now
+
+	var stdout, forwarded bytes.Buffer
+	logger := slog.New(newSinkHandler(sink{w: &stdout}, sink{w: &forwarded, limit: limit}))
+	for range 5 {
+		logger.Info("storm")
+	}
+	now = now.Add(time.Second)
+	logger.Info("calm")
+
+	if n := strings.Count(stdout.String(), "\n"); n != 6 {
+		t.Errorf("stdout has %d events, want all 6", n)
+	}
+
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(forwarded.String()), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 4 {
+		t.Fatalf("forwarded %d events, want 2 of the burst, the summary and the next", len(events))
+	}
+	summary := events[2]
+	if summary["message"] != "3 events dropped due to rate limiting" || summary[droppedEventsKey] != float64(3) || summary["level"] != "WARN" {
+		t.Errorf("summary = %v", summary)
+	}
+	if summary["type"] == nil || events[3]["message"] != "calm" {
+		t.Errorf("events = %v, want the summary in the Lagoon format before the next event", events)
+	}
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// The delay between attempts to reach an endpoint that was unreachable at
+// Initialize doubles from reconnectBackoff up to reconnectMaxBackoff. They
+// are variables so tests don't wait for them.
+var (
+	reconnectBackoff    = time.Second
+	reconnectMaxBackoff = time.Minute
+)
+
+// reconnectDelay is the delay before the next attempt after failures
+// consecutive failed ones, spread by up to half of it either way so a fleet
+// of services doesn't retry a recovering endpoint in lockstep
+func reconnectDelay(failures int) time.Duration {
+	delay := min(reconnectBackoff<<min(failures-1, 16), reconnectMaxBackoff)
+	return time.Duration(float64(delay) * (0.5 + rand.Float64()))
+}
+
+// reconnect dials until the endpoint is reachable and attaches the
+// destination built on the connection to target, giving up when ctx is done
+func reconnect(ctx context.Context, target *switchWriter, dial func() (net.Conn, error), destination func(net.Conn) io.Writer) {
+	clock := schedulerClock()
+	for failures := 1; ; failures++ {
+		select {
+		case <-clock.After(reconnectDelay(failures)):
+		case <-ctx.Done():
+			return
+		}
+
+		conn, err := dial()
+		if err != nil {
+			continue
+		}
+
+		w := destination(conn)
+		if _, ok := target.attach(ctx, w); !ok {
+			closeWriter(w)
+			return
+		}
+
+		diag().Info("Connected to log endpoint", "attempts", failures+1)
+		return
+	}
+}
+
+// redial detaches w, whose connection was lost with err, from target and
+// reconnects, unless w was already replaced. Records written meanwhile, and
+// those w gave up on, are spooled or discarded like before the first
+// connection.
+func redial(ctx context.Context, target *switchWriter, w io.Writer, err error, dial func() (net.Conn, error), destination func(net.Conn) io.Writer) {
+	if !target.detach(w) {
+		return
+	}
+	diag().Warn("Lost the connection to log endpoint, reconnecting", "error", err)
+	// records still queued for w are spooled rather than retried
+	if a, ok := w.(interface{ abort() }); ok {
+		a.abort()
+	}
+	closeWriter(w)
+
+	reconnect(ctx, target, dial, destination)
+}
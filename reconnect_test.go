@@ -0,0 +1,288 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+// fastReconnect shortens the reconnect backoff for the duration of t
+func fastReconnect(t *testing.T) {
+	t.Helper()
+	backoff, maxBackoff := reconnectBackoff, reconnectMaxBackoff
+	t.Cleanup(func() { reconnectBackoff, reconnectMaxBackoff = backoff, maxBackoff })
+	reconnectBackoff, reconnectMaxBackoff = 10*time.Millisecond, 50*time.Millisecond
+}
+
+func TestReconnectDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		base     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{10, time.Minute},
+		{100, time.Minute},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			got := reconnectDelay(tt.failures)
+			if got < tt.base/2 || got > tt.base*3/2 {
+				t.Fatalf("reconnectDelay(%d) = %v, want within %v and %v", tt.failures, got, tt.base/2, tt.base*3/2)
+			}
+		}
+	}
+}
+
+func TestInitialize_Reconnects(t *testing.T) {
+	preserveConfig(t)
+	fastReconnect(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	// reserve a port nothing listens on yet
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "reconnect-type"
+	cfg.Protocol = ProtocolTCP
+	cfg.LogHost = "127.0.0.1"
+	cfg.LogPort = port
+	diagnosed := &capturedDiagnostics{}
+	cfg.Diagnostics = slog.NewJSONHandler(diagnosed, nil)
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+
+	receiver, err := loggertest.ListenAddr(loggertest.TCP, listener.Addr().String())
+	if err != nil {
+		t.Skipf("port %d was taken before the endpoint came up: %v", port, err)
+	}
+	defer receiver.Close()
+
+	if !diagnosed.wait("Connected to log endpoint", 2*time.Second) {
+		t.Fatal("forwarder did not reconnect once the endpoint came up")
+	}
+	slog.Info("after reconnect")
+	if !receiver.Wait(1, time.Second) {
+		t.Fatalf("received %d records after reconnecting, want 1", receiver.Count())
+	}
+	if got := receiver.Events()[0]["message"]; got != "after reconnect" {
+		t.Errorf("message = %v, want %q", got, "after reconnect")
+	}
+}
+
+func TestInitialize_ReconnectsOnClock(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	// reserve a port nothing listens on yet
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	clock := loggertest.NewManualClock(time.Now())
+	cfg := NewConfig()
+	cfg.LogType = "clock-type"
+	cfg.Protocol = ProtocolTCP
+	cfg.LogHost = "127.0.0.1"
+	cfg.LogPort = port
+	cfg.Clock = clock
+	diagnosed := &capturedDiagnostics{}
+	cfg.Diagnostics = slog.NewJSONHandler(diagnosed, nil)
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+	if !clock.WaitTimers(1, time.Second) {
+		t.Fatal("forwarder did not back off on the clock")
+	}
+
+	receiver, err := loggertest.ListenAddr(loggertest.TCP, listener.Addr().String())
+	if err != nil {
+		t.Skipf("port %d was taken before the endpoint came up: %v", port, err)
+	}
+	defer receiver.Close()
+
+	// the endpoint is up, but nothing is retried until the clock moves
+	if diagnosed.wait("Connected to log endpoint", 50*time.Millisecond) {
+		t.Fatal("forwarder reconnected before the backoff was over")
+	}
+	clock.Advance(reconnectMaxBackoff)
+	if !diagnosed.wait("Connected to log endpoint", 2*time.Second) {
+		t.Fatal("forwarder did not reconnect once the clock advanced")
+	}
+}
+
+func TestShutdown_StopsReconnecting(t *testing.T) {
+	preserveConfig(t)
+	fastReconnect(t)
+	once = sync.Once{}
+
+	var mu sync.Mutex
+	dials := 0
+	dial := func() (net.Conn, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		dials++
+		return nil, &net.OpError{Op: "dial", Err: net.ErrClosed}
+	}
+	goBackground(func(ctx context.Context) { reconnect(ctx, forwarder, dial, nil) })
+
+	time.Sleep(50 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		Shutdown(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown() did not stop the reconnector")
+	}
+
+	mu.Lock()
+	stopped := dials
+	mu.Unlock()
+	if stopped == 0 {
+		t.Error("reconnector never dialled")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if dials != stopped {
+		t.Errorf("reconnector dialled %d times after Shutdown()", dials-stopped)
+	}
+}
+
+func TestSwitchWriter_AttachAfterCancel(t *testing.T) {
+	s := &switchWriter{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, ok := s.attach(ctx, &bytes.Buffer{}); ok {
+		t.Error("attach() = true after the context was cancelled, want false")
+	}
+	if s.w != nil {
+		t.Errorf("destination = %T after a cancelled attach(), want nil", s.w)
+	}
+	if _, ok := s.attach(context.Background(), &bytes.Buffer{}); !ok {
+		t.Error("attach() = false, want true")
+	}
+}
+
+// logUntil logs message every few milliseconds until ok reports true or the
+// timeout passes, and reports whether it did
+func logUntil(message string, timeout time.Duration, ok func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for !ok() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		slog.Info(message)
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+func TestInitialize_RedialsLostConnection(t *testing.T) {
+	preserveConfig(t)
+	fastReconnect(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	receiver, err := loggertest.Listen(loggertest.TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "redial-type"
+	cfg.Protocol = ProtocolTCP
+	cfg.LogHost = receiver.Host()
+	cfg.LogPort = receiver.Port()
+	diagnosed := &capturedDiagnostics{}
+	cfg.Diagnostics = slog.NewJSONHandler(diagnosed, nil)
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+
+	receiver.SetDown(true)
+	lost := func() bool { return strings.Contains(diagnosed.String(), "Lost the connection to log endpoint") }
+	if !logUntil("while down", 2*time.Second, lost) {
+		t.Fatal("forwarder did not notice the lost connection")
+	}
+	receiver.SetDown(false)
+
+	received := func() bool {
+		for _, event := range receiver.Events() {
+			if event["message"] == "after redial" {
+				return true
+			}
+		}
+		return false
+	}
+	if !logUntil("after redial", 2*time.Second, received) {
+		t.Fatal("forwarder did not redial once the endpoint came back")
+	}
+}
+
+func TestInitialize_FailsOverLostConnection(t *testing.T) {
+	preserveConfig(t)
+	fastReconnect(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	primary, err := loggertest.Listen(loggertest.TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Close()
+	fallback, err := loggertest.Listen(loggertest.TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fallback.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "failover-type"
+	cfg.Protocol = ProtocolTCP
+	cfg.LogHost = primary.Host()
+	cfg.LogPort = primary.Port()
+	cfg.FallbackHosts = []string{fallback.Addr()}
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+	slog.Info("before failover")
+	if !primary.Wait(1, time.Second) {
+		t.Fatalf("primary received %d records, want 1", primary.Count())
+	}
+
+	// the primary refuses connections from now on
+	primary.Close()
+	if !logUntil("after failover", 2*time.Second, func() bool { return fallback.Count() > 0 }) {
+		t.Fatal("forwarder did not fail over once the primary connection broke")
+	}
+	if got := fallback.Events()[0]["message"]; got != "after failover" {
+		t.Errorf("message = %v, want %q", got, "after failover")
+	}
+}
@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// LevelCritical is the level of records needing attention at once, such as
+// recovered panics, between slog.LevelError and the levels above it. It is
+// written as CRITICAL, Monolog's level of the same name.
+const LevelCritical = slog.LevelError + 4
+
+// levelString returns the name of level written in events
+func levelString(level slog.Level) string {
+	if level == LevelCritical {
+		return "CRITICAL"
+	}
+	return level.String()
+}
+
+// RecoverOption configures RecoverAndLog and RecoverMiddleware
+type RecoverOption func(*recovery)
+
+// recovery logs the panics it recovers
+type recovery struct {
+	logger  *slog.Logger
+	repanic bool
+}
+
+// Repanic panics again with the recovered value once it is logged, so the
+// panic still crashes the process or reaches the recovery of net/http
+func Repanic() RecoverOption {
+	return func(r *recovery) { r.repanic = true }
+}
+
+// RecoverLogger logs panics to l instead of the default logger, or the
+// logger of the request in RecoverMiddleware
+func RecoverLogger(l *slog.Logger) RecoverOption {
+	return func(r *recovery) { r.logger = l }
+}
+
+// RecoverAndLog recovers a panic of the calling goroutine and logs its value
+// and stack trace at LevelCritical, so it reaches the forwarder rather than
+// only the stderr of the container. It must be deferred directly:
+//
+//	defer logger.RecoverAndLog(logger.Repanic())
+//
+// Without Repanic the goroutine returns normally from the deferring function.
+func RecoverAndLog(opts ...RecoverOption) {
+	if v := recover(); v != nil {
+		newRecovery(opts).log(context.Background(), slog.Default(), v)
+	}
+}
+
+// RecoverMiddleware wraps next so a panic serving a request is logged by
+// RecoverAndLog with the logger of the request, and answered with a 500
+// when next wrote nothing yet. Wrapped by HTTPMiddleware, the access record
+// of the request is logged too:
+//
+//	handler := logger.HTTPMiddleware(logger.RecoverMiddleware(mux))
+//
+// http.ErrAbortHandler, which aborts a response on purpose, is panicked
+// again without being logged.
+func RecoverMiddleware(next http.Handler, opts ...RecoverOption) http.Handler {
+	rec := newRecovery(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoverWriter{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			if !rw.wrote && !rec.repanic {
+				http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			rec.log(r.Context(), FromContext(r.Context()), v, HTTPMethod(r.Method), HTTPPath(r.URL.Path))
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// newRecovery returns the recovery configured by opts
+func newRecovery(opts []RecoverOption) *recovery {
+	r := &recovery{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// log logs the panic v to the logger of r or logger, then panics again when
+// r repanics. It is called by the deferred function that recovered v, on the
+// stack of the panic.
+func (r *recovery) log(ctx context.Context, logger *slog.Logger, v any, attrs ...slog.Attr) {
+	if r.logger != nil {
+		logger = r.logger
+	}
+	if logger.Enabled(ctx, LevelCritical) {
+		attrs = append(attrs, slog.String(FieldPanic, fmt.Sprint(v)), slog.String(FieldStack, panicStack()))
+		record := slog.NewRecord(time.Now(), LevelCritical, "Recovered panic", panicPC())
+		record.AddAttrs(attrs...)
+		_ = logger.Handler().Handle(ctx, record)
+	}
+	if r.repanic {
+		panic(v)
+	}
+}
+
+// panicStack returns the stack trace of the goroutine from the panic, leaving
+// out the frames recovering it
+func panicStack() string {
+	stack := string(debug.Stack())
+	header, frames, _ := strings.Cut(stack, "\n")
+	if _, panicked, ok := strings.Cut(frames, "\npanic("); ok {
+		return header + "\npanic(" + panicked
+	}
+	return stack
+}
+
+// panicPC returns the pc of the function that panicked, the first frame
+// outside the runtime below runtime.gopanic, or 0 when it isn't found
+func panicPC() uintptr {
+	var pcs [maxCallerDepth]uintptr
+	n := runtime.Callers(2, pcs[:])
+	panicking := false
+	for _, pc := range pcs[:n] {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		switch {
+		case frame.Function == "runtime.gopanic":
+			panicking = true
+		case panicking && !strings.HasPrefix(frame.Function, "runtime."):
+			return pc
+		}
+	}
+	return 0
+}
+
+// recoverWriter records whether a handler wrote a response
+type recoverWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *recoverWriter) WriteHeader(code int) {
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recoverWriter) Write(p []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Hijack takes over the connection, which no longer takes a response
+func (w *recoverWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.wrote = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (w *recoverWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// panicker panics with an error, the frame a recovered panic is logged from
+func panicker() {
+	panic(errors.New("index out of range"))
+}
+
+// recoverRecords decodes the records logged to buf, one per line
+func recoverRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("invalid record %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	preserveConfig(t)
+
+	var buf bytes.Buffer
+	cfg := NewConfig()
+	cfg.LogType = "recover-type"
+	cfg.LogHost = "localhost"
+	h, err := NewWriterHandler(cfg, &buf)
+	if err != nil {
+		t.Fatalf("NewWriterHandler() returned unexpected error: %v", err)
+	}
+
+	func() {
+		defer RecoverAndLog(RecoverLogger(slog.New(h)))
+		panicker()
+	}()
+
+	record := recoverRecords(t, &buf)[0]
+	if record["level"] != "CRITICAL" || record["message"] != "Recovered panic" {
+		t.Errorf("record = %v, want a CRITICAL Recovered panic", record)
+	}
+	if record[FieldPanic] != "index out of range" {
+		t.Errorf("%s = %v, want the value of the panic", FieldPanic, record[FieldPanic])
+	}
+	if stack, _ := record[FieldStack].(string); !strings.HasPrefix(stack, "goroutine ") || !strings.Contains(stack, "\npanic(") || !strings.Contains(stack, ".panicker(") {
+		t.Errorf("%s = %q, want the stack of the panic", FieldStack, stack)
+	}
+}
+
+func TestRecoverAndLog_Repanic(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: LevelCritical}))
+
+	var repanicked any
+	func() {
+		defer func() { repanicked = recover() }()
+		defer RecoverAndLog(RecoverLogger(logger), Repanic())
+		panic("boom")
+	}()
+
+	if repanicked != "boom" {
+		t.Errorf("recovered %v after RecoverAndLog, want the panic again", repanicked)
+	}
+	if !strings.Contains(buf.String(), `"panic":"boom"`) {
+		t.Errorf("the panic wasn't logged before panicking again: %s", buf.String())
+	}
+}
+
+func TestPanicPC(t *testing.T) {
+	var pc uintptr
+	func() {
+		defer func() {
+			pc = panicPC()
+			recover()
+		}()
+		panicker()
+	}()
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if !strings.HasSuffix(frame.Function, ".panicker") {
+		t.Errorf("panicPC() is in %q, want panicker", frame.Function)
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	handler := HTTPMiddleware(RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panicker()
+	})), WithLogger(logger))
+
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	request.Header.Set(defaultRequestIDHeader, "req-7")
+	handler.ServeHTTP(response, request)
+
+	if response.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", response.Code)
+	}
+	records := recoverRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("logged %d records, want the panic and the access record: %s", len(records), buf.String())
+	}
+	panicked, access := records[0], records[1]
+	if panicked[FieldPanic] != "index out of range" || panicked[FieldRequestID] != "req-7" || panicked[FieldHTTPPath] != "/users/42" {
+		t.Errorf("panic record = %v, want the panic of the request", panicked)
+	}
+	if access[FieldHTTPStatusCode] != float64(500) {
+		t.Errorf("access record = %v, want status 500", access)
+	}
+}
+
+func TestRecoverMiddleware_Abort(t *testing.T) {
+	var buf bytes.Buffer
+	handler := RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), RecoverLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", v)
+		}
+		if buf.Len() > 0 {
+			t.Errorf("an aborted response was logged: %s", buf.String())
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestLevelCritical(t *testing.T) {
+	level, err := ParseLevel("critical")
+	if err != nil || level != LevelCritical {
+		t.Errorf(`ParseLevel("critical") = %v, %v, want LevelCritical`, level, err)
+	}
+	if levelString(LevelCritical) != "CRITICAL" || levelString(slog.LevelError+2) != "ERROR+2" {
+		t.Errorf("levelString() names levels other than LevelCritical")
+	}
+	if severity := syslogSeverity(LevelCritical); severity != 2 {
+		t.Errorf("syslogSeverity(LevelCritical) = %d, want 2", severity)
+	}
+}
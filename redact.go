@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// redactedMask replaces redacted values when Redaction.Mask is empty
+const redactedMask = "[REDACTED]"
+
+// Patterns of common secrets and personal data for Redaction.Patterns
+var (
+	// RedactCreditCards matches Visa, Mastercard, American Express and
+	// Discover card numbers, with or without spaces or dashes between digits
+	RedactCreditCards = regexp.MustCompile(`\b(?:4\d{3}|5[1-5]\d{2}|2[2-7]\d{2}|3[47]\d{2}|6(?:011|5\d{2}))(?:[ -]?\d){9,15}\b`)
+	// RedactEmails matches email addresses
+	RedactEmails = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// RedactBearerTokens matches bearer tokens such as those of an
+	// Authorization header
+	RedactBearerTokens = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// Redaction masks secrets and personal data before records leave the process
+type Redaction struct {
+	// Fields are the keys of attributes whose values are masked as a whole,
+	// e.g. "password" or "authorization", at any depth and in any case
+	Fields []string
+	// Patterns are masked where they match the message or a string value,
+	// e.g. RedactEmails
+	Patterns []*regexp.Regexp
+	// Mask replaces what is redacted, "[REDACTED]" when empty
+	Mask string
+}
+
+// Redact returns a processor masking the fields and patterns of r in the
+// message and the attributes, including those nested in groups such as
+// context and extra. Errors are written as their masked message.
+func Redact(r Redaction) Processor {
+	fields := make([]string, len(r.Fields))
+	for i, f := range r.Fields {
+		fields[i] = strings.ToLower(f)
+	}
+	rd := redactor{fields: fields, patterns: r.Patterns, mask: r.Mask}
+	if len(rd.mask) == 0 {
+		rd.mask = redactedMask
+	}
+
+	return func(_ context.Context, rec slog.Record) (slog.Record, bool) {
+		out := slog.NewRecord(rec.Time, rec.Level, rd.scrub(rec.Message), rec.PC)
+		rec.Attrs(func(a slog.Attr) bool {
+			out.AddAttrs(rd.attr(a))
+			return true
+		})
+		return out, true
+	}
+}
+
+// redactor is the prepared state of a Redaction
+type redactor struct {
+	fields   []string
+	patterns []*regexp.Regexp
+	mask     string
+}
+
+// attr returns a with its value masked, walking groups
+func (rd redactor) attr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if slices.Contains(rd.fields, strings.ToLower(a.Key)) {
+		a.Value = slog.StringValue(rd.mask)
+		return a
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		group := a.Value.Group()
+		scrubbed := make([]slog.Attr, len(group))
+		for i, m := range group {
+			scrubbed[i] = rd.attr(m)
+		}
+		a.Value = slog.GroupValue(scrubbed...)
+	case slog.KindString:
+		a.Value = slog.StringValue(rd.scrub(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok && len(rd.patterns) > 0 {
+			a.Value = slog.StringValue(rd.scrub(err.Error()))
+		}
+	}
+	return a
+}
+
+// scrub returns s with the matches of the patterns masked
+func (rd redactor) scrub(s string) string {
+	for _, p := range rd.patterns {
+		s = p.ReplaceAllLiteralString(s, rd.mask)
+	}
+	return s
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	redact := Redact(Redaction{
+		Fields:   []string{"password", "Authorization"},
+		Patterns: []*regexp.Regexp{RedactCreditCards, RedactEmails, RedactBearerTokens},
+	})
+
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "signup from jane@example.com", 0)
+	r.AddAttrs(
+		slog.String("PASSWORD", "hunter2"),
+		slog.Group("context",
+			slog.String("authorization", "Basic abc"),
+			slog.Group("payment", slog.String("card", "4111 1111 1111 1111")),
+		),
+		slog.String("header", "Bearer eyJhbGciOi.J9.sig"),
+		slog.Any("error", errors.New("token bearer abc123 rejected")),
+		slog.Int64("timestamp_ms", 1700000000000),
+	)
+	out, keep := redact(context.Background(), r)
+	if !keep {
+		t.Fatal("Redact dropped the record")
+	}
+
+	if out.Message != "signup from [REDACTED]" {
+		t.Errorf("message = %q, want the email masked", out.Message)
+	}
+	got := map[string]string{}
+	var walk func(prefix string, attrs []slog.Attr)
+	walk = func(prefix string, attrs []slog.Attr) {
+		for _, a := range attrs {
+			if a.Value.Kind() == slog.KindGroup {
+				walk(prefix+a.Key+".", a.Value.Group())
+				continue
+			}
+			got[prefix+a.Key] = a.Value.String()
+		}
+	}
+	var attrs []slog.Attr
+	out.Attrs(func(a slog.Attr) bool { attrs = append(attrs, a); return true })
+	walk("", attrs)
+
+	want := map[string]string{
+		"PASSWORD":              "[REDACTED]",
+		"context.authorization": "[REDACTED]",
+		"context.payment.card":  "[REDACTED]",
+		"header":                "[REDACTED]",
+		"error":                 "token [REDACTED] rejected",
+		"timestamp_ms":          "1700000000000",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %q, want %q", key, got[key], value)
+		}
+	}
+}
+
+func TestRedact_WithAttrs(t *testing.T) {
+	preserveConfig(t)
+	processors = []Processor{Redact(Redaction{Fields: []string{"token"}, Mask: "***"})}
+
+	var buf bytes.Buffer
+	slog.New(newHandler(&buf)).With("token", "s3cret").WithGroup("extra").Info("call", "token", "s3cret")
+
+	var event map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if strings.Contains(buf.String(), "s3cret") || event["token"] != "***" {
+		t.Errorf("output = %s, want the token masked everywhere", buf.String())
+	}
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Transport is a protocol provided by a sink module, which keeps the
+// dependencies of a cloud sink such as Kafka, CloudWatch or Splunk out of
+// this module.
+//
+// A sink module is a nested Go module below sinks/, with a go.mod of its own
+// requiring this module and the client of its service:
+//
+//	sinks/kafka/go.mod   module github.com/salsadigitalauorg/go-lagoon-log-forwarder/sinks/kafka
+//	sinks/kafka/kafka.go package kafka
+//
+// It exports its protocol name and a Register function taking its settings,
+// which registers the Transport:
+//
+//	const Protocol = "kafka"
+//
+//	func Register(cfg Config) {
+//		logger.RegisterTransport(Protocol, logger.Transport{
+//			Dial: func(ctx context.Context, endpoint logger.Endpoint) (io.WriteCloser, error) {
+//				return newProducer(ctx, cfg, endpoint)
+//			},
+//		})
+//	}
+//
+// Applications requiring the module call Register before NewHandler and set
+// Protocol, or the Protocol of a Destination, to the name. Applications that
+// don't never download its dependencies.
+type Transport struct {
+	// Dial connects to endpoint. Every write to the returned writer is one
+	// newline-delimited JSON event, or a batch of them with BatchSize, and
+	// fails when the events were not delivered, so the forwarder reconnects
+	// and retries them like those of the built-in protocols.
+	Dial func(ctx context.Context, endpoint Endpoint) (io.WriteCloser, error)
+	// BatchBytes caps a batch written at once, 1 MiB when 0
+	BatchBytes int
+}
+
+// Endpoint is the endpoint a Transport dials: LogHost and LogPort, an entry
+// of FallbackHosts or a Destination
+type Endpoint struct {
+	Protocol     string
+	Host         string
+	Port         int
+	WriteTimeout time.Duration // 0 waits forever
+	TLS          *TLSConfig    // nil when unset
+}
+
+// builtinProtocols can't be registered again
+var builtinProtocols = map[string]bool{
+	"":              true,
+	ProtocolUDP:     true,
+	ProtocolTCP:     true,
+	ProtocolUnix:    true,
+	ProtocolHTTP:    true,
+	ProtocolForward: true,
+	ProtocolOTLP:    true,
+}
+
+// transports are the registered transports by protocol
+var transports sync.Map
+
+// RegisterTransport makes a Transport available as protocol. It panics when
+// protocol is built in or registered already, or Dial is nil, as sink modules
+// register their transports once at startup.
+func RegisterTransport(protocol string, t Transport) {
+	if builtinProtocols[protocol] {
+		panic(fmt.Sprintf("logger: protocol %q is built in", protocol))
+	}
+	if t.Dial == nil {
+		panic(fmt.Sprintf("logger: transport %q has no Dial", protocol))
+	}
+	if _, loaded := transports.LoadOrStore(protocol, t); loaded {
+		panic(fmt.Sprintf("logger: transport %q registered twice", protocol))
+	}
+}
+
+// registeredTransport returns the Transport registered as protocol
+func registeredTransport(protocol string) (Transport, bool) {
+	t, ok := transports.Load(protocol)
+	if !ok {
+		return Transport{}, false
+	}
+	return t.(Transport), true
+}
+
+// dialTransport connects to host:port with a registered transport
+func dialTransport(ctx context.Context, t Transport, protocol, host string, port int, writeTimeout time.Duration, settings *TLSConfig) (net.Conn, error) {
+	start := time.Now()
+	w, err := t.Dial(ctx, Endpoint{Protocol: protocol, Host: host, Port: port, WriteTimeout: writeTimeout, TLS: settings})
+	var conn net.Conn
+	if err == nil {
+		conn = &transportConn{WriteCloser: w, addr: transportAddr{protocol, net.JoinHostPort(host, strconv.Itoa(port))}}
+	} else {
+		err = fmt.Errorf("dial %s: %w", protocol, err)
+	}
+	traceDial(protocol, host, port, start, conn, err)
+	return conn, err
+}
+
+// transportConn stands in for a connection of a registered transport, so
+// reconnecting, failover, batching and delivery workers treat it like the
+// built-in protocols
+type transportConn struct {
+	io.WriteCloser
+	addr transportAddr
+}
+
+// the rest of net.Conn, which transports have no use for
+
+func (c *transportConn) Read([]byte) (int, error)         { return 0, io.EOF }
+func (c *transportConn) LocalAddr() net.Addr              { return transportAddr{c.addr.protocol, ""} }
+func (c *transportConn) RemoteAddr() net.Addr             { return c.addr }
+func (c *transportConn) SetDeadline(time.Time) error      { return nil }
+func (c *transportConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *transportConn) SetWriteDeadline(time.Time) error { return nil }
+
+// transportAddr is the address of an endpoint of a registered transport
+type transportAddr struct {
+	protocol string
+	address  string
+}
+
+func (a transportAddr) Network() string { return a.protocol }
+func (a transportAddr) String() string  { return a.address }
@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryTransport collects the writes of the connections it dials
+type memoryTransport struct {
+	mu        sync.Mutex
+	endpoints []Endpoint
+	writes    bytes.Buffer
+}
+
+func (m *memoryTransport) dial(ctx context.Context, endpoint Endpoint) (io.WriteCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endpoints = append(m.endpoints, endpoint)
+	return m, nil
+}
+
+func (m *memoryTransport) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.writes.Write(p)
+}
+
+func (m *memoryTransport) Close() error { return nil }
+
+func (m *memoryTransport) events() []map[string]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var events []map[string]any
+	for line := range bytes.Lines(m.writes.Bytes()) {
+		var event map[string]any
+		if err := json.Unmarshal(line, &event); err == nil {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// registerTestTransport registers t as protocol for the duration of the test
+func registerTestTransport(tb testing.TB, protocol string, t Transport) {
+	tb.Helper()
+	RegisterTransport(protocol, t)
+	tb.Cleanup(func() { transports.Delete(protocol) })
+}
+
+func TestRegisterTransport_Panics(t *testing.T) {
+	dial := func(context.Context, Endpoint) (io.WriteCloser, error) { return nil, errors.New("unused") }
+	registerTestTransport(t, "registered", Transport{Dial: dial})
+
+	tests := []struct {
+		name      string
+		protocol  string
+		transport Transport
+	}{
+		{"built in", ProtocolTCP, Transport{Dial: dial}},
+		{"default", "", Transport{Dial: dial}},
+		{"without dial", "nodial", Transport{}},
+		{"twice", "registered", Transport{Dial: dial}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterTransport(%q) did not panic", tt.protocol)
+				}
+			}()
+			RegisterTransport(tt.protocol, tt.transport)
+		})
+	}
+}
+
+func TestConfigValidate_RegisteredTransport(t *testing.T) {
+	dial := func(context.Context, Endpoint) (io.WriteCloser, error) { return nil, errors.New("unused") }
+	registerTestTransport(t, "queue", Transport{Dial: dial})
+
+	cfg := NewConfig()
+	cfg.LogType = "queue-type"
+	cfg.Protocol = "queue"
+	cfg.TLS = &TLSConfig{}
+	cfg.Destinations = []Destination{{Name: "queue", Host: "queue.example.com", Protocol: "queue"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() returned unexpected error: %v", err)
+	}
+
+	cfg.Protocol = "unregistered"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() returned no error for an unregistered protocol")
+	}
+}
+
+func TestInitialize_RegisteredTransport(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	forwarded, regional := &memoryTransport{}, &memoryTransport{}
+	registerTestTransport(t, "memory", Transport{Dial: forwarded.dial})
+	registerTestTransport(t, "regional-memory", Transport{Dial: regional.dial, BatchBytes: 64})
+
+	cfg := NewConfig()
+	cfg.LogType = "registered-type"
+	cfg.LogHost = "broker.example.com"
+	cfg.LogPort = 9092
+	cfg.Protocol = "memory"
+	cfg.WriteTimeout = time.Second
+	cfg.Destinations = []Destination{{Name: "regional", Host: "eu.example.com", Port: 9093, Protocol: "regional-memory"}}
+
+	handler, err := NewHandler(cfg)
+	if err != nil {
+		t.Fatalf("NewHandler() returned unexpected error: %v", err)
+	}
+	slog.New(handler).Info("delivered", "seq", 1)
+
+	for _, tt := range []struct {
+		transport *memoryTransport
+		host      string
+		port      int
+	}{{forwarded, "broker.example.com", 9092}, {regional, "eu.example.com", 9093}} {
+		events := tt.transport.events()
+		if len(events) != 1 || events[0]["message"] != "delivered" || events[0]["type"] != "registered-type" {
+			t.Errorf("%s received %v, want the event", tt.host, events)
+		}
+		tt.transport.mu.Lock()
+		endpoints := tt.transport.endpoints
+		tt.transport.mu.Unlock()
+		if len(endpoints) == 0 || endpoints[0].Host != tt.host || endpoints[0].Port != tt.port {
+			t.Errorf("dialled %+v, want %s:%d", endpoints, tt.host, tt.port)
+		}
+	}
+	if got := newBatchWriter(io.Discard, "regional-memory", 10, time.Second).maxBytes; got != 64 {
+		t.Errorf("batch limit = %d, want the BatchBytes of the transport", got)
+	}
+}
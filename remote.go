@@ -0,0 +1,208 @@
+package logger
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RemoteSignatureHeader carries the signature of a remote configuration
+// document, "sha256=" followed by the hex HMAC-SHA256 of the body keyed with
+// the shared RemoteConfigKey
+const RemoteSignatureHeader = "X-Lagoon-Logs-Signature"
+
+const (
+	// remoteFetchTimeout bounds a single poll of the remote configuration
+	remoteFetchTimeout = 10 * time.Second
+	// remoteMaxBytes is the largest remote configuration document accepted
+	remoteMaxBytes = 64 << 10
+)
+
+// RemoteOverrides is the remote configuration document. Empty fields leave
+// the local configuration in place, so an empty document reverts every
+// override.
+type RemoteOverrides struct {
+	Level      string `json:"level"`      // minimum level logged
+	SampleRate int    `json:"sampleRate"` // keep one in this many records below warn, 0 or 1 keeps all
+	LogHost    string `json:"logHost"`    // endpoint records are forwarded to
+	LogPort    int    `json:"logPort"`
+}
+
+// SignRemoteConfig returns the RemoteSignatureHeader value of body signed
+// with key, for services publishing remote configuration
+func SignRemoteConfig(body []byte, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// remoteController polls the remote configuration and applies it
+type remoteController struct {
+	url      string
+	key      string
+	interval time.Duration
+	client   *http.Client
+
+	// host and port are the endpoint of the local configuration
+	host string
+	port int
+	// forwardTo switches the forwarder to another endpoint
+	forwardTo func(ctx context.Context, host string, port int) error
+	// level is the level of the local configuration
+	level string
+	// changelog receives an event listing the settings each document
+	// changed, nil logs none
+	changelog slog.Handler
+
+	applied RemoteOverrides
+	current struct {
+		host string
+		port int
+	}
+	failing bool
+}
+
+// run polls every interval until ctx is done, then reverts the overrides
+func (c *remoteController) run(ctx context.Context) {
+	defer func() {
+		remoteLevel.Store(nil)
+		remoteSampleRate.Store(0)
+		c.client.CloseIdleConnections()
+	}()
+	c.current.host, c.current.port = c.host, c.port
+
+	for {
+		overrides, err := c.fetch(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			// warn once until the document can be fetched again
+			if !c.failing {
+				diag().Warn("Failed to fetch remote configuration", "url", c.url, "error", err)
+			}
+			c.failing = true
+		case err == nil:
+			c.failing = false
+			c.apply(ctx, overrides)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.interval):
+		}
+	}
+}
+
+// fetch requests the remote configuration and checks its signature
+func (c *remoteController) fetch(ctx context.Context) (RemoteOverrides, error) {
+	var overrides RemoteOverrides
+
+	ctx, cancel := context.WithTimeout(ctx, remoteFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return overrides, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return overrides, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return overrides, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, remoteMaxBytes+1))
+	if err != nil {
+		return overrides, err
+	}
+	if len(body) > remoteMaxBytes {
+		return overrides, fmt.Errorf("document larger than %d bytes", remoteMaxBytes)
+	}
+
+	signature := resp.Header.Get(RemoteSignatureHeader)
+	if !hmac.Equal([]byte(signature), []byte(SignRemoteConfig(body, c.key))) {
+		return overrides, errors.New("invalid signature")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&overrides); err != nil {
+		return overrides, fmt.Errorf("invalid document: %w", err)
+	}
+	return overrides, overrides.validate()
+}
+
+func (o RemoteOverrides) validate() error {
+	if _, err := ParseLevel(o.Level); err != nil {
+		return err
+	}
+	if o.SampleRate < 0 {
+		return errors.New("sampleRate must not be negative")
+	}
+	if o.LogPort < 0 || o.LogPort > 65535 {
+		return fmt.Errorf("invalid logPort %d", o.LogPort)
+	}
+	return nil
+}
+
+// apply makes overrides effective, switching the forwarder when they name
+// another endpoint
+func (c *remoteController) apply(ctx context.Context, overrides RemoteOverrides) {
+	endpoint := net.JoinHostPort(c.current.host, strconv.Itoa(c.current.port))
+	host, port := c.host, c.port
+	if len(overrides.LogHost) > 0 {
+		host = overrides.LogHost
+	}
+	if overrides.LogPort > 0 {
+		port = overrides.LogPort
+	}
+	if host != c.current.host || port != c.current.port {
+		if err := c.forwardTo(ctx, host, port); err != nil {
+			// the endpoint is tried again on the next poll
+			diag().Warn("Failed to switch to the remote log endpoint", "log_host", host, "log_port", port, "error", err)
+			overrides.LogHost, overrides.LogPort = c.applied.LogHost, c.applied.LogPort
+		} else {
+			c.current.host, c.current.port = host, port
+		}
+	}
+
+	if len(overrides.Level) > 0 {
+		level, _ := ParseLevel(overrides.Level)
+		remoteLevel.Store(&level)
+	} else {
+		remoteLevel.Store(nil)
+	}
+	remoteSampleRate.Store(int64(overrides.SampleRate))
+
+	if overrides != c.applied {
+		logChanges(c.changelog, "remote",
+			change("level", levelName(cmp.Or(c.applied.Level, c.level)), levelName(cmp.Or(overrides.Level, c.level))),
+			change("sample_rate", strconv.Itoa(max(c.applied.SampleRate, 1)), strconv.Itoa(max(overrides.SampleRate, 1))),
+			change("log_endpoint", endpoint, net.JoinHostPort(c.current.host, strconv.Itoa(c.current.port))),
+		)
+		c.applied = overrides
+		diag().Info("Applied remote configuration",
+			slog.Group("remote",
+				slog.String("level", overrides.Level),
+				slog.Int("sample_rate", overrides.SampleRate),
+				slog.String("log_host", overrides.LogHost),
+				slog.Int("log_port", overrides.LogPort),
+			),
+		)
+	}
+}
@@ -0,0 +1,255 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+const remoteTestKey = "shared-secret"
+
+// remoteServer serves the document it holds signed with key
+type remoteServer struct {
+	*httptest.Server
+	document atomic.Value
+}
+
+func newRemoteServer(t *testing.T, key, document string) *remoteServer {
+	t.Helper()
+	s := &remoteServer{}
+	s.document.Store(document)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := []byte(s.document.Load().(string))
+		w.Header().Set(RemoteSignatureHeader, SignRemoteConfig(body, key))
+		w.Write(body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// resetRemote clears the remote overrides once t ends
+func resetRemote(t *testing.T) {
+	t.Cleanup(func() {
+		remoteLevel.Store(nil)
+		remoteSampleRate.Store(0)
+	})
+}
+
+func newTestController(url string) *remoteController {
+	return &remoteController{
+		url:       url,
+		key:       remoteTestKey,
+		interval:  time.Hour,
+		client:    &http.Client{},
+		forwardTo: func(context.Context, string, int) error { return nil },
+	}
+}
+
+func TestRemoteController_Apply(t *testing.T) {
+	resetRemote(t)
+	server := newRemoteServer(t, remoteTestKey, `{"level": "warn", "sampleRate": 3}`)
+	c := newTestController(server.URL)
+
+	overrides, err := c.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("fetch() returned unexpected error: %v", err)
+	}
+	c.apply(context.Background(), overrides)
+
+	leveler := overrideLeveler{slog.LevelDebug}
+	if got := leveler.Level(); got != slog.LevelWarn {
+		t.Errorf("Level() = %v, want %v", got, slog.LevelWarn)
+	}
+	if got := remoteSampleRate.Load(); got != 3 {
+		t.Errorf("sample rate = %d, want 3", got)
+	}
+
+	// an empty document reverts the overrides
+	c.apply(context.Background(), RemoteOverrides{})
+	if got := leveler.Level(); got != slog.LevelDebug {
+		t.Errorf("Level() after revert = %v, want %v", got, slog.LevelDebug)
+	}
+	if got := remoteSampleRate.Load(); got != 0 {
+		t.Errorf("sample rate after revert = %d, want 0", got)
+	}
+}
+
+func TestRemoteController_Changelog(t *testing.T) {
+	resetRemote(t)
+	var buf bytes.Buffer
+	c := newTestController("")
+	c.host, c.port = "logs.example.com", 5140
+	c.current.host, c.current.port = c.host, c.port
+	c.level = "info"
+	// the changelog is written even when the level filters info records
+	c.changelog = slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError})
+
+	steps := []struct {
+		overrides RemoteOverrides
+		changes   []any
+	}{
+		{RemoteOverrides{Level: "debug", SampleRate: 10}, []any{"level: info→debug", "sample_rate: 1→10"}},
+		{RemoteOverrides{Level: "debug", SampleRate: 10}, nil},
+		{RemoteOverrides{Level: "debug", SampleRate: 10, LogHost: "logs-canary"}, []any{"log_endpoint: logs.example.com:5140→logs-canary:5140"}},
+		{RemoteOverrides{}, []any{"level: debug→info", "sample_rate: 10→1", "log_endpoint: logs-canary:5140→logs.example.com:5140"}},
+	}
+	for i, step := range steps {
+		buf.Reset()
+		c.apply(context.Background(), step.overrides)
+
+		if step.changes == nil {
+			if buf.Len() > 0 {
+				t.Errorf("step %d logged %s, want no changes", i, buf.String())
+			}
+			continue
+		}
+		var event map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+			t.Fatalf("step %d logged %q: %v", i, buf.String(), err)
+		}
+		if event["msg"] != changelogMessage || event[changeSourceKey] != "remote" {
+			t.Errorf("step %d logged %v, want a remote changelog event", i, event)
+		}
+		if got, _ := event[changesKey].([]any); !slices.Equal(got, step.changes) {
+			t.Errorf("step %d changes = %v, want %v", i, got, step.changes)
+		}
+	}
+}
+
+func TestRemoteController_Rejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		document string
+	}{
+		{"wrong key", "other-secret", `{"level": "warn"}`},
+		{"unknown field", remoteTestKey, `{"level": "warn", "verbosity": 3}`},
+		{"invalid level", remoteTestKey, `{"level": "loud"}`},
+		{"negative sample rate", remoteTestKey, `{"sampleRate": -1}`},
+		{"invalid port", remoteTestKey, `{"logPort": 70000}`},
+		{"not json", remoteTestKey, `level=warn`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRemoteServer(t, tt.key, tt.document)
+			if _, err := newTestController(server.URL).fetch(context.Background()); err == nil {
+				t.Error("fetch() expected error")
+			}
+		})
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"level": "warn"}`)
+		}))
+		defer server.Close()
+		if _, err := newTestController(server.URL).fetch(context.Background()); err == nil {
+			t.Error("fetch() expected error for an unsigned document")
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+		if _, err := newTestController(server.URL).fetch(context.Background()); err == nil {
+			t.Error("fetch() expected error for a 404 response")
+		}
+	})
+}
+
+func TestRemoteController_Endpoint(t *testing.T) {
+	resetRemote(t)
+
+	var mu sync.Mutex
+	var switched []string
+	fail := true
+	c := newTestController("")
+	c.host, c.port = "configured", 5140
+	c.current.host, c.current.port = c.host, c.port
+	c.forwardTo = func(_ context.Context, host string, port int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		switched = append(switched, fmt.Sprintf("%s:%d", host, port))
+		if fail {
+			return fmt.Errorf("unreachable")
+		}
+		return nil
+	}
+
+	overrides := RemoteOverrides{LogHost: "remote", LogPort: 5141}
+	c.apply(context.Background(), overrides)
+	fail = false
+	// a failed switch is retried on the next poll, a successful one is not
+	c.apply(context.Background(), overrides)
+	c.apply(context.Background(), overrides)
+	// reverting the override switches back to the configured endpoint
+	c.apply(context.Background(), RemoteOverrides{})
+
+	want := []string{"remote:5141", "remote:5141", "configured:5140"}
+	if fmt.Sprint(switched) != fmt.Sprint(want) {
+		t.Errorf("switched to %v, want %v", switched, want)
+	}
+}
+
+func TestInitialize_RemoteConfig(t *testing.T) {
+	preserveConfig(t)
+	resetRemote(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	configured, err := loggertest.Listen(loggertest.UDP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer configured.Close()
+	remote, err := loggertest.Listen(loggertest.UDP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer remote.Close()
+
+	server := newRemoteServer(t, remoteTestKey, fmt.Sprintf(`{"level": "info", "logPort": %d}`, remote.Port()))
+
+	cfg := NewConfig()
+	cfg.LogType = "remote-type"
+	cfg.LogHost = configured.Host()
+	cfg.LogPort = configured.Port()
+	cfg.RemoteConfigURL = server.URL
+	cfg.RemoteConfigKey = remoteTestKey
+	cfg.RemoteConfigInterval = 10 * time.Millisecond
+	diagnosed := &capturedDiagnostics{}
+	cfg.Diagnostics = slog.NewJSONHandler(diagnosed, nil)
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+
+	// the overrides are announced once the forwarder switched
+	if !diagnosed.wait(fmt.Sprintf(`"log_port":%d`, remote.Port()), 2*time.Second) {
+		t.Fatal("forwarder did not switch to the remote endpoint")
+	}
+	slog.Debug("filtered by the remote level")
+	slog.Info("forwarded to the remote endpoint")
+	if !remote.Wait(2, time.Second) {
+		t.Fatalf("remote endpoint received %d records, want 2", remote.Count())
+	}
+	// the changelog is forwarded once the forwarder switched
+	events := remote.Events()
+	if got := events[0]["message"]; got != changelogMessage {
+		t.Errorf("message = %v, want the changelog", got)
+	}
+	if got := events[1]["message"]; got != "forwarded to the remote endpoint" {
+		t.Errorf("message = %v, want the info record", got)
+	}
+}
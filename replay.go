@@ -0,0 +1,151 @@
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// spoolSentFile lists the IDs of the records a replay delivered, with when
+// they were delivered
+const spoolSentFile = "spool.sent"
+
+// sentRecords remembers the spooled records a replay delivered for window,
+// so a replay repeated after a crash, before the spool knew they were
+// delivered, skips them rather than duplicating them downstream
+type sentRecords struct {
+	path   string
+	window time.Duration
+
+	mu      sync.Mutex
+	ids     map[uint64]time.Time
+	file    *os.File
+	written int // lines in file
+}
+
+// recordID identifies a spooled record by the hash of its bytes, which
+// include the timestamp of the record
+func recordID(record []byte) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write(record)
+	return hash.Sum64()
+}
+
+// openSentRecords reads the records delivered within window from dir
+func openSentRecords(dir string, window time.Duration) (*sentRecords, error) {
+	s := &sentRecords{path: filepath.Join(dir, spoolSentFile), window: window, ids: map[uint64]time.Time{}}
+
+	f, err := os.Open(s.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("open sent records: %w", err)
+	default:
+		cutoff := time.Now().Add(-window)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var id uint64
+			var sent int64
+			if _, err := fmt.Sscanf(scanner.Text(), "%x %d", &id, &sent); err != nil {
+				continue
+			}
+			if at := time.Unix(0, sent); at.After(cutoff) {
+				s.ids[id] = at
+			}
+		}
+		_ = f.Close()
+	}
+
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// seen reports whether record was delivered within the window
+func (s *sentRecords) seen(record []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	at, ok := s.ids[recordID(record)]
+	return ok && time.Since(at) < s.window
+}
+
+// add remembers that record was delivered. The line is written before the
+// replay moves on, so it survives a crash of the process.
+func (s *sentRecords) add(record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	id, now := recordID(record), time.Now()
+	s.ids[id] = now
+	if _, err := fmt.Fprintf(s.file, "%016x %d\n", id, now.UnixNano()); err != nil {
+		return fmt.Errorf("record sent: %w", err)
+	}
+	s.written++
+
+	// expired lines are dropped once they make up most of the file
+	if s.written > 2*len(s.ids)+1024 {
+		return s.compact()
+	}
+	return nil
+}
+
+// compact forgets the records delivered before the window and rewrites the
+// file with the others
+func (s *sentRecords) compact() error {
+	cutoff := time.Now().Add(-s.window)
+	for id, at := range s.ids {
+		if at.Before(cutoff) {
+			delete(s.ids, id)
+		}
+	}
+
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600) // #nosec G304 -- the directory is supplied by the operator
+	if err != nil {
+		return fmt.Errorf("compact sent records: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for id, at := range s.ids {
+		fmt.Fprintf(w, "%016x %d\n", id, at.UnixNano())
+	}
+	if err := errors.Join(w.Flush(), f.Close()); err != nil {
+		return fmt.Errorf("compact sent records: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("compact sent records: %w", err)
+	}
+
+	s.file, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0o600) // #nosec G304 -- the directory is supplied by the operator
+	if err != nil {
+		return fmt.Errorf("compact sent records: %w", err)
+	}
+	s.written = len(s.ids)
+	return nil
+}
+
+// Close closes the file, records delivered afterwards are not remembered
+func (s *sentRecords) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Resolver looks up the addresses of the log host. *net.Resolver implements
+// it, other implementations can route resolution through a platform's own
+// service discovery or stub it in tests.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) (addrs []string, err error)
+}
+
+// TTLResolver is a Resolver that also reports how long the addresses it
+// returns may be cached, which CacheResolver respects
+type TTLResolver interface {
+	Resolver
+	LookupHostTTL(ctx context.Context, host string) (addrs []string, ttl time.Duration, err error)
+}
+
+// CacheResolver returns a Resolver caching the addresses found by r for ttl,
+// or for their own TTL when r is a TTLResolver reporting a shorter one.
+// Failed lookups are not cached.
+func CacheResolver(r Resolver, ttl time.Duration) Resolver {
+	return &cacheResolver{resolver: r, ttl: ttl, entries: map[string]cacheEntry{}}
+}
+
+type cacheResolver struct {
+	resolver Resolver
+	ttl      time.Duration
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	// now is replaced in tests
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func (c *cacheResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	ttl := c.ttl
+	var addrs []string
+	var err error
+	if r, ok := c.resolver.(TTLResolver); ok {
+		var recordTTL time.Duration
+		addrs, recordTTL, err = r.LookupHostTTL(ctx, host)
+		ttl = min(ttl, recordTTL)
+	} else {
+		addrs, err = c.resolver.LookupHost(ctx, host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[host] = cacheEntry{addrs: addrs, expires: now().Add(ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+// stubResolver resolves every host to addrs, or fails with err
+type stubResolver struct {
+	addrs   []string
+	ttl     time.Duration
+	err     error
+	lookups atomic.Int32
+}
+
+func (r *stubResolver) LookupHost(context.Context, string) ([]string, error) {
+	r.lookups.Add(1)
+	return r.addrs, r.err
+}
+
+func (r *stubResolver) LookupHostTTL(ctx context.Context, host string) ([]string, time.Duration, error) {
+	addrs, err := r.LookupHost(ctx, host)
+	return addrs, r.ttl, err
+}
+
+func TestDial_Resolver(t *testing.T) {
+	receiver, err := loggertest.Listen(loggertest.TCP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer receiver.Close()
+
+	cfg := NewConfig()
+	cfg.Protocol = ProtocolTCP
+	cfg.LogHost = "logs.consul"
+	cfg.LogPort = receiver.Port()
+	cfg.Resolver = &stubResolver{addrs: []string{receiver.Host()}}
+
+	conn, err := Dial(cfg)
+	if err != nil {
+		t.Fatalf("Dial() returned unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("{\"seq\":1}\n")); err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+	if !receiver.Wait(1, time.Second) {
+		t.Fatal("record was not delivered to the resolved address")
+	}
+
+	lookupErr := errors.New("no such service")
+	cfg.Resolver = &stubResolver{err: lookupErr}
+	if _, err := Dial(cfg); !errors.Is(err, lookupErr) {
+		t.Errorf("Dial() error = %v, want %v", err, lookupErr)
+	}
+}
+
+func TestCacheResolver(t *testing.T) {
+	now := time.Now()
+	stub := &stubResolver{addrs: []string{"10.0.0.1"}, ttl: time.Hour}
+	cache := CacheResolver(stub, time.Minute).(*cacheResolver)
+	cache.now = func() time.Time { return now }
+
+	lookup := func() {
+		t.Helper()
+		addrs, err := cache.LookupHost(context.Background(), "logs")
+		if err != nil || len(addrs) != 1 || addrs[0] != "10.0.0.1" {
+			t.Fatalf("LookupHost() = %v, %v", addrs, err)
+		}
+	}
+
+	lookup()
+	lookup()
+	if n := stub.lookups.Load(); n != 1 {
+		t.Errorf("resolved %d times within the TTL, want 1", n)
+	}
+
+	// the cache TTL caps the longer record TTL
+	now = now.Add(time.Minute)
+	lookup()
+	if n := stub.lookups.Load(); n != 2 {
+		t.Errorf("resolved %d times after the TTL, want 2", n)
+	}
+
+	// a shorter record TTL is respected
+	stub.ttl = time.Second
+	now = now.Add(time.Minute)
+	lookup()
+	now = now.Add(time.Second)
+	lookup()
+	if n := stub.lookups.Load(); n != 4 {
+		t.Errorf("resolved %d times, want the record TTL respected", n)
+	}
+
+	// failures are not cached
+	stub.err = errors.New("timeout")
+	now = now.Add(time.Hour)
+	if _, err := cache.LookupHost(context.Background(), "logs"); err == nil {
+		t.Error("LookupHost() should return the error of the resolver")
+	}
+	stub.err = nil
+	lookup()
+	if n := stub.lookups.Load(); n != 6 {
+		t.Errorf("resolved %d times, want a retry after the failure", n)
+	}
+}
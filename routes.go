@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// levelRoute sends the records of a level, or of a level and above, to sinks
+type levelRoute struct {
+	level slog.Level
+	above bool
+	sinks []string
+}
+
+// levelRoutes are the routes of LevelRoutes, those of single levels first
+// and then those of a level and above, the highest level first, so the first
+// route matching a record is the most specific
+type levelRoutes []levelRoute
+
+// parseLevelRoutes parses routes whose keys are level names such as "debug",
+// or "error+" for a level and above, and whose sinks are one of known
+func parseLevelRoutes(routes map[string][]string, known []string) (levelRoutes, error) {
+	parsed := make(levelRoutes, 0, len(routes))
+	for key, sinks := range routes {
+		name, above := strings.CutSuffix(key, "+")
+		level, err := ParseLevel(name)
+		if err != nil || len(name) == 0 {
+			return nil, fmt.Errorf("levelRoutes: invalid level %q", key)
+		}
+		for _, s := range sinks {
+			if !slices.Contains(known, s) {
+				return nil, fmt.Errorf("levelRoutes %s: unknown sink %q", key, s)
+			}
+		}
+		for _, r := range parsed {
+			if r.level == level && r.above == above {
+				return nil, fmt.Errorf("levelRoutes: duplicate route %q", key)
+			}
+		}
+		parsed = append(parsed, levelRoute{level: level, above: above, sinks: sinks})
+	}
+	slices.SortFunc(parsed, func(a, b levelRoute) int {
+		if a.above != b.above {
+			if a.above {
+				return 1
+			}
+			return -1
+		}
+		return int(b.level) - int(a.level)
+	})
+	return parsed, nil
+}
+
+// allows reports whether a record at level is written to the sink named
+// name: by the first route matching level, or by every sink when none does.
+// Unnamed sinks, such as the writer of NewWriterHandler, take every record.
+func (rs levelRoutes) allows(level slog.Level, name string) bool {
+	if len(name) == 0 {
+		return true
+	}
+	for _, r := range rs {
+		if level == r.level || (r.above && level > r.level) {
+			return slices.Contains(r.sinks, name)
+		}
+	}
+	return true
+}
+
+// sinkNames returns the names of the sinks of c, which LevelRoutes route to
+func (c Config) sinkNames() []string {
+	names := []string{SinkStdout, SinkForwarder}
+	for _, d := range c.Destinations {
+		names = append(names, d.Name)
+	}
+	return names
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLevelRoutes_Allows(t *testing.T) {
+	routes, err := parseLevelRoutes(map[string][]string{
+		"debug":  {SinkStdout},
+		"debug+": {SinkStdout, SinkForwarder},
+		"error+": {SinkStdout, SinkForwarder, "pager"},
+		"warn":   {},
+	}, []string{SinkStdout, SinkForwarder, "pager"})
+	if err != nil {
+		t.Fatalf("parseLevelRoutes() returned unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		level slog.Level
+		sinks string
+	}{
+		{slog.LevelDebug - 4, "stdout,forwarder,pager"},
+		{slog.LevelDebug, "stdout"},
+		{slog.LevelInfo, "stdout,forwarder"},
+		{slog.LevelWarn, ""},
+		{slog.LevelWarn + 2, "stdout,forwarder"},
+		{slog.LevelError, "stdout,forwarder,pager"},
+		{LevelCritical, "stdout,forwarder,pager"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			var sinks []string
+			for _, name := range []string{SinkStdout, SinkForwarder, "pager"} {
+				if routes.allows(tt.level, name) {
+					sinks = append(sinks, name)
+				}
+			}
+			if strings.Join(sinks, ",") != tt.sinks {
+				t.Errorf("a record at %v is written to %v, want %s", tt.level, sinks, tt.sinks)
+			}
+		})
+	}
+
+	if !routes.allows(slog.LevelWarn, "") {
+		t.Error("an unnamed sink is routed")
+	}
+	if !(levelRoutes{}).allows(slog.LevelDebug, "pager") {
+		t.Error("a record without a route isn't written to every sink")
+	}
+}
+
+func TestHandler_LevelRoutes(t *testing.T) {
+	preserveConfig(t)
+	levelRouting = map[string][]string{"debug": {SinkStdout}, "error+": {SinkStdout, SinkForwarder, "pager"}}
+	destinations = []Destination{{Name: "pager", Host: "pager.example.com", Port: 5140}}
+
+	var stdout, forwarded, pager bytes.Buffer
+	logger := slog.New(newSinkHandler(
+		sink{name: SinkStdout, w: &stdout},
+		sink{name: SinkForwarder, w: &forwarded},
+		sink{name: "pager", w: &pager, level: slog.LevelWarn},
+	))
+	logger.Debug("cache miss")
+	logger.Info("request handled")
+	logger.Error("payment failed")
+
+	for _, tt := range []struct {
+		name     string
+		buf      *bytes.Buffer
+		messages []string
+	}{
+		{SinkStdout, &stdout, []string{"cache miss", "request handled", "payment failed"}},
+		{SinkForwarder, &forwarded, []string{"request handled", "payment failed"}},
+		{"pager", &pager, []string{"payment failed"}},
+	} {
+		if got := strings.Count(tt.buf.String(), "\n"); got != len(tt.messages) {
+			t.Errorf("%s got %d records, want %v: %s", tt.name, got, tt.messages, tt.buf.String())
+		}
+		for _, msg := range tt.messages {
+			if !strings.Contains(tt.buf.String(), msg) {
+				t.Errorf("%s = %s, want %q", tt.name, tt.buf.String(), msg)
+			}
+		}
+	}
+}
+
+func TestConfigValidate_LevelRoutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes map[string][]string
+		errMsg string
+	}{
+		{"valid", map[string][]string{"debug": {SinkStdout}, "error+": {SinkStdout, SinkForwarder, "audit"}}, ""},
+		{"numbered level", map[string][]string{"WARN+2+": {SinkForwarder}}, ""},
+		{"invalid level", map[string][]string{"loud+": {SinkStdout}}, `invalid level "loud+"`},
+		{"no level", map[string][]string{"+": {SinkStdout}}, `invalid level "+"`},
+		{"unknown sink", map[string][]string{"error": {"pagerduty"}}, `unknown sink "pagerduty"`},
+		{"duplicate", map[string][]string{"info": {SinkStdout}, "INFO": {SinkForwarder}}, "duplicate route"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.LogType = "routes-type"
+			cfg.Destinations = []Destination{{Name: "audit", Host: "audit.example.com", Port: 5140}}
+			cfg.LevelRoutes = tt.routes
+
+			err := cfg.Validate()
+			switch {
+			case len(tt.errMsg) == 0 && err != nil:
+				t.Errorf("Validate() returned unexpected error: %v", err)
+			case len(tt.errMsg) > 0 && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Validate() = %v, want %q", err, tt.errMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ScheduleWindow changes the level and sampling of records during recurring
+// hours of the week, e.g. debug records during business hours only
+type ScheduleWindow struct {
+	Days       string `json:"days"`       // e.g. "mon-fri" or "sat,sun", every day when empty
+	Start      string `json:"start"`      // "09:00", the window starts at midnight when empty
+	End        string `json:"end"`        // "17:30", exclusive, before Start when the window spans midnight
+	Level      string `json:"level"`      // minimum level logged during the window
+	SampleRate int    `json:"sampleRate"` // keep one in this many records below warn, 0 or 1 keeps all
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// window is a parsed ScheduleWindow
+type window struct {
+	days       [7]bool
+	start, end time.Duration // since midnight
+	level      slog.Level
+	sampleRate int
+}
+
+func (w ScheduleWindow) parse() (window, error) {
+	var parsed window
+
+	if len(w.Days) == 0 {
+		parsed.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, span := range strings.Split(w.Days, ",") {
+		if len(span) == 0 {
+			continue
+		}
+		from, to, isRange := strings.Cut(strings.ToLower(strings.TrimSpace(span)), "-")
+		if !isRange {
+			to = from
+		}
+		first, ok := weekdays[from]
+		last, ok2 := weekdays[to]
+		if !ok || !ok2 {
+			return parsed, fmt.Errorf("invalid days %q", w.Days)
+		}
+		// ranges such as fri-mon wrap around the week
+		for d := first; ; d = (d + 1) % 7 {
+			parsed.days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+
+	var err error
+	if parsed.start, err = parseClock(w.Start, 0); err != nil {
+		return parsed, err
+	}
+	if parsed.end, err = parseClock(w.End, 24*time.Hour); err != nil {
+		return parsed, err
+	}
+
+	if parsed.level, err = ParseLevel(w.Level); err != nil {
+		return parsed, err
+	}
+
+	if w.SampleRate < 0 {
+		return parsed, fmt.Errorf("negative sampleRate %d", w.SampleRate)
+	}
+	parsed.sampleRate = w.SampleRate
+
+	return parsed, nil
+}
+
+// parseClock parses a time of day such as "09:00", returning fallback when
+// clock is empty
+func parseClock(clock string, fallback time.Duration) (time.Duration, error) {
+	if len(clock) == 0 {
+		return fallback, nil
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// contains reports whether the window is active at t
+func (w window) contains(t time.Time) bool {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	clock := t.Sub(midnight)
+
+	if w.start < w.end {
+		return w.days[t.Weekday()] && clock >= w.start && clock < w.end
+	}
+	// the window starts on one of its days and ends on the next day
+	yesterday := (t.Weekday() + 6) % 7
+	return (w.days[t.Weekday()] && clock >= w.start) || (w.days[yesterday] && clock < w.end)
+}
+
+// schedule selects the level and sampling of records from the first window
+// active at the time, falling back to the configured level outside them. It
+// is the slog.Leveler of the JSON handler, so the level follows the clock.
+type schedule struct {
+	level    slog.Level
+	windows  []window
+	location *time.Location
+	now      func() time.Time
+	sampled  atomic.Uint64
+}
+
+func newSchedule(level slog.Level, windows []ScheduleWindow, timezone string) (*schedule, error) {
+	s := &schedule{level: level, location: time.Local, now: time.Now}
+
+	if len(timezone) > 0 {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scheduleTimezone: %w", err)
+		}
+		s.location = location
+	}
+
+	for i, w := range windows {
+		parsed, err := w.parse()
+		if err != nil {
+			return nil, fmt.Errorf("schedule[%d]: %w", i, err)
+		}
+		s.windows = append(s.windows, parsed)
+	}
+
+	return s, nil
+}
+
+// active returns the window active now
+func (s *schedule) active() (window, bool) {
+	now := s.now().In(s.location)
+	for _, w := range s.windows {
+		if w.contains(now) {
+			return w, true
+		}
+	}
+	return window{}, false
+}
+
+func (s *schedule) Level() slog.Level {
+	if w, ok := s.active(); ok {
+		return w.level
+	}
+	return s.level
+}
+
+// keep reports whether a record at level is kept by the sampling of the
+// active window. Warnings and errors are never sampled.
+func (s *schedule) keep(level slog.Level) bool {
+	if level >= slog.LevelWarn {
+		return true
+	}
+	w, ok := s.active()
+	if !ok || w.sampleRate <= 1 {
+		return true
+	}
+	return s.sampled.Add(1)%uint64(w.sampleRate) == 1
+}
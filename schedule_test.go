@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 2024-03-04 is a Monday
+func at(day int, clock string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02 15:04", "2024-03-"+clock, time.UTC)
+	return t.AddDate(0, 0, day)
+}
+
+func TestScheduleWindowContains(t *testing.T) {
+	tests := []struct {
+		name   string
+		window ScheduleWindow
+		time   time.Time
+		want   bool
+	}{
+		{"business hours", ScheduleWindow{Days: "mon-fri", Start: "09:00", End: "17:00"}, at(0, "04 10:30"), true},
+		{"before start", ScheduleWindow{Days: "mon-fri", Start: "09:00", End: "17:00"}, at(0, "04 08:59"), false},
+		{"end is exclusive", ScheduleWindow{Days: "mon-fri", Start: "09:00", End: "17:00"}, at(0, "04 17:00"), false},
+		{"weekend", ScheduleWindow{Days: "mon-fri", Start: "09:00", End: "17:00"}, at(5, "04 10:30"), false},
+		{"day list", ScheduleWindow{Days: "sat,sun"}, at(6, "04 23:59"), true},
+		{"week wrap", ScheduleWindow{Days: "fri-mon"}, at(0, "04 12:00"), true},
+		{"outside week wrap", ScheduleWindow{Days: "fri-mon"}, at(2, "04 12:00"), false},
+		{"overnight evening", ScheduleWindow{Days: "mon", Start: "22:00", End: "06:00"}, at(0, "04 23:00"), true},
+		{"overnight next morning", ScheduleWindow{Days: "mon", Start: "22:00", End: "06:00"}, at(1, "04 05:59"), true},
+		{"overnight previous morning", ScheduleWindow{Days: "mon", Start: "22:00", End: "06:00"}, at(0, "04 05:00"), false},
+		{"every day", ScheduleWindow{Start: "00:00", End: "01:00"}, at(3, "04 00:30"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := tt.window.parse()
+			if err != nil {
+				t.Fatalf("parse() returned unexpected error: %v", err)
+			}
+			if got := w.contains(tt.time); got != tt.want {
+				t.Errorf("contains(%v) = %v, want %v", tt.time.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleLevel(t *testing.T) {
+	s, err := newSchedule(slog.LevelInfo, []ScheduleWindow{
+		{Days: "mon-fri", Start: "09:00", End: "17:00", Level: "debug"},
+		{Start: "00:00", End: "06:00", Level: "warn"},
+	}, "")
+	if err != nil {
+		t.Fatalf("newSchedule() returned unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		time time.Time
+		want slog.Level
+	}{
+		{at(0, "04 10:00"), slog.LevelDebug},
+		{at(0, "04 03:00"), slog.LevelWarn},
+		{at(0, "04 20:00"), slog.LevelInfo},
+		{at(5, "04 10:00"), slog.LevelInfo},
+	}
+
+	for _, tt := range tests {
+		s.now = func() time.Time { return tt.time }
+		s.location = time.UTC
+		if got := s.Level(); got != tt.want {
+			t.Errorf("Level() at %v = %v, want %v", tt.time.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestScheduleTimezone(t *testing.T) {
+	s, err := newSchedule(slog.LevelInfo, []ScheduleWindow{{Start: "09:00", End: "17:00", Level: "debug"}}, "UTC")
+	if err != nil {
+		t.Fatalf("newSchedule() returned unexpected error: %v", err)
+	}
+
+	// 10:00 in UTC is 20:00 in UTC+10
+	s.now = func() time.Time { return at(0, "04 10:00").In(time.FixedZone("AEST", 10*60*60)) }
+	if got := s.Level(); got != slog.LevelDebug {
+		t.Errorf("Level() = %v, want %v in the schedule timezone", got, slog.LevelDebug)
+	}
+}
+
+func TestScheduleSampling(t *testing.T) {
+	s, err := newSchedule(slog.LevelDebug, []ScheduleWindow{{SampleRate: 4}}, "")
+	if err != nil {
+		t.Fatalf("newSchedule() returned unexpected error: %v", err)
+	}
+
+	kept := 0
+	for i := 0; i < 100; i++ {
+		if s.keep(slog.LevelInfo) {
+			kept++
+		}
+	}
+	if kept != 25 {
+		t.Errorf("kept %d of 100 info records, want 25", kept)
+	}
+
+	for i := 0; i < 10; i++ {
+		if !s.keep(slog.LevelWarn) {
+			t.Fatal("keep() sampled a warning")
+		}
+	}
+}
+
+func TestParseConfig_Schedule(t *testing.T) {
+	cfg, err := parseConfig(NewConfig(), []byte(`{
+		"logType": "test",
+		"level": "info",
+		"scheduleTimezone": "UTC",
+		"schedule": [{"days": "mon-fri", "start": "09:00", "end": "17:00", "level": "debug", "sampleRate": 10}]
+	}`))
+	if err != nil {
+		t.Fatalf("parseConfig() returned unexpected error: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() returned unexpected error: %v", err)
+	}
+
+	want := ScheduleWindow{Days: "mon-fri", Start: "09:00", End: "17:00", Level: "debug", SampleRate: 10}
+	if len(cfg.Schedule) != 1 || cfg.Schedule[0] != want {
+		t.Errorf("Schedule = %+v, want [%+v]", cfg.Schedule, want)
+	}
+}
+
+func TestHandler_Schedule(t *testing.T) {
+	preserveConfig(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "test"
+	cfg.Level = "warn"
+	cfg.Schedule = []ScheduleWindow{{Level: "debug", SampleRate: 2}}
+	if err := config(cfg); err != nil {
+		t.Fatalf("config() returned unexpected error: %v", err)
+	}
+
+	var buf strings.Builder
+	logger := slog.New(newHandler(&buf))
+	for i := 0; i < 4; i++ {
+		logger.Debug("sampled")
+	}
+	logger.Error("kept")
+
+	if got := strings.Count(buf.String(), `"sampled"`); got != 2 {
+		t.Errorf("logged %d of 4 debug records, want 2", got)
+	}
+	if !strings.Contains(buf.String(), `"kept"`) {
+		t.Error("error record was not logged")
+	}
+}
@@ -0,0 +1,202 @@
+package logger
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// schemaVersions are the message versions with a published JSON Schema
+var schemaVersions = []int{1, 2, 3}
+
+// schemaFiles are the JSON Schemas of the wire format, one per message
+// version, written by
+//
+//	go test -run TestSchema -update .
+//
+//go:embed schema/*.json
+var schemaFiles embed.FS
+
+// parsedSchemas caches the decoded schemas by version
+var parsedSchemas sync.Map
+
+// SchemaVersions returns the message versions Schema describes
+func SchemaVersions() []int {
+	return slices.Clone(schemaVersions)
+}
+
+// Schema returns the JSON Schema of the events of a message version, for
+// pipelines and consumers to validate against
+func Schema(version int) ([]byte, error) {
+	if !slices.Contains(schemaVersions, version) {
+		return nil, fmt.Errorf("no schema for message version %d", version)
+	}
+	return schemaFiles.ReadFile(fmt.Sprintf("schema/v%d.json", version))
+}
+
+// SchemaFields returns the sorted top-level fields of the events of a message
+// version, which relays keep when dropping or nesting the fields they don't
+// know
+func SchemaFields(version int) ([]string, error) {
+	schema, err := loadSchema(version)
+	if err != nil {
+		return nil, err
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	return slices.Sorted(maps.Keys(properties)), nil
+}
+
+// ValidateEvent checks a JSON event against the schema of its @version,
+// returning an error naming every violation. Integration tests validate what
+// reached a receiver with it, and StrictSchema every event as it is logged.
+func ValidateEvent(event []byte) error {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(event))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		return errors.New("event is not a JSON object")
+	}
+	number, _ := object["@version"].(json.Number)
+	version, err := number.Int64()
+	if err != nil {
+		return fmt.Errorf("invalid @version %v", object["@version"])
+	}
+
+	schema, err := loadSchema(int(version))
+	if err != nil {
+		return err
+	}
+	var violations []string
+	validateSchema(schema, value, "", &violations)
+	if len(violations) > 0 {
+		return fmt.Errorf("event violates the schema of version %d: %s", version, strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// loadSchema returns the decoded schema of a message version
+func loadSchema(version int) (map[string]any, error) {
+	if schema, ok := parsedSchemas.Load(version); ok {
+		return schema.(map[string]any), nil
+	}
+	data, err := Schema(version)
+	if err != nil {
+		return nil, err
+	}
+	var schema map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, fmt.Errorf("schema of version %d: %w", version, err)
+	}
+	parsedSchemas.Store(version, schema)
+	return schema, nil
+}
+
+// validateSchema appends the violations of value against schema, at the
+// JSON path path, to violations. It supports the keywords the published
+// schemas use: type, const, required, properties, additionalProperties,
+// items, minLength and format date-time.
+func validateSchema(schema map[string]any, value any, path string, violations *[]string) {
+	at := violationPath(path)
+	if want, ok := schema["type"].(string); ok && !hasSchemaType(value, want) {
+		*violations = append(*violations, fmt.Sprintf("%s: want %s, got %s", at, want, schemaType(value)))
+		return
+	}
+	if want, ok := schema["const"]; ok && fmt.Sprint(want) != fmt.Sprint(value) {
+		*violations = append(*violations, fmt.Sprintf("%s: want %v, got %v", at, want, value))
+	}
+
+	switch v := value.(type) {
+	case string:
+		if n, ok := schema["minLength"].(json.Number); ok {
+			if minLength, _ := n.Int64(); int64(len(v)) < minLength {
+				*violations = append(*violations, fmt.Sprintf("%s: shorter than %d", at, minLength))
+			}
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				*violations = append(*violations, fmt.Sprintf("%s: %q is not a date-time", at, v))
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	case map[string]any:
+		required, _ := schema["required"].([]any)
+		for _, key := range required {
+			if _, ok := v[key.(string)]; !ok {
+				*violations = append(*violations, fmt.Sprintf("%s: missing %s", at, key))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			child := path + "." + key
+			if property, ok := properties[key].(map[string]any); ok {
+				validateSchema(property, v[key], child, violations)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					*violations = append(*violations, fmt.Sprintf("%s: unexpected field", child[1:]))
+				}
+			case map[string]any:
+				validateSchema(additional, v[key], child, violations)
+			}
+		}
+	}
+}
+
+// violationPath returns the path of a value in a violation, "event" for the
+// root
+func violationPath(path string) string {
+	if len(path) == 0 {
+		return "event"
+	}
+	return path[1:]
+}
+
+// hasSchemaType reports whether value is of the JSON Schema type want
+func hasSchemaType(value any, want string) bool {
+	got := schemaType(value)
+	if want == "number" && got == "integer" {
+		return true
+	}
+	return got == want
+}
+
+// schemaType returns the JSON Schema type of a decoded value
+func schemaType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if strings.ContainsAny(string(v), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,236 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// schemaDocument generates the JSON Schema of a message version from the
+// fields the handler writes
+func schemaDocument(version int) orderedObject {
+	str := func(description string) orderedObject {
+		return orderedObject{{"type", "string"}, {"description", description}}
+	}
+	integer := func(description string) orderedObject {
+		return orderedObject{{"type", "integer"}, {"description", description}}
+	}
+	object := func(description string, properties orderedObject) orderedObject {
+		o := orderedObject{{"type", "object"}, {"description", description}}
+		if len(properties) > 0 {
+			o = append(o, objectField{"properties", properties})
+		}
+		return o
+	}
+	source := orderedObject{
+		{"file", str("Path of the source file")},
+		{"line", integer("Line in the source file")},
+	}
+
+	extra := object("Extra data of the event, as in Monolog", nil)
+	properties := orderedObject{
+		{"@timestamp", orderedObject{{"type", "string"}, {"format", "date-time"}, {"description", "Time of the event, RFC 3339 with nanoseconds"}}},
+		{"level", orderedObject{{"type", "string"}, {"minLength", 1}, {"description", "Level name, e.g. INFO or DEBUG+2"}}},
+		{"message", str("The log message")},
+		{"@version", orderedObject{{"type", "integer"}, {"const", version}, {"description", "Message version of the wire format"}}},
+		{"application", str("Name of the application")},
+		{"channel", str("Log channel, LagoonLogs by default")},
+		{"host", str("Host name of the sender")},
+		{"type", orderedObject{{"type", "string"}, {"minLength", 1}, {"description", "Log type, usually <project>-<environment>"}}},
+		{senderKey, str("Identity of the sending process")},
+		{schemaKey, str("Module version of the emitter, e.g. go-lagoon-log-forwarder@v1.4.0")},
+		{"context", object("Context data of the event, as in Monolog", nil)},
+		{"lagoon", object("Metadata of the Lagoon environment", orderedObject{
+			{"project", str("Lagoon project")},
+			{"environment", str("Lagoon environment")},
+			{"branch", str("Git branch")},
+			{"environment_type", str("production or development")},
+		})},
+		{ownerKey, str("Team owning the code that logged the event")},
+		{FieldTraceID, str("Trace of the logging call")},
+		{FieldSpanID, str("Span of the logging call")},
+		{"clock", object("Clock of the sender", orderedObject{
+			{"skew_ms", integer("Offset of the local clock in milliseconds")},
+		})},
+		{truncatedKey, integer("Attributes dropped by MaxAttrs")},
+	}
+	if version >= 2 {
+		extra = append(extra, objectField{"properties", orderedObject{
+			{"source", object("Caller that logged the event", source)},
+		}})
+	} else {
+		properties = append(properties, objectField{"source", object("Caller that logged the event", append(orderedObject{
+			{"function", str("Fully qualified function name")},
+		}, source...))})
+	}
+	properties = append(properties, objectField{"extra", extra})
+
+	return orderedObject{
+		{"$schema", "https://json-schema.org/draft/2020-12/schema"},
+		{"$id", fmt.Sprintf("https://github.com/salsadigitalauorg/go-lagoon-log-forwarder/schema/v%d.json", version)},
+		{"title", fmt.Sprintf("Lagoon log event, message version %d", version)},
+		{"description", "An event written by go-lagoon-log-forwarder. Attributes of the application are further fields."},
+		{"type", "object"},
+		{"required", []string{"@timestamp", "level", "message", "@version", "application", "channel", "host", "type"}},
+		{"properties", properties},
+		{"additionalProperties", true},
+	}
+}
+
+// TestSchema checks the embedded schemas are the generated ones. After
+// changing the fields, rewrite them with
+//
+//	go test -run TestSchema -update .
+func TestSchema(t *testing.T) {
+	for _, version := range SchemaVersions() {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			generated, err := json.MarshalIndent(schemaDocument(version), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			generated = append(generated, '\n')
+
+			path := filepath.Join("schema", fmt.Sprintf("v%d.json", version))
+			if *update {
+				if err := os.WriteFile(path, generated, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				t.Skip("embedded schemas are only read after rebuilding")
+			}
+
+			embedded, err := Schema(version)
+			if err != nil {
+				t.Fatalf("Schema(%d) returned unexpected error: %v", version, err)
+			}
+			if !bytes.Equal(embedded, generated) {
+				t.Errorf("%s is outdated, run with -update to generate it", path)
+			}
+		})
+	}
+
+	if _, err := Schema(99); err == nil {
+		t.Error("Schema(99) returned no error")
+	}
+}
+
+func TestSchemaFields(t *testing.T) {
+	fields, err := SchemaFields(3)
+	if err != nil {
+		t.Fatalf("SchemaFields(3) returned unexpected error: %v", err)
+	}
+	for _, want := range []string{"@timestamp", "message", "type", senderKey, schemaKey, "extra"} {
+		if !slices.Contains(fields, want) {
+			t.Errorf("SchemaFields(3) = %v, want %s", fields, want)
+		}
+	}
+	if !slices.IsSorted(fields) {
+		t.Errorf("SchemaFields(3) = %v, want them sorted", fields)
+	}
+	if slices.Contains(fields, "source") {
+		t.Errorf("SchemaFields(3) = %v, want source only in extra", fields)
+	}
+
+	if _, err := SchemaFields(99); err == nil {
+		t.Error("SchemaFields(99) returned no error")
+	}
+}
+
+func TestValidateEvent_Golden(t *testing.T) {
+	for _, version := range goldenVersions {
+		files, err := filepath.Glob(filepath.Join("testdata", "golden", fmt.Sprintf("v%d", version), "*.json"))
+		if err != nil || len(files) == 0 {
+			t.Fatalf("no golden files of version %d", version)
+		}
+		for _, file := range files {
+			event, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ValidateEvent(event); err != nil {
+				t.Errorf("%s: %v", file, err)
+			}
+		}
+	}
+}
+
+func TestValidateEvent(t *testing.T) {
+	valid := `"@timestamp":"2024-03-01T12:30:45Z","level":"INFO","message":"hi","application":"","channel":"LagoonLogs","host":"web","type":"project-main"`
+	tests := []struct {
+		name      string
+		event     string
+		violation string
+	}{
+		{"valid", `{` + valid + `,"@version":3,"extra":{"source":{"file":"main.go","line":7}}}`, ""},
+		{"not json", `{`, "invalid JSON"},
+		{"not an object", `[1]`, "not a JSON object"},
+		{"unknown version", `{` + valid + `,"@version":9}`, "no schema"},
+		{"missing field", `{"@version":3,"level":"INFO"}`, "missing message"},
+		{"wrong type", `{` + valid + `,"@version":3,"context":"text"}`, "context: want object, got string"},
+		{"invalid timestamp", strings.Replace(`{`+valid+`,"@version":3}`, "2024-03-01T12:30:45Z", "yesterday", 1), "not a date-time"},
+		{"empty type", strings.Replace(`{`+valid+`,"@version":3}`, "project-main", "", 1), "type: shorter than 1"},
+		{"source by version", `{` + valid + `,"@version":1,"source":{"function":"main.main","file":"main.go","line":"7"}}`, "source.line: want integer"},
+		{"nested in version 2", `{` + valid + `,"@version":2,"extra":{"source":{"line":1.5}}}`, "extra.source.line: want integer, got number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEvent([]byte(tt.event))
+			switch {
+			case len(tt.violation) == 0 && err != nil:
+				t.Errorf("ValidateEvent() returned unexpected error: %v", err)
+			case len(tt.violation) > 0 && (err == nil || !strings.Contains(err.Error(), tt.violation)):
+				t.Errorf("ValidateEvent() = %v, want %q", err, tt.violation)
+			}
+		})
+	}
+}
+
+func TestHandler_StrictSchema(t *testing.T) {
+	preserveConfig(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "strict-type"
+	cfg.LogHost = "localhost"
+	cfg.MessageVersion = 3
+	cfg.StrictSchema = true
+	var diagnostics bytes.Buffer
+	cfg.Diagnostics = slog.NewJSONHandler(&diagnostics, nil)
+
+	var buf bytes.Buffer
+	h, err := NewWriterHandler(cfg, &buf)
+	if err != nil {
+		t.Fatalf("NewWriterHandler() returned unexpected error: %v", err)
+	}
+	logger := slog.New(h)
+
+	logger.Info("valid", "seq", 1)
+	if buf.Len() == 0 {
+		t.Fatal("a valid event was not written")
+	}
+
+	buf.Reset()
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "invalid", 0)
+	r.AddAttrs(slog.String("context", "not a group"))
+	if err := h.Handle(context.Background(), r); err == nil {
+		t.Error("Handle() returned no error for an event violating the schema")
+	}
+	if buf.Len() > 0 {
+		t.Errorf("an invalid event was written: %s", buf.String())
+	}
+	if !strings.Contains(diagnostics.String(), "context: want object") {
+		t.Errorf("diagnostics = %s, want the violation", diagnostics.String())
+	}
+
+	cfg.MessageVersion = 7
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() returned no error for strictSchema without a schema")
+	}
+}
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// senderKey is the attribute identifying the process that sent an event
+const senderKey = "sender_id"
+
+// resolveSender returns the sender ID of the applied config, read from or
+// persisted to SenderIDFile unless SenderID is set, "" without either
+func resolveSender() string {
+	if len(senderID) > 0 || len(senderIDFile) == 0 {
+		return senderID
+	}
+
+	id, err := loadSenderID(senderIDFile)
+	if err != nil {
+		diag().Warn("Failed to persist the sender ID, it changes on restart", "path", senderIDFile, "error", err)
+	}
+	return id
+}
+
+// loadSenderID returns the sender ID persisted in path, generating and
+// persisting one when there is none yet. The generated ID is returned even
+// when persisting it failed.
+func loadSenderID(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if id := strings.TrimSpace(string(data)); len(id) > 0 {
+			return id, nil
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return newSenderID(), err
+	}
+
+	id := newSenderID()
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return id, err
+	}
+	return id, os.WriteFile(path, []byte(id+"\n"), 0o600)
+}
+
+// newSenderID returns a random sender ID
+func newSenderID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadSenderID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "sender-id")
+
+	id, err := loadSenderID(path)
+	if err != nil {
+		t.Fatalf("loadSenderID() returned unexpected error: %v", err)
+	}
+	if len(id) != 32 {
+		t.Errorf("generated ID = %q, want 32 hex digits", id)
+	}
+
+	again, err := loadSenderID(path)
+	if err != nil {
+		t.Fatalf("loadSenderID() returned unexpected error: %v", err)
+	}
+	if again != id {
+		t.Errorf("loadSenderID() = %q after a restart, want the persisted %q", again, id)
+	}
+
+	if err := os.WriteFile(path, []byte("pod-1234\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := loadSenderID(path); got != "pod-1234" {
+		t.Errorf("loadSenderID() = %q, want the ID in the file", got)
+	}
+}
+
+func TestLoadSenderID_Unwritable(t *testing.T) {
+	// a file where the directory should be can't be created
+	dir := t.TempDir()
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.WriteFile(blocked, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := loadSenderID(filepath.Join(blocked, "sender-id"))
+	if err == nil {
+		t.Error("loadSenderID() should fail when the ID can't be persisted")
+	}
+	if len(id) == 0 {
+		t.Error("loadSenderID() should still return a generated ID")
+	}
+}
+
+func TestHandler_SenderID(t *testing.T) {
+	preserveConfig(t)
+	path := filepath.Join(t.TempDir(), "sender-id")
+
+	senderOf := func(cfg Config) any {
+		t.Helper()
+		var buf strings.Builder
+		h, err := NewWriterHandler(cfg, &buf)
+		if err != nil {
+			t.Fatalf("NewWriterHandler() returned unexpected error: %v", err)
+		}
+		slog.New(h).Info("hello")
+
+		var event map[string]any
+		if err := json.Unmarshal([]byte(buf.String()), &event); err != nil {
+			t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+		}
+		return event[senderKey]
+	}
+
+	cfg := NewConfig()
+	cfg.LogType = "sender-type"
+	if got := senderOf(cfg); got != nil {
+		t.Errorf("sender_id = %v without a sender ID, want none", got)
+	}
+
+	cfg.SenderID = "0b6c2f1e-pod-uid"
+	if got := senderOf(cfg); got != "0b6c2f1e-pod-uid" {
+		t.Errorf("sender_id = %v, want the configured ID", got)
+	}
+
+	cfg.SenderID = ""
+	cfg.SenderIDFile = path
+	first := senderOf(cfg)
+	if first == nil {
+		t.Fatal("sender_id missing with a sender ID file")
+	}
+	if got := senderOf(cfg); got != first {
+		t.Errorf("sender_id = %v after a restart, want the persisted %v", got, first)
+	}
+}
@@ -0,0 +1,31 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+)
+
+// toggleDebug switches between debug records and the configured level every
+// time sig is received, until ctx is done
+func toggleDebug(ctx context.Context, sig os.Signal) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sig)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if levelSet.Load() && levelVar.Level() == slog.LevelDebug {
+				ResetLevel()
+				diag().Info("Debug logging disabled by signal", "signal", sig.String())
+				continue
+			}
+			SetLevel(slog.LevelDebug)
+			diag().Info("Debug logging enabled by signal", "signal", sig.String())
+		}
+	}
+}
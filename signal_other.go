@@ -0,0 +1,9 @@
+//go:build !unix
+
+package logger
+
+import "os"
+
+// debugSignals are the signals Config.DebugSignal can name, none outside
+// unix
+var debugSignals = map[string]os.Signal{}
@@ -0,0 +1,42 @@
+//go:build unix
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestToggleDebug(t *testing.T) {
+	t.Cleanup(ResetLevel)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		toggleDebug(ctx, syscall.SIGUSR1)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	// let toggleDebug register for the signal
+	time.Sleep(20 * time.Millisecond)
+
+	leveler := overrideLeveler{slog.LevelWarn}
+	for _, want := range []slog.Level{slog.LevelDebug, slog.LevelWarn, slog.LevelDebug} {
+		if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(time.Second)
+		for leveler.Level() != want && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if got := leveler.Level(); got != want {
+			t.Fatalf("Level() = %v after the signal, want %v", got, want)
+		}
+	}
+}
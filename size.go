@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"unicode/utf8"
+)
+
+// Strategies fitting a forwarded event into MaxMessageBytes
+const (
+	// TruncateMessage shortens the message, dropping the attributes too when
+	// they alone exceed the limit
+	TruncateMessage = "truncate"
+	// TruncateAttrs drops the attributes of the record, shortening the
+	// message too when it alone exceeds the limit
+	TruncateAttrs = "drop-attrs"
+	// TruncateSplit splits the message over several events carrying the same
+	// attributes and numbered by part and parts
+	TruncateSplit = "split"
+)
+
+// sizeMarker is set on every event changed to fit the size limit
+const sizeMarker = "truncated"
+
+// oversized holds the parts of a record that is rebuilt to fit a limit
+type oversized struct {
+	record slog.Record
+	base   []slog.Attr
+	attrs  []slog.Attr
+}
+
+// build returns the record with msg, the attributes kept and extra ones
+func (o oversized) build(msg string, attrs []slog.Attr, extra ...slog.Attr) slog.Record {
+	r := slog.NewRecord(o.record.Time, o.record.Level, msg, o.record.PC)
+	r.AddAttrs(o.base...)
+	r.AddAttrs(attrs...)
+	r.AddAttrs(extra...)
+	return r
+}
+
+// fit returns the events of an oversized record encoded in format, fitted
+// into maxBytes with the handler's truncation strategy. An event that can't
+// fit even without its message and attributes is returned as small as it
+// gets.
+func (h *handler) fit(ctx context.Context, format string, maxBytes int, o oversized) ([][]byte, error) {
+	marker := slog.Bool(sizeMarker, true)
+	size := func(msg string, attrs []slog.Attr, extra ...slog.Attr) (int, error) {
+		b, err := h.encodeRecord(ctx, format, o.build(msg, attrs, extra...))
+		return len(b), err
+	}
+
+	msg, attrs := o.record.Message, o.attrs
+	var err error
+	switch h.truncation {
+	case TruncateSplit:
+		return h.split(ctx, format, maxBytes, o)
+	case TruncateAttrs:
+		attrs = nil
+		msg, err = longestFit(msg, maxBytes, func(m string) (int, error) { return size(m, attrs, marker) })
+	default:
+		if n, serr := size("", attrs, marker); serr != nil {
+			return nil, serr
+		} else if n > maxBytes {
+			attrs = nil
+		}
+		msg, err = longestFit(msg, maxBytes, func(m string) (int, error) { return size(m, attrs, marker) })
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := h.encodeRecord(ctx, format, o.build(msg, attrs, marker))
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{b}, nil
+}
+
+// split returns the message of o spread over as many events as it takes to
+// fit each into maxBytes
+func (h *handler) split(ctx context.Context, format string, maxBytes int, o oversized) ([][]byte, error) {
+	attrs := o.attrs
+	// the part numbers are sized for the most parts a message can take
+	bound := max(len(o.record.Message), 1)
+	numbers := func(part, parts int) []slog.Attr {
+		return []slog.Attr{slog.Bool(sizeMarker, true), slog.Int("part", part), slog.Int("parts", parts)}
+	}
+	size := func(m string) (int, error) {
+		b, err := h.encodeRecord(ctx, format, o.build(m, attrs, numbers(bound, bound)...))
+		return len(b), err
+	}
+
+	if n, err := size(""); err != nil {
+		return nil, err
+	} else if n > maxBytes {
+		attrs = nil
+	}
+
+	var chunks []string
+	for rest := o.record.Message; len(rest) > 0; {
+		chunk, err := longestFit(rest, maxBytes, size)
+		if err != nil {
+			return nil, err
+		}
+		if len(chunk) == 0 {
+			// not even a single rune fits, it goes into an event of its own
+			_, width := utf8.DecodeRuneInString(rest)
+			chunk = rest[:max(width, 1)]
+		}
+		chunks = append(chunks, chunk)
+		rest = rest[len(chunk):]
+	}
+
+	if len(chunks) == 0 {
+		chunks = []string{""}
+	}
+
+	events := make([][]byte, 0, len(chunks))
+	for i, chunk := range chunks {
+		b, err := h.encodeRecord(ctx, format, o.build(chunk, attrs, numbers(i+1, len(chunks))...))
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, b)
+	}
+	return events, nil
+}
+
+// longestFit returns the longest prefix of msg, cut at a rune boundary,
+// whose event size stays within maxBytes
+func longestFit(msg string, maxBytes int, size func(string) (int, error)) (string, error) {
+	var err error
+	n := sort.Search(len(msg)+1, func(i int) bool {
+		if err != nil {
+			return true
+		}
+		var s int
+		s, err = size(cutRunes(msg, i))
+		return s > maxBytes
+	})
+	if err != nil {
+		return "", err
+	}
+	return cutRunes(msg, max(n-1, 0)), nil
+}
+
+// cutRunes returns the prefix of s up to n bytes without splitting a rune
+func cutRunes(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// encodeRecord returns a copy of r encoded in format
+func (h *handler) encodeRecord(ctx context.Context, format string, r slog.Record) ([]byte, error) {
+	e := h.encoders[format].Get().(*encoder)
+	defer h.encoders[format].Put(e)
+
+	e.buf.Reset()
+	if err := e.handler.Handle(ctx, r); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), e.buf.Bytes()...), nil
+}
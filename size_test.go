@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_MaxMessageBytes(t *testing.T) {
+	const maxBytes = 200
+	long := strings.Repeat("é", 150)
+
+	tests := []struct {
+		name       string
+		truncation string
+		msg        string
+		attrs      []any
+		check      func(t *testing.T, events []map[string]any)
+	}{
+		{"truncate message", TruncateMessage, long, []any{"k", "v"}, func(t *testing.T, events []map[string]any) {
+			msg := events[0]["msg"].(string)
+			if len(events) != 1 || !strings.HasPrefix(long, msg) || len(msg) == 0 || events[0]["k"] != "v" {
+				t.Errorf("events = %v, want the message shortened and the attributes kept", events)
+			}
+		}},
+		{"truncate large attrs", TruncateMessage, "short", []any{"k", strings.Repeat("v", 300)}, func(t *testing.T, events []map[string]any) {
+			if len(events) != 1 || events[0]["msg"] != "short" || events[0]["k"] != nil {
+				t.Errorf("events = %v, want the attributes dropped", events)
+			}
+		}},
+		{"drop attrs", TruncateAttrs, "short", []any{"k", strings.Repeat("v", 300)}, func(t *testing.T, events []map[string]any) {
+			if len(events) != 1 || events[0]["msg"] != "short" || events[0]["k"] != nil {
+				t.Errorf("events = %v, want the attributes dropped", events)
+			}
+		}},
+		{"split", TruncateSplit, long, []any{"k", "v"}, func(t *testing.T, events []map[string]any) {
+			var joined string
+			for i, event := range events {
+				joined += event["msg"].(string)
+				if event["part"] != float64(i+1) || event["parts"] != float64(len(events)) || event["k"] != "v" {
+					t.Errorf("event %d = %v, want it numbered with the attributes", i, event)
+				}
+			}
+			if len(events) < 2 || joined != long {
+				t.Errorf("split into %d events joining to %q, want the whole message", len(events), joined)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, forwarded bytes.Buffer
+			h := newJSONHandler([]sink{
+				{w: &stdout},
+				{w: &forwarded, maxBytes: maxBytes},
+			}, &slog.HandlerOptions{ReplaceAttr: dropTime}, []any{slog.String("type", "t")})
+			h.truncation = tt.truncation
+			slog.New(h).Info(tt.msg, tt.attrs...)
+
+			if !strings.Contains(stdout.String(), tt.msg) {
+				t.Errorf("stdout = %s, want the record unchanged", stdout.String())
+			}
+
+			var events []map[string]any
+			for _, line := range strings.Split(strings.TrimSpace(forwarded.String()), "\n") {
+				if len(line)+1 > maxBytes {
+					t.Errorf("event of %d bytes exceeds %d: %s", len(line)+1, maxBytes, line)
+				}
+				var event map[string]any
+				if err := json.Unmarshal([]byte(line), &event); err != nil {
+					t.Fatalf("invalid JSON %q: %v", line, err)
+				}
+				if event[sizeMarker] != true || event["type"] != "t" {
+					t.Errorf("event = %v, want the marker and static fields", event)
+				}
+				events = append(events, event)
+			}
+			tt.check(t, events)
+		})
+	}
+}
+
+func TestHandler_MaxMessageBytesKeepsSmallEvents(t *testing.T) {
+	var forwarded bytes.Buffer
+	h := newJSONHandler([]sink{{w: &forwarded, maxBytes: 200}}, &slog.HandlerOptions{ReplaceAttr: dropTime}, nil)
+	slog.New(h).Info("small")
+
+	if want := `{"level":"INFO","msg":"small"}` + "\n"; forwarded.String() != want {
+		t.Errorf("forwarded = %q, want %q", forwarded.String(), want)
+	}
+}
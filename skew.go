@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// skewProbeTimeout bounds a single clock skew measurement
+const skewProbeTimeout = 10 * time.Second
+
+// skewMeasurement is an offset recorded by a skew probe
+type skewMeasurement struct {
+	offset time.Duration
+}
+
+// measuredSkew is the last measurement, nil before the first one
+var measuredSkew atomic.Pointer[skewMeasurement]
+
+// clockSkew returns the last measured offset of the local clock from the
+// reference clock, positive when the local clock is ahead
+func clockSkew() (time.Duration, bool) {
+	m := measuredSkew.Load()
+	if m == nil {
+		return 0, false
+	}
+	return m.offset, true
+}
+
+// resetSkew forgets the measured offset
+func resetSkew() {
+	measuredSkew.Store(nil)
+}
+
+// measureSkew estimates the local clock offset NTP style from the Date
+// header of a HEAD request to url. The server sampled its clock somewhere
+// between sending the request and receiving the response, so the midpoint of
+// the round trip is compared with the middle of the header's second.
+func measureSkew(ctx context.Context, client *http.Client, url string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, skewProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	received := time.Now()
+	_ = resp.Body.Close()
+
+	reference, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, err
+	}
+
+	local := sent.Add(received.Sub(sent) / 2)
+	return local.Sub(reference.Add(500 * time.Millisecond)), nil
+}
+
+// probeSkew measures the clock skew every interval until ctx is cancelled.
+// It then forgets its last measurement, so events logged after the probe
+// stopped don't carry a stale estimate, unless a probe started by a later
+// Initialize has replaced it already.
+func probeSkew(ctx context.Context, url string, interval time.Duration) {
+	client := newBackgroundClient()
+	defer client.CloseIdleConnections()
+
+	var last *skewMeasurement
+	defer func() {
+		if last != nil {
+			measuredSkew.CompareAndSwap(last, nil)
+		}
+	}()
+
+	for {
+		if skew, err := measureSkew(ctx, client, url); err == nil {
+			last = &skewMeasurement{offset: skew}
+			measuredSkew.Store(last)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
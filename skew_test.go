@@ -0,0 +1,176 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMeasureSkew(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset time.Duration
+	}{
+		{"in sync", 0},
+		{"local behind", time.Hour},
+		{"local ahead", -90 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Date", time.Now().Add(tt.offset).UTC().Format(http.TimeFormat))
+			}))
+			defer server.Close()
+
+			skew, err := measureSkew(context.Background(), server.Client(), server.URL)
+			if err != nil {
+				t.Fatalf("measureSkew() returned unexpected error: %v", err)
+			}
+
+			// the Date header only has second resolution
+			want := -tt.offset
+			if diff := skew - want; diff < -time.Second || diff > time.Second {
+				t.Errorf("measureSkew() = %v, want %v ± 1s", skew, want)
+			}
+		})
+	}
+}
+
+func TestMeasureSkew_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+	}))
+	defer server.Close()
+
+	if _, err := measureSkew(context.Background(), server.Client(), server.URL); err == nil {
+		t.Error("measureSkew() should return error without a Date header")
+	}
+	if _, err := measureSkew(context.Background(), server.Client(), "http://127.0.0.1:0"); err == nil {
+		t.Error("measureSkew() should return error for an unreachable URL")
+	}
+}
+
+func TestProbeSkew(t *testing.T) {
+	defer resetSkew()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	goBackground(func(ctx context.Context) { probeSkew(ctx, server.URL, time.Hour) })
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if skew, ok := clockSkew(); ok {
+			if skew > -59*time.Minute {
+				t.Errorf("clockSkew() = %v, want about -1h", skew)
+			}
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := clockSkew(); !ok {
+		t.Error("probeSkew() did not record a measurement")
+	}
+
+	// stopBackground must terminate the probe while it waits for the interval
+	stopped := make(chan struct{})
+	go func() {
+		stopBackground()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stopBackground() did not stop the skew probe")
+	}
+}
+
+// skewServer returns a server whose Date header is offset from the local clock
+func skewServer(t *testing.T, offset time.Duration) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// waitSkew waits for a measured skew close to want
+func waitSkew(t *testing.T, want time.Duration) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if skew, ok := clockSkew(); ok && skew > want-time.Minute && skew < want+time.Minute {
+			return
+		}
+	}
+	t.Fatalf("clockSkew() did not measure about %v", want)
+}
+
+func TestShutdown_ResetsSkew(t *testing.T) {
+	defer resetSkew()
+
+	server := skewServer(t, time.Hour)
+	goBackground(func(ctx context.Context) { probeSkew(ctx, server.URL, time.Hour) })
+	waitSkew(t, -time.Hour)
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() returned unexpected error: %v", err)
+	}
+	if skew, ok := clockSkew(); ok {
+		t.Errorf("clockSkew() after Shutdown() = %v, want no measurement", skew)
+	}
+}
+
+func TestShutdown_TimedOutKeepsNextGeneration(t *testing.T) {
+	defer resetSkew()
+
+	// a task of the first generation outlives its Shutdown
+	release := make(chan struct{})
+	released := make(chan struct{})
+	goBackground(func(ctx context.Context) {
+		defer close(released)
+		<-ctx.Done()
+		<-release
+	})
+	first := skewServer(t, time.Hour)
+	goBackground(func(ctx context.Context) { probeSkew(ctx, first.URL, time.Hour) })
+	waitSkew(t, -time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := Shutdown(ctx); err == nil {
+		t.Fatal("Shutdown() should time out while a background task runs")
+	}
+
+	// the next generation starts before the first one has stopped
+	second := skewServer(t, -time.Hour)
+	goBackground(func(ctx context.Context) { probeSkew(ctx, second.URL, time.Hour) })
+	var stopped atomic.Bool
+	goBackground(func(ctx context.Context) {
+		<-ctx.Done()
+		stopped.Store(true)
+	})
+	waitSkew(t, time.Hour)
+
+	close(release)
+	<-released
+	// give the timed out Shutdown time to finish
+	time.Sleep(50 * time.Millisecond)
+
+	if skew, ok := clockSkew(); !ok || skew < 59*time.Minute {
+		t.Errorf("clockSkew() after the previous Shutdown finished = %v, %v, want about 1h", skew, ok)
+	}
+	if stopped.Load() {
+		t.Error("the previous Shutdown stopped a task of the next generation")
+	}
+	stopBackground()
+	if !stopped.Load() {
+		t.Error("stopBackground() did not wait for the next generation")
+	}
+}
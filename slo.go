@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// SLO is a latency and status budget for the requests of a route. Access
+// records of matching requests carry slo.target and slo.violated so SLO
+// dashboards can be built in Kibana.
+type SLO struct {
+	// Target names the objective in slo.target, defaulting to the route
+	Target string
+	// Latency is the slowest acceptable response, 0 accepts any
+	Latency time.Duration
+	// MaxStatus is the highest acceptable status code, 0 accepts anything
+	// below 500
+	MaxStatus int
+}
+
+// routeSLO is an SLO applying to the requests matching pattern
+type routeSLO struct {
+	pattern string
+	SLO
+}
+
+// WithSLO applies slo to the requests whose path matches pattern, either an
+// exact path or a prefix such as "/api/*". The first matching SLO applies.
+func WithSLO(pattern string, slo SLO) MiddlewareOption {
+	if len(slo.Target) == 0 {
+		slo.Target = pattern
+	}
+	return func(m *middleware) { m.slos = append(m.slos, routeSLO{pattern: pattern, SLO: slo}) }
+}
+
+// slo returns the SLO applying to path
+func (m *middleware) slo(path string) (SLO, bool) {
+	for _, s := range m.slos {
+		if matchRoute(s.pattern, path) {
+			return s.SLO, true
+		}
+	}
+	return SLO{}, false
+}
+
+// evaluate returns the slo group of a request that took elapsed and was
+// answered with status, naming the budget that was exceeded
+func (s SLO) evaluate(status int, elapsed time.Duration) slog.Attr {
+	maxStatus := s.MaxStatus
+	if maxStatus == 0 {
+		maxStatus = http.StatusInternalServerError - 1
+	}
+
+	attrs := []any{slog.String("target", s.Target)}
+	switch {
+	case status > maxStatus:
+		attrs = append(attrs, slog.Bool("violated", true), slog.String("reason", "status"))
+	case s.Latency > 0 && elapsed > s.Latency:
+		attrs = append(attrs, slog.Bool("violated", true), slog.String("reason", "latency"))
+	default:
+		attrs = append(attrs, slog.Bool("violated", false))
+	}
+
+	return slog.Group("slo", attrs...)
+}
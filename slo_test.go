@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSLO_Evaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		slo      SLO
+		status   int
+		elapsed  time.Duration
+		violated bool
+		reason   string
+	}{
+		{"within budget", SLO{Latency: time.Second}, 200, 10 * time.Millisecond, false, ""},
+		{"too slow", SLO{Latency: time.Second}, 200, 2 * time.Second, true, "latency"},
+		{"server error", SLO{}, 503, 0, true, "status"},
+		{"client error accepted", SLO{}, 404, 0, false, ""},
+		{"client error rejected", SLO{MaxStatus: 399}, 404, 0, true, "status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attr := tt.slo.evaluate(tt.status, tt.elapsed)
+			fields := map[string]any{}
+			for _, a := range attr.Value.Group() {
+				fields[a.Key] = a.Value.Any()
+			}
+
+			if attr.Key != "slo" {
+				t.Errorf("evaluate() key = %q, want slo", attr.Key)
+			}
+			if fields["violated"] != tt.violated {
+				t.Errorf("violated = %v, want %v", fields["violated"], tt.violated)
+			}
+			if reason, _ := fields["reason"].(string); reason != tt.reason {
+				t.Errorf("reason = %q, want %q", reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestHTTPMiddleware_SLO(t *testing.T) {
+	opts := []MiddlewareOption{
+		WithSLO("/api/checkout", SLO{Target: "checkout", Latency: time.Hour}),
+		WithSLO("/api/*", SLO{Latency: time.Hour}),
+	}
+
+	tests := []struct {
+		path     string
+		status   int
+		target   any
+		violated any
+	}{
+		{"/api/checkout", http.StatusOK, "checkout", false},
+		{"/api/users", http.StatusInternalServerError, "/api/*", true},
+		{"/", http.StatusOK, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			record := serve(t, statusHandler(tt.status), tt.path, opts...)
+			slo, _ := record["slo"].(map[string]any)
+
+			if slo["target"] != tt.target {
+				t.Errorf("slo.target = %v, want %v", slo["target"], tt.target)
+			}
+			if slo["violated"] != tt.violated {
+				t.Errorf("slo.violated = %v, want %v", slo["violated"], tt.violated)
+			}
+		})
+	}
+}
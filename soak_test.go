@@ -0,0 +1,123 @@
+//go:build soak
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+// The soak test runs for LAGOON_SOAK_DURATION (default 2m):
+//
+//	go test -tags soak -run Soak -timeout 30m .
+const (
+	soakRate      = 500 // events per second
+	soakFlapEvery = 10 * time.Second
+	soakFlapFor   = time.Second
+	// soakLossBudget is the share of events sent while the receiver was up
+	// that may still be lost
+	soakLossBudget = 0.01
+)
+
+func soakDuration(t *testing.T) time.Duration {
+	value := os.Getenv("LAGOON_SOAK_DURATION")
+	if len(value) == 0 {
+		return 2 * time.Minute
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		t.Fatalf("invalid LAGOON_SOAK_DURATION %q: %v", value, err)
+	}
+	return d
+}
+
+func TestSoak_UDP(t *testing.T) {
+	preserveConfig(t)
+
+	receiver, err := loggertest.Listen(loggertest.UDP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer receiver.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "soak"
+	cfg.LogHost = receiver.Host()
+	cfg.LogPort = receiver.Port()
+	cfg.DeliveryWorkers = 4
+	if err := config(cfg); err != nil {
+		t.Fatalf("config() returned unexpected error: %v", err)
+	}
+
+	// deliver through the pool only, stdout would drown the test output
+	pool := newOrderedPool(ordering, orderingKey, deliveryPolicy, deliveryWorkers, queueSize, dialForwarder)
+	log := slog.New(newHandler(pool))
+
+	var down atomic.Bool
+	ctx, cancel := context.WithTimeout(context.Background(), soakDuration(t))
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(soakFlapEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				down.Store(true)
+				receiver.SetDown(true)
+				time.Sleep(soakFlapFor)
+				receiver.SetDown(false)
+				down.Store(false)
+			}
+		}
+	}()
+
+	var sent, sentWhileDown int
+	ticker := time.NewTicker(time.Second / soakRate)
+	defer ticker.Stop()
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+			// count before and after, an event racing a flap may go either way
+			wasDown := down.Load()
+			log.Info("soak event", "seq", sent)
+			if wasDown || down.Load() {
+				sentWhileDown++
+			}
+			sent++
+		}
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Close() returned unexpected error: %v", err)
+	}
+	receiver.Wait(sent-sentWhileDown, 5*time.Second)
+
+	unique, duplicates := loggertest.Tally(receiver.Events(), "seq")
+	lost := sent - unique
+	budget := sentWhileDown + int(float64(sent-sentWhileDown)*soakLossBudget)
+
+	t.Logf("sent %d (%d while down), received %d unique, %d duplicates, %d dropped by the pool",
+		sent, sentWhileDown, unique, duplicates, pool.Dropped())
+
+	if lost > budget {
+		t.Errorf("lost %d events, budget is %d", lost, budget)
+	}
+	if duplicates > 0 {
+		t.Errorf("received %d duplicate events, want none over UDP", duplicates)
+	}
+	if receiver.Errors() > 0 {
+		t.Errorf("receiver could not parse %d payloads", receiver.Errors())
+	}
+}
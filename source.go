@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"log/slog"
+	"runtime"
+	"strings"
+)
+
+// Representations of the source attribute added when AddSource is set
+const (
+	// SourceGroup is slog's top-level source object with the function, file
+	// and line of the caller
+	SourceGroup = "group"
+	// SourceFlat writes the caller as top-level file and line fields
+	SourceFlat = "flat"
+	// SourceExtra writes the caller as extra.source.file and
+	// extra.source.line, where Lagoon's pipeline expects it
+	SourceExtra = "extra"
+)
+
+// resolveSourceFormat returns format, or the representation of the message
+// version when it is empty: slog's group up to version 1, extra afterwards
+func resolveSourceFormat(format string, version int) string {
+	if len(format) > 0 {
+		return format
+	}
+	if version >= 2 {
+		return SourceExtra
+	}
+	return SourceGroup
+}
+
+// addSourceAttrs adds the caller at pc to attrs in format, merging it into
+// an existing extra group
+func addSourceAttrs(attrs []slog.Attr, format string, pc uintptr) []slog.Attr {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	file, line := slog.String("file", frame.File), slog.Int("line", frame.Line)
+
+	if format == SourceFlat {
+		return append(attrs, file, line)
+	}
+
+	source := slog.Group("source", file, line)
+	for i, a := range attrs {
+		if a.Key == "extra" && a.Value.Kind() == slog.KindGroup {
+			group := append(append(make([]slog.Attr, 0, len(a.Value.Group())+1), a.Value.Group()...), source)
+			attrs[i] = slog.Attr{Key: a.Key, Value: slog.GroupValue(group...)}
+			return attrs
+		}
+	}
+	return append(attrs, slog.Group("extra", source))
+}
+
+// frameFilter holds the package path prefixes of the frames skipped when
+// finding the caller
+type frameFilter []string
+
+// maxCallerDepth is how far up the stack a caller is looked for
+const maxCallerDepth = 64
+
+// caller returns the pc of the first frame at or above pc that f doesn't
+// skip. The record is handled on the goroutine that logged it, so pc is
+// found in the current stack; otherwise, or when every frame is skipped,
+// pc is returned unchanged.
+func (f frameFilter) caller(pc uintptr) uintptr {
+	var pcs [maxCallerDepth]uintptr
+	n := runtime.Callers(2, pcs[:])
+	for i, p := range pcs[:n] {
+		if p != pc {
+			continue
+		}
+		for _, p := range pcs[i:n] {
+			frame, _ := runtime.CallersFrames([]uintptr{p}).Next()
+			if !f.skip(frame) {
+				return p
+			}
+		}
+		break
+	}
+	return pc
+}
+
+// skip reports whether frame belongs to a package matching a prefix of f
+func (f frameFilter) skip(frame runtime.Frame) bool {
+	pkg := framePackage(frame.Function)
+	for _, prefix := range f {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if prefix == "vendor" && strings.Contains(frame.File, "/vendor/") {
+			return true
+		}
+		if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// framePackage returns the package path of a fully qualified function name
+// such as "github.com/acme/app/db.(*Conn).Query"
+func framePackage(function string) string {
+	slash := strings.LastIndexByte(function, '/') + 1
+	if dot := strings.IndexByte(function[slash:], '.'); dot >= 0 {
+		return function[:slash+dot]
+	}
+	return function
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"encoding/json"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSourceFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		version int
+		check   func(event map[string]any) bool
+	}{
+		{"group", SourceGroup, 3, func(e map[string]any) bool {
+			source, ok := e["source"].(map[string]any)
+			return ok && strings.HasSuffix(source["file"].(string), "source_test.go") && source["function"] != nil
+		}},
+		{"flat", SourceFlat, 1, func(e map[string]any) bool {
+			return strings.HasSuffix(e["file"].(string), "source_test.go") && e["line"].(float64) > 0 && e["source"] == nil
+		}},
+		{"extra", SourceExtra, 1, func(e map[string]any) bool {
+			source := e["extra"].(map[string]any)["source"].(map[string]any)
+			// attributes logged in the extra group are kept
+			return strings.HasSuffix(source["file"].(string), "source_test.go") && e["extra"].(map[string]any)["k"] == "v"
+		}},
+		{"version 1 default", "", 1, func(e map[string]any) bool { return e["source"] != nil }},
+		{"version 3 default", "", 3, func(e map[string]any) bool {
+			_, ok := e["extra"].(map[string]any)["source"]
+			return ok && e["source"] == nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preserveConfig(t)
+
+			cfg := NewConfig()
+			cfg.LogType = "source-type"
+			cfg.SourceFormat = tt.format
+			cfg.MessageVersion = tt.version
+			if err := config(cfg); err != nil {
+				t.Fatalf("config() returned unexpected error: %v", err)
+			}
+
+			var buf strings.Builder
+			slog.New(newHandler(&buf)).WithGroup("extra").Info("hello", "k", "v")
+
+			var event map[string]any
+			if err := json.Unmarshal([]byte(buf.String()), &event); err != nil {
+				t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+			}
+			if !tt.check(event) {
+				t.Errorf("unexpected source representation in %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestSourceSkip(t *testing.T) {
+	preserveConfig(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "source-type"
+	cfg.SourceFormat = SourceFlat
+	// skipping this package leaves the testing package running the test
+	cfg.SourceSkip = []string{"github.com/salsadigitalauorg/go-lagoon-log-forwarder"}
+	if err := config(cfg); err != nil {
+		t.Fatalf("config() returned unexpected error: %v", err)
+	}
+
+	var buf strings.Builder
+	slog.New(newHandler(&buf)).Info("hello")
+
+	var event map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &event); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if file, _ := event["file"].(string); !strings.HasSuffix(file, "testing/testing.go") {
+		t.Errorf("file = %v, want the testing package past the skipped frames", event["file"])
+	}
+}
+
+func TestFrameFilter_Skip(t *testing.T) {
+	filter := frameFilter{"runtime", "github.com/acme/logutil/", "vendor"}
+
+	tests := []struct {
+		frame runtime.Frame
+		want  bool
+	}{
+		{runtime.Frame{Function: "runtime.goexit"}, true},
+		{runtime.Frame{Function: "runtime/debug.Stack"}, true},
+		{runtime.Frame{Function: "github.com/acme/logutil.Info"}, true},
+		{runtime.Frame{Function: "github.com/acme/logutil/http.(*Logger).Serve.func1"}, true},
+		{runtime.Frame{Function: "github.com/acme/logutilx.Info"}, false},
+		{runtime.Frame{Function: "github.com/other/lib.Call", File: "/app/vendor/github.com/other/lib/call.go"}, true},
+		{runtime.Frame{Function: "main.main", File: "/app/main.go"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := filter.skip(tt.frame); got != tt.want {
+			t.Errorf("skip(%s) = %v, want %v", tt.frame.Function, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Files of a spool directory. Records are appended to spoolFile, which is
+// renamed to spoolReplayFile while it is replayed; a replay file left behind
+// holds records a previous replay could not deliver.
+const (
+	spoolFile       = "spool.ndjson"
+	spoolReplayFile = "spool.replay.ndjson"
+)
+
+// spool buffers forwarded records on disk while the endpoint is unreachable,
+// so they are delivered in order once the forwarder connects. Records
+// exceeding maxBytes are dropped. Spooled records survive a restart and are
+// replayed by the next process using the directory.
+type spool struct {
+	dir      string
+	maxBytes int64
+
+	mu          sync.Mutex
+	current     *os.File
+	size        int64 // bytes in the current file
+	replaySize  int64 // bytes in the replay file
+	dropped     atomic.Uint64
+	replayMutex sync.Mutex
+	// sent remembers the records replays delivered, nil without replay
+	// protection
+	sent    *sentRecords
+	skipped atomic.Uint64
+}
+
+// openSpool opens the spool in dir, creating the directory when needed
+func openSpool(dir string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create spool: %w", err)
+	}
+
+	s := &spool{dir: dir, maxBytes: maxBytes}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(filepath.Join(dir, spoolReplayFile)); err == nil {
+		s.replaySize = info.Size()
+	}
+	return s, nil
+}
+
+// protectReplays makes replays skip the records a replay delivered within
+// window, such as those replayed again after a crash. It must be called
+// before records are replayed.
+func (s *spool) protectReplays(window time.Duration) error {
+	sent, err := openSentRecords(s.dir, window)
+	if err != nil {
+		return err
+	}
+	s.sent = sent
+	return nil
+}
+
+// open opens the current file for appending
+func (s *spool) open() error {
+	f, err := os.OpenFile(filepath.Join(s.dir, spoolFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open spool: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("open spool: %w", err)
+	}
+	s.current, s.size = f, info.Size()
+	return nil
+}
+
+// Write appends the record p, dropping it when the spool is full or closed
+func (s *spool) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil || s.size+s.replaySize+int64(len(p)) > s.maxBytes {
+		s.dropped.Add(1)
+		countDropped(SinkForwarder, 1)
+		return len(p), nil
+	}
+
+	n, err := s.current.Write(p)
+	s.size += int64(n)
+	return n, err
+}
+
+// replay writes the spooled records to w, oldest first, including those
+// spooled while it runs, and returns how many were written. Records w fails
+// to take are kept for the next replay.
+func (s *spool) replay(ctx context.Context, w io.Writer) (int, error) {
+	s.replayMutex.Lock()
+	defer s.replayMutex.Unlock()
+
+	replayed := 0
+	for {
+		s.mu.Lock()
+		if s.current == nil {
+			s.mu.Unlock()
+			return replayed, nil
+		}
+		if s.replaySize == 0 {
+			if s.size == 0 {
+				s.mu.Unlock()
+				return replayed, nil
+			}
+			if err := s.rotate(); err != nil {
+				s.mu.Unlock()
+				return replayed, err
+			}
+		}
+		s.mu.Unlock()
+
+		n, err := s.replayFile(ctx, w)
+		replayed += n
+		if err != nil {
+			return replayed, err
+		}
+	}
+}
+
+// rotate moves the current file aside for replaying and starts a new one
+func (s *spool) rotate() error {
+	if err := s.current.Close(); err != nil {
+		return fmt.Errorf("rotate spool: %w", err)
+	}
+	if err := os.Rename(filepath.Join(s.dir, spoolFile), filepath.Join(s.dir, spoolReplayFile)); err != nil {
+		return fmt.Errorf("rotate spool: %w", err)
+	}
+	s.replaySize = s.size
+	return s.open()
+}
+
+// replayFile writes the records of the replay file to w and removes it, or
+// keeps the records that were not written
+func (s *spool) replayFile(ctx context.Context, w io.Writer) (int, error) {
+	path := filepath.Join(s.dir, spoolReplayFile)
+	f, err := os.Open(path) // #nosec G304 -- the directory is supplied by the operator
+	if err != nil {
+		return 0, fmt.Errorf("replay spool: %w", err)
+	}
+	defer f.Close()
+
+	replayed := 0
+	reader := bufio.NewReader(f)
+	for {
+		record, err := reader.ReadBytes('\n')
+		switch {
+		case len(record) == 0:
+		case s.sent != nil && s.sent.seen(record):
+			s.skipped.Add(1)
+		default:
+			if werr := writeRecord(ctx, w, record); werr != nil {
+				return replayed, s.keep(record, reader, werr)
+			}
+			replayed++
+			if s.sent != nil {
+				if err := s.sent.add(record); err != nil {
+					diag().Warn("Failed to remember a replayed record, it may be duplicated after a crash", "error", err)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return replayed, fmt.Errorf("replay spool: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return replayed, fmt.Errorf("replay spool: %w", err)
+	}
+	s.replaySize = 0
+	return replayed, nil
+}
+
+// keep rewrites the replay file with record and the rest of reader, after
+// writing record failed with cause
+func (s *spool) keep(record []byte, reader io.Reader, cause error) error {
+	path := filepath.Join(s.dir, spoolReplayFile)
+	tmp, err := os.CreateTemp(s.dir, spoolReplayFile+".*")
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+	size, err := tmp.Write(record)
+	if err == nil {
+		var rest int64
+		rest, err = io.Copy(tmp, reader)
+		size += int(rest)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Join(cause, err)
+	}
+
+	s.mu.Lock()
+	s.replaySize = int64(size)
+	s.mu.Unlock()
+	return fmt.Errorf("replay spool: %w", cause)
+}
+
+// writeRecord writes record to w, waiting while a delivery queue is full
+// until ctx is done
+func writeRecord(ctx context.Context, w io.Writer, record []byte) error {
+	if pool, ok := w.(*deliveryPool); ok {
+		return pool.enqueue(ctx, record, true)
+	}
+	_, err := w.Write(record)
+	return err
+}
+
+// Dropped returns the number of records dropped because the spool was full
+func (s *spool) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Skipped returns the number of records replays skipped because a replay
+// already delivered them
+func (s *spool) Skipped() uint64 {
+	return s.skipped.Load()
+}
+
+// Close closes the current file, records written afterwards are dropped.
+// Spooled records stay on disk for the next process.
+func (s *spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return nil
+	}
+	err := s.current.Close()
+	s.current = nil
+	if s.sent != nil {
+		err = errors.Join(err, s.sent.Close())
+	}
+	return err
+}
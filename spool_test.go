@@ -0,0 +1,294 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+// failingWriter takes n writes, then fails
+type failingWriter struct {
+	records []string
+	n       int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(w.records) == w.n {
+		return 0, errors.New("endpoint went away")
+	}
+	w.records = append(w.records, string(p))
+	return len(p), nil
+}
+
+func TestSpool_ReplaysInOrderAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+
+	sp, err := openSpool(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("openSpool() returned unexpected error: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(sp, "record-%d\n", i)
+	}
+	if err := sp.Close(); err != nil {
+		t.Fatalf("Close() returned unexpected error: %v", err)
+	}
+
+	// a new process picks the spool up, a replay fails midway
+	sp, err = openSpool(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("openSpool() returned unexpected error: %v", err)
+	}
+	defer sp.Close()
+
+	broken := &failingWriter{n: 2}
+	if n, err := sp.replay(context.Background(), broken); err == nil || n != 2 {
+		t.Fatalf("replay() = %d, %v, want 2 records and an error", n, err)
+	}
+	fmt.Fprintf(sp, "record-5\n")
+
+	w := &failingWriter{n: 100}
+	if n, err := sp.replay(context.Background(), w); err != nil || n != 4 {
+		t.Fatalf("replay() = %d, %v, want 4 records", n, err)
+	}
+	got := strings.Join(append(broken.records, w.records...), "")
+	if want := "record-0\nrecord-1\nrecord-2\nrecord-3\nrecord-4\nrecord-5\n"; got != want {
+		t.Errorf("replayed %q, want %q", got, want)
+	}
+
+	if n, err := sp.replay(context.Background(), w); err != nil || n != 0 {
+		t.Errorf("replay() of an empty spool = %d, %v, want 0", n, err)
+	}
+}
+
+func TestSpool_ProtectReplays(t *testing.T) {
+	dir := t.TempDir()
+	records := "record-0\nrecord-1\nrecord-2\n"
+
+	sp, err := openSpool(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("openSpool() returned unexpected error: %v", err)
+	}
+	if err := sp.protectReplays(time.Hour); err != nil {
+		t.Fatalf("protectReplays() returned unexpected error: %v", err)
+	}
+	fmt.Fprint(sp, records)
+	w := &failingWriter{n: 100}
+	if n, err := sp.replay(context.Background(), w); err != nil || n != 3 {
+		t.Fatalf("replay() = %d, %v, want 3 records", n, err)
+	}
+	if err := sp.Close(); err != nil {
+		t.Fatalf("Close() returned unexpected error: %v", err)
+	}
+
+	// the process crashed before the replay file was removed
+	if err := os.WriteFile(filepath.Join(dir, spoolReplayFile), []byte(records), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sp, err = openSpool(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("openSpool() returned unexpected error: %v", err)
+	}
+	defer sp.Close()
+	if err := sp.protectReplays(time.Hour); err != nil {
+		t.Fatalf("protectReplays() returned unexpected error: %v", err)
+	}
+	fmt.Fprint(sp, "record-3\n")
+
+	w = &failingWriter{n: 100}
+	if n, err := sp.replay(context.Background(), w); err != nil || n != 1 {
+		t.Fatalf("replay() after the crash = %d, %v, want 1 record", n, err)
+	}
+	if got := strings.Join(w.records, ""); got != "record-3\n" || sp.Skipped() != 3 {
+		t.Errorf("replayed %q skipping %d, want the records delivered before the crash skipped", got, sp.Skipped())
+	}
+}
+
+func TestSentRecords_Window(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * time.Minute).UnixNano()
+	data := fmt.Sprintf("%016x %d\n%016x %d\nnot an id\n", recordID([]byte("old\n")), old, recordID([]byte("new\n")), time.Now().UnixNano())
+	if err := os.WriteFile(filepath.Join(dir, spoolSentFile), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sent, err := openSentRecords(dir, time.Minute)
+	if err != nil {
+		t.Fatalf("openSentRecords() returned unexpected error: %v", err)
+	}
+	defer sent.Close()
+	if sent.seen([]byte("old\n")) || !sent.seen([]byte("new\n")) {
+		t.Error("seen() want only the records delivered within the window")
+	}
+	compacted, err := os.ReadFile(filepath.Join(dir, spoolSentFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(compacted), "\n") != 1 {
+		t.Errorf("%s = %q, want the expired and invalid lines dropped", spoolSentFile, compacted)
+	}
+}
+
+func TestSpool_DropsWhenFull(t *testing.T) {
+	sp, err := openSpool(t.TempDir(), 20)
+	if err != nil {
+		t.Fatalf("openSpool() returned unexpected error: %v", err)
+	}
+	defer sp.Close()
+
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(sp, "record-%d\n", i)
+	}
+	if sp.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", sp.Dropped())
+	}
+
+	w := &failingWriter{n: 100}
+	if n, _ := sp.replay(context.Background(), w); n != 2 {
+		t.Errorf("replay() = %d records, want 2", n)
+	}
+}
+
+func TestInitialize_SpoolsWhileUnreachable(t *testing.T) {
+	preserveConfig(t)
+	fastReconnect(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	// reserve a port nothing listens on yet
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "spool-type"
+	cfg.Protocol = ProtocolTCP
+	cfg.LogHost = "127.0.0.1"
+	cfg.LogPort = port
+	cfg.SpoolDir = t.TempDir()
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		slog.Info("while down", "seq", i)
+	}
+
+	receiver, err := loggertest.ListenAddr(loggertest.TCP, listener.Addr().String())
+	if err != nil {
+		t.Skipf("port %d was taken before the endpoint came up: %v", port, err)
+	}
+	defer receiver.Close()
+
+	// loggers of earlier tests may forward records as well
+	var spooled []map[string]any
+	deadline := time.Now().Add(2 * time.Second)
+	for len(spooled) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		spooled = spooled[:0]
+		for _, event := range receiver.Events() {
+			if event["message"] == "while down" {
+				spooled = append(spooled, event)
+			}
+		}
+	}
+	if len(spooled) != 3 {
+		t.Fatalf("received %d spooled records, want 3", len(spooled))
+	}
+	for i, event := range spooled {
+		if event["seq"] != float64(i) {
+			t.Errorf("event %d = %v, want spooled record %d", i, event, i)
+		}
+	}
+}
+
+func TestInitialize_SpoolsAfterConnectionLost(t *testing.T) {
+	for _, workers := range []int{0, 1} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			preserveConfig(t)
+			fastReconnect(t)
+			once = sync.Once{}
+			defer Shutdown(context.Background())
+
+			first, err := loggertest.Listen(loggertest.TCP)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer first.Close()
+
+			cfg := NewConfig()
+			cfg.LogType = "spool-type"
+			cfg.Protocol = ProtocolTCP
+			cfg.LogHost = first.Host()
+			cfg.LogPort = first.Port()
+			cfg.DeliveryWorkers = workers
+			cfg.SpoolDir = t.TempDir()
+			diagnosed := &capturedDiagnostics{}
+			cfg.Diagnostics = slog.NewJSONHandler(diagnosed, nil)
+			if err := Initialize(cfg); err != nil {
+				t.Fatalf("Initialize() returned unexpected error: %v", err)
+			}
+			slog.Info("before")
+			if !first.Wait(1, time.Second) {
+				t.Fatalf("received %d records before the endpoint went away, want 1", first.Count())
+			}
+
+			// the endpoint refuses connections until it comes back below
+			first.Close()
+			lost := func() bool { return strings.Contains(diagnosed.String(), "Lost the connection to log endpoint") }
+			if !logUntil("while down", 5*time.Second, lost) {
+				t.Fatal("forwarder did not notice the lost connection")
+			}
+			for i := 0; i < 3; i++ {
+				slog.Info("spooled", "seq", i)
+			}
+
+			second, err := loggertest.ListenAddr(loggertest.TCP, first.Addr())
+			if err != nil {
+				t.Skipf("%s was taken before the endpoint came back: %v", first.Addr(), err)
+			}
+			defer second.Close()
+
+			var spooled []map[string]any
+			failed := 0
+			deadline := time.Now().Add(2 * time.Second)
+			for len(spooled) < 3 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+				spooled, failed = spooled[:0], 0
+				for _, event := range second.Events() {
+					switch event["message"] {
+					case "spooled":
+						spooled = append(spooled, event)
+					case "while down":
+						failed++
+					}
+				}
+			}
+			if len(spooled) != 3 {
+				t.Fatalf("received %d spooled records, want 3", len(spooled))
+			}
+			for i, event := range spooled {
+				if event["seq"] != float64(i) {
+					t.Errorf("event %d = %v, want spooled record %d", i, event, i)
+				}
+			}
+			// the record that revealed the lost connection is spooled too
+			if failed == 0 {
+				t.Error("the record that failed to reach the endpoint was not spooled")
+			}
+		})
+	}
+}
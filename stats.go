@@ -0,0 +1,211 @@
+package logger
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SinkTotals are the records a sink wrote and dropped
+type SinkTotals struct {
+	Records        int64 `json:"records"` // records written, counted for stdout and the forwarder
+	Bytes          int64 `json:"bytes"`
+	DroppedRecords int64 `json:"dropped_records"` // over the egress budget or rate, or undeliverable
+	WriteErrors    int64 `json:"write_errors"`
+}
+
+// LifetimeStats are the totals of the sinks over the life of a pod, the
+// totals read from StatsFile plus those of the current process
+type LifetimeStats struct {
+	Started  time.Time             `json:"started"`  // when the first process recorded in StatsFile started
+	Restarts int                   `json:"restarts"` // processes started before the current one
+	Updated  time.Time             `json:"updated"`
+	Sinks    map[string]SinkTotals `json:"sinks"`
+}
+
+// sinkCounters are the totals of a sink in the current process
+type sinkCounters struct {
+	records, bytes, dropped, errors atomic.Int64
+}
+
+var (
+	// processStart is when the current process started counting
+	processStart = time.Now()
+	// counters holds the *sinkCounters of every sink by name
+	counters sync.Map
+
+	statsMu sync.Mutex
+	// statsBaseline are the totals read from statsPath, which the counters
+	// of the current process add to
+	statsBaseline LifetimeStats
+	statsPath     string
+)
+
+// countersOf returns the counters of sink
+func countersOf(sink string) *sinkCounters {
+	if c, ok := counters.Load(sink); ok {
+		return c.(*sinkCounters)
+	}
+	c, _ := counters.LoadOrStore(sink, &sinkCounters{})
+	return c.(*sinkCounters)
+}
+
+// countWrite counts a record of n bytes written to sink
+func countWrite(sink string, n int) {
+	c := countersOf(sink)
+	c.records.Add(1)
+	c.bytes.Add(int64(n))
+}
+
+// countDropped counts n records dropped by sink
+func countDropped(sink string, n int) {
+	countersOf(sink).dropped.Add(int64(n))
+}
+
+// Lifetime returns the totals of the sinks since the first process recorded
+// in StatsFile started, or since the current one did without StatsFile
+func Lifetime() LifetimeStats {
+	statsMu.Lock()
+	stats := statsBaseline
+	statsMu.Unlock()
+
+	stats.Started = cmp.Or(stats.Started, processStart)
+	stats.Updated = time.Now()
+	stats.Sinks = maps.Clone(stats.Sinks)
+	if stats.Sinks == nil {
+		stats.Sinks = map[string]SinkTotals{}
+	}
+	counters.Range(func(key, value any) bool {
+		c, totals := value.(*sinkCounters), stats.Sinks[key.(string)]
+		totals.Records += c.records.Load()
+		totals.Bytes += c.bytes.Load()
+		totals.DroppedRecords += c.dropped.Load()
+		totals.WriteErrors += c.errors.Load()
+		stats.Sinks[key.(string)] = totals
+		return true
+	})
+	return stats
+}
+
+// loadStats reads the totals of earlier processes from path, once per
+// process, so initializing again doesn't count them twice. A missing file
+// starts the totals of a new pod.
+func loadStats(path string) error {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	if path == statsPath {
+		return nil
+	}
+	statsPath, statsBaseline = path, LifetimeStats{}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- the path is configured by the operator
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var stats LifetimeStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return err
+	}
+	stats.Restarts++
+	statsBaseline = stats
+	return nil
+}
+
+// saveStats writes the lifetime totals to path, replacing it atomically so a
+// crash never leaves a partial file
+func saveStats(path string) error {
+	data, err := json.Marshal(Lifetime())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// persistStats saves the lifetime totals to path every interval and once
+// more when ctx is done
+func persistStats(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := saveStats(path); err != nil {
+				diag().Warn("Failed to save the lifetime stats", "path", path, "error", err)
+			}
+			return
+		case <-ticker.C:
+			if err := saveStats(path); err != nil {
+				diag().Warn("Failed to save the lifetime stats", "path", path, "error", err)
+			}
+		}
+	}
+}
+
+// emitStats logs the records the sinks wrote and dropped during every
+// interval to h until ctx is done
+func emitStats(ctx context.Context, h slog.Handler, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	previous := Lifetime().Sinks
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			stats := Lifetime()
+			if err := h.Handle(ctx, statsRecord(now, interval, previous, stats)); err != nil {
+				diag().Warn("Failed to log the stats event", "error", err)
+			}
+			previous = stats.Sinks
+		}
+	}
+}
+
+// statsRecord returns the event of the totals of the sinks in stats since
+// previous, with the per second rates over interval
+func statsRecord(now time.Time, interval time.Duration, previous map[string]SinkTotals, stats LifetimeStats) slog.Record {
+	r := slog.NewRecord(now, slog.LevelInfo, "Forwarder stats", 0)
+	r.AddAttrs(
+		slog.Int64("interval_ms", interval.Milliseconds()),
+		slog.Int("restarts", stats.Restarts),
+	)
+
+	seconds := interval.Seconds()
+	sinks := make([]any, 0, len(stats.Sinks))
+	for _, name := range slices.Sorted(maps.Keys(stats.Sinks)) {
+		current, last := stats.Sinks[name], previous[name]
+		records := current.Records - last.Records
+		sinks = append(sinks, slog.Group(name,
+			slog.Int64("records", records),
+			slog.Int64("bytes", current.Bytes-last.Bytes),
+			slog.Int64("dropped_records", current.DroppedRecords-last.DroppedRecords),
+			slog.Int64("write_errors", current.WriteErrors-last.WriteErrors),
+			slog.Float64("records_per_second", float64(records)/seconds),
+		))
+	}
+	r.AddAttrs(slog.Group("sinks", sinks...))
+	return r
+}
@@ -0,0 +1,175 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// resetStats starts the lifetime totals from zero for a test
+func resetStats(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		counters.Clear()
+		statsMu.Lock()
+		statsBaseline, statsPath = LifetimeStats{}, ""
+		statsMu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestLifetime_AcrossRestarts(t *testing.T) {
+	resetStats(t)
+	path := filepath.Join(t.TempDir(), "state", "stats.json")
+
+	if err := loadStats(path); err != nil {
+		t.Fatalf("loadStats() of a new pod returned unexpected error: %v", err)
+	}
+	countWrite(SinkForwarder, 100)
+	countWrite(SinkForwarder, 50)
+	countDropped(SinkForwarder, 2)
+	recordError(SinkForwarder, OpWrite, os.ErrDeadlineExceeded)
+	if err := saveStats(path); err != nil {
+		t.Fatalf("saveStats() returned unexpected error: %v", err)
+	}
+	first := Lifetime()
+
+	// a restart reads the totals back and counts from zero
+	counters.Clear()
+	statsPath = ""
+	if err := loadStats(path); err != nil {
+		t.Fatalf("loadStats() returned unexpected error: %v", err)
+	}
+	countWrite(SinkForwarder, 10)
+	countDropped(SinkStdout, 1)
+
+	stats := Lifetime()
+	expected := SinkTotals{Records: 3, Bytes: 160, DroppedRecords: 2, WriteErrors: 1}
+	if stats.Sinks[SinkForwarder] != expected {
+		t.Errorf("forwarder totals = %+v, want %+v", stats.Sinks[SinkForwarder], expected)
+	}
+	if stats.Sinks[SinkStdout].DroppedRecords != 1 {
+		t.Errorf("stdout totals = %+v, want 1 dropped record", stats.Sinks[SinkStdout])
+	}
+	if stats.Restarts != 1 || !stats.Started.Equal(first.Started) {
+		t.Errorf("restarts = %d, started = %v, want 1 restart of the pod started at %v", stats.Restarts, stats.Started, first.Started)
+	}
+
+	// initializing again in the same process doesn't add the file twice
+	if err := loadStats(path); err != nil {
+		t.Fatalf("loadStats() returned unexpected error: %v", err)
+	}
+	if got := Lifetime().Sinks[SinkForwarder].Records; got != 3 {
+		t.Errorf("records after loading again = %d, want 3", got)
+	}
+}
+
+func TestLoadStats_Invalid(t *testing.T) {
+	resetStats(t)
+	path := filepath.Join(t.TempDir(), "stats.json")
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := loadStats(path); err == nil {
+		t.Error("loadStats() of a corrupt file returned no error")
+	}
+	if stats := Lifetime(); stats.Restarts != 0 || len(stats.Sinks) != 0 {
+		t.Errorf("Lifetime() = %+v, want the totals of the current process", stats)
+	}
+}
+
+func TestPersistStats(t *testing.T) {
+	resetStats(t)
+	path := filepath.Join(t.TempDir(), "stats.json")
+	countWrite(SinkStdout, 42)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		persistStats(ctx, path, time.Hour)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("the stats were not saved when ctx was done: %v", err)
+	}
+	var stats LifetimeStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		t.Fatalf("invalid stats file %q: %v", data, err)
+	}
+	if stats.Sinks[SinkStdout].Bytes != 42 {
+		t.Errorf("saved stats = %s, want the bytes written to stdout", data)
+	}
+}
+
+func TestStatsRecord(t *testing.T) {
+	preserveConfig(t)
+	previous := map[string]SinkTotals{SinkForwarder: {Records: 10, Bytes: 1000, DroppedRecords: 1}}
+	stats := LifetimeStats{Restarts: 2, Sinks: map[string]SinkTotals{
+		SinkForwarder: {Records: 40, Bytes: 4000, DroppedRecords: 4, WriteErrors: 1},
+		SinkStdout:    {Records: 5, Bytes: 500},
+	}}
+
+	var buf bytes.Buffer
+	h := newChannelHandler("forwarder-stats", sink{w: &buf})
+	if err := h.Handle(context.Background(), statsRecord(time.Now(), 10*time.Second, previous, stats)); err != nil {
+		t.Fatalf("Handle() returned unexpected error: %v", err)
+	}
+
+	var event struct {
+		Channel  string                    `json:"channel"`
+		Message  string                    `json:"message"`
+		Restarts int                       `json:"restarts"`
+		Sinks    map[string]map[string]any `json:"sinks"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("invalid event %q: %v", buf.Bytes(), err)
+	}
+	if event.Channel != "forwarder-stats" || event.Message != "Forwarder stats" || event.Restarts != 2 {
+		t.Errorf("event = %s, want the stats on the forwarder-stats channel", buf.Bytes())
+	}
+	want := map[string]any{"records": 30.0, "bytes": 3000.0, "dropped_records": 3.0, "write_errors": 1.0, "records_per_second": 3.0}
+	for key, value := range want {
+		if got := event.Sinks[SinkForwarder][key]; got != value {
+			t.Errorf("sinks.forwarder.%s = %v, want %v", key, got, value)
+		}
+	}
+	if got := event.Sinks[SinkStdout]["records"]; got != 5.0 {
+		t.Errorf("sinks.stdout.records = %v, want 5 from a sink new in the interval", got)
+	}
+}
+
+func TestEmitStats(t *testing.T) {
+	preserveConfig(t)
+	resetStats(t)
+
+	events := &capturedDiagnostics{}
+	h := newChannelHandler("forwarder-stats", sink{w: events})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		emitStats(ctx, h, 10*time.Millisecond)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	if !events.wait(`"channel":"forwarder-stats"`, time.Second) {
+		t.Fatalf("events = %s, want the stats on the forwarder-stats channel", events.String())
+	}
+	countWrite(SinkForwarder, 100)
+	if !events.wait(`"forwarder":{"records":1,"bytes":100`, time.Second) {
+		t.Errorf("events = %s, want the record written to the forwarder", events.String())
+	}
+}
@@ -0,0 +1,200 @@
+package logger
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FormatSyslog is the Lagoon JSON event wrapped in an RFC 5424 syslog
+// message, the format of the forwarder with Config.Syslog
+const FormatSyslog = "syslog"
+
+// SyslogConfig wraps every forwarded event in an RFC 5424 syslog message,
+// for an rsyslog or syslog-ng relay in front of Logstash. The message is the
+// Lagoon JSON event, so the relay can pass it on unchanged.
+type SyslogConfig struct {
+	Facility string `json:"facility"` // e.g. "local0" (default), "user" or "daemon"
+	AppName  string `json:"appName"`  // APP-NAME of the messages, ApplicationName when empty
+	// SDID adds the log type, channel and application as structured data
+	// with this SD-ID, e.g. "lagoon@32473". Empty writes no structured data.
+	SDID string `json:"sdId"`
+}
+
+// syslogFacilities are the facility codes of RFC 5424 by name
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+func (c *SyslogConfig) validate() error {
+	if _, ok := syslogFacilities[c.Facility]; len(c.Facility) > 0 && !ok {
+		return fmt.Errorf("unknown syslog.facility %q", c.Facility)
+	}
+	if strings.ContainsAny(c.SDID, " =]\"") {
+		return fmt.Errorf("invalid syslog.sdId %q", c.SDID)
+	}
+	return nil
+}
+
+// syslogFrame is the part of the syslog messages that is the same for every
+// record of a config
+type syslogFrame struct {
+	facility int
+	// header follows the timestamp up to the structured data
+	header string
+	sd     string
+}
+
+// newSyslogFrame returns the frame of the applied config
+func newSyslogFrame() syslogFrame {
+	settings := SyslogConfig{}
+	if syslogSettings != nil {
+		settings = *syslogSettings
+	}
+
+	var sd string
+	if len(settings.SDID) > 0 {
+		var params strings.Builder
+		for _, p := range [][2]string{{"type", logType}, {"channel", logChannel}, {"application", applicationName}} {
+			if len(p[1]) > 0 {
+				fmt.Fprintf(&params, " %s=\"%s\"", p[0], syslogEscape(p[1]))
+			}
+		}
+		sd = "[" + settings.SDID + params.String() + "]"
+	}
+
+	appName := cmp.Or(settings.AppName, applicationName)
+	return syslogFrame{
+		facility: syslogFacilities[cmp.Or(settings.Facility, "local0")],
+		header:   " " + syslogField(hostname, 255) + " " + syslogField(appName, 48) + " " + strconv.Itoa(os.Getpid()) + " - ",
+		sd:       cmp.Or(sd, "-"),
+	}
+}
+
+// syslogHandler writes the records of its JSON handler to w in syslog
+// messages
+type syslogHandler struct {
+	syslogFrame
+	w    io.Writer
+	json slog.Handler
+	buf  *bytes.Buffer
+}
+
+// newSyslogHandler returns a handler writing the events of opts and attrs to
+// w framed by frame
+func newSyslogHandler(w io.Writer, opts *slog.HandlerOptions, attrs []any, frame syslogFrame) *syslogHandler {
+	buf := &bytes.Buffer{}
+	return &syslogHandler{
+		syslogFrame: frame,
+		w:           w,
+		json:        slog.New(slog.NewJSONHandler(buf, opts)).With(attrs...).Handler(),
+		buf:         buf,
+	}
+}
+
+func (h *syslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.json.Enabled(ctx, level)
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.buf.Reset()
+	if err := h.json.Handle(ctx, r); err != nil {
+		return err
+	}
+	event := bytes.TrimSuffix(h.buf.Bytes(), []byte("\n"))
+
+	timestamp := "-"
+	if !r.Time.IsZero() {
+		timestamp = r.Time.Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+	header := "<" + strconv.Itoa(h.facility*8+syslogSeverity(r.Level)) + ">1 " + timestamp + h.header + h.sd + " "
+
+	_, err := fmt.Fprintf(h.w, "%s%s", header, event)
+	return err
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.json = h.json.WithAttrs(attrs)
+	return &c
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.json = h.json.WithGroup(name)
+	return &c
+}
+
+// syslogWriter returns w writing syslog messages over protocol. Over TCP
+// every message is prefixed with its length, the octet counting RFC 6587
+// describes for streams.
+func syslogWriter(w io.Writer, protocol string) io.Writer {
+	if protocol == ProtocolTCP {
+		return octetCounter{w: w}
+	}
+	return w
+}
+
+// octetCounter prefixes every message written to w with its length
+type octetCounter struct {
+	w io.Writer
+}
+
+func (c octetCounter) Write(p []byte) (int, error) {
+	return c.writeContext(context.Background(), p)
+}
+
+func (c octetCounter) writeContext(ctx context.Context, p []byte) (int, error) {
+	msg := make([]byte, 0, len(p)+8)
+	msg = strconv.AppendInt(msg, int64(len(p)), 10)
+	msg = append(append(msg, ' '), p...)
+	if _, err := writeContext(ctx, c.w, msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// syslogSeverity maps a level to the severity of RFC 5424
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= LevelCritical:
+		return 2 // critical
+	case level >= slog.LevelError:
+		return 3 // error
+	case level >= slog.LevelWarn:
+		return 4 // warning
+	case level >= slog.LevelInfo:
+		return 6 // informational
+	default:
+		return 7 // debug
+	}
+}
+
+// syslogField returns s as a header field of at most n printable ASCII
+// characters, "-" when it is empty
+func syslogField(s string, n int) string {
+	if len(s) == 0 {
+		return "-"
+	}
+	field := []byte(s)
+	for i, c := range field {
+		if c < 33 || c > 126 {
+			field[i] = '_'
+		}
+	}
+	return string(field[:min(len(field), n)])
+}
+
+// syslogEscape escapes a structured data parameter value
+func syslogEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogHandler(t *testing.T) {
+	preserveConfig(t)
+	hostname = "web-1"
+	applicationName = "drupal"
+	logType = "lagoon-test"
+	logChannel = "app"
+
+	pid := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name     string
+		protocol string
+		settings SyslogConfig
+		level    slog.Level
+		want     string
+	}{
+		{"defaults", ProtocolUDP, SyslogConfig{}, slog.LevelInfo, "<134>1 - web-1 drupal " + pid + " - - "},
+		{"facility and app name", ProtocolUDP, SyslogConfig{Facility: "daemon", AppName: "api"}, slog.LevelError, "<27>1 - web-1 api " + pid + " - - "},
+		{"structured data", ProtocolUDP, SyslogConfig{SDID: "lagoon@32473"}, slog.LevelWarn,
+			"<132>1 - web-1 drupal " + pid + ` - [lagoon@32473 type="lagoon-test" channel="app" application="drupal"] `},
+		{"octet counted", ProtocolTCP, SyslogConfig{}, slog.LevelDebug, "<135>1 - web-1 drupal " + pid + " - - "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocol = tt.protocol
+			syslogSettings = &tt.settings
+
+			var buf bytes.Buffer
+			h := newJSONHandler([]sink{{w: syslogWriter(&buf, tt.protocol), format: FormatSyslog, level: slog.LevelDebug}},
+				&slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: dropTime}, []any{slog.String("type", logType)})
+			slog.New(h).Log(t.Context(), tt.level, "hello")
+
+			msg := buf.String()
+			if tt.protocol == ProtocolTCP {
+				length, rest, _ := strings.Cut(msg, " ")
+				if length != strconv.Itoa(len(rest)) {
+					t.Errorf("length prefix = %s, want %d", length, len(rest))
+				}
+				msg = rest
+			}
+
+			header, event, ok := strings.Cut(msg, "{")
+			fields := strings.SplitN(header, " ", 3)
+			if len(fields) == 3 {
+				if _, err := time.Parse(time.RFC3339Nano, fields[1]); err != nil {
+					t.Errorf("timestamp %q is not RFC 3339: %v", fields[1], err)
+				}
+				header = fields[0] + " - " + fields[2]
+			}
+			if !ok || header != tt.want {
+				t.Errorf("header = %q, want %q", header, tt.want)
+			}
+			var decoded map[string]any
+			if err := json.Unmarshal([]byte("{"+event), &decoded); err != nil {
+				t.Fatalf("invalid JSON %q: %v", event, err)
+			}
+			if decoded["msg"] != "hello" || decoded["type"] != "lagoon-test" {
+				t.Errorf("event = %v, want the Lagoon JSON event", decoded)
+			}
+		})
+	}
+}
+
+func TestDial_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram() returned unexpected error: %v", err)
+	}
+	defer listener.Close()
+
+	cfg := NewConfig()
+	cfg.Protocol = ProtocolUnix
+	cfg.LogHost = path
+
+	conn, err := Dial(cfg)
+	if err != nil {
+		t.Fatalf("Dial() returned unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	const record = `<134>1 - web-1 drupal 1 - - {"msg":"hello"}`
+	if _, err := fmt.Fprint(conn, record); err != nil {
+		t.Fatalf("Write() returned unexpected error: %v", err)
+	}
+
+	buf := make([]byte, 1024)
+	_ = listener.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := listener.Read(buf)
+	if err != nil || string(buf[:n]) != record {
+		t.Errorf("Read() = %q, %v, want %q", buf[:n], err, record)
+	}
+}
@@ -0,0 +1,164 @@
+package tailer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Formats of the log files of container runtimes, such as those under
+// /var/log/containers, whose lines wrap the output of a container. The output
+// is parsed as FormatJSON and stamped with the time the runtime read it, and
+// the partial lines a runtime splits long output into are joined again.
+const (
+	// FormatCRI is the format of containerd and CRI-O,
+	// "<time> <stream> <P|F> <output>"
+	FormatCRI = "cri"
+	// FormatDocker is the json-file format of Docker,
+	// {"log": "<output>\n", "stream": "<stream>", "time": "<time>"}
+	FormatDocker = "docker"
+	// FormatContainer detects FormatCRI or FormatDocker on every line
+	FormatContainer = "container"
+)
+
+// StreamKey is the attribute holding the stream a container wrote the output
+// to, stdout or stderr
+const StreamKey = "stream"
+
+// criPartial is the tag of a CRI line continued by the next line of its stream
+const criPartial = "P"
+
+// containerFormats are the formats parsed by a ContainerParser
+var containerFormats = []string{FormatCRI, FormatDocker, FormatContainer}
+
+// ContainerParser parses the lines of a container log file, joining the
+// partial lines of each stream. A parser follows a single file.
+type ContainerParser struct {
+	format  string
+	parse   parser
+	partial map[string]*strings.Builder
+	// started is when the first partial line of a stream was read
+	started map[string]time.Time
+}
+
+// NewContainerParser returns the parser of a container log format
+func NewContainerParser(format string) (*ContainerParser, error) {
+	if !slices.Contains(containerFormats, format) {
+		return nil, fmt.Errorf("unknown container format %q", format)
+	}
+	return newContainerParser(format, ParseJSON), nil
+}
+
+// newContainerParser returns the parser of format parsing the output with
+// parse
+func newContainerParser(format string, parse parser) *ContainerParser {
+	return &ContainerParser{format: format, parse: parse, partial: map[string]*strings.Builder{}, started: map[string]time.Time{}}
+}
+
+// Parse returns the record of the output of line, false while the output is
+// partial. Lines that aren't of the format are parsed as FormatJSON at now.
+func (p *ContainerParser) Parse(line string, now time.Time) (slog.Record, bool) {
+	line = strings.TrimRight(line, "\r\n")
+
+	var output, stream string
+	var timestamp time.Time
+	var partial, ok bool
+	switch {
+	case p.format != FormatCRI && strings.HasPrefix(line, "{"):
+		output, stream, timestamp, partial, ok = parseDocker(line)
+	case p.format != FormatDocker:
+		output, stream, timestamp, partial, ok = parseCRI(line)
+	}
+	if !ok {
+		return p.parse(line, now), true
+	}
+
+	if partial {
+		b := p.partial[stream]
+		if b == nil {
+			b = &strings.Builder{}
+			p.partial[stream], p.started[stream] = b, timestamp
+		}
+		b.WriteString(output)
+		if b.Len() < maxLine {
+			return slog.Record{}, false
+		}
+		// output longer than a line is forwarded in parts
+		return p.flush(stream), true
+	}
+	if b := p.partial[stream]; b != nil {
+		b.WriteString(output)
+		return p.flush(stream), true
+	}
+	return p.record(output, stream, timestamp), true
+}
+
+// Flush returns the records of the partial output not completed yet, such as
+// that of a container that was stopped mid-line
+func (p *ContainerParser) Flush() []slog.Record {
+	var records []slog.Record
+	for _, stream := range slices.Sorted(maps.Keys(p.partial)) {
+		records = append(records, p.flush(stream))
+	}
+	return records
+}
+
+// flush returns the record of the partial output of stream and forgets it
+func (p *ContainerParser) flush(stream string) slog.Record {
+	output, timestamp := p.partial[stream].String(), p.started[stream]
+	delete(p.partial, stream)
+	delete(p.started, stream)
+	return p.record(output, stream, timestamp)
+}
+
+// record returns the record of the output a container wrote to stream
+func (p *ContainerParser) record(output, stream string, timestamp time.Time) slog.Record {
+	r := p.parse(output, timestamp)
+	r.AddAttrs(slog.String(StreamKey, stream))
+	return r
+}
+
+// parseCRI splits a line of the CRI format, whose tag may carry further
+// tags after a colon
+func parseCRI(line string) (output, stream string, timestamp time.Time, partial, ok bool) {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) < 3 || !validStream(fields[1]) {
+		return "", "", time.Time{}, false, false
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return "", "", time.Time{}, false, false
+	}
+	if len(fields) == 4 {
+		output = fields[3]
+	}
+	tag, _, _ := strings.Cut(fields[2], ":")
+	return output, fields[1], timestamp, tag == criPartial, true
+}
+
+// parseDocker decodes a line of the json-file format, whose output is
+// partial unless it ends with a newline
+func parseDocker(line string) (output, stream string, timestamp time.Time, partial, ok bool) {
+	var entry struct {
+		Log    string    `json:"log"`
+		Stream string    `json:"stream"`
+		Time   time.Time `json:"time"`
+	}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil || !validStream(entry.Stream) || entry.Time.IsZero() {
+		return "", "", time.Time{}, false, false
+	}
+	output, complete := strings.CutSuffix(entry.Log, "\n")
+	if complete {
+		output = strings.TrimSuffix(output, "\r")
+	}
+	return output, entry.Stream, entry.Time, !complete, true
+}
+
+// validStream reports whether stream is a stream of a container
+func validStream(stream string) bool {
+	return stream == "stdout" || stream == "stderr"
+}
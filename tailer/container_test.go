@@ -0,0 +1,151 @@
+package tailer
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseAll parses lines with p and returns the message and stream of every
+// record completed
+func parseAll(p *ContainerParser, lines ...string) []string {
+	var out []string
+	for _, line := range lines {
+		if r, ok := p.Parse(line, time.Now()); ok {
+			out = append(out, attrs(r)[StreamKey]+":"+r.Message)
+		}
+	}
+	return out
+}
+
+func TestContainerParser_CRI(t *testing.T) {
+	p, err := NewContainerParser(FormatCRI)
+	if err != nil {
+		t.Fatalf("NewContainerParser() returned unexpected error: %v", err)
+	}
+
+	r, ok := p.Parse(`2026-10-15T09:30:00.123456789+10:00 stderr F {"message": "db down", "level": "error", "db": "postgres"}`, time.Now())
+	if !ok {
+		t.Fatal("Parse() of a full line returned no record")
+	}
+	if r.Message != "db down" || r.Level != slog.LevelError || !r.Time.Equal(time.Date(2026, 10, 14, 23, 30, 0, 123456789, time.UTC)) {
+		t.Errorf("Parse() = %q at %v %v, want the output parsed at the time of the runtime", r.Message, r.Level, r.Time)
+	}
+	if got := attrs(r); got[StreamKey] != "stderr" || got["db"] != "postgres" {
+		t.Errorf("Parse() attrs = %v, want the stream and the fields of the output", got)
+	}
+
+	got := parseAll(p,
+		"2026-10-15T09:30:01Z stdout P first ",
+		"2026-10-15T09:30:01Z stderr F interleaved",
+		"2026-10-15T09:30:01Z stdout P half ",
+		"2026-10-15T09:30:02Z stdout F:x done",
+		"2026-10-15T09:30:03Z stdout F ",
+	)
+	want := []string{"stderr:interleaved", "stdout:first half done", "stdout:"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Parse() of partial lines = %q, want %q", got, want)
+	}
+}
+
+func TestContainerParser_Docker(t *testing.T) {
+	p, _ := NewContainerParser(FormatDocker)
+
+	got := parseAll(p,
+		`{"log":"GET /cart 200\n","stream":"stdout","time":"2026-10-15T09:30:00.5Z"}`,
+		`{"log":"panic: out of ","stream":"stderr","time":"2026-10-15T09:30:01Z"}`,
+		`{"log":"memory\r\n","stream":"stderr","time":"2026-10-15T09:30:01Z"}`,
+	)
+	want := []string{"stdout:GET /cart 200", "stderr:panic: out of memory"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Parse() = %q, want %q", got, want)
+	}
+
+	r, _ := p.Parse(`{"log":"x\n","stream":"stdout","time":"2026-10-15T09:30:00.5Z"}`, time.Now())
+	if !r.Time.Equal(time.Date(2026, 10, 15, 9, 30, 0, 5e8, time.UTC)) {
+		t.Errorf("Parse() time = %v, want the time of the runtime", r.Time)
+	}
+}
+
+func TestContainerParser_Container(t *testing.T) {
+	p, _ := NewContainerParser(FormatContainer)
+
+	got := parseAll(p,
+		`{"log":"from docker\n","stream":"stdout","time":"2026-10-15T09:30:00Z"}`,
+		"2026-10-15T09:30:00Z stdout F from cri",
+		"not a container line",
+	)
+	want := []string{"stdout:from docker", "stdout:from cri", ":not a container line"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Parse() = %q, want %q", got, want)
+	}
+
+	// a format doesn't detect the other one
+	cri, _ := NewContainerParser(FormatCRI)
+	r, _ := cri.Parse(`{"log":"x\n","stream":"stdout","time":"2026-10-15T09:30:00Z"}`, time.Now())
+	if got := attrs(r); got["log"] != "x\n" {
+		t.Errorf("Parse() of a docker line as CRI attrs = %v, want it parsed as JSON", got)
+	}
+}
+
+func TestContainerParser_Flush(t *testing.T) {
+	p, _ := NewContainerParser(FormatCRI)
+	parseAll(p, "2026-10-15T09:30:00Z stdout P cut ", "2026-10-15T09:30:00Z stderr P short")
+
+	var got []string
+	for _, r := range p.Flush() {
+		got = append(got, attrs(r)[StreamKey]+":"+r.Message)
+	}
+	if want := []string{"stderr:short", "stdout:cut "}; !slices.Equal(got, want) {
+		t.Errorf("Flush() = %q, want %q", got, want)
+	}
+	if len(p.Flush()) != 0 {
+		t.Error("Flush() returned the partial output again")
+	}
+
+	long := strings.Repeat("x", maxLine/2)
+	if got := parseAll(p, "2026-10-15T09:30:00Z stdout P "+long, "2026-10-15T09:30:00Z stdout P "+long); len(got) != 1 {
+		t.Errorf("Parse() of partial output longer than a line returned %d records, want it forwarded", len(got))
+	}
+
+	if _, err := NewContainerParser(FormatJSON); err == nil {
+		t.Error("NewContainerParser() of a line format returned no error")
+	}
+}
+
+func TestTailer_Container(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shop-1_shop_php-0123abcd.log")
+
+	rec := &recorder{}
+	tl, err := New(Config{Paths: []string{filepath.Join(dir, "*.log")}, Format: FormatCRI, FromStart: true}, rec)
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+	appendFile(t, path, "2026-10-15T09:30:00Z stdout F started\n2026-10-15T09:30:01Z stdout P long \n")
+	poll(t, tl)
+	appendFile(t, path, "2026-10-15T09:30:01Z stdout F line\n2026-10-15T09:30:02Z stderr P stopped mid")
+	poll(t, tl)
+	appendFile(t, path, "-line\n")
+	poll(t, tl)
+	tl.Close(context.Background())
+
+	want := []string{filepath.Base(path) + ":started", filepath.Base(path) + ":long line", filepath.Base(path) + ":stopped mid-line"}
+	if got := rec.lines(); !slices.Equal(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+
+	rec = &recorder{}
+	tl, _ = New(Config{Paths: []string{filepath.Join(dir, "*.log")}, Format: FormatCRI}, rec)
+	poll(t, tl)
+	appendFile(t, path, "2026-10-15T09:30:03Z stderr P never finished\n")
+	poll(t, tl)
+	tl.Close(context.Background())
+	if got, want := rec.lines(), []string{filepath.Base(path) + ":never finished"}; !slices.Equal(got, want) {
+		t.Errorf("lines after closing = %q, want the partial output flushed", got)
+	}
+}
@@ -0,0 +1,74 @@
+package tailer
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// Policies for the fields of parsed lines the receiving schema doesn't know
+const (
+	// UnknownPass forwards unknown fields as they are
+	UnknownPass = "pass"
+	// UnknownDrop discards unknown fields
+	UnknownDrop = "drop"
+	// UnknownNest moves unknown fields into the UnknownGroup group, so a
+	// newer emitter can't change the mapping of fields of the index
+	UnknownNest = "nest"
+)
+
+// UnknownGroup is the group UnknownNest moves unknown fields into
+const UnknownGroup = "unknown"
+
+// levelOriginalKey keeps a level that couldn't be parsed, which is added by
+// the parsers and never unknown
+const levelOriginalKey = "level_original"
+
+// FieldPolicy decides what happens to the fields of parsed lines, so events
+// relayed from a fleet of mixed versions during an upgrade only reach the
+// index with the fields it expects. The message, level and time lifted onto
+// the record are never affected.
+type FieldPolicy struct {
+	// Unknown is UnknownPass (default), UnknownDrop or UnknownNest
+	Unknown string
+	// Known are the top-level fields kept as they are, e.g. the properties
+	// of logger.SchemaFields and those of the application
+	Known []string
+}
+
+// Validate reports whether p is a known policy
+func (p FieldPolicy) Validate() error {
+	switch p.Unknown {
+	case "", UnknownPass, UnknownDrop, UnknownNest:
+		return nil
+	default:
+		return fmt.Errorf("unknown field policy %q", p.Unknown)
+	}
+}
+
+// Apply returns r with the policy applied to its attributes
+func (p FieldPolicy) Apply(r slog.Record) slog.Record {
+	if p.Unknown != UnknownDrop && p.Unknown != UnknownNest {
+		return r
+	}
+
+	var known, unknown []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == levelOriginalKey || slices.Contains(p.Known, a.Key) {
+			known = append(known, a)
+		} else {
+			unknown = append(unknown, a)
+		}
+		return true
+	})
+	if len(unknown) == 0 {
+		return r
+	}
+
+	applied := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	applied.AddAttrs(known...)
+	if p.Unknown == UnknownNest {
+		applied.AddAttrs(slog.Attr{Key: UnknownGroup, Value: slog.GroupValue(unknown...)})
+	}
+	return applied
+}
@@ -0,0 +1,65 @@
+package tailer
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestFieldPolicy_Apply(t *testing.T) {
+	line := `{"message": "cart saved", "level": "notice", "type": "shop", "user": "42", "cart": {"items": 3}}`
+	known := []string{"type", "user"}
+
+	tests := []struct {
+		policy string
+		want   map[string]string
+	}{
+		{"", map[string]string{"type": "shop", "user": "42", "cart": "map[items:3]", levelOriginalKey: "notice"}},
+		{UnknownPass, map[string]string{"type": "shop", "user": "42", "cart": "map[items:3]", levelOriginalKey: "notice"}},
+		{UnknownDrop, map[string]string{"type": "shop", "user": "42", levelOriginalKey: "notice"}},
+		{UnknownNest, map[string]string{"type": "shop", "user": "42", levelOriginalKey: "notice", UnknownGroup: "[cart=map[items:3]]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			now := time.Now()
+			r := FieldPolicy{Unknown: tt.policy, Known: known}.Apply(ParseJSON(line, now))
+			if r.Message != "cart saved" || r.Level != slog.LevelInfo || !r.Time.Equal(now) {
+				t.Errorf("Apply() = %q at %v %v, want the lifted fields kept", r.Message, r.Level, r.Time)
+			}
+			got := attrs(r)
+			if len(got) != len(tt.want) {
+				t.Errorf("Apply() attrs = %v, want %v", got, tt.want)
+			}
+			for key, want := range tt.want {
+				if got[key] != want {
+					t.Errorf("Apply() attrs[%q] = %q, want %q", key, got[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestFieldPolicy_Validate(t *testing.T) {
+	for _, policy := range []string{"", UnknownPass, UnknownDrop, UnknownNest} {
+		if err := (FieldPolicy{Unknown: policy}).Validate(); err != nil {
+			t.Errorf("Validate() of %q returned unexpected error: %v", policy, err)
+		}
+	}
+
+	cfg := Config{Paths: []string{"*.log"}, Fields: FieldPolicy{Unknown: "keep"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() of an unknown field policy returned no error")
+	}
+}
+
+func TestNewParser_Fields(t *testing.T) {
+	parse, err := newParser(Config{Format: FormatRegex, Pattern: `^(?P<channel>\w+): (?P<message>.*)$`, Fields: FieldPolicy{Unknown: UnknownDrop}})
+	if err != nil {
+		t.Fatalf("newParser() returned unexpected error: %v", err)
+	}
+	r := parse("cron: finished", time.Now())
+	if got := attrs(r); r.Message != "finished" || len(got) != 0 {
+		t.Errorf("parse() = %q %v, want the unknown channel dropped", r.Message, got)
+	}
+}
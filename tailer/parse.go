@@ -0,0 +1,187 @@
+package tailer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Formats of the lines of a file
+const (
+	FormatPlain = "plain"
+	FormatJSON  = "json"
+	FormatRegex = "regex"
+)
+
+// Named groups of a Pattern lifted onto the record
+const (
+	groupMessage = "message"
+	groupLevel   = "level"
+	groupTime    = "time"
+)
+
+// parser converts a line into a record
+type parser func(line string, now time.Time) slog.Record
+
+// newParser returns the parser of the format of cfg, applying its field
+// policy
+func newParser(cfg Config) (parser, error) {
+	if err := cfg.Fields.Validate(); err != nil {
+		return nil, err
+	}
+	parse, err := formatParser(cfg)
+	if err != nil || cfg.Fields.Unknown == "" || cfg.Fields.Unknown == UnknownPass {
+		return parse, err
+	}
+	return func(line string, now time.Time) slog.Record {
+		return cfg.Fields.Apply(parse(line, now))
+	}, nil
+}
+
+// formatParser returns the parser of the format of cfg
+func formatParser(cfg Config) (parser, error) {
+	switch cfg.Format {
+	case "", FormatPlain:
+		return ParsePlain, nil
+	case FormatJSON:
+		return ParseJSON, nil
+	case FormatCRI, FormatDocker, FormatContainer:
+		// the output of the container, the files are read by a
+		// ContainerParser
+		return ParseJSON, nil
+	case FormatRegex:
+		if len(cfg.Pattern) == 0 {
+			return nil, fmt.Errorf("format %s requires a pattern", FormatRegex)
+		}
+		pattern, err := regexp.Compile(cfg.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %w", err)
+		}
+		layout := cfg.TimeLayout
+		if len(layout) == 0 {
+			layout = time.RFC3339Nano
+		}
+		return func(line string, now time.Time) slog.Record {
+			return parseRegex(pattern, layout, line, now)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", cfg.Format)
+	}
+}
+
+// ParsePlain returns line as the message of an info record stamped with now
+func ParsePlain(line string, now time.Time) slog.Record {
+	return slog.NewRecord(now, slog.LevelInfo, strings.TrimRight(line, "\r\n"), 0)
+}
+
+// ParseJSON converts a line into a record. JSON objects have their message,
+// level and time keys lifted onto the record and the remaining keys attached
+// as attributes; anything else is parsed by ParsePlain.
+func ParseJSON(line string, now time.Time) slog.Record {
+	line = strings.TrimRight(line, "\r\n")
+
+	fields := map[string]any{}
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	if !strings.HasPrefix(strings.TrimSpace(line), "{") || decoder.Decode(&fields) != nil {
+		return ParsePlain(line, now)
+	}
+
+	message := liftString(fields, "message", "msg")
+	level := parseLevel(liftString(fields, "level"), fields)
+	timestamp := now
+	if text := liftString(fields, "@timestamp", "time"); len(text) > 0 {
+		if parsed, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			timestamp = parsed
+		}
+	}
+
+	record := slog.NewRecord(timestamp, level, message, 0)
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		record.AddAttrs(slog.Any(key, fields[key]))
+	}
+
+	return record
+}
+
+// parseRegex converts a line matching pattern into a record whose message,
+// level and time are the named groups of the same names, parsing the time
+// with layout, and whose attributes are the other named groups. Lines not
+// matching pattern are parsed by ParsePlain.
+func parseRegex(pattern *regexp.Regexp, layout, line string, now time.Time) slog.Record {
+	line = strings.TrimRight(line, "\r\n")
+	match := pattern.FindStringSubmatch(line)
+	if match == nil {
+		return ParsePlain(line, now)
+	}
+
+	message, timestamp := line, now
+	fields := map[string]any{}
+	var levelText string
+	var attrs []slog.Attr
+	for i, name := range pattern.SubexpNames() {
+		switch name {
+		case "":
+		case groupMessage:
+			message = match[i]
+		case groupLevel:
+			levelText = match[i]
+		case groupTime:
+			if parsed, err := time.Parse(layout, match[i]); err == nil {
+				timestamp = parsed
+			}
+		default:
+			attrs = append(attrs, slog.String(name, match[i]))
+		}
+	}
+
+	record := slog.NewRecord(timestamp, parseLevel(levelText, fields), message, 0)
+	record.AddAttrs(attrs...)
+	for key, value := range fields {
+		record.AddAttrs(slog.Any(key, value))
+	}
+	return record
+}
+
+// parseLevel returns the level named text, info when it is empty. Unknown
+// levels are kept visible as level_original in fields rather than dropped.
+func parseLevel(text string, fields map[string]any) slog.Level {
+	level := slog.LevelInfo
+	if len(text) == 0 {
+		return level
+	}
+	if strings.EqualFold(text, "warning") {
+		return slog.LevelWarn
+	}
+	if err := level.UnmarshalText([]byte(text)); err != nil {
+		fields[levelOriginalKey] = text
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// liftString removes the first of keys present in fields and returns its
+// value as a string
+func liftString(fields map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := fields[key]; ok {
+			delete(fields, key)
+			if text, ok := value.(string); ok {
+				return text
+			}
+			encoded, _ := json.Marshal(value)
+			return string(encoded)
+		}
+	}
+	return ""
+}
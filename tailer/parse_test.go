@@ -0,0 +1,90 @@
+package tailer
+
+import (
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// attrs returns the attributes of r as strings
+func attrs(r slog.Record) map[string]string {
+	out := map[string]string{}
+	r.Attrs(func(a slog.Attr) bool {
+		out[a.Key] = a.Value.String()
+		return true
+	})
+	return out
+}
+
+func TestParseJSON(t *testing.T) {
+	now := time.Now()
+	r := ParseJSON(`{"msg": "db down", "level": "warning", "time": "2026-10-15T09:30:00Z", "db": "postgres"}`, now)
+	if r.Message != "db down" || r.Level != slog.LevelWarn || !r.Time.Equal(time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("ParseJSON() = %q at %v %v, want the message, level and time lifted", r.Message, r.Level, r.Time)
+	}
+	if got := attrs(r); len(got) != 1 || got["db"] != "postgres" {
+		t.Errorf("ParseJSON() attrs = %v, want the other keys", got)
+	}
+
+	if r := ParseJSON("plain text\n", now); r.Message != "plain text" || !r.Time.Equal(now) {
+		t.Errorf("ParseJSON() of plain text = %q at %v, want the line at now", r.Message, r.Time)
+	}
+}
+
+func TestParseRegex(t *testing.T) {
+	pattern := regexp.MustCompile(`^\[(?P<time>[^\]]+)\] (?P<channel>\w+)\.(?P<level>\w+): (?P<message>.*)$`)
+	layout := "2006-01-02 15:04:05"
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		line    string
+		message string
+		level   slog.Level
+		time    time.Time
+		attrs   map[string]string
+	}{
+		{
+			name:    "match",
+			line:    "[2026-10-15 09:30:00] app.ERROR: Payment failed",
+			message: "Payment failed",
+			level:   slog.LevelError,
+			time:    time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC),
+			attrs:   map[string]string{"channel": "app"},
+		},
+		{
+			name:    "unknown level",
+			line:    "[2026-10-15 09:30:00] app.NOTICE: Cache cleared",
+			message: "Cache cleared",
+			level:   slog.LevelInfo,
+			time:    time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC),
+			attrs:   map[string]string{"channel": "app", "level_original": "NOTICE"},
+		},
+		{
+			name:    "no match",
+			line:    "Stack trace:",
+			message: "Stack trace:",
+			level:   slog.LevelInfo,
+			time:    now,
+			attrs:   map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := parseRegex(pattern, layout, tt.line, now)
+			if r.Message != tt.message || r.Level != tt.level || !r.Time.Equal(tt.time) {
+				t.Errorf("parseRegex() = %q at %v %v, want %q at %v %v", r.Message, r.Level, r.Time, tt.message, tt.level, tt.time)
+			}
+			got := attrs(r)
+			if len(got) != len(tt.attrs) {
+				t.Errorf("parseRegex() attrs = %v, want %v", got, tt.attrs)
+			}
+			for key, value := range tt.attrs {
+				if got[key] != value {
+					t.Errorf("parseRegex() attrs = %v, want %v", got, tt.attrs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+// Package tailer follows log files and forwards their lines through a slog
+// handler, such as the Lagoon handler of the go-lagoon-log-forwarder
+// package, so applications that only write files can be forwarded by a
+// sidecar. Files are found by glob patterns and followed across rotation by
+// their identity (device and inode), and lines are parsed as plain text,
+// JSON, with a regular expression or as the log files of container runtimes.
+package tailer
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// FileKey is the attribute holding the path of the file a line was read from
+const FileKey = "file"
+
+// maxLine is the longest line buffered, longer ones are forwarded in parts
+const maxLine = 1 << 20
+
+// readSize is how much of a file is read at once
+const readSize = 64 << 10
+
+// Config configures a Tailer
+type Config struct {
+	// Paths are the glob patterns of the files to follow, checked again on
+	// every poll so files created later are followed too
+	Paths []string
+	// Format is FormatPlain (default), FormatJSON, FormatRegex or one of the
+	// container log formats FormatCRI, FormatDocker and FormatContainer
+	Format string
+	// Pattern is the regular expression of FormatRegex. Its named groups
+	// message, level and time are lifted onto the record, the others are
+	// added as attributes. TimeLayout parses the time group, RFC 3339 when
+	// empty.
+	Pattern    string
+	TimeLayout string
+	// PollInterval is how often the files are read, 1s when 0
+	PollInterval time.Duration
+	// FromStart forwards the files present when the tailer starts from
+	// their beginning rather than their end. Files found later are always
+	// read from their beginning.
+	FromStart bool
+	// Fields is the policy for the fields of lines that the receiving
+	// schema doesn't know, which are passed on when it is empty
+	Fields FieldPolicy
+}
+
+// followed is a file being read. Its descriptor is kept open, so the rest of
+// a file renamed by rotation is still read.
+type followed struct {
+	path    string
+	f       *os.File
+	info    fs.FileInfo
+	offset  int64
+	partial []byte
+	// container joins the partial lines of the container log formats
+	container *ContainerParser
+}
+
+// Tailer follows the files matching its patterns
+type Tailer struct {
+	cfg     Config
+	handler slog.Handler
+	parse   parser
+
+	files   map[string]*followed
+	started bool
+	// failed counts the records the handler failed to forward since they
+	// were last reported, the first error is kept
+	failed  int
+	failure error
+}
+
+// Validate reports the first invalid setting of c
+func (c Config) Validate() error {
+	if len(c.Paths) == 0 {
+		return errors.New("no paths to follow")
+	}
+	for _, pattern := range c.Paths {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("path %q: %w", pattern, err)
+		}
+	}
+	if c.PollInterval < 0 {
+		return errors.New("poll interval must not be negative")
+	}
+	_, err := newParser(c)
+	return err
+}
+
+// New returns a tailer forwarding the lines of the files of cfg to h
+func New(cfg Config, h slog.Handler) (*Tailer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = time.Second
+	}
+	parse, _ := newParser(cfg)
+	return &Tailer{cfg: cfg, handler: h, parse: parse, files: map[string]*followed{}}, nil
+}
+
+// Run polls the files until ctx is done, then closes the tailer
+func (t *Tailer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.cfg.PollInterval)
+	defer ticker.Stop()
+	defer t.Close(context.WithoutCancel(ctx))
+
+	for {
+		if err := t.Poll(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll forwards the lines written since the last poll. Files that were
+// rotated are read to their end before the file replacing them is read.
+// Records the handler fails to forward are skipped and reported in a warning
+// of the default logger, so the lines after them are still forwarded.
+func (t *Tailer) Poll(ctx context.Context) error {
+	defer t.report()
+
+	paths, err := t.glob()
+	if err != nil {
+		return err
+	}
+	matched := make(map[string]fs.FileInfo, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			matched[path] = info
+		}
+	}
+
+	// the followed files are drained first, so the lines of a rotated file
+	// come before those of the new one
+	for _, path := range slices.Sorted(maps.Keys(t.files)) {
+		file := t.files[path]
+		if err := t.read(ctx, file); err != nil {
+			return err
+		}
+		if info, ok := matched[path]; ok && os.SameFile(file.info, info) {
+			continue
+		}
+		// rotated: the file is followed under its new name if it still
+		// matches, otherwise it is done
+		delete(t.files, path)
+		if renamed, ok := t.renamed(file, matched); ok {
+			file.path = renamed
+			t.files[renamed] = file
+			continue
+		}
+		t.finish(ctx, file)
+	}
+
+	for _, path := range paths {
+		info, ok := matched[path]
+		if _, followed := t.files[path]; followed || !ok {
+			continue
+		}
+		file, err := t.open(path, info)
+		if err != nil {
+			slog.Default().Warn("Failed to follow log file", "path", path, "error", err)
+			continue
+		}
+		t.files[path] = file
+		if err := t.read(ctx, file); err != nil {
+			return err
+		}
+	}
+	t.started = true
+	return nil
+}
+
+// report warns about the records the handler failed to forward since the
+// last report
+func (t *Tailer) report() {
+	if t.failed == 0 {
+		return
+	}
+	slog.Default().Warn("Failed to forward log lines", "failed", t.failed, "error", t.failure)
+	t.failed, t.failure = 0, nil
+}
+
+// glob returns the sorted paths matching the patterns
+func (t *Tailer) glob() ([]string, error) {
+	var paths []string
+	for _, pattern := range t.cfg.Paths {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths), nil
+}
+
+// renamed returns the matched path of file after it was renamed
+func (t *Tailer) renamed(file *followed, matched map[string]fs.FileInfo) (string, bool) {
+	for path, info := range matched {
+		if _, taken := t.files[path]; !taken && os.SameFile(file.info, info) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// open starts following path. Files present at the first poll are read from
+// their end unless FromStart is set.
+func (t *Tailer) open(path string, info fs.FileInfo) (*followed, error) {
+	f, err := os.Open(path) // #nosec G304 -- paths match the patterns supplied by the operator
+	if err != nil {
+		return nil, err
+	}
+	file := &followed{path: path, f: f, info: info}
+	if slices.Contains(containerFormats, t.cfg.Format) {
+		file.container = newContainerParser(t.cfg.Format, t.parse)
+	}
+	if !t.started && !t.cfg.FromStart {
+		if file.offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// read forwards the lines appended to file since it was last read, starting
+// over when it was truncated. The file is read in chunks of readSize, so a
+// large backlog is forwarded without being held in memory at once.
+func (t *Tailer) read(ctx context.Context, file *followed) error {
+	if info, err := file.f.Stat(); err == nil && info.Size() < file.offset {
+		if _, err := file.f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		file.offset, file.partial = 0, nil
+	}
+
+	buf := make([]byte, readSize)
+	for {
+		n, err := file.f.Read(buf)
+		if n > 0 {
+			file.offset += int64(n)
+			t.lines(ctx, file, buf[:n])
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// lines forwards the lines completed by data, keeping the rest of data as
+// the partial line of file
+func (t *Tailer) lines(ctx context.Context, file *followed, data []byte) {
+	data = append(file.partial, data...)
+	for {
+		line, rest, ok := bytes.Cut(data, []byte{'\n'})
+		if !ok {
+			break
+		}
+		t.forward(ctx, file, line)
+		data = rest
+	}
+	if len(data) >= maxLine {
+		t.forward(ctx, file, data)
+		data = nil
+	}
+	file.partial = append(file.partial[:0], data...)
+}
+
+// forward handles the record of line unless it is empty
+func (t *Tailer) forward(ctx context.Context, file *followed, line []byte) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	if file.container == nil {
+		t.handle(ctx, file, t.parse(string(line), time.Now()))
+		return
+	}
+	if r, ok := file.container.Parse(string(line), time.Now()); ok {
+		t.handle(ctx, file, r)
+	}
+}
+
+// handle forwards the record of a line of file, counting a failure
+func (t *Tailer) handle(ctx context.Context, file *followed, r slog.Record) {
+	if !t.handler.Enabled(ctx, r.Level) {
+		return
+	}
+	r.AddAttrs(slog.String(FileKey, file.path))
+	if err := t.handler.Handle(ctx, r); err != nil {
+		t.failed++
+		t.failure = cmp.Or(t.failure, err)
+	}
+}
+
+// finish forwards the line file was left with and closes it
+func (t *Tailer) finish(ctx context.Context, file *followed) {
+	if len(file.partial) > 0 {
+		t.forward(ctx, file, file.partial)
+	}
+	if file.container != nil {
+		for _, r := range file.container.Flush() {
+			t.handle(ctx, file, r)
+		}
+	}
+	_ = file.f.Close()
+}
+
+// Close forwards the lines left without a newline, and the partial lines of
+// the container formats still waiting for their last part, then closes the
+// files. Run closes the tailer when it returns, one that is only polled must
+// be closed once it is done.
+func (t *Tailer) Close(ctx context.Context) {
+	defer t.report()
+	for path, file := range t.files {
+		t.finish(ctx, file)
+		delete(t.files, path)
+	}
+}
@@ -0,0 +1,261 @@
+package tailer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects the records handled
+type recorder struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (r *recorder) Enabled(context.Context, slog.Level) bool { return true }
+func (r *recorder) WithAttrs([]slog.Attr) slog.Handler       { return r }
+func (r *recorder) WithGroup(string) slog.Handler            { return r }
+
+func (r *recorder) Handle(_ context.Context, record slog.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, record.Clone())
+	return nil
+}
+
+// lines returns the messages handled, each with the base name of its file
+func (r *recorder) lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var lines []string
+	for _, record := range r.records {
+		record.Attrs(func(a slog.Attr) bool {
+			if a.Key == FileKey {
+				lines = append(lines, filepath.Base(a.Value.String())+":"+record.Message)
+			}
+			return true
+		})
+	}
+	return lines
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func poll(t *testing.T, tl *Tailer) {
+	t.Helper()
+	if err := tl.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll() returned unexpected error: %v", err)
+	}
+}
+
+func TestTailer_FollowsGlobs(t *testing.T) {
+	dir := t.TempDir()
+	appendFile(t, filepath.Join(dir, "app.log"), "before start\n")
+
+	rec := &recorder{}
+	tl, err := New(Config{Paths: []string{filepath.Join(dir, "*.log")}}, rec)
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+	poll(t, tl)
+	appendFile(t, filepath.Join(dir, "app.log"), "first\nsec")
+	appendFile(t, filepath.Join(dir, "worker.log"), "created later\n")
+	appendFile(t, filepath.Join(dir, "ignored.txt"), "not matched\n")
+	poll(t, tl)
+	appendFile(t, filepath.Join(dir, "app.log"), "ond\n\n")
+	poll(t, tl)
+
+	want := "app.log:first|worker.log:created later|app.log:second"
+	if got := strings.Join(rec.lines(), "|"); got != want {
+		t.Errorf("forwarded %q, want %q", got, want)
+	}
+}
+
+func TestTailer_FromStart(t *testing.T) {
+	dir := t.TempDir()
+	appendFile(t, filepath.Join(dir, "app.log"), "before start\n")
+
+	rec := &recorder{}
+	tl, err := New(Config{Paths: []string{filepath.Join(dir, "app.log")}, FromStart: true}, rec)
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+	poll(t, tl)
+	if got := strings.Join(rec.lines(), "|"); got != "app.log:before start" {
+		t.Errorf("forwarded %q, want the lines written before the start", got)
+	}
+}
+
+func TestTailer_Chunks(t *testing.T) {
+	dir := t.TempDir()
+	long := strings.Repeat("x", readSize+10)
+	appendFile(t, filepath.Join(dir, "app.log"), "first\n"+long+"\nlast\n")
+
+	rec := &recorder{}
+	tl, _ := New(Config{Paths: []string{filepath.Join(dir, "app.log")}, FromStart: true}, rec)
+	poll(t, tl)
+	want := "app.log:first|app.log:" + long + "|app.log:last"
+	if got := strings.Join(rec.lines(), "|"); got != want {
+		t.Errorf("forwarded %d bytes, want the line across chunks kept whole", len(got))
+	}
+}
+
+func TestTailer_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "")
+
+	rec := &recorder{}
+	tl, err := New(Config{Paths: []string{filepath.Join(dir, "app.log*")}}, rec)
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+	poll(t, tl)
+
+	// renamed to a matching name, then written to once more before the
+	// application reopens its file
+	appendFile(t, path, "before rotation\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path+".1", "late write\n")
+	appendFile(t, path, "after rotation\n")
+	poll(t, tl)
+
+	// renamed away from the patterns
+	if err := os.Rename(path, filepath.Join(dir, "archived")); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "new file\n")
+	poll(t, tl)
+
+	// truncated in place
+	appendFile(t, path, "more\n")
+	poll(t, tl)
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "truncated\n")
+	poll(t, tl)
+
+	// the rest of a rotated file is forwarded under the name it was
+	// followed by
+	want := "app.log:before rotation|app.log:late write|app.log:after rotation|app.log:new file|app.log:more|app.log:truncated"
+	if got := strings.Join(rec.lines(), "|"); got != want {
+		t.Errorf("forwarded %q, want %q", got, want)
+	}
+}
+
+func TestTailer_Run(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "")
+
+	rec := &recorder{}
+	tl, err := New(Config{Paths: []string{path}, PollInterval: 5 * time.Millisecond}, rec)
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tl.Run(ctx) }()
+
+	time.Sleep(20 * time.Millisecond)
+	appendFile(t, path, "complete\nwithout newline")
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run() returned unexpected error: %v", err)
+	}
+	if got := strings.Join(rec.lines(), "|"); got != "app.log:complete|app.log:without newline" {
+		t.Errorf("forwarded %q, want the last line forwarded once Run returns", got)
+	}
+}
+
+// failing is a recorder failing to handle the records of one message
+type failing struct {
+	recorder
+	message string
+}
+
+func (f *failing) Handle(ctx context.Context, record slog.Record) error {
+	if record.Message == f.message {
+		return errors.New("endpoint went away")
+	}
+	return f.recorder.Handle(ctx, record)
+}
+
+func TestTailer_HandleErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	rec := &failing{message: "lost"}
+	tl, err := New(Config{Paths: []string{path}, FromStart: true}, rec)
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+	appendFile(t, path, "first\nlost\nafter\n")
+	poll(t, tl)
+	appendFile(t, path, "next\n")
+	poll(t, tl)
+
+	if got := strings.Join(rec.lines(), "|"); got != "app.log:first|app.log:after|app.log:next" {
+		t.Errorf("forwarded %q, want the lines after the failed one forwarded", got)
+	}
+}
+
+func TestTailer_Close(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	rec := &recorder{}
+	tl, err := New(Config{Paths: []string{path}, FromStart: true}, rec)
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+	appendFile(t, path, "complete\nwithout newline")
+	poll(t, tl)
+	if got := strings.Join(rec.lines(), "|"); got != "app.log:complete" {
+		t.Fatalf("forwarded %q before Close, want the complete line", got)
+	}
+	tl.Close(context.Background())
+
+	if got := strings.Join(rec.lines(), "|"); got != "app.log:complete|app.log:without newline" {
+		t.Errorf("forwarded %q, want the last line forwarded by Close", got)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		cfg  Config
+	}{
+		{"no paths", Config{}},
+		{"bad pattern", Config{Paths: []string{"["}}},
+		{"unknown format", Config{Paths: []string{"*.log"}, Format: "xml"}},
+		{"regex without pattern", Config{Paths: []string{"*.log"}, Format: FormatRegex}},
+		{"invalid regex", Config{Paths: []string{"*.log"}, Format: FormatRegex, Pattern: "("}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg, &recorder{}); err == nil {
+				t.Error("New() returned no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// TLSConfig secures the TCP connection to a Logstash tcp input with ssl
+// enabled. The files are read again for every new connection, so rotated
+// certificates are picked up when the forwarder reconnects without
+// restarting the application.
+type TLSConfig struct {
+	CAFile             string `json:"caFile"`             // PEM bundle verifying the endpoint, system roots when empty
+	CertFile           string `json:"certFile"`           // PEM client certificate for mutual TLS
+	KeyFile            string `json:"keyFile"`            // PEM key of CertFile
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"` // accept any endpoint certificate, for testing only
+	ServerName         string `json:"serverName"`         // name verified in the endpoint certificate, LogHost when empty
+}
+
+func (c *TLSConfig) validate() error {
+	if (len(c.CertFile) == 0) != (len(c.KeyFile) == 0) {
+		return errors.New("tls.certFile and tls.keyFile must be set together")
+	}
+	return nil
+}
+
+// ClientConfig reads the certificates into the client config the forwarder
+// connects to host with, for tools checking an endpoint the same way
+func (c *TLSConfig) ClientConfig(host string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if len(config.ServerName) == 0 {
+		config.ServerName = host
+	}
+
+	if len(c.CAFile) > 0 {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read tls ca: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("read tls ca: no certificates in %s", c.CAFile)
+		}
+	}
+
+	if len(c.CertFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read tls certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// dialTLS opens a TCP connection to host:port and completes the TLS handshake
+// within the dial timeout
+func dialTLS(ctx context.Context, host, addr string, port int, writeTimeout time.Duration, settings *TLSConfig) (net.Conn, error) {
+
+	config, err := settings.ClientConfig(host)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: dialTimeout, KeepAlive: tcpKeepAlive},
+		Config:    config,
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(addr, strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("dial tls: %w", err)
+	}
+
+	if writeTimeout <= 0 {
+		return conn, nil
+	}
+	return &deadlineConn{Conn: conn, timeout: writeTimeout}, nil
+}
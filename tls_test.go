@@ -0,0 +1,271 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of a leaf named name, valid for
+// 127.0.0.1
+func (ca *testCA) issue(t *testing.T, name string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tlsLine is a line received by a test TLS endpoint with the name of the
+// client certificate it was sent with
+type tlsLine struct {
+	client string
+	text   string
+}
+
+// listenTLS starts a TLS endpoint signed by ca, requiring client certificates
+// from it when mutual is set
+func listenTLS(t *testing.T, ca *testCA, mutual bool) (int, <-chan tlsLine) {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, "logstash")
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if mutual {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = x509.NewCertPool()
+		config.ClientCAs.AddCert(ca.cert)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	lines := make(chan tlsLine, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				tlsConn := conn.(*tls.Conn)
+				if err := tlsConn.Handshake(); err != nil {
+					return
+				}
+				var client string
+				if peers := tlsConn.ConnectionState().PeerCertificates; len(peers) > 0 {
+					client = peers[0].Subject.CommonName
+				}
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- tlsLine{client, scanner.Text()}
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, lines
+}
+
+// sendTLS dials the endpoint with cfg and writes one line
+func sendTLS(t *testing.T, cfg Config, line string) error {
+	t.Helper()
+	conn, err := Dial(cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(line + "\n"))
+	return err
+}
+
+func receiveTLS(t *testing.T, lines <-chan tlsLine) tlsLine {
+	t.Helper()
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(2 * time.Second):
+		t.Fatal("no line received over tls")
+		return tlsLine{}
+	}
+}
+
+func tlsTestConfig(port int, settings *TLSConfig) Config {
+	cfg := NewConfig()
+	cfg.Protocol = ProtocolTCP
+	cfg.LogHost = "127.0.0.1"
+	cfg.LogPort = port
+	cfg.TLS = settings
+	return cfg
+}
+
+func TestDial_TLS(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	writeFile(t, caFile, ca.pem)
+
+	port, lines := listenTLS(t, ca, false)
+
+	if err := sendTLS(t, tlsTestConfig(port, &TLSConfig{CAFile: caFile}), `{"seq":1}`); err != nil {
+		t.Fatalf("Dial() returned unexpected error: %v", err)
+	}
+	if line := receiveTLS(t, lines); line.text != `{"seq":1}` {
+		t.Errorf("received %q, want %q", line.text, `{"seq":1}`)
+	}
+}
+
+func TestDial_TLSVerification(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	otherCAFile := filepath.Join(dir, "other.pem")
+	writeFile(t, caFile, ca.pem)
+	writeFile(t, otherCAFile, newTestCA(t).pem)
+
+	port, lines := listenTLS(t, ca, false)
+
+	tests := []struct {
+		name     string
+		settings *TLSConfig
+		wantErr  bool
+	}{
+		{"system roots", &TLSConfig{}, true},
+		{"other ca", &TLSConfig{CAFile: otherCAFile}, true},
+		{"wrong server name", &TLSConfig{CAFile: caFile, ServerName: "logs.example.com"}, true},
+		{"insecure skip verify", &TLSConfig{InsecureSkipVerify: true}, false},
+		{"missing ca file", &TLSConfig{CAFile: filepath.Join(dir, "missing.pem")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sendTLS(t, tlsTestConfig(port, tt.settings), "{}")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Dial() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				receiveTLS(t, lines)
+			}
+		})
+	}
+}
+
+func TestDial_TLSClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	writeFile(t, caFile, ca.pem)
+
+	port, lines := listenTLS(t, ca, true)
+	settings := &TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+
+	// files are read for every connection, so a rotated certificate is used
+	// by the next one
+	for _, name := range []string{"client-v1", "client-v2"} {
+		certPEM, keyPEM := ca.issue(t, name)
+		writeFile(t, certFile, certPEM)
+		writeFile(t, keyFile, keyPEM)
+
+		if err := sendTLS(t, tlsTestConfig(port, settings), "{}"); err != nil {
+			t.Fatalf("Dial() returned unexpected error: %v", err)
+		}
+		if line := receiveTLS(t, lines); line.client != name {
+			t.Errorf("client certificate = %q, want %q", line.client, name)
+		}
+	}
+}
+
+func TestDial_TLSRequiresClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	writeFile(t, caFile, ca.pem)
+
+	port, lines := listenTLS(t, ca, true)
+
+	// TLS 1.3 reports a rejected client certificate on the first read, so
+	// the endpoint must not receive the line
+	_ = sendTLS(t, tlsTestConfig(port, &TLSConfig{CAFile: caFile}), "{}")
+	select {
+	case line := <-lines:
+		t.Errorf("endpoint received %q without a client certificate", line.text)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
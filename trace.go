@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// traceEnv turns tracing on without changing the application, like
+// Config.Trace
+const traceEnv = "LAGOON_LOGS_DEBUG"
+
+// tracing reports whether the decisions of Initialize are traced
+var tracing atomic.Bool
+
+// traceRequested reports whether cfg or the environment asks for tracing
+func traceRequested(cfg Config) bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(traceEnv))
+	return cfg.Trace || enabled
+}
+
+// trace writes a decision of the forwarder to the diagnostics when tracing
+// is on
+func trace(msg string, args ...any) {
+	if tracing.Load() {
+		diag().Info("trace: "+msg, args...)
+	}
+}
+
+// traceEnvironment reports the environment variables FromEnv applied to c
+// and whether the log type follows the Lagoon namespace. FromEnv runs before
+// the config is applied, so c decides whether to trace.
+func traceEnvironment(c Config, applied []string, lagoon bool) {
+	if !traceRequested(c) {
+		return
+	}
+
+	args := []any{"variables", applied}
+	if lagoon {
+		args = append(args, "lagoon_log_type", c.LogType)
+	}
+	diag().Info("trace: Read configuration from the environment", args...)
+}
+
+// traceConfig reports the configuration applied and the build-time defaults
+// it started from
+func traceConfig() {
+	if !tracing.Load() {
+		return
+	}
+
+	trace("Applied configuration",
+		"protocol", protocol,
+		"log_host", logHost,
+		"log_port", logPort,
+		"log_type", logType,
+		"level", level,
+		"build_log_host", buildLogHost,
+		"build_log_port", buildLogPort,
+		"build_log_type_prefix", buildLogTypePrefix,
+	)
+}
+
+// traceResolve reports the addresses host resolves to when the dialer
+// resolves it, as the dial only reports the one it connected to
+func traceResolve(ctx context.Context, host string) {
+	if !tracing.Load() {
+		return
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	traceResolved(host, addrs, err)
+}
+
+// traceResolved reports the result of looking host up
+func traceResolved(host string, addrs []string, err error) {
+	if err != nil {
+		trace("Failed to resolve log host", "log_host", host, "error", err)
+		return
+	}
+	trace("Resolved log host", "log_host", host, "addresses", addrs)
+}
+
+// traceDial reports a dial to the endpoint that started at start
+func traceDial(protocol, host string, port int, start time.Time, conn net.Conn, err error) {
+	if !tracing.Load() {
+		return
+	}
+
+	args := []any{"protocol", protocol, "address", net.JoinHostPort(host, strconv.Itoa(port)), "duration", time.Since(start)}
+	if err != nil {
+		trace("Failed to dial log endpoint", append(args, "error", err)...)
+		return
+	}
+	trace("Dialled log endpoint", append(args, "remote_address", conn.RemoteAddr().String())...)
+}
+
+// traceSinks reports where records are written
+func traceSinks(connected bool) {
+	if !tracing.Load() {
+		return
+	}
+
+	trace("Chose sinks",
+		"stdout_format", resolveFormat(stdoutFormat),
+		"stdout_level", stdoutLevel,
+		"forward_level", forwardLevel,
+		"connected", connected,
+		"delivery_workers", deliveryWorkers,
+		"batch_size", batchSize,
+		"spool_dir", spoolDir,
+		"destinations", len(destinations),
+		"fallback_hosts", len(fallbackHosts),
+	)
+}
+
+// resolveFormat returns format, FormatJSON when it is empty
+func resolveFormat(format string) string {
+	if len(format) == 0 {
+		return FormatJSON
+	}
+	return format
+}
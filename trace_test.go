@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+func TestTrace(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	receiver, err := loggertest.Listen(loggertest.UDP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer receiver.Close()
+
+	// the environment is read before the config applies its diagnostics
+	diagnosed := &capturedDiagnostics{}
+	handler := slog.NewJSONHandler(diagnosed, nil)
+	setDiagnostics(handler)
+
+	t.Setenv(traceEnv, "true")
+	t.Setenv("LOGGER_TYPE", "trace-type")
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("NewConfigFromEnv() returned unexpected error: %v", err)
+	}
+	cfg.LogHost = receiver.Host()
+	cfg.LogPort = receiver.Port()
+	cfg.Diagnostics = handler
+
+	if _, err := NewHandler(cfg); err != nil {
+		t.Fatalf("NewHandler() returned unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		`"trace: Read configuration from the environment"`,
+		`"LOGGER_TYPE"`,
+		`"trace: Applied configuration"`,
+		`"trace: Resolved log host"`,
+		`"trace: Dialled log endpoint"`,
+		`"trace: Chose sinks"`,
+	} {
+		if !strings.Contains(diagnosed.String(), want) {
+			t.Errorf("diagnostics are missing %s:\n%s", want, diagnosed.String())
+		}
+	}
+}
+
+func TestTrace_Off(t *testing.T) {
+	preserveConfig(t)
+
+	diagnosed := &capturedDiagnostics{}
+	cfg := NewConfig()
+	cfg.LogType = "trace-type"
+	cfg.Diagnostics = slog.NewJSONHandler(diagnosed, nil)
+	if _, err := NewWriterHandler(cfg, &strings.Builder{}); err != nil {
+		t.Fatalf("NewWriterHandler() returned unexpected error: %v", err)
+	}
+
+	if strings.Contains(diagnosed.String(), "trace:") {
+		t.Errorf("diagnostics = %s, want no trace without Trace", diagnosed.String())
+	}
+}
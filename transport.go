@@ -0,0 +1,163 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Transport protocols of the forwarder connection
+const (
+	// ProtocolUDP sends every record as a datagram, dropping it silently when
+	// the network or Logstash is overloaded
+	ProtocolUDP = "udp"
+	// ProtocolTCP sends records as newline delimited JSON over a stream, for
+	// a Logstash tcp input with the json_lines codec
+	ProtocolTCP = "tcp"
+	// ProtocolUnix sends every record as a datagram to the unix socket at
+	// LogHost, such as the /dev/log of a syslog daemon
+	ProtocolUnix = "unix"
+	// ProtocolHTTP POSTs records as newline delimited JSON to a Logstash
+	// http input or the Elasticsearch bulk API, for networks blocking UDP
+	ProtocolHTTP = "http"
+	// ProtocolForward sends records over the Fluent forward protocol, for a
+	// Fluentd or Fluent Bit forward input
+	ProtocolForward = "forward"
+	// ProtocolOTLP exports records as OpenTelemetry log records over
+	// OTLP/HTTP or OTLP/gRPC, for an OpenTelemetry collector
+	ProtocolOTLP = "otlp"
+)
+
+const (
+	// dialTimeout bounds connecting to a TCP endpoint
+	dialTimeout = 5 * time.Second
+	// tcpKeepAlive is the keep-alive period of TCP connections, so idle
+	// connections dropped by a load balancer are noticed
+	tcpKeepAlive = 30 * time.Second
+)
+
+// endpointSettings are the settings of an endpoint specific to its protocol
+type endpointSettings struct {
+	tls     *TLSConfig
+	http    *HTTPConfig
+	forward *ForwardConfig
+	otlp    *OTLPConfig
+}
+
+// forwarderSettings returns the endpoint settings of the applied config
+func forwarderSettings() endpointSettings {
+	return endpointSettings{tls: tlsSettings, http: httpSettings, forward: fluentSettings, otlp: otlpSettings}
+}
+
+// endpointSettings returns the settings of the endpoint of c
+func (c Config) endpointSettings() endpointSettings {
+	return endpointSettings{tls: c.TLS, http: c.HTTP, forward: c.Forward, otlp: c.OTLP}
+}
+
+// dialEndpoint opens a connection to host:port over protocol, configured by
+// settings and secured when they include TLS. A resolver looks host up
+// first, and its addresses are tried in order; otherwise the dialer resolves
+// host.
+func dialEndpoint(protocol, host string, port int, writeTimeout time.Duration, settings endpointSettings, resolver Resolver) (net.Conn, error) {
+	return dialEndpointContext(context.Background(), protocol, host, port, writeTimeout, settings, resolver)
+}
+
+// dialEndpointContext is dialEndpoint with name resolution and connecting
+// abandoned once ctx is done
+func dialEndpointContext(ctx context.Context, protocol, host string, port int, writeTimeout time.Duration, settings endpointSettings, resolver Resolver) (net.Conn, error) {
+	if t, ok := registeredTransport(protocol); ok {
+		return dialTransport(ctx, t, protocol, host, port, writeTimeout, settings.tls)
+	}
+	if protocol == ProtocolForward {
+		return dialForward(ctx, host, port, writeTimeout, settings.tls, settings.forward, resolver)
+	}
+	if protocol == ProtocolHTTP {
+		start := time.Now()
+		conn, err := dialHTTP(ctx, host, port, writeTimeout, settings.tls, settings.http, resolver)
+		traceDial(protocol, host, port, start, conn, err)
+		return conn, err
+	}
+	if protocol == ProtocolOTLP {
+		start := time.Now()
+		conn, err := dialOTLP(ctx, host, port, writeTimeout, settings.tls, settings.otlp, resolver)
+		traceDial(protocol, host, port, start, conn, err)
+		return conn, err
+	}
+	if protocol == ProtocolUnix {
+		start := time.Now()
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "unixgram", host)
+		traceDial(protocol, host, 0, start, conn, err)
+		if err != nil {
+			return nil, fmt.Errorf("dial unix: %w", err)
+		}
+		return conn, nil
+	}
+
+	addrs := []string{host}
+	if resolver != nil {
+		resolved, err := resolver.LookupHost(ctx, host)
+		traceResolved(host, resolved, err)
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s: %w", host, err)
+		}
+		addrs = resolved
+	} else {
+		traceResolve(ctx, host)
+	}
+
+	var conn net.Conn
+	var err error
+	for _, addr := range addrs {
+		start := time.Now()
+		switch {
+		case protocol == ProtocolTCP && settings.tls != nil:
+			// the certificate is verified against the host, not the address
+			conn, err = dialTLS(ctx, host, addr, port, writeTimeout, settings.tls)
+		case protocol == ProtocolTCP:
+			conn, err = dialTCP(ctx, addr, port, writeTimeout)
+		default:
+			conn, err = dialUDP(ctx, addr, port)
+		}
+
+		traceDial(protocol, addr, port, start, conn, err)
+		if err == nil {
+			return conn, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("resolve %s: no addresses", host)
+	}
+	return nil, err
+}
+
+func dialTCP(ctx context.Context, host string, port int, writeTimeout time.Duration) (net.Conn, error) {
+
+	dialer := net.Dialer{Timeout: dialTimeout, KeepAlive: tcpKeepAlive}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		diag().Error("Failed to dial tcp")
+		return nil, fmt.Errorf("dial tcp: %w", err)
+	}
+
+	if writeTimeout <= 0 {
+		return conn, nil
+	}
+	return &deadlineConn{Conn: conn, timeout: writeTimeout}, nil
+}
+
+// deadlineConn fails writes that take longer than timeout, so a stalled
+// endpoint can't block logging indefinitely
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Write(p []byte) (int, error) {
+	if err := c.Conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(p)
+}
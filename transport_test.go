@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/salsadigitalauorg/go-lagoon-log-forwarder/loggertest"
+)
+
+func TestDial_TCP(t *testing.T) {
+	receiver, err := loggertest.Listen(loggertest.TCP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer receiver.Close()
+
+	cfg := NewConfig()
+	cfg.Protocol = ProtocolTCP
+	cfg.LogHost = receiver.Host()
+	cfg.LogPort = receiver.Port()
+
+	conn, err := Dial(cfg)
+	if err != nil {
+		t.Fatalf("Dial() returned unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	// records are separated by the newline the JSON handler ends them with
+	for _, record := range []string{"{\"seq\":1}\n", "{\"seq\":2}\n"} {
+		if _, err := conn.Write([]byte(record)); err != nil {
+			t.Fatalf("Write() returned unexpected error: %v", err)
+		}
+	}
+
+	if !receiver.Wait(2, time.Second) {
+		t.Fatalf("received %d records over tcp, want 2", receiver.Count())
+	}
+}
+
+func TestDial_TCPUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cfg := NewConfig()
+	cfg.Protocol = ProtocolTCP
+	cfg.LogHost = "127.0.0.1"
+	cfg.LogPort = port
+
+	if conn, err := Dial(cfg); err == nil {
+		conn.Close()
+		t.Error("Dial() should fail when nothing listens on the tcp port")
+	}
+}
+
+func TestDeadlineConn(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	conn := &deadlineConn{Conn: client, timeout: 10 * time.Millisecond}
+
+	// nothing reads from the pipe, so the write stalls until the deadline
+	_, err := conn.Write([]byte("stalled"))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Write() = %v, want deadline exceeded", err)
+	}
+}
+
+func TestInitialize_TCP(t *testing.T) {
+	preserveConfig(t)
+	once = sync.Once{}
+	defer Shutdown(context.Background())
+
+	receiver, err := loggertest.Listen(loggertest.TCP)
+	if err != nil {
+		t.Fatalf("Listen() returned unexpected error: %v", err)
+	}
+	defer receiver.Close()
+
+	cfg := NewConfig()
+	cfg.LogType = "tcp-type"
+	cfg.Protocol = ProtocolTCP
+	cfg.LogHost = receiver.Host()
+	cfg.LogPort = receiver.Port()
+	if err := Initialize(cfg); err != nil {
+		t.Fatalf("Initialize() returned unexpected error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		slog.Info("tcp record", "seq", i)
+	}
+
+	if !receiver.Wait(3, time.Second) {
+		t.Fatalf("received %d records over tcp, want 3", receiver.Count())
+	}
+	if got := receiver.Events()[0]["type"]; got != "tcp-type" {
+		t.Errorf("type = %v, want tcp-type", got)
+	}
+}
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// stringPolicy prepares the message and string attributes so Elasticsearch
+// accepts them, whatever user input was echoed into them
+type stringPolicy struct {
+	// normalize is applied to every string, nil leaves them alone
+	normalize func(string) string
+	// maxRunes cuts strings to that many characters, 0 keeps all
+	maxRunes int
+}
+
+func (p stringPolicy) enabled() bool {
+	return p.normalize != nil || p.maxRunes > 0
+}
+
+// apply returns s as valid UTF-8, normalized and cut to the limit
+func (p stringPolicy) apply(s string) string {
+	if !p.enabled() {
+		return s
+	}
+
+	// invalid sequences are replaced like the JSON encoding would, before
+	// they can confuse the normalization or the count
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	}
+	if p.normalize != nil {
+		s = p.normalize(s)
+	}
+	if p.maxRunes > 0 && len(s) > p.maxRunes {
+		s = cutToRunes(s, p.maxRunes)
+	}
+	return s
+}
+
+// applyAttrs returns attrs with their string values prepared, walking groups
+func (p stringPolicy) applyAttrs(attrs []slog.Attr) []slog.Attr {
+	if !p.enabled() {
+		return attrs
+	}
+
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		a.Value = a.Value.Resolve()
+		switch a.Value.Kind() {
+		case slog.KindGroup:
+			a.Value = slog.GroupValue(p.applyAttrs(a.Value.Group())...)
+		case slog.KindString:
+			a.Value = slog.StringValue(p.apply(a.Value.String()))
+		}
+		out[i] = a
+	}
+	return out
+}
+
+// cutToRunes returns the first n runes of s
+func cutToRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
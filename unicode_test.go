@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestStringPolicy(t *testing.T) {
+	// decomposes é, standing in for norm.NFD.String
+	decompose := func(s string) string { return strings.ReplaceAll(s, "é", "é") }
+
+	tests := []struct {
+		name   string
+		policy stringPolicy
+		input  string
+		expect string
+	}{
+		{"disabled keeps invalid UTF-8", stringPolicy{}, "bad\xff", "bad\xff"},
+		{"invalid UTF-8 replaced", stringPolicy{maxRunes: 10}, "bad\xff\xfeend", "bad�end"},
+		{"cut to runes", stringPolicy{maxRunes: 4}, "héllo wörld", "héll"},
+		{"short string kept", stringPolicy{maxRunes: 4}, "hé", "hé"},
+		{"normalized", stringPolicy{normalize: decompose}, "café", "café"},
+		{"normalized before the cut", stringPolicy{normalize: decompose, maxRunes: 4}, "café", "cafe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.apply(tt.input); got != tt.expect {
+				t.Errorf("apply(%q) = %q, want %q", tt.input, got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestHandler_MaxStringRunes(t *testing.T) {
+	preserveConfig(t)
+	maxStringRunes = 5
+
+	var buf bytes.Buffer
+	slog.New(newHandler(&buf)).Info("a long message\xff", slog.Group("context", slog.String("input", "user input")), "count", 123456789)
+
+	out := buf.String()
+	if !utf8.ValidString(out) {
+		t.Errorf("output is not valid UTF-8: %q", out)
+	}
+	for _, want := range []string{`"message":"a lon"`, `"input":"user "`, `"count":123456789`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output = %s, want %s", out, want)
+		}
+	}
+}
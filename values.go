@@ -0,0 +1,299 @@
+package logger
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// fieldsMarker is the key reporting how many fields a value lost to
+// MaxValueFields
+const fieldsMarker = "_truncated"
+
+// valuePolicy controls how arbitrary values passed with slog.Any are encoded.
+// Structs, maps and slices are walked by reflection, keeping exported fields
+// only and bounding their size and depth, so a large or recursive value can't
+// hang the encoder or produce a huge event. A zero policy leaves values to
+// encoding/json.
+type valuePolicy struct {
+	maxFields      int
+	maxDepth       int
+	preferStringer bool
+}
+
+// applyAttrs encodes the Any values of attrs, including those inside groups
+func (p valuePolicy) applyAttrs(attrs []slog.Attr) []slog.Attr {
+	if p == (valuePolicy{}) {
+		return attrs
+	}
+
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		a.Value = a.Value.Resolve()
+		switch a.Value.Kind() {
+		case slog.KindGroup:
+			a.Value = slog.GroupValue(p.applyAttrs(a.Value.Group())...)
+		case slog.KindAny:
+			// errors are written as their message by the JSON handler
+			if _, ok := a.Value.Any().(error); !ok {
+				a.Value = slog.AnyValue(p.encode(reflect.ValueOf(a.Value.Any())))
+			}
+		}
+		out[i] = a
+	}
+	return out
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	stringerType      = reflect.TypeFor[fmt.Stringer]()
+	errorType         = reflect.TypeFor[error]()
+)
+
+// cycleMarker replaces a value found inside itself
+const cycleMarker = "[cycle]"
+
+// encode converts v into a value encoding/json writes within the policy limits
+func (p valuePolicy) encode(v reflect.Value) any {
+	e := &valueEncoder{valuePolicy: p, visiting: map[visit]bool{}}
+	return e.encode(v, 0)
+}
+
+// valueEncoder is the state of encoding a single value
+type valueEncoder struct {
+	valuePolicy
+	// visiting holds the pointers, maps and slices on the path from the root
+	// to the value being encoded. Meeting one again means the value contains
+	// itself, while values shared by siblings are encoded each time.
+	visiting map[visit]bool
+}
+
+// visit identifies a referenced value, slices sharing an array differ in
+// length
+type visit struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// enter marks v as being encoded and reports false when it already is
+func (e *valueEncoder) enter(v reflect.Value) (visit, bool) {
+	key := visit{typ: v.Type(), ptr: v.Pointer()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if e.visiting[key] {
+		return key, false
+	}
+	e.visiting[key] = true
+	return key, true
+}
+
+func (e *valueEncoder) encode(v reflect.Value, depth int) any {
+	p := e.valuePolicy
+	if !v.IsValid() {
+		return nil
+	}
+
+	if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil
+	}
+
+	if v.CanInterface() {
+		switch t := v.Type(); {
+		case t.Implements(jsonMarshalerType), t.Implements(textMarshalerType):
+			// the type chose its own encoding
+			return v.Interface()
+		case t.Implements(errorType):
+			return v.Interface().(error).Error()
+		case p.preferStringer && t.Implements(stringerType):
+			return v.Interface().(fmt.Stringer).String()
+		}
+	}
+
+	if kind := v.Kind(); (kind == reflect.Pointer || kind == reflect.Map || kind == reflect.Slice) && !v.IsNil() {
+		key, ok := e.enter(v)
+		if !ok {
+			return cycleMarker
+		}
+		defer delete(e.visiting, key)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return e.encode(v.Elem(), depth)
+	case reflect.Struct:
+		if p.tooDeep(depth) {
+			return depthMarker
+		}
+		return e.encodeStruct(v, depth)
+	case reflect.Map:
+		if p.tooDeep(depth) {
+			return depthMarker
+		}
+		return e.encodeMap(v, depth)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			// byte slices are base64 encoded like encoding/json does
+			return v.Bytes()
+		}
+		if p.tooDeep(depth) {
+			return depthMarker
+		}
+		return e.encodeSlice(v, depth)
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		// encoding/json can't write these
+		return fmt.Sprintf("[unsupported: %s]", v.Type())
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	}
+
+	return fmt.Sprint(v)
+}
+
+func (p valuePolicy) tooDeep(depth int) bool {
+	return p.maxDepth > 0 && depth >= p.maxDepth
+}
+
+// encodeStruct keeps the exported fields of v under their JSON names,
+// flattening embedded structs like encoding/json does
+func (e *valueEncoder) encodeStruct(v reflect.Value, depth int) any {
+	var object orderedObject
+	e.appendFields(&object, v, depth)
+	return e.truncate(object)
+}
+
+func (e *valueEncoder) appendFields(object *orderedObject, v reflect.Value, depth int) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && len(options) == 0 {
+			continue
+		}
+
+		value := v.Field(i)
+		if field.Anonymous && len(name) == 0 {
+			embedded := value
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				e.appendFields(object, embedded, depth)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if strings.Contains(options, "omitempty") && value.IsZero() {
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		*object = append(*object, objectField{name, e.encode(value, depth+1)})
+	}
+}
+
+// encodeMap keeps the entries of v sorted by key
+func (e *valueEncoder) encodeMap(v reflect.Value, depth int) any {
+	object := make(orderedObject, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		object = append(object, objectField{fmt.Sprint(iter.Key().Interface()), iter.Value()})
+	}
+	sort.Slice(object, func(i, j int) bool { return object[i].key < object[j].key })
+
+	object = e.truncate(object)
+	for i := range object {
+		if value, ok := object[i].value.(reflect.Value); ok {
+			object[i].value = e.encode(value, depth+1)
+		}
+	}
+	return object
+}
+
+func (e *valueEncoder) encodeSlice(v reflect.Value, depth int) any {
+	n := v.Len()
+	if e.maxFields > 0 && n > e.maxFields {
+		n = e.maxFields
+	}
+
+	items := make([]any, 0, n+1)
+	for i := 0; i < n; i++ {
+		items = append(items, e.encode(v.Index(i), depth+1))
+	}
+	if n < v.Len() {
+		items = append(items, fmt.Sprintf("[truncated: %d more]", v.Len()-n))
+	}
+	return items
+}
+
+// truncate keeps the first maxFields fields of object followed by a field
+// counting the ones dropped
+func (p valuePolicy) truncate(object orderedObject) orderedObject {
+	if p.maxFields == 0 || len(object) <= p.maxFields {
+		return object
+	}
+	dropped := len(object) - p.maxFields
+	return append(object[:p.maxFields:p.maxFields], objectField{fieldsMarker, dropped})
+}
+
+// orderedObject is a JSON object whose fields keep their order
+type orderedObject []objectField
+
+type objectField struct {
+	key   string
+	value any
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := encodeJSON(&buf, field.key); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		if err := encodeJSON(&buf, field.value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encodeJSON writes v to buf without escaping HTML, like the slog JSON
+// handler
+func encodeJSON(buf *bytes.Buffer, v any) error {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1) // drop the newline Encode appends
+	return nil
+}
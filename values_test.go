@@ -0,0 +1,207 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type valueInner struct {
+	Name string
+}
+
+type valueEmbedded struct {
+	Embedded string
+}
+
+type valueStruct struct {
+	valueEmbedded
+	ID       int               `json:"id"`
+	Renamed  string            `json:"renamed_field"`
+	Skipped  string            `json:"-"`
+	Empty    string            `json:",omitempty"`
+	Inner    *valueInner       `json:"inner"`
+	Items    []int             `json:"items"`
+	Labels   map[string]string `json:"labels"`
+	Err      error             `json:"err"`
+	Callback func()            `json:"callback"`
+	secret   string
+}
+
+type stringerValue struct{ v int }
+
+func (s stringerValue) String() string { return "stringer" }
+
+// encoded returns the JSON written for v under policy
+func encoded(t *testing.T, policy valuePolicy, v any) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, policy.encode(reflect.ValueOf(v))); err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestValuePolicy_Encode(t *testing.T) {
+	big := valueStruct{
+		valueEmbedded: valueEmbedded{Embedded: "promoted"},
+		ID:            7,
+		Renamed:       "<b>",
+		Skipped:       "hidden",
+		Inner:         &valueInner{Name: "inner"},
+		Items:         []int{1, 2, 3},
+		Labels:        map[string]string{"b": "2", "a": "1"},
+		Err:           errors.New("boom"),
+		secret:        "hidden",
+	}
+
+	tests := []struct {
+		name     string
+		policy   valuePolicy
+		value    any
+		expected string
+	}{
+		{
+			name:     "struct fields",
+			policy:   valuePolicy{maxDepth: 8},
+			value:    big,
+			expected: `{"Embedded":"promoted","id":7,"renamed_field":"<b>","inner":{"Name":"inner"},"items":[1,2,3],"labels":{"a":"1","b":"2"},"err":"boom","callback":"[unsupported: func()]"}`,
+		},
+		{
+			name:     "max fields",
+			policy:   valuePolicy{maxFields: 2},
+			value:    map[string]int{"a": 1, "b": 2, "c": 3, "d": 4},
+			expected: `{"a":1,"b":2,"_truncated":2}`,
+		},
+		{
+			name:     "max elements",
+			policy:   valuePolicy{maxFields: 2},
+			value:    []string{"a", "b", "c"},
+			expected: `["a","b","[truncated: 1 more]"]`,
+		},
+		{
+			name:     "max depth",
+			policy:   valuePolicy{maxDepth: 1},
+			value:    big,
+			expected: `{"Embedded":"promoted","id":7,"renamed_field":"<b>","inner":"[truncated: max depth]","items":"[truncated: max depth]","labels":"[truncated: max depth]","err":"boom","callback":"[unsupported: func()]"}`,
+		},
+		{
+			name:     "stringer ignored by default",
+			policy:   valuePolicy{maxDepth: 8},
+			value:    stringerValue{v: 1},
+			expected: `{}`,
+		},
+		{
+			name:     "stringer preferred",
+			policy:   valuePolicy{preferStringer: true},
+			value:    stringerValue{v: 1},
+			expected: `"stringer"`,
+		},
+		{
+			name:     "marshalers keep their encoding",
+			policy:   valuePolicy{maxDepth: 8, preferStringer: true},
+			value:    []any{net.IPv4(10, 0, 0, 1), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+			expected: `["10.0.0.1","2024-01-02T03:04:05Z"]`,
+		},
+		{
+			name:     "nil pointer",
+			policy:   valuePolicy{maxDepth: 8},
+			value:    (*valueInner)(nil),
+			expected: `null`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encoded(t, tt.policy, tt.value); got != tt.expected {
+				t.Errorf("encode() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValuePolicy_ZeroLeavesValues(t *testing.T) {
+	attrs := []slog.Attr{slog.Any("v", valueInner{Name: "x"})}
+	got := valuePolicy{}.applyAttrs(attrs)
+	if _, ok := got[0].Value.Any().(valueInner); !ok {
+		t.Errorf("zero policy changed the value to %T", got[0].Value.Any())
+	}
+}
+
+func TestHandler_ValuePolicy(t *testing.T) {
+	preserveConfig(t)
+
+	cfg := NewConfig()
+	cfg.LogType = "values-type"
+	cfg.LogHost = "localhost"
+	cfg.MaxValueFields = 2
+
+	var buf bytes.Buffer
+	h, err := NewWriterHandler(cfg, &buf)
+	if err != nil {
+		t.Fatalf("NewWriterHandler() returned unexpected error: %v", err)
+	}
+
+	slog.New(h).WithGroup("request").Info("values", "user", valueStruct{ID: 1, Renamed: "x"}, "error", errors.New("failed"))
+
+	var event struct {
+		Request struct {
+			User  map[string]any `json:"user"`
+			Error string         `json:"error"`
+		} `json:"request"`
+	}
+	if err := json.NewDecoder(strings.NewReader(buf.String())).Decode(&event); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	if event.Request.User["id"] != float64(1) || event.Request.User[fieldsMarker] == nil {
+		t.Errorf("user = %v, want the id field and a truncation marker", event.Request.User)
+	}
+	if event.Request.Error != "failed" {
+		t.Errorf("error = %q, want %q", event.Request.Error, "failed")
+	}
+}
+
+type cycleNode struct {
+	Name string
+	Next *cycleNode
+}
+
+func TestValuePolicy_Cycles(t *testing.T) {
+	node := &cycleNode{Name: "a"}
+	node.Next = &cycleNode{Name: "b", Next: node}
+
+	selfMap := map[string]any{"name": "m"}
+	selfMap["self"] = selfMap
+
+	selfSlice := []any{"s", nil}
+	selfSlice[1] = selfSlice
+
+	shared := &valueInner{Name: "shared"}
+
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{"pointer", node, `{"Name":"a","Next":{"Name":"b","Next":"[cycle]"}}`},
+		{"map", selfMap, `{"name":"m","self":"[cycle]"}`},
+		{"slice", selfSlice, `["s","[cycle]"]`},
+		{"shared values are not cycles", []any{shared, shared}, `[{"Name":"shared"},{"Name":"shared"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// no depth limit, only cycle detection stops the recursion
+			if got := encoded(t, valuePolicy{maxFields: 10}, tt.value); got != tt.expected {
+				t.Errorf("encode() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// maxWriterLine is the longest line a Writer buffers, longer ones are logged
+// in parts
+const maxWriterLine = 64 << 10
+
+// lineWriter logs the lines written to it
+type lineWriter struct {
+	level slog.Level
+	attrs []slog.Attr
+
+	mu      sync.Mutex
+	partial []byte
+}
+
+// Writer returns a writer logging every line written to it as a record at
+// level with attrs, with the default logger, so the output of a command or a
+// library writing to an io.Writer reaches the endpoint as events:
+//
+//	cmd.Stdout = logger.Writer(slog.LevelInfo, slog.String("command", "drush"))
+//	server.ErrorLog = log.New(logger.Writer(slog.LevelError), "", 0)
+//
+// Empty lines are dropped and lines are logged once their newline is
+// written. The writer is safe for concurrent use and implements io.Closer,
+// logging a last line written without a newline.
+func Writer(level slog.Level, attrs ...slog.Attr) io.Writer {
+	return &lineWriter{level: level, attrs: attrs}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data := append(w.partial, p...)
+	for {
+		line, rest, ok := bytes.Cut(data, []byte{'\n'})
+		if !ok {
+			break
+		}
+		w.log(line)
+		data = rest
+	}
+	if len(data) >= maxWriterLine {
+		w.log(data)
+		data = nil
+	}
+	w.partial = append(w.partial[:0], data...)
+	return len(p), nil
+}
+
+// Close logs the line written without a newline, if any
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.log(w.partial)
+	w.partial = nil
+	return nil
+}
+
+// log logs line unless it is empty
+func (w *lineWriter) log(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) == 0 {
+		return
+	}
+	ctx := context.Background()
+	h := slog.Default().Handler()
+	if !h.Enabled(ctx, w.level) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), w.level, string(line), 0)
+	r.AddAttrs(w.attrs...)
+	_ = h.Handle(ctx, r)
+}
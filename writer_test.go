@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// writerEvents returns the events logged by fn with the default logger
+func writerEvents(t *testing.T, fn func()) []map[string]any {
+	t.Helper()
+	preserveConfig(t)
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(newSinkHandler(sink{w: &buf})))
+	defer slog.SetDefault(previous)
+
+	fn()
+
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if len(line) == 0 {
+			continue
+		}
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestWriter(t *testing.T) {
+	events := writerEvents(t, func() {
+		w := Writer(slog.LevelWarn, slog.String("stream", "driver"))
+		io.WriteString(w, "connection reset\r\n\nretry")
+		io.WriteString(w, "ing in 1s\nclosed")
+		w.(io.Closer).Close()
+	})
+
+	var messages []string
+	for _, event := range events {
+		messages = append(messages, event["message"].(string))
+		if event["level"] != "WARN" || event["stream"] != "driver" {
+			t.Errorf("event = %v, want the level and attributes of the writer", event)
+		}
+	}
+	if want := []string{"connection reset", "retrying in 1s", "closed"}; strings.Join(messages, "|") != strings.Join(want, "|") {
+		t.Errorf("messages = %q, want %q", messages, want)
+	}
+}
+
+func TestWriter_LongLine(t *testing.T) {
+	events := writerEvents(t, func() {
+		io.WriteString(Writer(slog.LevelInfo), strings.Repeat("x", maxWriterLine+10))
+	})
+	if len(events) != 1 || len(events[0]["message"].(string)) != maxWriterLine+10 {
+		t.Errorf("got %d events, want a line past the buffer logged without its newline", len(events))
+	}
+}
+
+func TestWriter_Integrations(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	events := writerEvents(t, func() {
+		log.New(Writer(slog.LevelError), "", 0).Print("http: TLS handshake error")
+
+		cmd := exec.Command("sh", "-c", "echo migrated; echo failed >&2")
+		cmd.Stdout = Writer(slog.LevelInfo, slog.String("command", "migrate"))
+		cmd.Stderr = Writer(slog.LevelError, slog.String("command", "migrate"))
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("Run() returned unexpected error: %v", err)
+		}
+	})
+
+	got := map[string]any{}
+	for _, event := range events {
+		got[event["message"].(string)] = event["level"]
+	}
+	want := map[string]any{"http: TLS handshake error": "ERROR", "migrated": "INFO", "failed": "ERROR"}
+	for msg, level := range want {
+		if got[msg] != level {
+			t.Errorf("%q logged at %v, want %v", msg, got[msg], level)
+		}
+	}
+}